@@ -20,6 +20,7 @@ package api
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"sync"
 
 	"github.com/Peripli/service-manager/operations"
@@ -40,6 +41,7 @@ import (
 	"github.com/Peripli/service-manager/api/filters"
 	"github.com/Peripli/service-manager/api/info"
 	"github.com/Peripli/service-manager/api/osb"
+	"github.com/Peripli/service-manager/pkg/brokerclient"
 	"github.com/Peripli/service-manager/pkg/health"
 	"github.com/Peripli/service-manager/pkg/web"
 	"github.com/Peripli/service-manager/storage"
@@ -49,25 +51,36 @@ const osbVersion = "2.13"
 
 // Settings type to be loaded from the environment
 type Settings struct {
-	TokenIssuerURL  string   `mapstructure:"token_issuer_url" description:"url of the token issuer which to use for validating tokens"`
-	ClientID        string   `mapstructure:"client_id" description:"id of the client from which the token must be issued"`
-	TokenBasicAuth  bool     `mapstructure:"token_basic_auth" description:"specifies if client credentials to the authorization server should be sent in the header as basic auth (true) or in the body (false)"`
-	ProtectedLabels []string `mapstructure:"protected_labels" description:"defines labels which cannot be modified/added by REST API requests"`
-	OSBVersion      string   `mapstructure:"-"`
-	MaxPageSize     int      `mapstructure:"max_page_size" description:"maximum number of items that could be returned in a single page"`
-	DefaultPageSize int      `mapstructure:"default_page_size" description:"default number of items returned in a single page if not specified in request"`
+	TokenIssuerURL          string                               `mapstructure:"token_issuer_url" description:"url of the token issuer which to use for validating tokens"`
+	ClientID                string                               `mapstructure:"client_id" description:"id of the client from which the token must be issued"`
+	TokenBasicAuth          bool                                 `mapstructure:"token_basic_auth" description:"specifies if client credentials to the authorization server should be sent in the header as basic auth (true) or in the body (false)"`
+	ProtectedLabels         []string                             `mapstructure:"protected_labels" description:"defines labels which cannot be modified/added by REST API requests"`
+	OSBVersion              string                               `mapstructure:"-"`
+	MaxPageSize             int                                  `mapstructure:"max_page_size" description:"maximum number of items that could be returned in a single page"`
+	DefaultPageSize         int                                  `mapstructure:"default_page_size" description:"default number of items returned in a single page if not specified in request"`
+	SecretFieldsScope       string                               `mapstructure:"secret_fields_scope" description:"scope required to read the secret fields of resources, e.g. the context of service instances. If empty, secret fields are not redacted"`
+	OSBResponseCacheSize    int                                  `mapstructure:"osb_response_cache_size" description:"maximum number of cached instance and binding fetch responses of brokers which enable caching with the osb_response_cache_ttl label. 0 disables caching"`
+	OSBCircuitBreaker       *brokerclient.CircuitBreakerSettings `mapstructure:"osb_circuit_breaker"`
+	OSBRecordingSize        int                                  `mapstructure:"osb_recording_size" description:"maximum number of recorded OSB exchanges of brokers which enable recording with the osb_recording label. 0 disables recording"`
+	OSBReplayHosts          []string                             `mapstructure:"osb_replay_hosts" description:"hosts, optionally with port, to which recorded OSB exchanges may be replayed instead of their brokers, e.g. local stubs of brokers"`
+	StrictCatalogValidation bool                                 `mapstructure:"strict_catalog_validation" description:"specifies if broker catalogs with warnings, e.g. services without description, are rejected on broker registration and update"`
 }
 
 // DefaultSettings returns default values for API settings
 func DefaultSettings() *Settings {
 	return &Settings{
-		TokenIssuerURL:  "",
-		ClientID:        "",
-		TokenBasicAuth:  true, // RFC 6749 section 2.3.1
-		OSBVersion:      osbVersion,
-		MaxPageSize:     200,
-		DefaultPageSize: 50,
-		ProtectedLabels: []string{},
+		TokenIssuerURL:       "",
+		ClientID:             "",
+		TokenBasicAuth:       true, // RFC 6749 section 2.3.1
+		OSBVersion:           osbVersion,
+		MaxPageSize:          200,
+		DefaultPageSize:      50,
+		ProtectedLabels:      []string{},
+		SecretFieldsScope:    "",
+		OSBResponseCacheSize: 1000,
+		OSBCircuitBreaker:    brokerclient.DefaultCircuitBreakerSettings(),
+		OSBRecordingSize:     100,
+		OSBReplayHosts:       []string{},
 	}
 }
 
@@ -76,21 +89,49 @@ func (s *Settings) Validate() error {
 	if (len(s.TokenIssuerURL)) == 0 {
 		return fmt.Errorf("validate Settings: APITokenIssuerURL missing")
 	}
-	return nil
+	if s.OSBResponseCacheSize < 0 {
+		return fmt.Errorf("validate Settings: APIOSBResponseCacheSize must not be negative")
+	}
+	if s.OSBRecordingSize < 0 {
+		return fmt.Errorf("validate Settings: APIOSBRecordingSize must not be negative")
+	}
+	return s.OSBCircuitBreaker.Validate()
 }
 
 type Options struct {
 	Repository        storage.TransactionalRepository
+	Schemas           storage.SchemaProvider
 	APISettings       *Settings
 	OperationSettings *operations.Settings
 	WSSettings        *ws.Settings
 	Notificator       storage.Notificator
 	WaitGroup         *sync.WaitGroup
+	// OSBCircuitBreakers guards the calls of the OSB API to the brokers. Guarding is disabled if nil.
+	OSBCircuitBreakers *brokerclient.CircuitBreakers
+	// BrokerAuthenticator authenticates the calls of the OSB API to the brokers. Only basic credentials of brokers are used if nil.
+	BrokerAuthenticator *brokerclient.BrokerAuthenticator
 }
 
 // New returns the minimum set of REST APIs needed for the Service Manager
 func New(ctx context.Context, e env.Environment, options *Options) (*web.API, error) {
-	return &web.API{
+	var osbResponseCache *osb.ResponseCache
+	if options.APISettings.OSBResponseCacheSize > 0 {
+		osbResponseCache = osb.NewResponseCache(options.APISettings.OSBResponseCacheSize)
+	}
+	brokerFetcher := func(ctx context.Context, brokerID string) (*types.ServiceBroker, error) {
+		byID := query.ByField(query.EqualsOperator, "id", brokerID)
+		br, err := options.Repository.Get(ctx, types.ServiceBrokerType, byID)
+		if err != nil {
+			return nil, util.HandleStorageError(err, "broker")
+		}
+		return br.(*types.ServiceBroker), nil
+	}
+	var osbRecorder *osb.Recorder
+	if options.APISettings.OSBRecordingSize > 0 {
+		osbRecorder = osb.NewRecorder(options.APISettings.OSBRecordingSize)
+	}
+
+	webAPI := &web.API{
 		// Default controllers - more filters can be registered using the relevant API methods
 		Controllers: []web.Controller{
 			NewAsyncController(ctx, options, web.ServiceBrokersURL, types.ServiceBrokerType, false, func() types.Object {
@@ -102,6 +143,15 @@ func New(ctx context.Context, e env.Environment, options *Options) (*web.API, er
 			NewController(ctx, options, web.VisibilitiesURL, types.VisibilityType, func() types.Object {
 				return &types.Visibility{}
 			}),
+			NewController(ctx, options, web.SavedQueriesURL, types.SavedQueryType, func() types.Object {
+				return &types.SavedQuery{}
+			}),
+			NewController(ctx, options, web.LabelDefinitionsURL, types.LabelDefinitionType, func() types.Object {
+				return &types.LabelDefinition{}
+			}),
+			NewController(ctx, options, web.TransformationRulesURL, types.TransformationRuleType, func() types.Object {
+				return &types.TransformationRule{}
+			}),
 			NewServiceInstanceController(ctx, options),
 			NewServiceBindingController(ctx, options),
 			apiNotifications.NewController(ctx, options.Repository, options.WSSettings, options.Notificator),
@@ -114,14 +164,11 @@ func New(ctx context.Context, e env.Environment, options *Options) (*web.API, er
 				TokenBasicAuth: options.APISettings.TokenBasicAuth,
 			},
 			&osb.Controller{
-				BrokerFetcher: func(ctx context.Context, brokerID string) (*types.ServiceBroker, error) {
-					byID := query.ByField(query.EqualsOperator, "id", brokerID)
-					br, err := options.Repository.Get(ctx, types.ServiceBrokerType, byID)
-					if err != nil {
-						return nil, util.HandleStorageError(err, "broker")
-					}
-					return br.(*types.ServiceBroker), nil
-				},
+				BrokerFetcher:       brokerFetcher,
+				ResponseCache:       osbResponseCache,
+				CircuitBreakers:     options.OSBCircuitBreakers,
+				BrokerAuthenticator: options.BrokerAuthenticator,
+				Recorder:            osbRecorder,
 			},
 			&configuration.Controller{
 				Environment: e,
@@ -131,18 +178,31 @@ func New(ctx context.Context, e env.Environment, options *Options) (*web.API, er
 		// Default filters - more filters can be registered using the relevant API methods
 		Filters: []web.Filter{
 			&filters.Logging{},
-			&filters.SelectionCriteria{},
+			&filters.SelectionCriteria{Repository: options.Repository, Schemas: options.Schemas},
 			filters.NewProtectedLabelsFilter(options.APISettings.ProtectedLabels),
 			&filters.ProtectedSMPlatformFilter{},
 			&filters.ServiceInstanceFilter{},
 			&filters.ServiceInstanceStripFilter{},
 			&filters.ServiceBindingStripFilter{},
+			&filters.ServiceBrokerStripFilter{},
 			&filters.PlatformAwareVisibilityFilter{},
 			&filters.PatchOnlyLabelsFilter{},
 			filters.NewPlansFilterByVisibility(options.Repository),
 			filters.NewServicesFilterByVisibility(options.Repository),
+			filters.NewParametersSchemaFilter(options.Repository),
 			&filters.CheckBrokerCredentialsFilter{},
+			&filters.SavedQueriesFilter{Schemas: options.Schemas},
 		},
 		Registry: health.NewDefaultRegistry(),
-	}, nil
+	}
+	if osbRecorder != nil {
+		webAPI.Controllers = append(webAPI.Controllers, &osb.RecordingsController{
+			Recorder:            osbRecorder,
+			BrokerFetcher:       brokerFetcher,
+			BrokerAuthenticator: options.BrokerAuthenticator,
+			DoRequest:           http.DefaultClient.Do,
+			ReplayHosts:         options.APISettings.OSBReplayHosts,
+		})
+	}
+	return webAPI, nil
 }
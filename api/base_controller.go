@@ -34,6 +34,7 @@ import (
 
 	"github.com/Peripli/service-manager/pkg/log"
 	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/security/http/authz"
 	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/Peripli/service-manager/pkg/util"
 	"github.com/Peripli/service-manager/pkg/web"
@@ -51,6 +52,7 @@ type BaseController struct {
 	resourceBaseURL string
 	objectType      types.ObjectType
 	repository      storage.Repository
+	schemas         storage.SchemaProvider
 	objectBlueprint func() types.Object
 
 	DefaultPageSize int
@@ -58,6 +60,9 @@ type BaseController struct {
 
 	supportsAsync  bool
 	isAsyncDefault bool
+
+	// secretFieldsScope is the scope required to read the secret fields of the objects
+	secretFieldsScope string
 }
 
 // NewController returns a new base controller
@@ -70,13 +75,15 @@ func NewController(ctx context.Context, options *Options, resourceBaseURL string
 		}
 	}
 	controller := &BaseController{
-		repository:      options.Repository,
-		resourceBaseURL: resourceBaseURL,
-		objectBlueprint: objectBlueprint,
-		objectType:      objectType,
-		DefaultPageSize: options.APISettings.DefaultPageSize,
-		MaxPageSize:     options.APISettings.MaxPageSize,
-		scheduler:       operations.NewScheduler(ctx, options.Repository, options.OperationSettings, poolSize, options.WaitGroup),
+		repository:        options.Repository,
+		schemas:           options.Schemas,
+		resourceBaseURL:   resourceBaseURL,
+		objectBlueprint:   objectBlueprint,
+		objectType:        objectType,
+		DefaultPageSize:   options.APISettings.DefaultPageSize,
+		MaxPageSize:       options.APISettings.MaxPageSize,
+		secretFieldsScope: options.APISettings.SecretFieldsScope,
+		scheduler:         operations.NewScheduler(ctx, options.Repository, options.OperationSettings, poolSize, options.WaitGroup),
 	}
 
 	return controller
@@ -101,6 +108,13 @@ func (c *BaseController) Routes() []web.Route {
 			},
 			Handler: c.CreateObject,
 		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   c.resourceBaseURL + web.SchemaURL,
+			},
+			Handler: c.GetSchema,
+		},
 		{
 			Endpoint: web.Endpoint{
 				Method: http.MethodGet,
@@ -313,7 +327,7 @@ func (c *BaseController) GetSingleObject(r *web.Request) (*web.Response, error)
 		return nil, util.HandleStorageError(err, c.objectType.String())
 	}
 
-	cleanObject(ctx, object)
+	c.cleanObject(ctx, object)
 	displayOp := r.URL.Query().Get(web.QueryParamLastOp)
 	if displayOp == "true" {
 		if err := attachLastOperation(ctx, objectID, object, r, c.repository); err != nil {
@@ -324,6 +338,30 @@ func (c *BaseController) GetSingleObject(r *web.Request) (*web.Response, error)
 	return util.NewJSONResponse(http.StatusOK, object)
 }
 
+// GetSchema handles the fetching of the field schema of the object type
+func (c *BaseController) GetSchema(r *web.Request) (*web.Response, error) {
+	if c.schemas == nil {
+		return nil, &util.HTTPError{
+			ErrorType:   "NotFound",
+			Description: fmt.Sprintf("schema of %s is not available", c.objectType),
+			StatusCode:  http.StatusNotFound,
+		}
+	}
+
+	log.C(r.Context()).Debugf("Getting schema of %s", c.objectType)
+	schema, err := c.schemas.Schema(c.objectType)
+	if err != nil {
+		log.C(r.Context()).Debugf("Could not get schema of %s: %s", c.objectType, err)
+		return nil, &util.HTTPError{
+			ErrorType:   "NotFound",
+			Description: fmt.Sprintf("schema of %s is not available", c.objectType),
+			StatusCode:  http.StatusNotFound,
+		}
+	}
+
+	return util.NewJSONResponse(http.StatusOK, schema)
+}
+
 // GetOperation handles the fetching of a single operation with the id specified for the specified resource
 func (c *BaseController) GetOperation(r *web.Request) (*web.Response, error) {
 	objectID := r.PathParams[web.PathParamResourceID]
@@ -375,22 +413,35 @@ func (c *BaseController) ListObjects(r *web.Request) (*web.Response, error) {
 	}
 
 	rawToken := r.URL.Query().Get("token")
-	pagingSequence, err := c.parsePageToken(ctx, rawToken)
-	if err != nil {
-		return nil, err
+	if isSearch(criteria) {
+		// search results are ordered by relevance which cannot be continued by paging sequence, so only the most
+		// relevant items are returned without a token for the next page
+		if rawToken != "" {
+			return nil, &util.HTTPError{
+				ErrorType:   "TokenInvalid",
+				Description: "Paging tokens are not supported for searches.",
+				StatusCode:  http.StatusBadRequest,
+			}
+		}
+		criteria = append(criteria, query.LimitResultBy(limit),
+			query.OrderResultBy("paging_sequence", query.AscOrder))
+	} else {
+		pagingSequence, err := c.parsePageToken(ctx, rawToken)
+		if err != nil {
+			return nil, err
+		}
+		criteria = append(criteria, query.LimitResultBy(limit+pagingLimitOffset),
+			query.OrderResultBy("paging_sequence", query.AscOrder),
+			query.ByField(query.GreaterThanOperator, "paging_sequence", pagingSequence))
 	}
 
-	criteria = append(criteria, query.LimitResultBy(limit+pagingLimitOffset),
-		query.OrderResultBy("paging_sequence", query.AscOrder),
-		query.ByField(query.GreaterThanOperator, "paging_sequence", pagingSequence))
-
 	log.C(ctx).Debugf("Getting a page of %ss", c.objectType)
 	objectList, err := c.repository.List(ctx, c.objectType, criteria...)
 	if err != nil {
 		return nil, util.HandleStorageError(err, c.objectType.String())
 	}
 
-	page := pageFromObjectList(ctx, objectList, count, limit)
+	page := c.pageFromObjectList(ctx, objectList, count, limit)
 	resp, err := util.NewJSONResponse(http.StatusOK, page)
 	if err != nil {
 		return nil, err
@@ -491,16 +542,37 @@ func (c *BaseController) PatchObject(r *web.Request) (*web.Response, error) {
 		return nil, util.HandleStorageError(err, c.objectType.String())
 	}
 
-	cleanObject(ctx, object)
+	c.cleanObject(ctx, object)
 	return util.NewJSONResponse(http.StatusOK, object)
 }
 
-func cleanObject(ctx context.Context, object types.Object) {
+func (c *BaseController) cleanObject(ctx context.Context, object types.Object) {
 	if secured, ok := object.(types.Strip); ok {
 		secured.Sanitize()
 	} else {
 		log.C(ctx).Debugf("Object of type %s with id %s is not secured, so no credentials are cleaned up on response", object.GetType(), object.GetID())
 	}
+
+	if !c.canReadSecretFields(ctx) && types.RedactSecretFields(object) {
+		log.C(ctx).Debugf("Secret fields of object of type %s with id %s are redacted on response", object.GetType(), object.GetID())
+	}
+}
+
+// canReadSecretFields checks whether the user has the scope required to read secret fields
+func (c *BaseController) canReadSecretFields(ctx context.Context) bool {
+	if c.secretFieldsScope == "" {
+		return true
+	}
+	user, found := web.UserFromContext(ctx)
+	if !found {
+		return false
+	}
+	hasScope, err := authz.HasScope(user, c.secretFieldsScope)
+	if err != nil {
+		log.C(ctx).WithError(err).Debug("Could not check if user can read secret fields")
+		return false
+	}
+	return hasScope
 }
 
 func attachLastOperation(ctx context.Context, objectID string, object types.Object, r *web.Request, repository storage.Repository) error {
@@ -609,12 +681,21 @@ func (c *BaseController) checkAsyncSupport() error {
 	return nil
 }
 
+func isSearch(criteria []query.Criterion) bool {
+	for _, criterion := range criteria {
+		if criterion.Type == query.SearchQuery {
+			return true
+		}
+	}
+	return false
+}
+
 func generateTokenForItem(obj types.Object) string {
 	nextPageToken := obj.GetPagingSequence()
 	return base64.StdEncoding.EncodeToString([]byte(strconv.FormatInt(nextPageToken, 10)))
 }
 
-func pageFromObjectList(ctx context.Context, objectList types.ObjectList, count, limit int) *types.ObjectPage {
+func (c *BaseController) pageFromObjectList(ctx context.Context, objectList types.ObjectList, count, limit int) *types.ObjectPage {
 	page := &types.ObjectPage{
 		ItemsCount: count,
 		Items:      make([]types.Object, 0, objectList.Len()),
@@ -622,7 +703,7 @@ func pageFromObjectList(ctx context.Context, objectList types.ObjectList, count,
 
 	for i := 0; i < objectList.Len(); i++ {
 		obj := objectList.ItemAt(i)
-		cleanObject(ctx, obj)
+		c.cleanObject(ctx, obj)
 		page.Items = append(page.Items, obj)
 	}
 
@@ -0,0 +1,181 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package changes
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const (
+	sinceQueryParam    = "since"
+	resourceQueryParam = "resource"
+	waitQueryParam     = "wait"
+	maxItemsQueryParam = "max_items"
+
+	// recheckInterval is the interval in which waiting requests look for changes without being notified, as
+	// notifications might be lost while the connection for changes is re-established
+	recheckInterval = time.Second
+)
+
+// Controller serves the changes of all resources recorded by the storage. Requests wait for new changes if there are
+// none since the requested revision.
+type Controller struct {
+	ChangeFeed storage.ChangeFeed
+	// MaxWait is the maximum time a request waits for new changes. It must be lower than the request timeout of the server.
+	MaxWait         time.Duration
+	DefaultPageSize int
+	MaxPageSize     int
+}
+
+type changesResponse struct {
+	Changes []*types.Change `json:"changes"`
+	// Revision is the revision to request the next changes since
+	Revision int64 `json:"revision"`
+}
+
+var _ web.Controller = &Controller{}
+
+// Routes implements web.Controller
+func (c *Controller) Routes() []web.Route {
+	return []web.Route{
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   web.ChangesURL,
+			},
+			Handler: c.listChanges,
+		},
+	}
+}
+
+func (c *Controller) listChanges(req *web.Request) (*web.Response, error) {
+	ctx := req.Context()
+	params := req.URL.Query()
+
+	since, err := c.parseSince(params.Get(sinceQueryParam))
+	if err != nil {
+		return nil, err
+	}
+	wait, err := c.parseWait(params.Get(waitQueryParam))
+	if err != nil {
+		return nil, err
+	}
+	limit, err := c.parseLimit(params.Get(maxItemsQueryParam))
+	if err != nil {
+		return nil, err
+	}
+	var resourceTypes []types.ObjectType
+	for _, resources := range params[resourceQueryParam] {
+		for _, resource := range strings.Split(resources, ",") {
+			if resource = strings.TrimSpace(resource); len(resource) != 0 {
+				resourceTypes = append(resourceTypes, types.ObjectType(resource))
+			}
+		}
+	}
+
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+	for {
+		// the channel must be obtained before listing, so that changes recorded in between are not missed
+		changed := c.ChangeFeed.Changed()
+		changes, err := c.ChangeFeed.ListChanges(ctx, since, resourceTypes, limit)
+		if err != nil {
+			return nil, util.HandleStorageError(err, "changes")
+		}
+		if len(changes) != 0 || wait == 0 {
+			return respond(since, changes)
+		}
+
+		select {
+		case <-changed:
+		case <-time.After(recheckInterval):
+		case <-deadline.C:
+			return respond(since, changes)
+		case <-ctx.Done():
+			log.C(ctx).Debug("Request cancelled while waiting for changes")
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func respond(since int64, changes []*types.Change) (*web.Response, error) {
+	revision := since
+	if len(changes) != 0 {
+		revision = changes[len(changes)-1].Revision
+	}
+	return util.NewJSONResponse(http.StatusOK, &changesResponse{
+		Changes:  changes,
+		Revision: revision,
+	})
+}
+
+func (c *Controller) parseSince(value string) (int64, error) {
+	if len(value) == 0 {
+		return 0, nil
+	}
+	since, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || since < 0 {
+		return 0, badRequest("%s should be a non-negative integer", sinceQueryParam)
+	}
+	return since, nil
+}
+
+func (c *Controller) parseWait(value string) (time.Duration, error) {
+	if len(value) == 0 {
+		return 0, nil
+	}
+	wait, err := time.ParseDuration(value)
+	if err != nil || wait < 0 {
+		return 0, badRequest("%s should be a non-negative duration, e.g. 10s", waitQueryParam)
+	}
+	if wait > c.MaxWait {
+		wait = c.MaxWait
+	}
+	return wait, nil
+}
+
+func (c *Controller) parseLimit(value string) (int, error) {
+	if len(value) == 0 {
+		return c.DefaultPageSize, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 {
+		return 0, badRequest("%s should be a positive integer", maxItemsQueryParam)
+	}
+	if limit > c.MaxPageSize {
+		limit = c.MaxPageSize
+	}
+	return limit, nil
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &util.HTTPError{
+		ErrorType:   "BadRequest",
+		Description: fmt.Sprintf(format, args...),
+		StatusCode:  http.StatusBadRequest,
+	}
+}
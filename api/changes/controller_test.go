@@ -0,0 +1,206 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package changes_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Peripli/service-manager/api/changes"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestChanges(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Changes API Suite")
+}
+
+type fakeChangeFeed struct {
+	mutex         sync.Mutex
+	changes       []*types.Change
+	changed       chan struct{}
+	resourceTypes []types.ObjectType
+	limit         int
+	// deletedRevision is the highest revision of the deleted changes
+	deletedRevision int64
+}
+
+func (f *fakeChangeFeed) ListChanges(_ context.Context, since int64, resourceTypes []types.ObjectType, limit int) ([]*types.Change, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.resourceTypes = resourceTypes
+	f.limit = limit
+	if since < f.deletedRevision {
+		return nil, &util.ErrChangesDeleted{Revision: f.deletedRevision}
+	}
+	result := make([]*types.Change, 0)
+	for _, change := range f.changes {
+		if change.Revision > since && len(result) < limit {
+			result = append(result, change)
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeChangeFeed) Changed() <-chan struct{} {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.changed
+}
+
+func (f *fakeChangeFeed) DeleteChanges(context.Context, time.Time) error {
+	return nil
+}
+
+func (f *fakeChangeFeed) record(change *types.Change) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.changes = append(f.changes, change)
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+type changesResponse struct {
+	Changes  []*types.Change `json:"changes"`
+	Revision int64           `json:"revision"`
+}
+
+var _ = Describe("Changes API", func() {
+	var feed *fakeChangeFeed
+	var controller *changes.Controller
+
+	listChanges := func(query string) (*web.Response, error) {
+		route := controller.Routes()[0]
+		request := &web.Request{Request: httptest.NewRequest(http.MethodGet, web.ChangesURL+"?"+query, nil)}
+		return route.Handler(request)
+	}
+
+	parse := func(response *web.Response) *changesResponse {
+		result := &changesResponse{}
+		Expect(json.Unmarshal(response.Body, result)).To(Succeed())
+		return result
+	}
+
+	BeforeEach(func() {
+		feed = &fakeChangeFeed{
+			changed: make(chan struct{}),
+			changes: []*types.Change{
+				{Revision: 1, ResourceType: types.ServiceInstanceType, ResourceID: "instance", Operation: types.CREATE},
+				{Revision: 2, ResourceType: types.ServiceBindingType, ResourceID: "binding", Operation: types.CREATE},
+			},
+		}
+		controller = &changes.Controller{
+			ChangeFeed:      feed,
+			MaxWait:         time.Minute,
+			DefaultPageSize: 50,
+			MaxPageSize:     100,
+		}
+	})
+
+	Describe("Routes", func() {
+		It("returns one route", func() {
+			routes := controller.Routes()
+			Expect(routes).To(HaveLen(1))
+			Expect(routes[0].Endpoint.Path).To(Equal(web.ChangesURL))
+			Expect(routes[0].Endpoint.Method).To(Equal(http.MethodGet))
+		})
+	})
+
+	Context("when changes since the revision were deleted", func() {
+		It("returns 410 Gone with the revision to resynchronize from", func() {
+			feed.deletedRevision = 1
+			_, err := listChanges("since=0")
+			Expect(err).To(HaveOccurred())
+			httpErr, ok := err.(*util.HTTPError)
+			Expect(ok).To(BeTrue())
+			Expect(httpErr.StatusCode).To(Equal(http.StatusGone))
+			Expect(httpErr.Description).To(ContainSubstring("since revision 1"))
+		})
+	})
+
+	Context("when there are changes since the revision", func() {
+		It("returns them together with the revision of the last change", func() {
+			response, err := listChanges("since=1")
+			Expect(err).ToNot(HaveOccurred())
+			result := parse(response)
+			Expect(result.Changes).To(HaveLen(1))
+			Expect(result.Changes[0].ResourceID).To(Equal("binding"))
+			Expect(result.Revision).To(Equal(int64(2)))
+		})
+
+		It("passes the requested resource types", func() {
+			_, err := listChanges("resource=" + types.ServiceInstanceType.String() + "," + types.ServiceBindingType.String())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(feed.resourceTypes).To(ConsistOf(types.ServiceInstanceType, types.ServiceBindingType))
+		})
+
+		It("limits the number of changes to the maximum page size", func() {
+			_, err := listChanges("max_items=1000")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(feed.limit).To(Equal(100))
+		})
+	})
+
+	Context("when there are no changes since the revision", func() {
+		It("returns immediately if no wait is requested", func() {
+			response, err := listChanges("since=2")
+			Expect(err).ToNot(HaveOccurred())
+			result := parse(response)
+			Expect(result.Changes).To(BeEmpty())
+			Expect(result.Revision).To(Equal(int64(2)))
+		})
+
+		It("waits for new changes", func() {
+			go func() {
+				defer GinkgoRecover()
+				time.Sleep(100 * time.Millisecond)
+				feed.record(&types.Change{Revision: 3, ResourceType: types.PlatformType, ResourceID: "platform", Operation: types.UPDATE})
+			}()
+			response, err := listChanges("since=2&wait=10s")
+			Expect(err).ToNot(HaveOccurred())
+			result := parse(response)
+			Expect(result.Changes).To(HaveLen(1))
+			Expect(result.Revision).To(Equal(int64(3)))
+		})
+
+		It("returns no changes once the wait is over", func() {
+			controller.MaxWait = 100 * time.Millisecond
+			response, err := listChanges("since=2&wait=10s")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parse(response).Changes).To(BeEmpty())
+		})
+	})
+
+	Context("with invalid parameters", func() {
+		It("returns bad request", func() {
+			for _, query := range []string{"since=abc", "since=-1", "wait=forever", "max_items=0"} {
+				_, err := listChanges(query)
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusBadRequest))
+			}
+		})
+	})
+})
@@ -0,0 +1,81 @@
+package configuration
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// EncryptionKeysResponse describes the active encryption key
+type EncryptionKeysResponse struct {
+	ActiveKeyID string `json:"active_key_id"`
+}
+
+// EncryptionKeysController rotates the encryption key used for credentials
+type EncryptionKeysController struct {
+	ctx         context.Context
+	keys        *storage.EncryptionKeys
+	reencrypter *storage.CredentialsReencrypter
+	wg          *sync.WaitGroup
+}
+
+// NewEncryptionKeysController returns a controller for the encryption keys. The credentials are re-encrypted
+// with the reencrypter in the background after each rotation.
+func NewEncryptionKeysController(ctx context.Context, keys *storage.EncryptionKeys, reencrypter *storage.CredentialsReencrypter, wg *sync.WaitGroup) *EncryptionKeysController {
+	return &EncryptionKeysController{
+		ctx:         ctx,
+		keys:        keys,
+		reencrypter: reencrypter,
+		wg:          wg,
+	}
+}
+
+func (c *EncryptionKeysController) getEncryptionKeys(r *web.Request) (*web.Response, error) {
+	log.C(r.Context()).Debug("Obtaining active encryption key...")
+
+	return util.NewJSONResponse(http.StatusOK, &EncryptionKeysResponse{
+		ActiveKeyID: c.keys.ActiveKeyID(),
+	})
+}
+
+func (c *EncryptionKeysController) rotateEncryptionKey(r *web.Request) (*web.Response, error) {
+	ctx := r.Context()
+	log.C(ctx).Info("Attempting to rotate encryption key")
+
+	keyID, err := c.keys.Rotate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the re-encryption outlives the request so it runs with the context of the application
+	c.reencrypter.Start(c.ctx, c.wg)
+
+	return util.NewJSONResponse(http.StatusAccepted, &EncryptionKeysResponse{
+		ActiveKeyID: keyID,
+	})
+}
+
+// Routes provides endpoints for obtaining and rotating the encryption key
+func (c *EncryptionKeysController) Routes() []web.Route {
+	return []web.Route{
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   web.EncryptionKeysURL,
+			},
+			Handler: c.getEncryptionKeys,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPost,
+				Path:   web.EncryptionKeysURL,
+			},
+			Handler: c.rotateEncryptionKey,
+		},
+	}
+}
@@ -55,6 +55,13 @@ func Register(ctx context.Context, cfg *config.Settings, smb *sm.ServiceManagerB
 		web.ServiceBindingsURL+"/**",
 		web.ConfigURL+"/**",
 		web.ProfileURL+"/**",
+		web.ChangesURL+"/**",
+		web.SavedQueriesURL+"/**",
+		web.LabelDefinitionsURL+"/**",
+		web.TransformationRulesURL+"/**",
+		web.OSBRecordingsURL+"/**",
+		web.OutdatedInstancesURL+"/**",
+		web.ServiceInstanceUpgradesURL+"/**",
 	).
 		Method(http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete).
 		WithAuthentication(bearerAuthenticator).Required()
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package filters
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// GlobalAccessFilterName is the name of the global access filter
+const GlobalAccessFilterName = "GlobalAccessFilter"
+
+// GlobalAccessFilter denies the requests of tenants to the endpoints which expose the resources of all tenants.
+// Such endpoints can be accessed only with global access or by requests without a tenant.
+type GlobalAccessFilter struct {
+	ExtractTenant func(request *web.Request) (string, error)
+}
+
+// Name implements web.Named and returns the filter name
+func (f *GlobalAccessFilter) Name() string {
+	return GlobalAccessFilterName
+}
+
+// Run implements web.Middleware and denies the request if it is made by a tenant without global access
+func (f *GlobalAccessFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	user, found := web.UserFromContext(req.Context())
+	if !found || user.AccessLevel == web.GlobalAccess {
+		return next.Handle(req)
+	}
+
+	tenant, err := f.ExtractTenant(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(tenant) != 0 {
+		return nil, &util.HTTPError{
+			ErrorType:   "Forbidden",
+			Description: fmt.Sprintf("access to %s requires global access", req.URL.Path),
+			StatusCode:  http.StatusForbidden,
+		}
+	}
+	return next.Handle(req)
+}
+
+// FilterMatchers implements web.Filter.FilterMatchers and specifies that the filter runs on the endpoints which
+// expose the resources of all tenants
+func (f *GlobalAccessFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path(
+					web.ChangesURL+"/**",
+					web.LabelDefinitionsURL+"/**",
+					web.EncryptionKeysURL+"/**",
+					web.TransformationRulesURL+"/**",
+					web.OSBRecordingsURL+"/**",
+					web.OutdatedInstancesURL+"/**",
+					web.ServiceInstanceUpgradesURL+"/**",
+				),
+			},
+		},
+	}
+}
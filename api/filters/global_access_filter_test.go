@@ -0,0 +1,124 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package filters_test
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Peripli/service-manager/api/filters"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/pkg/web/webfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GlobalAccessFilter", func() {
+	var fakeHandler *webfakes.FakeHandler
+	var request *web.Request
+	var filter *filters.GlobalAccessFilter
+	var tenant string
+
+	withUser := func(accessLevel web.AccessLevel) {
+		request.Request = request.WithContext(web.ContextWithUser(context.Background(), &web.UserContext{
+			AuthenticationType: web.Bearer,
+			Name:               "test",
+			AccessLevel:        accessLevel,
+		}))
+	}
+
+	BeforeEach(func() {
+		fakeHandler = &webfakes.FakeHandler{}
+		req, err := http.NewRequest(http.MethodGet, "http://example.com"+web.ChangesURL, nil)
+		Expect(err).ToNot(HaveOccurred())
+		request = &web.Request{Request: req}
+		tenant = "tenantID"
+		filter = &filters.GlobalAccessFilter{
+			ExtractTenant: func(*web.Request) (string, error) {
+				return tenant, nil
+			},
+		}
+	})
+
+	Context("when the request is made by a tenant", func() {
+		It("denies the request", func() {
+			withUser(web.TenantAccess)
+			_, err := filter.Run(request, fakeHandler)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusForbidden))
+			Expect(fakeHandler.HandleCallCount()).To(Equal(0))
+		})
+	})
+
+	Context("when the request is made with global access", func() {
+		It("proceeds with the next in chain", func() {
+			withUser(web.GlobalAccess)
+			_, err := filter.Run(request, fakeHandler)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeHandler.HandleCallCount()).To(Equal(1))
+		})
+	})
+
+	Context("when the request has no tenant", func() {
+		It("proceeds with the next in chain", func() {
+			tenant = ""
+			withUser(web.TenantAccess)
+			_, err := filter.Run(request, fakeHandler)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeHandler.HandleCallCount()).To(Equal(1))
+		})
+	})
+
+	DescribeTable("denies tenants access to the endpoints of global resources",
+		func(method, path string) {
+			matched := false
+			for _, filterMatcher := range filter.FilterMatchers() {
+				matchesAll := true
+				for _, matcher := range filterMatcher.Matchers {
+					match, err := matcher.Matches(web.Endpoint{Method: method, Path: path})
+					Expect(err).ToNot(HaveOccurred())
+					matchesAll = matchesAll && match
+				}
+				matched = matched || matchesAll
+			}
+			Expect(matched).To(BeTrue())
+
+			req, err := http.NewRequest(method, "http://example.com"+path, nil)
+			Expect(err).ToNot(HaveOccurred())
+			request = &web.Request{Request: req}
+			withUser(web.TenantAccess)
+			_, err = filter.Run(request, fakeHandler)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusForbidden))
+			Expect(fakeHandler.HandleCallCount()).To(Equal(0))
+		},
+		Entry("changes", http.MethodGet, web.ChangesURL),
+		Entry("label definitions", http.MethodPost, web.LabelDefinitionsURL),
+		Entry("label definition", http.MethodPatch, web.LabelDefinitionsURL+"/{"+web.PathParamID+"}"),
+		Entry("encryption keys", http.MethodPost, web.EncryptionKeysURL),
+		Entry("transformation rules", http.MethodPost, web.TransformationRulesURL),
+		Entry("transformation rule", http.MethodDelete, web.TransformationRulesURL+"/{"+web.PathParamID+"}"),
+		Entry("OSB recordings", http.MethodGet, web.OSBRecordingsURL),
+		Entry("OSB recording replay", http.MethodPost, web.OSBRecordingsURL+"/{"+web.PathParamID+"}/replay"),
+		Entry("outdated service instances", http.MethodGet, web.OutdatedInstancesURL),
+		Entry("service instance upgrades", http.MethodPost, web.ServiceInstanceUpgradesURL),
+		Entry("service instance upgrade pause", http.MethodPost, web.ServiceInstanceUpgradesURL+"/{"+web.PathParamID+"}/pause"),
+	)
+})
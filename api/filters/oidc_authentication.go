@@ -56,6 +56,13 @@ func oidcAuthnMatchers() []web.FilterMatcher {
 					web.ServiceInstancesURL+"/**",
 					web.ConfigURL+"/**",
 					web.ProfileURL+"/**",
+					web.ChangesURL+"/**",
+					web.SavedQueriesURL+"/**",
+					web.LabelDefinitionsURL+"/**",
+					web.TransformationRulesURL+"/**",
+					web.OSBRecordingsURL+"/**",
+					web.OutdatedInstancesURL+"/**",
+					web.ServiceInstanceUpgradesURL+"/**",
 				),
 			},
 		},
@@ -0,0 +1,141 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package filters
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// ParametersSchemaFilterName is the name of the filter which validates parameters against the schemas of the plans
+const ParametersSchemaFilterName = "ParametersSchemaFilter"
+
+// parametersSchemaFilter validates the parameters of service instance and binding requests against the parameters
+// schemas of the plans, so that invalid parameters are rejected before the operation is created
+type parametersSchemaFilter struct {
+	repository storage.Repository
+}
+
+// NewParametersSchemaFilter creates a new parametersSchemaFilter filter
+func NewParametersSchemaFilter(repository storage.Repository) *parametersSchemaFilter {
+	return &parametersSchemaFilter{
+		repository: repository,
+	}
+}
+
+func (*parametersSchemaFilter) Name() string {
+	return ParametersSchemaFilterName
+}
+
+func (f *parametersSchemaFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	ctx := req.Context()
+	parameters := gjson.GetBytes(req.Body, "parameters")
+	if !parameters.Exists() {
+		return next.Handle(req)
+	}
+
+	planID, schemaPath, err := f.requestPlan(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if planID == "" {
+		log.C(ctx).Info("Plan of the request not found. Proceeding with the next handler...")
+		return next.Handle(req)
+	}
+	plan, err := f.repository.Get(ctx, types.ServicePlanType, query.ByField(query.EqualsOperator, "id", planID))
+	if err != nil {
+		if err == util.ErrNotFoundInStorage {
+			return next.Handle(req)
+		}
+		return nil, util.HandleStorageError(err, types.ServicePlanType.String())
+	}
+
+	violations, err := plan.(*types.ServicePlan).ValidateParameters(schemaPath, json.RawMessage(parameters.Raw))
+	if err != nil {
+		log.C(ctx).WithError(err).Warnf("Parameters of the request are not validated")
+		return next.Handle(req)
+	}
+	if len(violations) != 0 {
+		return nil, types.NewParametersError(violations)
+	}
+	return next.Handle(req)
+}
+
+// requestPlan returns the ID of the plan of the request and the path of the parameters schema which applies to the request
+func (f *parametersSchemaFilter) requestPlan(ctx context.Context, req *web.Request) (string, string, error) {
+	if strings.HasPrefix(req.URL.Path, web.ServiceBindingsURL) {
+		instanceID := gjson.GetBytes(req.Body, serviceInstanceIDProperty).String()
+		planID, err := f.instancePlan(ctx, instanceID)
+		return planID, types.BindingCreateParametersSchema, err
+	}
+
+	planID := gjson.GetBytes(req.Body, planIDProperty).String()
+	if req.Method == http.MethodPost {
+		return planID, types.InstanceCreateParametersSchema, nil
+	}
+	if planID == "" {
+		// the plan of an instance is not changed by an update without plan
+		var err error
+		planID, err = f.instancePlan(ctx, req.PathParams[web.PathParamResourceID])
+		if err != nil {
+			return "", "", err
+		}
+	}
+	return planID, types.InstanceUpdateParametersSchema, nil
+}
+
+func (f *parametersSchemaFilter) instancePlan(ctx context.Context, instanceID string) (string, error) {
+	if instanceID == "" {
+		return "", nil
+	}
+	instance, err := f.repository.Get(ctx, types.ServiceInstanceType, query.ByField(query.EqualsOperator, "id", instanceID))
+	if err != nil {
+		if err == util.ErrNotFoundInStorage {
+			return "", nil
+		}
+		return "", util.HandleStorageError(err, types.ServiceInstanceType.String())
+	}
+	return instance.(*types.ServiceInstance).ServicePlanID, nil
+}
+
+func (*parametersSchemaFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path(web.ServiceInstancesURL + "/**"),
+				web.Methods(http.MethodPost, http.MethodPatch),
+			},
+		},
+		{
+			Matchers: []web.Matcher{
+				web.Path(web.ServiceBindingsURL + "/**"),
+				web.Methods(http.MethodPost),
+			},
+		},
+	}
+}
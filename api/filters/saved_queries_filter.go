@@ -0,0 +1,134 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/tidwall/gjson"
+)
+
+// SavedQueriesFilterName is the name of the saved queries filter
+const SavedQueriesFilterName = "SavedQueriesFilter"
+
+// SavedQueriesFilter validates the queries of saved queries and restricts access to saved queries owned by other users.
+// Shared saved queries can be modified only by users with global access.
+type SavedQueriesFilter struct {
+	// Schemas provides the field schemas against which the field queries of saved queries are validated
+	Schemas storage.SchemaProvider
+}
+
+// Name implements the web.Filter interface and returns the identifier of the filter.
+func (*SavedQueriesFilter) Name() string {
+	return SavedQueriesFilterName
+}
+
+// Run implements web.Middleware and validates the saved query and applies the ownership rules for the current user
+func (f *SavedQueriesFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	ctx := req.Context()
+	user, found := web.UserFromContext(ctx)
+	isGlobal := !found || user.AccessLevel == web.GlobalAccess
+
+	if req.Method == http.MethodPost || req.Method == http.MethodPatch {
+		if err := f.validateSavedQueryCriteria(req.Body); err != nil {
+			return nil, err
+		}
+
+		owner := gjson.GetBytes(req.Body, "owner")
+		if owner.Exists() && owner.String() != "" && !isGlobal && owner.String() != user.Name {
+			return nil, &util.HTTPError{
+				ErrorType:   "BadRequest",
+				Description: "owner of a saved query can only be set to the current user",
+				StatusCode:  http.StatusBadRequest,
+			}
+		}
+	}
+
+	if req.Method != http.MethodPost && !isGlobal {
+		ownerCriterion := query.ByField(query.EqualsOrNilOperator, "owner", user.Name)
+		if req.Method == http.MethodPatch || req.Method == http.MethodDelete {
+			// shared saved queries can be modified only with global access
+			log.C(ctx).Debugf("Restricting modification of saved queries to the ones owned by user %s", user.Name)
+			ownerCriterion = query.ByField(query.EqualsOperator, "owner", user.Name)
+		} else {
+			log.C(ctx).Debugf("Restricting saved queries to the ones shared or owned by user %s", user.Name)
+		}
+		var err error
+		ctx, err = query.AddCriteria(ctx, ownerCriterion)
+		if err != nil {
+			return nil, err
+		}
+		req.Request = req.WithContext(ctx)
+	}
+
+	return next.Handle(req)
+}
+
+// FilterMatchers implements the web.Filter interface and returns the conditions on which the filter should be executed.
+func (*SavedQueriesFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path(web.SavedQueriesURL + "/**"),
+				web.Methods(http.MethodGet, http.MethodPost, http.MethodPatch, http.MethodDelete),
+			},
+		},
+	}
+}
+
+func (f *SavedQueriesFilter) validateSavedQueryCriteria(body []byte) error {
+	var criteria []query.Criterion
+	for criterionType, key := range map[query.CriterionType]string{
+		query.FieldQuery: "field_query",
+		query.LabelQuery: "label_query",
+	} {
+		expression := gjson.GetBytes(body, key).String()
+		queryCriteria, err := query.Parse(criterionType, expression)
+		if err != nil {
+			return &util.HTTPError{
+				ErrorType:   "BadRequest",
+				Description: "invalid " + key + ": " + err.Error(),
+				StatusCode:  http.StatusBadRequest,
+			}
+		}
+		criteria = append(criteria, queryCriteria...)
+	}
+
+	resourceType := gjson.GetBytes(body, "resource_type").String()
+	if f.Schemas == nil || resourceType == "" {
+		return nil
+	}
+	schema, err := f.Schemas.Schema(types.ObjectType(resourceType))
+	if err != nil {
+		// unsupported resource types are reported by the validation of the saved query itself
+		return nil
+	}
+	if err := schema.Validate(criteria...); err != nil {
+		return &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: "invalid saved query: " + err.Error(),
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+	return nil
+}
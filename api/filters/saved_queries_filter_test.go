@@ -0,0 +1,81 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package filters_test
+
+import (
+	"net/http"
+
+	"github.com/Peripli/service-manager/api/filters"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/pkg/web/webfakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Saved queries filter", func() {
+	var (
+		filter  *filters.SavedQueriesFilter
+		handler *webfakes.FakeHandler
+	)
+
+	newRequest := func(method string, user *web.UserContext) *web.Request {
+		req, err := http.NewRequest(method, web.SavedQueriesURL+"/query-id", nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		req = req.WithContext(web.ContextWithUser(req.Context(), user))
+		return &web.Request{Request: req, Body: []byte(`{}`)}
+	}
+
+	handledCriteria := func() []query.Criterion {
+		Expect(handler.HandleCallCount()).To(Equal(1))
+		return query.CriteriaForContext(handler.HandleArgsForCall(0).Context())
+	}
+
+	BeforeEach(func() {
+		handler = &webfakes.FakeHandler{}
+		filter = &filters.SavedQueriesFilter{}
+	})
+
+	Context("for user without global access", func() {
+		user := &web.UserContext{Name: "user", AccessLevel: web.TenantAccess}
+
+		It("allows reading shared saved queries and the ones owned by the user", func() {
+			_, err := filter.Run(newRequest(http.MethodGet, user), handler)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(handledCriteria()).To(ConsistOf(query.ByField(query.EqualsOrNilOperator, "owner", "user")))
+		})
+
+		for _, method := range []string{http.MethodPatch, http.MethodDelete} {
+			method := method
+			It("allows "+method+" only of saved queries owned by the user", func() {
+				_, err := filter.Run(newRequest(method, user), handler)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(handledCriteria()).To(ConsistOf(query.ByField(query.EqualsOperator, "owner", "user")))
+			})
+		}
+	})
+
+	Context("for user with global access", func() {
+		It("allows modification of shared saved queries", func() {
+			user := &web.UserContext{Name: "admin", AccessLevel: web.GlobalAccess}
+			_, err := filter.Run(newRequest(http.MethodDelete, user), handler)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(handledCriteria()).To(BeEmpty())
+		})
+	})
+})
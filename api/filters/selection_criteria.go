@@ -17,10 +17,16 @@
 package filters
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/Peripli/service-manager/pkg/log"
 	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
 	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
 )
 
 const (
@@ -29,7 +35,22 @@ const (
 )
 
 // SelectionCriteria is filter that configures selection criteria per request.
+// If a list request references a saved query via the view query parameter, the criteria of the saved query
+// are composed with the criteria provided in the request. If the field schema of the requested resource is
+// available, the field names and values in the criteria are validated against it. The q query parameter
+// adds a full-text search criterion.
 type SelectionCriteria struct {
+	// Repository is used to fetch the saved query referenced by the view query parameter
+	Repository storage.Repository
+
+	// Schemas provides the field schemas against which the criteria of the request are validated
+	Schemas storage.SchemaProvider
+
+	// TenantLabelKey is the key of the tenant label used to scope saved queries when multitenancy is enabled
+	TenantLabelKey string
+
+	// ExtractTenant extracts the tenant of the request when multitenancy is enabled
+	ExtractTenant func(request *web.Request) (string, error)
 }
 
 // Name implements the web.Filter interface and returns the identifier of the filter.
@@ -49,6 +70,19 @@ func (l *SelectionCriteria) Run(req *web.Request, next web.Handler) (*web.Respon
 		}
 		criteria = append(criteria, queryCriteria...)
 	}
+	if searchText := req.URL.Query().Get(web.QueryParamSearch); searchText != "" && req.Method == http.MethodGet {
+		criteria = append(criteria, query.ByText(searchText))
+	}
+	if viewName := req.URL.Query().Get(web.QueryParamView); viewName != "" && req.Method == http.MethodGet && isCollectionPath(req.URL.Path) {
+		viewCriteria, err := l.viewCriteria(req, viewName)
+		if err != nil {
+			return nil, err
+		}
+		criteria = append(criteria, viewCriteria...)
+	}
+	if err := l.validateCriteria(req, criteria); err != nil {
+		return nil, err
+	}
 	ctx, err := query.AddCriteria(ctx, criteria...)
 	if err != nil {
 		return nil, err
@@ -57,6 +91,122 @@ func (l *SelectionCriteria) Run(req *web.Request, next web.Handler) (*web.Respon
 	return next.Handle(req)
 }
 
+func (l *SelectionCriteria) validateCriteria(req *web.Request, criteria []query.Criterion) error {
+	if l.Schemas == nil || len(criteria) == 0 {
+		return nil
+	}
+	resourceType, ok := resourceTypeFromPath(req.URL.Path)
+	if !ok {
+		return nil
+	}
+	schema, err := l.Schemas.Schema(resourceType)
+	if err != nil {
+		log.C(req.Context()).Debugf("Skipping validation of criteria: %s", err)
+		return nil
+	}
+	return schema.Validate(criteria...)
+}
+
+// resourceTypeFromPath returns the resource type of requests to a collection of resources or to a single resource
+func resourceTypeFromPath(path string) (types.ObjectType, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) < 2 || len(segments) > 3 {
+		return "", false
+	}
+	return types.ObjectType("/" + segments[0] + "/" + segments[1]), true
+}
+
+// isCollectionPath returns whether the path references a collection of resources rather than a single resource
+func isCollectionPath(path string) bool {
+	return len(strings.Split(strings.Trim(path, "/"), "/")) == 2
+}
+
+func (l *SelectionCriteria) viewCriteria(req *web.Request, viewName string) ([]query.Criterion, error) {
+	ctx := req.Context()
+	if l.Repository == nil {
+		return nil, &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: "saved queries are not supported",
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+
+	resourceType := types.ObjectType(strings.TrimSuffix(req.URL.Path, "/"))
+	savedQuery, err := l.fetchSavedQuery(req, viewName, resourceType)
+	if err != nil {
+		return nil, err
+	}
+	log.C(ctx).Debugf("Applying criteria of saved query %s to list of %s", savedQuery.Name, resourceType)
+
+	var criteria []query.Criterion
+	fieldCriteria, err := query.Parse(query.FieldQuery, savedQuery.FieldQuery)
+	if err != nil {
+		return nil, err
+	}
+	criteria = append(criteria, fieldCriteria...)
+	labelCriteria, err := query.Parse(query.LabelQuery, savedQuery.LabelQuery)
+	if err != nil {
+		return nil, err
+	}
+	criteria = append(criteria, labelCriteria...)
+
+	return criteria, nil
+}
+
+func (l *SelectionCriteria) fetchSavedQuery(req *web.Request, viewName string, resourceType types.ObjectType) (*types.SavedQuery, error) {
+	ctx := req.Context()
+	userName := ""
+	criteria := []query.Criterion{
+		query.ByField(query.EqualsOperator, "name", viewName),
+		query.ByField(query.EqualsOperator, "resource_type", string(resourceType)),
+	}
+	if user, found := web.UserFromContext(ctx); found {
+		userName = user.Name
+		if user.AccessLevel != web.GlobalAccess && l.ExtractTenant != nil {
+			tenant, err := l.ExtractTenant(req)
+			if err != nil {
+				return nil, err
+			}
+			if len(tenant) != 0 {
+				criteria = append(criteria, query.ByLabel(query.EqualsOperator, l.TenantLabelKey, tenant))
+			}
+		}
+	}
+	criteria = append(criteria, query.ByField(query.EqualsOrNilOperator, "owner", userName))
+
+	savedQueries, err := l.Repository.List(ctx, types.SavedQueryType, criteria...)
+	if err != nil {
+		return nil, util.HandleStorageError(err, types.SavedQueryType.String())
+	}
+
+	var shared []*types.SavedQuery
+	for i := 0; i < savedQueries.Len(); i++ {
+		savedQuery := savedQueries.ItemAt(i).(*types.SavedQuery)
+		// a saved query owned by the current user takes precedence over shared saved queries with the same name
+		if savedQuery.Owner != "" && savedQuery.Owner == userName {
+			return savedQuery, nil
+		}
+		shared = append(shared, savedQuery)
+	}
+
+	switch len(shared) {
+	case 0:
+		return nil, &util.HTTPError{
+			ErrorType:   "NotFound",
+			Description: fmt.Sprintf("could not find saved query %s for %s", viewName, resourceType),
+			StatusCode:  http.StatusNotFound,
+		}
+	case 1:
+		return shared[0], nil
+	default:
+		return nil, &util.HTTPError{
+			ErrorType:   "Conflict",
+			Description: fmt.Sprintf("found %d saved queries with name %s for %s", len(shared), viewName, resourceType),
+			StatusCode:  http.StatusConflict,
+		}
+	}
+}
+
 // FilterMatchers implements the web.Filter interface and returns the conditions on which the filter should be executed.
 func (*SelectionCriteria) FilterMatchers() []web.FilterMatcher {
 	return []web.FilterMatcher{
@@ -0,0 +1,192 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters_test
+
+import (
+	"net/http"
+
+	"github.com/Peripli/service-manager/api/filters"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/pkg/web/webfakes"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Selection criteria filter", func() {
+	var (
+		filter     *filters.SelectionCriteria
+		handler    *webfakes.FakeHandler
+		repository *storagefakes.FakeStorage
+	)
+
+	newRequest := func(url string, user *web.UserContext) *web.Request {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		Expect(err).ShouldNot(HaveOccurred())
+		if user != nil {
+			req = req.WithContext(web.ContextWithUser(req.Context(), user))
+		}
+		return &web.Request{Request: req}
+	}
+
+	handledCriteria := func() []query.Criterion {
+		Expect(handler.HandleCallCount()).To(Equal(1))
+		return query.CriteriaForContext(handler.HandleArgsForCall(0).Context())
+	}
+
+	BeforeEach(func() {
+		handler = &webfakes.FakeHandler{}
+		repository = &storagefakes.FakeStorage{}
+		filter = &filters.SelectionCriteria{Repository: repository}
+	})
+
+	When("no view is requested", func() {
+		It("adds only the criteria from the request", func() {
+			_, err := filter.Run(newRequest("/v1/service_instances?fieldQuery=name+eq+'instance'", nil), handler)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(repository.ListCallCount()).To(Equal(0))
+			Expect(handledCriteria()).To(ConsistOf(query.ByField(query.EqualsOperator, "name", "instance")))
+		})
+	})
+
+	When("search text is provided", func() {
+		It("adds a search criterion", func() {
+			_, err := filter.Run(newRequest("/v1/service_instances?q=my+instance&fieldQuery=name+eq+'instance'", nil), handler)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(handledCriteria()).To(ConsistOf(
+				query.ByField(query.EqualsOperator, "name", "instance"),
+				query.ByText("my instance"),
+			))
+		})
+
+		It("fails when the search text contains no words", func() {
+			_, err := filter.Run(newRequest("/v1/service_instances?q=*", nil), handler)
+			Expect(err).Should(HaveOccurred())
+			Expect(handler.HandleCallCount()).To(Equal(0))
+		})
+	})
+
+	When("a view is requested", func() {
+		BeforeEach(func() {
+			repository.ListReturns(types.NewObjectArray(&types.SavedQuery{
+				Name:         "prod",
+				ResourceType: types.ServiceInstanceType,
+				FieldQuery:   "platform_id eq 'cf'",
+				LabelQuery:   "env eq 'prod'",
+			}), nil)
+		})
+
+		It("composes the criteria of the saved query with the criteria from the request", func() {
+			_, err := filter.Run(newRequest("/v1/service_instances?view=prod&fieldQuery=name+eq+'instance'", nil), handler)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			Expect(handledCriteria()).To(ConsistOf(
+				query.ByField(query.EqualsOperator, "name", "instance"),
+				query.ByField(query.EqualsOperator, "platform_id", "cf"),
+				query.ByLabel(query.EqualsOperator, "env", "prod"),
+			))
+		})
+
+		It("ignores the view when a single resource is requested", func() {
+			_, err := filter.Run(newRequest("/v1/service_instances/instance-id?view=prod", nil), handler)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(repository.ListCallCount()).To(Equal(0))
+			Expect(handledCriteria()).To(BeEmpty())
+		})
+
+		It("looks up the saved query by name, resource type and owner", func() {
+			user := &web.UserContext{Name: "user", AccessLevel: web.TenantAccess}
+			_, err := filter.Run(newRequest("/v1/service_instances?view=prod", user), handler)
+			Expect(err).ShouldNot(HaveOccurred())
+
+			Expect(repository.ListCallCount()).To(Equal(1))
+			_, objectType, criteria := repository.ListArgsForCall(0)
+			Expect(objectType).To(Equal(types.SavedQueryType))
+			Expect(criteria).To(ConsistOf(
+				query.ByField(query.EqualsOperator, "name", "prod"),
+				query.ByField(query.EqualsOperator, "resource_type", string(types.ServiceInstanceType)),
+				query.ByField(query.EqualsOrNilOperator, "owner", "user"),
+			))
+		})
+
+		When("multitenancy is enabled", func() {
+			BeforeEach(func() {
+				filter.TenantLabelKey = "tenant"
+				filter.ExtractTenant = func(request *web.Request) (string, error) {
+					return "tenant-id", nil
+				}
+			})
+
+			It("scopes the saved query lookup to the tenant of the user", func() {
+				user := &web.UserContext{Name: "user", AccessLevel: web.TenantAccess}
+				_, err := filter.Run(newRequest("/v1/service_instances?view=prod", user), handler)
+				Expect(err).ShouldNot(HaveOccurred())
+
+				_, _, criteria := repository.ListArgsForCall(0)
+				Expect(criteria).To(ContainElement(query.ByLabel(query.EqualsOperator, "tenant", "tenant-id")))
+			})
+		})
+
+		When("both a shared and an owned saved query match", func() {
+			BeforeEach(func() {
+				repository.ListReturns(types.NewObjectArray(
+					&types.SavedQuery{Name: "prod", FieldQuery: "name eq 'shared'"},
+					&types.SavedQuery{Name: "prod", FieldQuery: "name eq 'owned'", Owner: "user"},
+				), nil)
+			})
+
+			It("applies the saved query owned by the user", func() {
+				user := &web.UserContext{Name: "user", AccessLevel: web.TenantAccess}
+				_, err := filter.Run(newRequest("/v1/service_instances?view=prod", user), handler)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(handledCriteria()).To(ConsistOf(query.ByField(query.EqualsOperator, "name", "owned")))
+			})
+		})
+
+		When("the saved query does not exist", func() {
+			BeforeEach(func() {
+				repository.ListReturns(types.NewObjectArray(), nil)
+			})
+
+			It("returns 404", func() {
+				_, err := filter.Run(newRequest("/v1/service_instances?view=missing", nil), handler)
+				Expect(err).Should(HaveOccurred())
+				Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusNotFound))
+				Expect(handler.HandleCallCount()).To(Equal(0))
+			})
+		})
+
+		When("no repository is configured", func() {
+			It("returns 400", func() {
+				filter = &filters.SelectionCriteria{}
+				_, err := filter.Run(newRequest("/v1/service_instances?view=prod", nil), handler)
+				Expect(err).Should(HaveOccurred())
+				Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusBadRequest))
+			})
+		})
+	})
+
+	It("fails when the request criteria are invalid", func() {
+		_, err := filter.Run(newRequest("/v1/service_instances?fieldQuery=name+eqq+'x'", nil), handler)
+		Expect(err).Should(HaveOccurred())
+		Expect(handler.HandleCallCount()).To(Equal(0))
+	})
+})
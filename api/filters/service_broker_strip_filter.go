@@ -0,0 +1,57 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+const ServiceBrokerStripFilterName = "ServiceBrokerStripFilter"
+
+var serviceBrokerUnmodifiableProperties = []string{
+	"health_status", "health_error", "catalog_warnings",
+}
+
+// ServiceBrokerStripFilter checks post/patch request body for unmodifiable properties
+type ServiceBrokerStripFilter struct {
+}
+
+func (*ServiceBrokerStripFilter) Name() string {
+	return ServiceBrokerStripFilterName
+}
+
+func (*ServiceBrokerStripFilter) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	var err error
+	req.Body, err = removePropertiesFromRequest(req.Context(), req.Body, serviceBrokerUnmodifiableProperties)
+	if err != nil {
+		return nil, err
+	}
+	return next.Handle(req)
+}
+
+func (*ServiceBrokerStripFilter) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path(web.ServiceBrokersURL + "/**"),
+				web.Methods(http.MethodPost, http.MethodPatch),
+			},
+		},
+	}
+}
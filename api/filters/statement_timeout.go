@@ -0,0 +1,57 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package filters
+
+import (
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// StatementTimeoutFilterName is the name of the statement timeout filter
+const StatementTimeoutFilterName = "StatementTimeoutFilter"
+
+// StatementTimeout is a filter which limits the duration of the storage statements executed while handling a request,
+// so that statements do not outlive the request
+type StatementTimeout struct {
+	Timeout time.Duration
+}
+
+// Name implements the web.Filter interface and returns the identifier of the filter.
+func (*StatementTimeout) Name() string {
+	return StatementTimeoutFilterName
+}
+
+// Run sets the statement timeout in the context of the request
+func (f *StatementTimeout) Run(req *web.Request, next web.Handler) (*web.Response, error) {
+	if f.Timeout > 0 {
+		req.Request = req.WithContext(storage.ContextWithStatementTimeout(req.Context(), f.Timeout))
+	}
+	return next.Handle(req)
+}
+
+// FilterMatchers implements the web.Filter interface and returns the conditions on which the filter should be executed.
+func (*StatementTimeout) FilterMatchers() []web.FilterMatcher {
+	return []web.FilterMatcher{
+		{
+			Matchers: []web.Matcher{
+				web.Path("/**"),
+			},
+		},
+	}
+}
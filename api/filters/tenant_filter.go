@@ -25,6 +25,7 @@ func NewMultitenancyFilters(labelKey string, extractTenantFunc func(request *web
 	return []web.Filter{
 		newTenantCriteriaFilter(labelKey, extractTenantFunc),
 		newTenantLabelingFilter(labelKey, extractTenantFunc),
+		&GlobalAccessFilter{ExtractTenant: extractTenantFunc},
 	}
 }
 
@@ -167,5 +168,11 @@ func (f *TenantFilter) FilterMatchers() []web.FilterMatcher {
 				web.Methods(f.Methods...),
 			},
 		},
+		{
+			Matchers: []web.Matcher{
+				web.Path(web.SavedQueriesURL + "/**"),
+				web.Methods(f.Methods...),
+			},
+		},
 	}
 }
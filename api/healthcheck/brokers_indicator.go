@@ -0,0 +1,212 @@
+/*
+ *    Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/brokerclient"
+	"github.com/Peripli/service-manager/pkg/health"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// CatalogFetcherFunc fetches the catalog of a broker
+type CatalogFetcherFunc func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error)
+
+// NewBrokersIndicator returns new health indicator which probes the catalog endpoint of each registered broker.
+// A broker is down after failuresThreshold consecutive failed probes. The status of the brokers is stored with the
+// health store, so that it can be queried through the API. Only the Service Manager instance which holds the lock
+// probes the brokers, the other instances report the stored status. The breakers, if any, are reported as well.
+func NewBrokersIndicator(ctx context.Context, repository storage.Repository, healthStore storage.BrokerHealthStore, locker storage.Locker, fetchCatalog CatalogFetcherFunc, breakers *brokerclient.CircuitBreakers, failuresThreshold int64) health.Indicator {
+	if failuresThreshold <= 0 {
+		failuresThreshold = 1
+	}
+	return &brokersIndicator{
+		ctx:               ctx,
+		repository:        repository,
+		healthStore:       healthStore,
+		locker:            locker,
+		fetchCatalog:      fetchCatalog,
+		breakers:          breakers,
+		failuresThreshold: failuresThreshold,
+		failures:          make(map[string]int64),
+	}
+}
+
+type brokersIndicator struct {
+	ctx               context.Context
+	repository        storage.Repository
+	healthStore       storage.BrokerHealthStore
+	locker            storage.Locker
+	fetchCatalog      CatalogFetcherFunc
+	breakers          *brokerclient.CircuitBreakers
+	failuresThreshold int64
+
+	// failures holds the number of consecutive failed probes per broker id
+	failures map[string]int64
+	// probing is whether this instance holds the lock and probes the brokers
+	probing bool
+}
+
+type probeResult struct {
+	latency time.Duration
+	err     error
+}
+
+// Name returns the name of the indicator
+func (bi *brokersIndicator) Name() string {
+	return health.BrokersIndicatorName
+}
+
+// Status returns status of the health check
+func (bi *brokersIndicator) Status() (interface{}, error) {
+	objList, err := bi.repository.List(bi.ctx, types.ServiceBrokerType)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch brokers health from storage: %v", err)
+	}
+	brokers := objList.(*types.ServiceBrokers).ServiceBrokers
+	if !bi.acquireProbing() {
+		return bi.storedStatus(brokers)
+	}
+	results := bi.probe(brokers)
+
+	breakerStates := bi.breakerStates()
+
+	failures := make(map[string]int64, len(brokers))
+	details := make(map[string]*health.Health)
+	unavailableBrokers := 0
+	for i, broker := range brokers {
+		result := results[i]
+		brokerHealth := health.New().WithDetail("latency", result.latency.String())
+		if state, found := breakerStates[broker.BrokerURL]; found {
+			brokerHealth.WithDetail("circuit_breaker", state)
+		}
+
+		status := health.StatusUp
+		if result.err != nil {
+			failures[broker.ID] = bi.failures[broker.ID] + 1
+			brokerHealth.WithDetail("last_error", result.err.Error()).
+				WithDetail("failures", failures[broker.ID])
+			if failures[broker.ID] >= bi.failuresThreshold {
+				status = health.StatusDown
+				unavailableBrokers++
+			}
+		}
+		details[broker.Name] = brokerHealth.WithStatus(status)
+
+		if err := bi.updateBrokerHealth(broker, status, result.err); err != nil {
+			log.C(bi.ctx).WithError(err).Errorf("Could not store health status of broker %s", broker.Name)
+		}
+	}
+	bi.failures = failures
+
+	if unavailableBrokers > 0 {
+		err = fmt.Errorf("there are %d unavailable brokers", unavailableBrokers)
+	}
+	return details, err
+}
+
+// storedStatus reports the status of the brokers stored by the instance which probes them
+func (bi *brokersIndicator) storedStatus(brokers []*types.ServiceBroker) (interface{}, error) {
+	breakerStates := bi.breakerStates()
+	details := make(map[string]*health.Health)
+	unavailableBrokers := 0
+	for _, broker := range brokers {
+		brokerHealth := health.New()
+		if state, found := breakerStates[broker.BrokerURL]; found {
+			brokerHealth.WithDetail("circuit_breaker", state)
+		}
+		status := health.StatusUnknown
+		if broker.HealthStatus != "" {
+			status = health.Status(broker.HealthStatus)
+		}
+		if status == health.StatusDown {
+			brokerHealth.WithDetail("last_error", broker.HealthError)
+			unavailableBrokers++
+		}
+		details[broker.Name] = brokerHealth.WithStatus(status)
+	}
+
+	var err error
+	if unavailableBrokers > 0 {
+		err = fmt.Errorf("there are %d unavailable brokers", unavailableBrokers)
+	}
+	return details, err
+}
+
+// acquireProbing returns whether this instance probes the brokers. The first instance which acquires the lock keeps
+// it and probes the brokers until it stops, the lock is then released with its database session.
+func (bi *brokersIndicator) acquireProbing() bool {
+	if bi.locker == nil || bi.probing {
+		return true
+	}
+	if err := bi.locker.TryLock(bi.ctx); err != nil {
+		log.C(bi.ctx).Debugf("Brokers are probed by another instance: %s", err)
+		return false
+	}
+	bi.probing = true
+	return true
+}
+
+// breakerStates returns the states of the circuit breakers per broker URL
+func (bi *brokersIndicator) breakerStates() map[string]brokerclient.BreakerState {
+	breakerStates := make(map[string]brokerclient.BreakerState)
+	if bi.breakers != nil {
+		for _, status := range bi.breakers.Statuses() {
+			breakerStates[status.URL] = status.State
+		}
+	}
+	return breakerStates
+}
+
+// probe fetches the catalogs of the brokers concurrently and returns the results in the order of the brokers
+func (bi *brokersIndicator) probe(brokers []*types.ServiceBroker) []*probeResult {
+	results := make([]*probeResult, len(brokers))
+	wg := &sync.WaitGroup{}
+	for i, broker := range brokers {
+		wg.Add(1)
+		go func(i int, broker *types.ServiceBroker) {
+			defer wg.Done()
+			start := time.Now()
+			_, err := bi.fetchCatalog(bi.ctx, broker)
+			results[i] = &probeResult{
+				latency: time.Since(start),
+				err:     err,
+			}
+		}(i, broker)
+	}
+	wg.Wait()
+	return results
+}
+
+// updateBrokerHealth stores the status of the broker if it has changed
+func (bi *brokersIndicator) updateBrokerHealth(broker *types.ServiceBroker, status health.Status, probeErr error) error {
+	healthError := ""
+	if status == health.StatusDown {
+		healthError = probeErr.Error()
+	}
+	if broker.HealthStatus == string(status) && broker.HealthError == healthError {
+		return nil
+	}
+
+	return bi.healthStore.UpdateBrokerHealth(bi.ctx, broker.ID, string(status), healthError)
+}
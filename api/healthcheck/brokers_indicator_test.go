@@ -0,0 +1,186 @@
+/*
+ *    Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/brokerclient"
+	"github.com/Peripli/service-manager/pkg/health"
+	"github.com/Peripli/service-manager/pkg/types"
+	storagefakes2 "github.com/Peripli/service-manager/storage/storagefakes"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type brokerHealthUpdate struct {
+	brokerID, status, healthError string
+}
+
+type fakeBrokerHealthStore struct {
+	updates []brokerHealthUpdate
+}
+
+func (s *fakeBrokerHealthStore) UpdateBrokerHealth(ctx context.Context, brokerID, status, healthError string) error {
+	s.updates = append(s.updates, brokerHealthUpdate{brokerID: brokerID, status: status, healthError: healthError})
+	return nil
+}
+
+type fakeLocker struct {
+	tryLockErr   error
+	tryLockCalls int
+}
+
+func (l *fakeLocker) Lock(ctx context.Context) error {
+	return nil
+}
+
+func (l *fakeLocker) TryLock(ctx context.Context) error {
+	l.tryLockCalls++
+	return l.tryLockErr
+}
+
+func (l *fakeLocker) Unlock(ctx context.Context) error {
+	return nil
+}
+
+var _ = Describe("Brokers Indicator", func() {
+	var indicator health.Indicator
+	var repository *storagefakes2.FakeStorage
+	var healthStore *fakeBrokerHealthStore
+	var locker *fakeLocker
+	var fetchCalls int
+	var breakers *brokerclient.CircuitBreakers
+	var broker *types.ServiceBroker
+	var fetchErr error
+	var ctx context.Context
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		fetchErr = nil
+		fetchCalls = 0
+		broker = &types.ServiceBroker{
+			Base:      types.Base{ID: "broker-id"},
+			Name:      "test-broker",
+			BrokerURL: "http://broker.com",
+		}
+		repository = &storagefakes2.FakeStorage{}
+		repository.ListReturns(&types.ServiceBrokers{ServiceBrokers: []*types.ServiceBroker{broker}}, nil)
+		healthStore = &fakeBrokerHealthStore{}
+		locker = &fakeLocker{}
+		breakers = brokerclient.NewCircuitBreakers(&brokerclient.CircuitBreakerSettings{
+			FailureThreshold: 1,
+			OpenTimeout:      time.Minute,
+			HalfOpenRequests: 1,
+		})
+		fetchCatalog := func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error) {
+			fetchCalls++
+			release, _, err := breakers.Acquire(ctx, broker)
+			if err != nil {
+				return nil, err
+			}
+			release(fetchErr != nil)
+			return []byte(`{}`), fetchErr
+		}
+		indicator = NewBrokersIndicator(ctx, repository, healthStore, locker, fetchCatalog, breakers, 2)
+	})
+
+	Context("Name", func() {
+		It("should not be empty", func() {
+			Expect(indicator.Name()).Should(Equal(health.BrokersIndicatorName))
+		})
+	})
+
+	Context("Brokers fail the probes", func() {
+		BeforeEach(func() {
+			fetchErr = errors.New("broker err")
+		})
+		It("should return error after the failures threshold is reached", func() {
+			details, err := indicator.Status()
+			Expect(err).ShouldNot(HaveOccurred())
+			brokerHealth := details.(map[string]*health.Health)[broker.Name]
+			Expect(brokerHealth.Status).Should(Equal(health.StatusUp))
+			Expect(brokerHealth.Details["last_error"]).Should(Equal(fetchErr.Error()))
+			Expect(healthStore.updates).Should(HaveLen(1))
+
+			details, err = indicator.Status()
+			Expect(err).Should(HaveOccurred())
+			brokerHealth = details.(map[string]*health.Health)[broker.Name]
+			Expect(brokerHealth.Status).Should(Equal(health.StatusDown))
+			Expect(brokerHealth.Details["circuit_breaker"]).Should(Equal(brokerclient.BreakerOpen))
+
+			Expect(healthStore.updates).Should(HaveLen(2))
+			Expect(healthStore.updates[1].brokerID).Should(Equal(broker.ID))
+			Expect(healthStore.updates[1].status).Should(Equal(string(health.StatusDown)))
+			Expect(healthStore.updates[1].healthError).ShouldNot(BeEmpty())
+		})
+	})
+
+	Context("Storage returns error", func() {
+		var expectedErr error
+		BeforeEach(func() {
+			expectedErr = errors.New("storage err")
+			repository.ListReturns(nil, expectedErr)
+		})
+		It("should return error", func() {
+			_, err := indicator.Status()
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(expectedErr.Error()))
+		})
+	})
+
+	Context("All brokers are available", func() {
+		It("should store the status of brokers once", func() {
+			details, err := indicator.Status()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(details.(map[string]*health.Health)[broker.Name].Status).Should(Equal(health.StatusUp))
+			Expect(healthStore.updates).Should(HaveLen(1))
+
+			_, err = indicator.Status()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(healthStore.updates).Should(HaveLen(1))
+		})
+
+		It("should keep the lock once acquired", func() {
+			_, err := indicator.Status()
+			Expect(err).ShouldNot(HaveOccurred())
+			_, err = indicator.Status()
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(locker.tryLockCalls).Should(Equal(1))
+			Expect(fetchCalls).Should(Equal(2))
+		})
+	})
+
+	Context("Another instance probes the brokers", func() {
+		BeforeEach(func() {
+			locker.tryLockErr = errors.New("already locked")
+			broker.HealthStatus = string(health.StatusDown)
+			broker.HealthError = "broker err"
+		})
+		It("should report the stored status without probing", func() {
+			details, err := indicator.Status()
+			Expect(err).Should(HaveOccurred())
+			brokerHealth := details.(map[string]*health.Health)[broker.Name]
+			Expect(brokerHealth.Status).Should(Equal(health.StatusDown))
+			Expect(brokerHealth.Details["last_error"]).Should(Equal("broker err"))
+			Expect(fetchCalls).Should(Equal(0))
+			Expect(healthStore.updates).Should(BeEmpty())
+		})
+	})
+})
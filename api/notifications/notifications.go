@@ -22,8 +22,18 @@ import (
 const (
 	LastKnownRevisionHeader     = "last_notification_revision"
 	LastKnownRevisionQueryParam = "last_notification_revision"
+
+	// ResyncRequiredCloseCode is the websocket close code sent together with the resync reason when the proxy has
+	// to resynchronize instead of reconnecting with its last known revision
+	ResyncRequiredCloseCode = 4410
 )
 
+// resyncResponse is returned together with http status GONE when the proxy has to resynchronize
+type resyncResponse struct {
+	Error  string `json:"error"`
+	Reason string `json:"reason"`
+}
+
 func (c *Controller) handleWS(req *web.Request) (*web.Response, error) {
 	ctx := req.Context()
 	logger := log.C(ctx)
@@ -54,8 +64,12 @@ func (c *Controller) handleWS(req *web.Request) (*web.Response, error) {
 	}
 	notificationQueue, lastKnownToSMRevision, err := c.notificator.RegisterConsumer(platform, revisionKnownToProxy)
 	if err != nil {
-		if err == util.ErrInvalidNotificationRevision {
-			return util.NewJSONResponse(http.StatusGone, nil)
+		if resyncErr, ok := err.(*util.ErrNotificationResyncRequired); ok {
+			logger.Infof("Platform %s has to resync notifications: %s", platform.ID, resyncErr.Reason)
+			return util.NewJSONResponse(http.StatusGone, &resyncResponse{
+				Error:  "ResyncRequired",
+				Reason: resyncErr.Reason,
+			})
 		}
 		return nil, err
 	}
@@ -111,6 +125,13 @@ func (c *Controller) writeLoop(ctx context.Context, conn *websocket.Conn, q stor
 			return
 		case notification, ok := <-notificationChannel:
 			if !ok {
+				if resyncErr, isResync := q.Err().(*util.ErrNotificationResyncRequired); isResync {
+					log.C(ctx).Infof("Notifications channel is closed as resync is required: %s. Closing websocket connection...", resyncErr.Reason)
+					if err := c.sendClose(ctx, conn, ResyncRequiredCloseCode, resyncErr.Reason); err != nil {
+						log.C(ctx).WithError(err).Error("Could not send close")
+					}
+					return
+				}
 				log.C(ctx).Infof("Notifications channel is closed. Closing websocket connection...")
 				return
 			}
@@ -84,7 +84,7 @@ func (c *Controller) closeConn(ctx context.Context, cancel context.CancelFunc, c
 	// if base context is cancelled, write loop will quit and write to done
 	<-done
 
-	if err := c.sendClose(ctx, conn, websocket.CloseGoingAway); err != nil {
+	if err := c.sendClose(ctx, conn, websocket.CloseGoingAway, ""); err != nil {
 		log.C(ctx).WithError(err).Error("Could not send close")
 	}
 
@@ -93,8 +93,8 @@ func (c *Controller) closeConn(ctx context.Context, cancel context.CancelFunc, c
 	}
 }
 
-func (c *Controller) sendClose(ctx context.Context, conn *websocket.Conn, closeCode int) error {
-	message := websocket.FormatCloseMessage(closeCode, "")
+func (c *Controller) sendClose(ctx context.Context, conn *websocket.Conn, closeCode int, reason string) error {
+	message := websocket.FormatCloseMessage(closeCode, reason)
 	err := conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(c.wsSettings.WriteTimeout))
 	if err != nil && err != websocket.ErrCloseSent {
 		log.C(ctx).WithError(err).Error("Could not write websocket close message")
@@ -26,12 +26,15 @@ import (
 	"net/http/httputil"
 	"net/url"
 	"regexp"
+	"strconv"
+	"time"
 
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/sjson"
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/Peripli/service-manager/pkg/brokerclient"
 	"github.com/Peripli/service-manager/pkg/log"
 	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/Peripli/service-manager/pkg/util"
@@ -46,6 +49,14 @@ type BrokerFetcherFunc func(ctx context.Context, brokerID string) (*types.Servic
 // Controller implements api.Controller by providing OSB API logic
 type Controller struct {
 	BrokerFetcher BrokerFetcherFunc
+	// ResponseCache caches the instance and binding fetch responses of brokers which enable caching. Caching is disabled if nil.
+	ResponseCache *ResponseCache
+	// CircuitBreakers guards the calls to the brokers. Guarding is disabled if nil.
+	CircuitBreakers *brokerclient.CircuitBreakers
+	// BrokerAuthenticator authenticates the calls to the brokers. Only basic credentials of brokers are used if nil.
+	BrokerAuthenticator *brokerclient.BrokerAuthenticator
+	// Recorder records the exchanges with the brokers which enable recording. Recording is disabled if nil.
+	Recorder *Recorder
 }
 
 var _ web.Controller = &Controller{}
@@ -54,6 +65,14 @@ func (c *Controller) proxyHandler(r *web.Request) (*web.Response, error) {
 	return c.handler(r, c.proxy)
 }
 
+func (c *Controller) fetchHandler(r *web.Request) (*web.Response, error) {
+	return c.handler(r, c.fetch)
+}
+
+func (c *Controller) modifyHandler(r *web.Request) (*web.Response, error) {
+	return c.handler(r, c.modify)
+}
+
 func (c *Controller) catalogHandler(r *web.Request) (*web.Response, error) {
 	return c.handler(r, c.catalog)
 }
@@ -100,6 +119,39 @@ func (c *Controller) catalog(r *web.Request, logger *logrus.Entry, broker *types
 	return util.NewJSONResponse(http.StatusOK, &broker.Catalog)
 }
 
+func (c *Controller) fetch(r *web.Request, logger *logrus.Entry, broker *types.ServiceBroker) (*web.Response, error) {
+	ttl := responseCacheTTL(r.Context(), broker)
+	if c.ResponseCache == nil || ttl <= 0 {
+		return c.proxy(r, logger, broker)
+	}
+
+	// the key is built before proxying, as proxying modifies the request path
+	resource := r.URL.Path
+	key := resource + "?" + r.URL.RawQuery
+	if user, ok := web.UserFromContext(r.Context()); ok {
+		key = user.Name + ":" + key
+	}
+	if response, found := c.ResponseCache.Get(key); found {
+		logger.Debugf("Serving cached response of service broker %s for %s", broker.Name, resource)
+		return response, nil
+	}
+
+	response, err := c.proxy(r, logger, broker)
+	if err == nil && response.StatusCode == http.StatusOK {
+		c.ResponseCache.Put(key, resource, response, ttl)
+	}
+	return response, err
+}
+
+func (c *Controller) modify(r *web.Request, logger *logrus.Entry, broker *types.ServiceBroker) (*web.Response, error) {
+	resource := r.URL.Path
+	response, err := c.proxy(r, logger, broker)
+	if c.ResponseCache != nil {
+		c.ResponseCache.Invalidate(resource)
+	}
+	return response, err
+}
+
 func (c *Controller) proxy(r *web.Request, logger *logrus.Entry, broker *types.ServiceBroker) (*web.Response, error) {
 	ctx := r.Context()
 
@@ -111,7 +163,11 @@ func (c *Controller) proxy(r *web.Request, logger *logrus.Entry, broker *types.S
 	}
 
 	modifiedRequest := r.Request.WithContext(ctx)
-	modifiedRequest.SetBasicAuth(broker.Credentials.Basic.Username, broker.Credentials.Basic.Password)
+	transport, err := c.authenticate(modifiedRequest, broker)
+	if err != nil {
+		logger.WithError(err).Errorf("Could not authenticate to service broker %s", broker.Name)
+		return nil, err
+	}
 	modifiedRequest.Body = ioutil.NopCloser(bytes.NewReader(r.Body))
 	modifiedRequest.ContentLength = int64(len(r.Body))
 	modifiedRequest.URL.Path = m[1]
@@ -121,15 +177,44 @@ func (c *Controller) proxy(r *web.Request, logger *logrus.Entry, broker *types.S
 	modifiedRequest.Host = targetBrokerURL.Host
 
 	proxy := buildProxy(targetBrokerURL, logger, broker)
+	proxy.Transport = transport
+
+	// the circuit breaker is acquired after the authentication, so that it is held only during the call to the broker
+	failed := false
+	if c.CircuitBreakers != nil {
+		release, retryAfter, err := c.CircuitBreakers.Acquire(ctx, broker)
+		if err != nil {
+			logger.WithError(err).Warnf("Call to service broker %s failed fast", broker.Name)
+			return util.NewJSONResponseWithHeaders(http.StatusServiceUnavailable, err, map[string]string{
+				"Retry-After": strconv.Itoa(brokerclient.RetryAfterSeconds(retryAfter)),
+			})
+		}
+		defer func() {
+			release(failed)
+		}()
+	}
 
 	recorder := httptest.NewRecorder()
 
+	started := time.Now()
 	proxy.ServeHTTP(recorder, modifiedRequest)
+	failed = recorder.Code >= http.StatusInternalServerError
+	if recorder.Code == http.StatusUnauthorized && c.BrokerAuthenticator != nil {
+		c.BrokerAuthenticator.InvalidateToken(broker)
+	}
 
 	brokerResponseBody, err := ioutil.ReadAll(recorder.Body)
 	if err != nil {
 		return nil, err
 	}
+	if c.Recorder != nil && recordingEnabled(ctx, broker) {
+		exchange, err := newExchange(broker, modifiedRequest, m[1], r.Body, recorder.Code, recorder.Header(), brokerResponseBody, started)
+		if err != nil {
+			logger.WithError(err).Errorf("Could not record exchange with service broker %s", broker.Name)
+		} else {
+			c.Recorder.Record(exchange)
+		}
+	}
 
 	responseBody := brokerResponseBody
 
@@ -162,6 +247,26 @@ func (c *Controller) proxy(r *web.Request, logger *logrus.Entry, broker *types.S
 	return resp, nil
 }
 
+// authenticate replaces the authorization of the request with the credentials of the broker and returns the transport to the broker
+func (c *Controller) authenticate(request *http.Request, broker *types.ServiceBroker) (http.RoundTripper, error) {
+	request.Header.Del("Authorization")
+	if c.BrokerAuthenticator == nil {
+		if broker.Credentials != nil && broker.Credentials.Basic != nil {
+			request.SetBasicAuth(broker.Credentials.Basic.Username, broker.Credentials.Basic.Password)
+		}
+		return http.DefaultTransport, nil
+	}
+
+	authorization, err := c.BrokerAuthenticator.Authorization(request.Context(), broker)
+	if err != nil {
+		return nil, err
+	}
+	if authorization != "" {
+		request.Header.Set("Authorization", authorization)
+	}
+	return c.BrokerAuthenticator.Transport(broker)
+}
+
 func buildProxy(targetBrokerURL *url.URL, logger *logrus.Entry, broker *types.ServiceBroker) *httputil.ReverseProxy {
 	proxy := httputil.NewSingleHostReverseProxy(targetBrokerURL)
 	director := proxy.Director
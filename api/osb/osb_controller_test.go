@@ -0,0 +1,121 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package osb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/brokerclient"
+	"github.com/Peripli/service-manager/pkg/httpclient"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Controller", func() {
+	var breakers *brokerclient.CircuitBreakers
+	var broker *types.ServiceBroker
+
+	BeforeEach(func() {
+		breakers = brokerclient.NewCircuitBreakers(&brokerclient.CircuitBreakerSettings{
+			FailureThreshold: 2,
+			OpenTimeout:      time.Minute,
+			HalfOpenRequests: 1,
+		})
+		broker = &types.ServiceBroker{
+			Base: types.Base{ID: "broker-id"},
+			Name: "broker",
+		}
+	})
+
+	It("fails fast proxied calls with Retry-After", func() {
+		brokerRequests := 0
+		brokerServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			brokerRequests++
+			w.WriteHeader(http.StatusInternalServerError)
+			_, err := w.Write([]byte(`{}`))
+			Expect(err).ToNot(HaveOccurred())
+		}))
+		defer brokerServer.Close()
+		broker.BrokerURL = brokerServer.URL
+		controller := &Controller{
+			BrokerFetcher: func(ctx context.Context, brokerID string) (*types.ServiceBroker, error) {
+				return broker, nil
+			},
+			CircuitBreakers: breakers,
+		}
+
+		handle := func() *web.Response {
+			request := httptest.NewRequest(http.MethodGet, web.OSBURL+"/broker-id/v2/service_instances/instance-id", nil)
+			response, err := controller.proxyHandler(&web.Request{
+				Request:    request,
+				PathParams: map[string]string{BrokerIDPathParam: "broker-id"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			return response
+		}
+
+		Expect(handle().StatusCode).To(Equal(http.StatusInternalServerError))
+		Expect(handle().StatusCode).To(Equal(http.StatusInternalServerError))
+		response := handle()
+		Expect(response.StatusCode).To(Equal(http.StatusServiceUnavailable))
+		Expect(response.Header.Get("Retry-After")).To(Equal("60"))
+		Expect(brokerRequests).To(Equal(2))
+	})
+
+	It("acquires the circuit breaker only after the authentication to the broker", func() {
+		breakers = brokerclient.NewCircuitBreakers(&brokerclient.CircuitBreakerSettings{RateLimit: 1})
+		brokerServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			_, err := w.Write([]byte(`{}`))
+			Expect(err).ToNot(HaveOccurred())
+		}))
+		defer brokerServer.Close()
+		broker.BrokerURL = brokerServer.URL
+
+		handle := func(controller *Controller, broker *types.ServiceBroker) (*web.Response, error) {
+			controller.BrokerFetcher = func(ctx context.Context, brokerID string) (*types.ServiceBroker, error) {
+				return broker, nil
+			}
+			controller.CircuitBreakers = breakers
+			request := httptest.NewRequest(http.MethodGet, web.OSBURL+"/broker-id/v2/service_instances/instance-id", nil)
+			return controller.proxyHandler(&web.Request{
+				Request:    request,
+				PathParams: map[string]string{BrokerIDPathParam: "broker-id"},
+			})
+		}
+
+		oauth2Broker := *broker
+		oauth2Broker.Credentials = &types.Credentials{OAuth2: &types.OAuth2{TokenURL: "https://token.example.com", ClientID: "client-id"}}
+		authenticator := brokerclient.NewBrokerAuthenticator(func(*http.Request) (*http.Response, error) {
+			return nil, errors.New("token endpoint is unreachable")
+		}, httpclient.DefaultSettings())
+		_, err := handle(&Controller{BrokerAuthenticator: authenticator}, &oauth2Broker)
+		Expect(err).To(HaveOccurred())
+
+		// the failed authentication did not consume the only call of the rate limit
+		response, err := handle(&Controller{}, broker)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+	})
+})
@@ -0,0 +1,133 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package osb
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// ParametersSchemaPluginName is the name of the plugin which validates OSB parameters against the schemas of the plans
+const ParametersSchemaPluginName = "ParametersSchemaPlugin"
+
+type parametersSchemaPlugin struct {
+	repository storage.Repository
+}
+
+// NewParametersSchemaPlugin creates new plugin that validates the parameters of provision, update and bind requests
+// against the parameters schemas of the plans before the requests are forwarded to the broker
+func NewParametersSchemaPlugin(repository storage.Repository) *parametersSchemaPlugin {
+	return &parametersSchemaPlugin{
+		repository: repository,
+	}
+}
+
+// Name returns the name of the plugin
+func (p *parametersSchemaPlugin) Name() string {
+	return ParametersSchemaPluginName
+}
+
+// Provision intercepts provision requests and validates their parameters against the service_instance.create schema
+func (p *parametersSchemaPlugin) Provision(req *web.Request, next web.Handler) (*web.Response, error) {
+	return p.validate(req, next, types.InstanceCreateParametersSchema)
+}
+
+// UpdateService intercepts update service instance requests and validates their parameters against the service_instance.update schema
+func (p *parametersSchemaPlugin) UpdateService(req *web.Request, next web.Handler) (*web.Response, error) {
+	return p.validate(req, next, types.InstanceUpdateParametersSchema)
+}
+
+// Bind intercepts bind requests and validates their parameters against the service_binding.create schema
+func (p *parametersSchemaPlugin) Bind(req *web.Request, next web.Handler) (*web.Response, error) {
+	return p.validate(req, next, types.BindingCreateParametersSchema)
+}
+
+func (p *parametersSchemaPlugin) validate(req *web.Request, next web.Handler, schemaPath string) (*web.Response, error) {
+	ctx := req.Context()
+	parameters := gjson.GetBytes(req.Body, "parameters")
+	if !parameters.Exists() {
+		return next.Handle(req)
+	}
+
+	plan, err := p.requestPlan(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if plan == nil {
+		log.C(ctx).Debugf("Plan of the request not found. Parameters are not validated")
+		return next.Handle(req)
+	}
+	violations, err := plan.ValidateParameters(schemaPath, json.RawMessage(parameters.Raw))
+	if err != nil {
+		log.C(ctx).WithError(err).Warnf("Parameters of the request are not validated")
+		return next.Handle(req)
+	}
+	if len(violations) != 0 {
+		return nil, types.NewParametersError(violations)
+	}
+	return next.Handle(req)
+}
+
+// requestPlan returns the plan of the request, which is the plan of the instance for updates without plan, or nil if it is not found
+func (p *parametersSchemaPlugin) requestPlan(ctx context.Context, req *web.Request) (*types.ServicePlan, error) {
+	catalogPlanID := gjson.GetBytes(req.Body, "plan_id").String()
+	if catalogPlanID == "" {
+		instanceID := req.PathParams[InstanceIDPathParam]
+		instance, err := p.repository.Get(ctx, types.ServiceInstanceType, query.ByField(query.EqualsOperator, "id", instanceID))
+		if err != nil {
+			if err == util.ErrNotFoundInStorage {
+				return nil, nil
+			}
+			return nil, util.HandleStorageError(err, types.ServiceInstanceType.String())
+		}
+		return p.getPlan(ctx, query.ByField(query.EqualsOperator, "id", instance.(*types.ServiceInstance).ServicePlanID))
+	}
+
+	catalogServiceID := gjson.GetBytes(req.Body, "service_id").String()
+	serviceOffering, err := p.repository.Get(ctx, types.ServiceOfferingType,
+		query.ByField(query.EqualsOperator, "broker_id", req.PathParams[BrokerIDPathParam]),
+		query.ByField(query.EqualsOperator, "catalog_id", catalogServiceID))
+	if err != nil {
+		if err == util.ErrNotFoundInStorage {
+			return nil, nil
+		}
+		return nil, util.HandleStorageError(err, types.ServiceOfferingType.String())
+	}
+	return p.getPlan(ctx,
+		query.ByField(query.EqualsOperator, "service_offering_id", serviceOffering.GetID()),
+		query.ByField(query.EqualsOperator, "catalog_id", catalogPlanID))
+}
+
+func (p *parametersSchemaPlugin) getPlan(ctx context.Context, criteria ...query.Criterion) (*types.ServicePlan, error) {
+	plan, err := p.repository.Get(ctx, types.ServicePlanType, criteria...)
+	if err != nil {
+		if err == util.ErrNotFoundInStorage {
+			return nil, nil
+		}
+		return nil, util.HandleStorageError(err, types.ServicePlanType.String())
+	}
+	return plan.(*types.ServicePlan), nil
+}
@@ -0,0 +1,231 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package osb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+)
+
+// RecordingLabelKey is the label of a broker which enables the recording of its OSB exchanges if its value is true
+const RecordingLabelKey = "osb_recording"
+
+// redactedValue replaces the sensitive headers and fields of recorded exchanges
+const redactedValue = "<redacted>"
+
+// sensitiveHeaders are the headers which are never recorded in clear
+var sensitiveHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization", "X-Broker-Api-Originating-Identity"}
+
+// sensitiveFields are the parts of field names which denote fields which are never recorded in clear
+var sensitiveFields = []string{"credentials", "password", "secret", "token", "private_key"}
+
+// Exchange is a recorded OSB request to a broker and the response of the broker. The sensitive headers and the
+// credentials, passwords, secrets, tokens and private keys in the bodies are redacted.
+type Exchange struct {
+	ID         string    `json:"id"`
+	BrokerID   string    `json:"broker_id"`
+	BrokerName string    `json:"broker_name"`
+	Time       time.Time `json:"time"`
+	Duration   string    `json:"duration"`
+
+	Method        string          `json:"method"`
+	Path          string          `json:"path"`
+	Query         string          `json:"query,omitempty"`
+	RequestHeader http.Header     `json:"request_header"`
+	RequestBody   json.RawMessage `json:"request_body,omitempty"`
+
+	StatusCode     int             `json:"status_code"`
+	ResponseHeader http.Header     `json:"response_header"`
+	ResponseBody   json.RawMessage `json:"response_body,omitempty"`
+}
+
+// Recorder keeps the latest OSB exchanges of the brokers which enable recording with the osb_recording label.
+// The recorder is local to the Service Manager instance and drops the oldest exchanges once it is full.
+type Recorder struct {
+	mutex        sync.RWMutex
+	maxExchanges int
+	exchanges    []*Exchange
+}
+
+// NewRecorder returns a Recorder which keeps at most maxExchanges exchanges
+func NewRecorder(maxExchanges int) *Recorder {
+	return &Recorder{
+		maxExchanges: maxExchanges,
+	}
+}
+
+// Record records the exchange and drops the oldest exchange if the recorder is full
+func (r *Recorder) Record(exchange *Exchange) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(r.exchanges) >= r.maxExchanges {
+		last := len(r.exchanges) - 1
+		copy(r.exchanges, r.exchanges[1:])
+		r.exchanges[last] = nil
+		r.exchanges = r.exchanges[:last]
+	}
+	r.exchanges = append(r.exchanges, exchange)
+}
+
+// List returns the recorded exchanges of the broker, or of all brokers if brokerID is empty, starting with the latest one
+func (r *Recorder) List(brokerID string) []*Exchange {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	exchanges := make([]*Exchange, 0)
+	for i := len(r.exchanges) - 1; i >= 0; i-- {
+		if brokerID == "" || r.exchanges[i].BrokerID == brokerID {
+			exchanges = append(exchanges, r.exchanges[i])
+		}
+	}
+	return exchanges
+}
+
+// Get returns the recorded exchange with the id
+func (r *Recorder) Get(id string) (*Exchange, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for _, exchange := range r.exchanges {
+		if exchange.ID == id {
+			return exchange, true
+		}
+	}
+	return nil, false
+}
+
+// Clear removes the recorded exchanges of the broker, or of all brokers if brokerID is empty
+func (r *Recorder) Clear(brokerID string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	exchanges := r.exchanges[:0]
+	for _, exchange := range r.exchanges {
+		if brokerID != "" && exchange.BrokerID != brokerID {
+			exchanges = append(exchanges, exchange)
+		}
+	}
+	for i := len(exchanges); i < len(r.exchanges); i++ {
+		r.exchanges[i] = nil
+	}
+	r.exchanges = exchanges
+}
+
+// newExchange returns a sanitized copy of the request to the broker, which was sent at started, and the response of the broker.
+// The path is the OSB path of the request.
+func newExchange(broker *types.ServiceBroker, request *http.Request, path string, requestBody []byte, statusCode int, responseHeader http.Header, responseBody []byte, started time.Time) (*Exchange, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, fmt.Errorf("could not generate id of OSB exchange: %s", err)
+	}
+	return &Exchange{
+		ID:             id.String(),
+		BrokerID:       broker.ID,
+		BrokerName:     broker.Name,
+		Time:           started.UTC(),
+		Duration:       time.Since(started).String(),
+		Method:         request.Method,
+		Path:           path,
+		Query:          request.URL.RawQuery,
+		RequestHeader:  sanitizeHeader(request.Header),
+		RequestBody:    sanitizeBody(requestBody),
+		StatusCode:     statusCode,
+		ResponseHeader: sanitizeHeader(responseHeader),
+		ResponseBody:   sanitizeBody(responseBody),
+	}, nil
+}
+
+// recordingEnabled returns whether the OSB exchanges of the broker are recorded
+func recordingEnabled(ctx context.Context, broker *types.ServiceBroker) bool {
+	values := broker.Labels[RecordingLabelKey]
+	if len(values) == 0 {
+		return false
+	}
+	enabled, err := strconv.ParseBool(values[0])
+	if err != nil {
+		log.C(ctx).WithError(err).Errorf("Invalid value %s of label %s of broker %s. Exchanges are not recorded", values[0], RecordingLabelKey, broker.Name)
+		return false
+	}
+	return enabled
+}
+
+func sanitizeHeader(header http.Header) http.Header {
+	sanitized := make(http.Header, len(header))
+	for name, values := range header {
+		sanitized[name] = append([]string(nil), values...)
+	}
+	for _, name := range sensitiveHeaders {
+		if _, found := sanitized[http.CanonicalHeaderKey(name)]; found {
+			sanitized.Set(name, redactedValue)
+		}
+	}
+	return sanitized
+}
+
+// sanitizeBody returns the JSON body with redacted sensitive fields. Bodies which are not JSON are not recorded,
+// as their sensitive parts cannot be identified.
+func sanitizeBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		sanitized, _ := json.Marshal("<" + strconv.Itoa(len(body)) + " bytes which are not JSON>")
+		return sanitized
+	}
+	sanitized, err := json.Marshal(redactFields(value))
+	if err != nil {
+		return nil
+	}
+	return sanitized
+}
+
+func redactFields(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for field, fieldValue := range value {
+			if isSensitiveField(field) {
+				value[field] = redactedValue
+			} else {
+				value[field] = redactFields(fieldValue)
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = redactFields(item)
+		}
+	}
+	return value
+}
+
+func isSensitiveField(field string) bool {
+	field = strings.ToLower(field)
+	for _, sensitiveField := range sensitiveFields {
+		if strings.Contains(field, sensitiveField) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,217 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package osb
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Recorder", func() {
+	var recorder *Recorder
+
+	exchange := func(id, brokerID string) *Exchange {
+		return &Exchange{ID: id, BrokerID: brokerID}
+	}
+
+	BeforeEach(func() {
+		recorder = NewRecorder(2)
+	})
+
+	It("keeps the latest exchanges", func() {
+		recorder.Record(exchange("1", "broker-1"))
+		recorder.Record(exchange("2", "broker-2"))
+		recorder.Record(exchange("3", "broker-1"))
+
+		Expect(recorder.List("")).To(Equal([]*Exchange{exchange("3", "broker-1"), exchange("2", "broker-2")}))
+		_, found := recorder.Get("1")
+		Expect(found).To(BeFalse())
+	})
+
+	It("lists and clears the exchanges of a broker", func() {
+		recorder.Record(exchange("1", "broker-1"))
+		recorder.Record(exchange("2", "broker-2"))
+		Expect(recorder.List("broker-1")).To(Equal([]*Exchange{exchange("1", "broker-1")}))
+
+		recorder.Clear("broker-1")
+		Expect(recorder.List("")).To(Equal([]*Exchange{exchange("2", "broker-2")}))
+	})
+
+	It("redacts sensitive headers and fields", func() {
+		request := httptest.NewRequest(http.MethodPut, "/v2/service_instances/1/service_bindings/1?accepts_incomplete=true", nil)
+		request.Header.Set("Authorization", "Basic YWRtaW46YWRtaW4=")
+		request.Header.Set("X-Broker-API-Version", "2.14")
+		requestBody := []byte(`{"parameters": {"db_password": "secret", "size": 1}}`)
+		responseBody := []byte(`{"credentials": {"user": "admin"}, "endpoints": [{"host": "db", "access_token": "token"}]}`)
+
+		exchange, err := newExchange(&types.ServiceBroker{Base: types.Base{ID: "broker-id"}}, request, request.URL.Path, requestBody, http.StatusCreated, http.Header{}, responseBody, time.Now())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(exchange.Query).To(Equal("accepts_incomplete=true"))
+		Expect(exchange.RequestHeader.Get("Authorization")).To(Equal(redactedValue))
+		Expect(exchange.RequestHeader.Get("X-Broker-API-Version")).To(Equal("2.14"))
+		Expect(request.Header.Get("Authorization")).To(Equal("Basic YWRtaW46YWRtaW4="))
+		Expect(exchange.RequestBody).To(MatchJSON(`{"parameters": {"db_password": "<redacted>", "size": 1}}`))
+		Expect(exchange.ResponseBody).To(MatchJSON(`{"credentials": "<redacted>", "endpoints": [{"host": "db", "access_token": "<redacted>"}]}`))
+	})
+
+	It("does not record bodies which are not JSON", func() {
+		Expect(sanitizeBody([]byte("password=secret"))).To(MatchJSON(`"<15 bytes which are not JSON>"`))
+	})
+})
+
+var _ = Describe("Recording OSB exchanges", func() {
+	const bindingPath = web.OSBURL + "/broker-id/v2/service_instances/instance-id/service_bindings/binding-id"
+
+	var controller *Controller
+	var recordingsController *RecordingsController
+	var broker *types.ServiceBroker
+	var brokerServer *httptest.Server
+	var brokerRequests []*http.Request
+	var brokerRequestBodies []string
+
+	BeforeEach(func() {
+		brokerRequests = nil
+		brokerRequestBodies = nil
+		brokerServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := ioutil.ReadAll(r.Body)
+			Expect(err).ToNot(HaveOccurred())
+			brokerRequests = append(brokerRequests, r)
+			brokerRequestBodies = append(brokerRequestBodies, string(body))
+			w.WriteHeader(http.StatusCreated)
+			_, err = w.Write([]byte(`{"credentials": {"password": "secret"}}`))
+			Expect(err).ToNot(HaveOccurred())
+		}))
+		broker = &types.ServiceBroker{
+			Base:        types.Base{ID: "broker-id", Labels: types.Labels{RecordingLabelKey: {"true"}}},
+			Name:        "broker",
+			BrokerURL:   brokerServer.URL,
+			Credentials: &types.Credentials{Basic: &types.Basic{Username: "admin", Password: "admin"}},
+		}
+		brokerFetcher := func(ctx context.Context, brokerID string) (*types.ServiceBroker, error) {
+			return broker, nil
+		}
+		recorder := NewRecorder(10)
+		controller = &Controller{
+			BrokerFetcher: brokerFetcher,
+			Recorder:      recorder,
+		}
+		recordingsController = &RecordingsController{
+			Recorder:      recorder,
+			BrokerFetcher: brokerFetcher,
+			DoRequest:     http.DefaultClient.Do,
+		}
+	})
+
+	AfterEach(func() {
+		brokerServer.Close()
+	})
+
+	bind := func() {
+		request := httptest.NewRequest(http.MethodPut, bindingPath, nil)
+		response, err := controller.proxyHandler(&web.Request{
+			Request:    request,
+			PathParams: map[string]string{BrokerIDPathParam: "broker-id"},
+			Body:       []byte(`{"service_id": "service", "plan_id": "plan"}`),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusCreated))
+	}
+
+	It("records the exchanges of brokers which enable recording", func() {
+		bind()
+
+		exchanges := recordingsController.Recorder.List("broker-id")
+		Expect(exchanges).To(HaveLen(1))
+		Expect(exchanges[0].Method).To(Equal(http.MethodPut))
+		Expect(exchanges[0].Path).To(Equal("/v2/service_instances/instance-id/service_bindings/binding-id"))
+		Expect(exchanges[0].RequestHeader.Get("Authorization")).To(Equal(redactedValue))
+		Expect(exchanges[0].RequestBody).To(MatchJSON(`{"service_id": "service", "plan_id": "plan"}`))
+		Expect(exchanges[0].StatusCode).To(Equal(http.StatusCreated))
+		Expect(exchanges[0].ResponseBody).To(MatchJSON(`{"credentials": "<redacted>"}`))
+	})
+
+	It("does not record the exchanges of other brokers", func() {
+		broker.Labels = types.Labels{}
+		bind()
+		Expect(recordingsController.Recorder.List("")).To(BeEmpty())
+	})
+
+	It("replays recorded exchanges to the broker with its credentials", func() {
+		bind()
+		exchange := recordingsController.Recorder.List("")[0]
+
+		request := httptest.NewRequest(http.MethodPost, web.OSBRecordingsURL+"/"+exchange.ID+"/replay", strings.NewReader(""))
+		response, err := recordingsController.replayExchange(&web.Request{
+			Request:    request,
+			PathParams: map[string]string{web.PathParamID: exchange.ID},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		replayed := &Exchange{}
+		Expect(json.Unmarshal(response.Body, replayed)).To(Succeed())
+		Expect(replayed.StatusCode).To(Equal(http.StatusCreated))
+		Expect(brokerRequests).To(HaveLen(2))
+		Expect(brokerRequests[1].Header.Get("Authorization")).To(Equal(brokerRequests[0].Header.Get("Authorization")))
+		Expect(brokerRequests[1].URL.String()).To(Equal(brokerRequests[0].URL.String()))
+		Expect(brokerRequestBodies[1]).To(MatchJSON(brokerRequestBodies[0]))
+	})
+
+	It("does not send the credentials of the broker to other targets", func() {
+		bind()
+		exchange := recordingsController.Recorder.List("")[0]
+		broker.BrokerURL = "http://localhost:1"
+		recordingsController.ReplayHosts = []string{strings.TrimPrefix(brokerServer.URL, "http://")}
+
+		request := httptest.NewRequest(http.MethodPost, web.OSBRecordingsURL+"/"+exchange.ID+"/replay", nil)
+		_, err := recordingsController.replayExchange(&web.Request{
+			Request:    request,
+			PathParams: map[string]string{web.PathParamID: exchange.ID},
+			Body:       []byte(`{"target_url": "` + brokerServer.URL + `"}`),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(brokerRequests).To(HaveLen(2))
+		Expect(brokerRequests[1].Header.Get("Authorization")).To(BeEmpty())
+	})
+
+	It("does not replay exchanges to targets on other hosts", func() {
+		bind()
+		exchange := recordingsController.Recorder.List("")[0]
+
+		request := httptest.NewRequest(http.MethodPost, web.OSBRecordingsURL+"/"+exchange.ID+"/replay", nil)
+		_, err := recordingsController.replayExchange(&web.Request{
+			Request:    request,
+			PathParams: map[string]string{web.PathParamID: exchange.ID},
+			Body:       []byte(`{"target_url": "` + brokerServer.URL + `"}`),
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(brokerRequests).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,216 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package osb
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/brokerclient"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+const brokerIDQueryParam = "broker_id"
+
+// RecordingsController serves the OSB exchanges recorded by the Service Manager instance and replays them
+type RecordingsController struct {
+	Recorder      *Recorder
+	BrokerFetcher BrokerFetcherFunc
+	// BrokerAuthenticator authenticates the replays to the brokers
+	BrokerAuthenticator *brokerclient.BrokerAuthenticator
+	// DoRequest sends the replays to target URLs other than the broker, e.g. a local stub of the broker
+	DoRequest util.DoRequestFunc
+	// ReplayHosts are the hosts of the target URLs to which the exchanges may be replayed instead of the broker
+	ReplayHosts []string
+}
+
+type exchangesResponse struct {
+	Exchanges []*Exchange `json:"exchanges"`
+}
+
+type replayRequest struct {
+	// TargetURL is the URL to replay the exchange to instead of the URL of the broker. Its host must be one of the
+	// replay hosts. The credentials of the broker are sent only to the broker.
+	TargetURL string `json:"target_url"`
+}
+
+var _ web.Controller = &RecordingsController{}
+
+// Routes implements web.Controller
+func (c *RecordingsController) Routes() []web.Route {
+	return []web.Route{
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   web.OSBRecordingsURL,
+			},
+			Handler: c.listExchanges,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   fmt.Sprintf("%s/{%s}", web.OSBRecordingsURL, web.PathParamID),
+			},
+			Handler: c.getExchange,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodDelete,
+				Path:   web.OSBRecordingsURL,
+			},
+			Handler: c.clearExchanges,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPost,
+				Path:   fmt.Sprintf("%s/{%s}/replay", web.OSBRecordingsURL, web.PathParamID),
+			},
+			Handler: c.replayExchange,
+		},
+	}
+}
+
+func (c *RecordingsController) listExchanges(req *web.Request) (*web.Response, error) {
+	return util.NewJSONResponse(http.StatusOK, &exchangesResponse{
+		Exchanges: c.Recorder.List(req.URL.Query().Get(brokerIDQueryParam)),
+	})
+}
+
+func (c *RecordingsController) getExchange(req *web.Request) (*web.Response, error) {
+	exchange, err := c.exchange(req)
+	if err != nil {
+		return nil, err
+	}
+	return util.NewJSONResponse(http.StatusOK, exchange)
+}
+
+func (c *RecordingsController) clearExchanges(req *web.Request) (*web.Response, error) {
+	c.Recorder.Clear(req.URL.Query().Get(brokerIDQueryParam))
+	return util.NewJSONResponse(http.StatusOK, map[string]string{})
+}
+
+// replayExchange re-sends the recorded request to the broker or to the target URL and returns the new exchange.
+// As the recorded request is sanitized, the redacted headers and fields are replayed redacted.
+func (c *RecordingsController) replayExchange(req *web.Request) (*web.Response, error) {
+	ctx := req.Context()
+	exchange, err := c.exchange(req)
+	if err != nil {
+		return nil, err
+	}
+	replay := &replayRequest{}
+	if len(req.Body) != 0 {
+		if err := util.BytesToObject(req.Body, replay); err != nil {
+			return nil, err
+		}
+	}
+
+	broker, err := c.BrokerFetcher(ctx, exchange.BrokerID)
+	if err != nil {
+		return nil, err
+	}
+	targetURL := broker.BrokerURL
+	doRequest := c.DoRequest
+	if replay.TargetURL != "" {
+		parsedURL, err := url.Parse(replay.TargetURL)
+		if err != nil || !parsedURL.IsAbs() {
+			return nil, &util.HTTPError{
+				ErrorType:   "BadRequest",
+				Description: fmt.Sprintf("target_url %s must be an absolute URL", replay.TargetURL),
+				StatusCode:  http.StatusBadRequest,
+			}
+		}
+		if !c.isReplayHost(parsedURL.Host) {
+			return nil, &util.HTTPError{
+				ErrorType:   "BadRequest",
+				Description: fmt.Sprintf("target_url %s is not on one of the allowed replay hosts", replay.TargetURL),
+				StatusCode:  http.StatusBadRequest,
+			}
+		}
+		targetURL = replay.TargetURL
+	} else if c.BrokerAuthenticator != nil {
+		if doRequest, err = c.BrokerAuthenticator.DoRequestFunc(broker); err != nil {
+			return nil, err
+		}
+	} else if broker.Credentials != nil && broker.Credentials.Basic != nil {
+		doRequest = util.BasicAuthDecorator(broker.Credentials.Basic.Username, broker.Credentials.Basic.Password, doRequest)
+	}
+
+	replayURL := strings.TrimSuffix(targetURL, "/") + exchange.Path
+	if exchange.Query != "" {
+		replayURL += "?" + exchange.Query
+	}
+	request, err := http.NewRequest(exchange.Method, replayURL, bytes.NewReader(exchange.RequestBody))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range exchange.RequestHeader {
+		if len(values) == 1 && values[0] == redactedValue {
+			continue
+		}
+		request.Header[name] = append([]string(nil), values...)
+	}
+	request.Header.Del("Content-Length")
+
+	log.C(ctx).Infof("Replaying OSB exchange %s with service broker %s to %s", exchange.ID, broker.Name, replayURL)
+	started := time.Now()
+	response, err := doRequest(request.WithContext(ctx))
+	if err != nil {
+		return nil, &util.HTTPError{
+			ErrorType:   "ServiceBrokerErr",
+			Description: fmt.Sprintf("could not replay OSB exchange %s to %s: %s", exchange.ID, replayURL, err),
+			StatusCode:  http.StatusBadGateway,
+		}
+	}
+	responseBody, err := util.BodyToBytes(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	replayed, err := newExchange(broker, request, exchange.Path, exchange.RequestBody, response.StatusCode, response.Header, responseBody, started)
+	if err != nil {
+		return nil, err
+	}
+	return util.NewJSONResponse(http.StatusOK, replayed)
+}
+
+func (c *RecordingsController) exchange(req *web.Request) (*Exchange, error) {
+	id := req.PathParams[web.PathParamID]
+	exchange, found := c.Recorder.Get(id)
+	if !found {
+		return nil, &util.HTTPError{
+			ErrorType:   "NotFound",
+			Description: fmt.Sprintf("recorded OSB exchange with id %s not found", id),
+			StatusCode:  http.StatusNotFound,
+		}
+	}
+	return exchange, nil
+}
+
+func (c *RecordingsController) isReplayHost(host string) bool {
+	for _, replayHost := range c.ReplayHosts {
+		if strings.EqualFold(host, replayHost) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package osb
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+// ResponseCacheTTLLabelKey is the label of a broker which enables caching of its instance and binding fetch responses
+// for the specified duration, e.g. 30s
+const ResponseCacheTTLLabelKey = "osb_response_cache_ttl"
+
+// ResponseCache caches the responses of the idempotent OSB fetch operations per broker, platform and resource.
+// The cache is local to the Service Manager instance, so the TTL bounds the staleness of responses of resources
+// modified through other instances.
+type ResponseCache struct {
+	mutex      sync.Mutex
+	maxEntries int
+	entries    map[string]*cacheEntry
+	now        func() time.Time
+}
+
+type cacheEntry struct {
+	resource  string
+	response  *web.Response
+	expiresAt time.Time
+}
+
+// NewResponseCache returns a ResponseCache which holds at most maxEntries responses
+func NewResponseCache(maxEntries int) *ResponseCache {
+	return &ResponseCache{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*cacheEntry),
+		now:        time.Now,
+	}
+}
+
+// Get returns a copy of the cached response with the key if it has not expired
+func (rc *ResponseCache) Get(key string) (*web.Response, bool) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	entry, found := rc.entries[key]
+	if !found {
+		return nil, false
+	}
+	if !rc.now().Before(entry.expiresAt) {
+		delete(rc.entries, key)
+		return nil, false
+	}
+	return copyResponse(entry.response), true
+}
+
+// Put caches a copy of the response of the resource with the key for the ttl
+func (rc *ResponseCache) Put(key, resource string, response *web.Response, ttl time.Duration) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	if _, found := rc.entries[key]; !found && len(rc.entries) >= rc.maxEntries {
+		rc.evict()
+	}
+	rc.entries[key] = &cacheEntry{
+		resource:  resource,
+		response:  copyResponse(response),
+		expiresAt: rc.now().Add(ttl),
+	}
+}
+
+// Invalidate removes the cached responses of the resource and of the resources nested in it for all platforms,
+// e.g. of the bindings of an instance
+func (rc *ResponseCache) Invalidate(resource string) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	for key, entry := range rc.entries {
+		if entry.resource == resource || strings.HasPrefix(entry.resource, resource+"/") {
+			delete(rc.entries, key)
+		}
+	}
+}
+
+// evict removes the expired entries or an arbitrary entry if none has expired. It must be called under mutex.Lock.
+func (rc *ResponseCache) evict() {
+	now := rc.now()
+	for key, entry := range rc.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(rc.entries, key)
+		}
+	}
+	if len(rc.entries) < rc.maxEntries {
+		return
+	}
+	for key := range rc.entries {
+		delete(rc.entries, key)
+		return
+	}
+}
+
+func copyResponse(response *web.Response) *web.Response {
+	header := make(http.Header, len(response.Header))
+	for name, values := range response.Header {
+		header[name] = append([]string(nil), values...)
+	}
+	return &web.Response{
+		StatusCode: response.StatusCode,
+		Header:     header,
+		Body:       append([]byte(nil), response.Body...),
+	}
+}
+
+// responseCacheTTL returns for how long the fetch responses of the broker are cached or 0 if caching is disabled
+func responseCacheTTL(ctx context.Context, broker *types.ServiceBroker) time.Duration {
+	values := broker.Labels[ResponseCacheTTLLabelKey]
+	if len(values) == 0 {
+		return 0
+	}
+	ttl, err := time.ParseDuration(values[0])
+	if err != nil {
+		log.C(ctx).WithError(err).Errorf("Invalid value %s of label %s of broker %s. Responses are not cached", values[0], ResponseCacheTTLLabelKey, broker.Name)
+		return 0
+	}
+	return ttl
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package osb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ResponseCache", func() {
+	var cache *ResponseCache
+	var now time.Time
+
+	response := func(body string) *web.Response {
+		return &web.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: []byte(body)}
+	}
+
+	BeforeEach(func() {
+		now = time.Now()
+		cache = NewResponseCache(2)
+		cache.now = func() time.Time { return now }
+	})
+
+	It("returns cached responses until they expire", func() {
+		cache.Put("key", "/instance", response("cached"), time.Minute)
+		cached, found := cache.Get("key")
+		Expect(found).To(BeTrue())
+		Expect(string(cached.Body)).To(Equal("cached"))
+
+		now = now.Add(time.Minute)
+		_, found = cache.Get("key")
+		Expect(found).To(BeFalse())
+	})
+
+	It("invalidates the responses of the resource and of its nested resources", func() {
+		cache.Put("instance", "/instances/1", response("instance"), time.Minute)
+		cache.Put("binding", "/instances/1/bindings/1", response("binding"), time.Minute)
+		cache.Invalidate("/instances/1")
+		_, found := cache.Get("instance")
+		Expect(found).To(BeFalse())
+		_, found = cache.Get("binding")
+		Expect(found).To(BeFalse())
+	})
+
+	It("does not invalidate the responses of other resources", func() {
+		cache.Put("instance", "/instances/10", response("instance"), time.Minute)
+		cache.Invalidate("/instances/1")
+		_, found := cache.Get("instance")
+		Expect(found).To(BeTrue())
+	})
+
+	It("holds at most the maximum number of entries", func() {
+		cache.Put("expired", "/instances/1", response("expired"), time.Second)
+		cache.Put("valid", "/instances/2", response("valid"), time.Minute)
+		now = now.Add(time.Second)
+		cache.Put("new", "/instances/3", response("new"), time.Minute)
+		Expect(cache.entries).To(HaveLen(2))
+		_, found := cache.Get("valid")
+		Expect(found).To(BeTrue())
+	})
+})
+
+var _ = Describe("Controller with response cache", func() {
+	const instancePath = web.OSBURL + "/broker-id/v2/service_instances/instance-id"
+
+	var controller *Controller
+	var broker *types.ServiceBroker
+	var brokerServer *httptest.Server
+	var brokerRequests int
+
+	handle := func(method, path string) *web.Response {
+		request := httptest.NewRequest(method, path, nil)
+		ctx := web.ContextWithUser(request.Context(), &web.UserContext{Name: "platform"})
+		webRequest := &web.Request{
+			Request:    request.WithContext(ctx),
+			PathParams: map[string]string{BrokerIDPathParam: "broker-id"},
+		}
+		handler := controller.fetchHandler
+		if method != http.MethodGet {
+			handler = controller.modifyHandler
+		}
+		response, err := handler(webRequest)
+		Expect(err).ToNot(HaveOccurred())
+		return response
+	}
+
+	BeforeEach(func() {
+		brokerRequests = 0
+		brokerServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			brokerRequests++
+			w.WriteHeader(http.StatusOK)
+			_, err := w.Write([]byte(`{}`))
+			Expect(err).ToNot(HaveOccurred())
+		}))
+		broker = &types.ServiceBroker{
+			Base:        types.Base{ID: "broker-id", Labels: types.Labels{ResponseCacheTTLLabelKey: {"1m"}}},
+			Name:        "broker",
+			BrokerURL:   brokerServer.URL,
+			Credentials: &types.Credentials{Basic: &types.Basic{Username: "admin", Password: "admin"}},
+		}
+		controller = &Controller{
+			BrokerFetcher: func(ctx context.Context, brokerID string) (*types.ServiceBroker, error) {
+				return broker, nil
+			},
+			ResponseCache: NewResponseCache(10),
+		}
+	})
+
+	AfterEach(func() {
+		brokerServer.Close()
+	})
+
+	It("serves repeated fetches from the cache", func() {
+		Expect(handle(http.MethodGet, instancePath).StatusCode).To(Equal(http.StatusOK))
+		Expect(handle(http.MethodGet, instancePath).StatusCode).To(Equal(http.StatusOK))
+		Expect(brokerRequests).To(Equal(1))
+	})
+
+	It("fetches from the broker after the resource is modified", func() {
+		handle(http.MethodGet, instancePath)
+		handle(http.MethodDelete, instancePath)
+		handle(http.MethodGet, instancePath)
+		Expect(brokerRequests).To(Equal(3))
+	})
+
+	It("does not cache responses of brokers without the ttl label", func() {
+		broker.Labels = nil
+		handle(http.MethodGet, instancePath)
+		handle(http.MethodGet, instancePath)
+		Expect(brokerRequests).To(Equal(2))
+	})
+})
@@ -0,0 +1,207 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package osb
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// TransformationPluginName is the name of the plugin which applies the transformation rules to OSB requests and responses
+const TransformationPluginName = "TransformationPlugin"
+
+type transformationPlugin struct {
+	repository storage.Repository
+}
+
+// NewTransformationPlugin creates new plugin that transforms provision, update and bind requests before they are forwarded
+// to the broker and the responses of the broker according to the transformation rules which select the request
+func NewTransformationPlugin(repository storage.Repository) *transformationPlugin {
+	return &transformationPlugin{
+		repository: repository,
+	}
+}
+
+// Name returns the name of the plugin
+func (p *transformationPlugin) Name() string {
+	return TransformationPluginName
+}
+
+// Provision intercepts provision requests and applies the transformation rules to the request and the response
+func (p *transformationPlugin) Provision(req *web.Request, next web.Handler) (*web.Response, error) {
+	return p.transform(req, next, types.TransformProvision)
+}
+
+// UpdateService intercepts update service instance requests and applies the transformation rules to the request and the response
+func (p *transformationPlugin) UpdateService(req *web.Request, next web.Handler) (*web.Response, error) {
+	return p.transform(req, next, types.TransformUpdate)
+}
+
+// Bind intercepts bind requests and applies the transformation rules to the request and the response
+func (p *transformationPlugin) Bind(req *web.Request, next web.Handler) (*web.Response, error) {
+	return p.transform(req, next, types.TransformBind)
+}
+
+func (p *transformationPlugin) transform(req *web.Request, next web.Handler, operation types.TransformationOperation) (*web.Response, error) {
+	ctx := req.Context()
+	rules, err := p.selectRules(req, operation)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return next.Handle(req)
+	}
+
+	for _, rule := range rules {
+		if !rule.AppliesTo(operation, types.RequestPhase) {
+			continue
+		}
+		log.C(ctx).Debugf("Applying transformation rule %s to %s request", rule.Name, operation)
+		if req.Body, err = rule.Apply(req.Body); err != nil {
+			return nil, &util.HTTPError{
+				ErrorType:   "BadRequest",
+				Description: err.Error(),
+				StatusCode:  http.StatusBadRequest,
+			}
+		}
+	}
+
+	resp, err := next.Handle(req)
+	if err != nil || resp.StatusCode >= http.StatusBadRequest || !gjson.ValidBytes(resp.Body) {
+		return resp, err
+	}
+	// the broker has already processed the request, so the response is returned untransformed if a rule fails
+	body := resp.Body
+	for _, rule := range rules {
+		if !rule.AppliesTo(operation, types.ResponsePhase) {
+			continue
+		}
+		log.C(ctx).Debugf("Applying transformation rule %s to %s response", rule.Name, operation)
+		if body, err = rule.Apply(body); err != nil {
+			log.C(ctx).WithError(err).Errorf("Could not apply transformation rule %s to %s response. Returning the untransformed response", rule.Name, operation)
+			return resp, nil
+		}
+	}
+	resp.Body = body
+	return resp, nil
+}
+
+// selectRules returns the transformation rules of the operation which select the request, ordered by priority
+func (p *transformationPlugin) selectRules(req *web.Request, operation types.TransformationOperation) ([]*types.TransformationRule, error) {
+	ctx := req.Context()
+	objectList, err := p.repository.List(ctx, types.TransformationRuleType, query.OrderResultBy("priority", query.AscOrder))
+	if err != nil {
+		return nil, util.HandleStorageError(err, types.TransformationRuleType.String())
+	}
+	rules := objectList.(*types.TransformationRules).TransformationRules
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	selector, err := p.requestSelector(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	var selected []*types.TransformationRule
+	for _, rule := range rules {
+		if !rule.AppliesTo(operation, types.RequestPhase) && !rule.AppliesTo(operation, types.ResponsePhase) {
+			continue
+		}
+		if selector.matches(rule) {
+			selected = append(selected, rule)
+		}
+	}
+	return selected, nil
+}
+
+// transformationSelector holds the broker, service offering, plan and platform type of an OSB request
+type transformationSelector struct {
+	brokerID          string
+	serviceOfferingID string
+	servicePlanID     string
+	platformType      string
+}
+
+func (s *transformationSelector) matches(rule *types.TransformationRule) bool {
+	return matchesSelector(rule.BrokerID, s.brokerID) &&
+		matchesSelector(rule.ServiceOfferingID, s.serviceOfferingID) &&
+		matchesSelector(rule.ServicePlanID, s.servicePlanID) &&
+		matchesSelector(rule.PlatformType, s.platformType)
+}
+
+func matchesSelector(ruleValue, requestValue string) bool {
+	return ruleValue == "" || ruleValue == requestValue
+}
+
+func (p *transformationPlugin) requestSelector(ctx context.Context, req *web.Request) (*transformationSelector, error) {
+	platform, err := extractPlatformFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	selector := &transformationSelector{
+		brokerID:     req.PathParams[BrokerIDPathParam],
+		platformType: platform.Type,
+	}
+
+	catalogServiceID := gjson.GetBytes(req.Body, "service_id").String()
+	if catalogServiceID == "" {
+		return selector, nil
+	}
+	serviceOffering, err := p.repository.Get(ctx, types.ServiceOfferingType,
+		query.ByField(query.EqualsOperator, "broker_id", selector.brokerID),
+		query.ByField(query.EqualsOperator, "catalog_id", catalogServiceID))
+	if err != nil {
+		if err == util.ErrNotFoundInStorage {
+			return selector, nil
+		}
+		return nil, util.HandleStorageError(err, types.ServiceOfferingType.String())
+	}
+	selector.serviceOfferingID = serviceOffering.GetID()
+
+	catalogPlanID := gjson.GetBytes(req.Body, "plan_id").String()
+	if catalogPlanID == "" {
+		// the plan of an instance is not changed by an update without plan
+		instanceID := req.PathParams[InstanceIDPathParam]
+		instance, err := p.repository.Get(ctx, types.ServiceInstanceType, query.ByField(query.EqualsOperator, "id", instanceID))
+		if err == nil {
+			selector.servicePlanID = instance.(*types.ServiceInstance).ServicePlanID
+		} else if err != util.ErrNotFoundInStorage {
+			return nil, util.HandleStorageError(err, types.ServiceInstanceType.String())
+		}
+		return selector, nil
+	}
+	servicePlan, err := p.repository.Get(ctx, types.ServicePlanType,
+		query.ByField(query.EqualsOperator, "service_offering_id", selector.serviceOfferingID),
+		query.ByField(query.EqualsOperator, "catalog_id", catalogPlanID))
+	if err != nil {
+		if err == util.ErrNotFoundInStorage {
+			return selector, nil
+		}
+		return nil, util.HandleStorageError(err, types.ServicePlanType.String())
+	}
+	selector.servicePlanID = servicePlan.GetID()
+	return selector, nil
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package osb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TransformationPlugin", func() {
+	var repository *storagefakes.FakeStorage
+	var plugin *transformationPlugin
+	var rules []*types.TransformationRule
+	var brokerRequestBody []byte
+
+	next := web.HandlerFunc(func(req *web.Request) (*web.Response, error) {
+		brokerRequestBody = req.Body
+		return &web.Response{StatusCode: http.StatusCreated, Header: http.Header{}, Body: []byte(`{"dashboard_url":"http://dashboard.com"}`)}, nil
+	})
+
+	provision := func() (*web.Response, error) {
+		request := httptest.NewRequest(http.MethodPut, web.OSBURL+"/broker-id/v2/service_instances/instance-id", nil)
+		ctx := web.ContextWithUser(request.Context(), &web.UserContext{
+			Name: "platform",
+			Data: func(data interface{}) error {
+				return json.Unmarshal([]byte(`{"id":"platform-id","name":"platform","type":"cf"}`), data)
+			},
+		})
+		return plugin.Provision(&web.Request{
+			Request:    request.WithContext(ctx),
+			PathParams: map[string]string{BrokerIDPathParam: "broker-id"},
+			Body:       []byte(`{"parameters":{}}`),
+		}, next)
+	}
+
+	BeforeEach(func() {
+		rules = nil
+		brokerRequestBody = nil
+		repository = &storagefakes.FakeStorage{}
+		repository.ListStub = func(ctx context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			return &types.TransformationRules{TransformationRules: rules}, nil
+		}
+		plugin = NewTransformationPlugin(repository)
+	})
+
+	It("transforms the request and the response", func() {
+		rules = []*types.TransformationRule{
+			{
+				Name:       "request",
+				Operations: []types.TransformationOperation{types.TransformProvision},
+				Actions:    []*types.TransformationAction{{Type: types.SetAction, Path: "parameters.region", Value: json.RawMessage(`"eu"`)}},
+			},
+			{
+				Name:       "response",
+				Operations: []types.TransformationOperation{types.TransformProvision},
+				Phase:      types.ResponsePhase,
+				Actions:    []*types.TransformationAction{{Type: types.DeleteAction, Path: "dashboard_url"}},
+			},
+		}
+		response, err := provision()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(brokerRequestBody)).To(MatchJSON(`{"parameters":{"region":"eu"}}`))
+		Expect(string(response.Body)).To(MatchJSON(`{}`))
+	})
+
+	It("returns the untransformed response if a response rule fails", func() {
+		rules = []*types.TransformationRule{
+			{
+				Name:       "renamed",
+				Operations: []types.TransformationOperation{types.TransformProvision},
+				Phase:      types.ResponsePhase,
+				Actions:    []*types.TransformationAction{{Type: types.RenameAction, Path: "dashboard_url", To: "url"}},
+			},
+			{
+				Name:       "failing",
+				Operations: []types.TransformationOperation{types.TransformProvision},
+				Phase:      types.ResponsePhase,
+				Priority:   1,
+				Actions:    []*types.TransformationAction{{Type: types.SetAction, Path: "labels.*", Value: json.RawMessage(`"value"`)}},
+			},
+		}
+		response, err := provision()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusCreated))
+		Expect(string(response.Body)).To(MatchJSON(`{"dashboard_url":"http://dashboard.com"}`))
+	})
+})
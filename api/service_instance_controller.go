@@ -47,6 +47,13 @@ func (c *ServiceInstanceController) Routes() []web.Route {
 			},
 			Handler: c.CreateObject,
 		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   c.resourceBaseURL + web.SchemaURL,
+			},
+			Handler: c.GetSchema,
+		},
 		{
 			Endpoint: web.Endpoint{
 				Method: http.MethodGet,
@@ -0,0 +1,190 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package upgrades
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const (
+	servicePlanIDQueryParam = "service_plan_id"
+	brokerIDQueryParam      = "broker_id"
+
+	defaultConcurrency = 1
+)
+
+// Controller lists the outdated service instances and manages their bulk upgrades
+type Controller struct {
+	Repository storage.Repository
+	Upgrader   *Upgrader
+}
+
+type outdatedInstancesResponse struct {
+	ServiceInstances []*OutdatedInstance `json:"service_instances"`
+}
+
+type upgradesResponse struct {
+	Upgrades []*types.ServiceInstanceUpgrade `json:"upgrades"`
+}
+
+type upgradeRequest struct {
+	ServicePlanID    string `json:"service_plan_id"`
+	BrokerID         string `json:"broker_id"`
+	Concurrency      *int   `json:"concurrency"`
+	CanaryPercentage int    `json:"canary_percentage"`
+}
+
+var _ web.Controller = &Controller{}
+
+// Routes implements web.Controller
+func (c *Controller) Routes() []web.Route {
+	return []web.Route{
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   web.OutdatedInstancesURL,
+			},
+			Handler: c.listOutdatedInstances,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPost,
+				Path:   web.ServiceInstanceUpgradesURL,
+			},
+			Handler: c.startUpgrade,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   web.ServiceInstanceUpgradesURL,
+			},
+			Handler: c.listUpgrades,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodGet,
+				Path:   fmt.Sprintf("%s/{%s}", web.ServiceInstanceUpgradesURL, web.PathParamID),
+			},
+			Handler: c.getUpgrade,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPost,
+				Path:   fmt.Sprintf("%s/{%s}/pause", web.ServiceInstanceUpgradesURL, web.PathParamID),
+			},
+			Handler: c.pauseUpgrade,
+		},
+		{
+			Endpoint: web.Endpoint{
+				Method: http.MethodPost,
+				Path:   fmt.Sprintf("%s/{%s}/resume", web.ServiceInstanceUpgradesURL, web.PathParamID),
+			},
+			Handler: c.resumeUpgrade,
+		},
+	}
+}
+
+func (c *Controller) listOutdatedInstances(req *web.Request) (*web.Response, error) {
+	params := req.URL.Query()
+	outdatedInstances, err := ListOutdatedInstances(req.Context(), c.Repository, params.Get(servicePlanIDQueryParam), params.Get(brokerIDQueryParam))
+	if err != nil {
+		return nil, err
+	}
+	return util.NewJSONResponse(http.StatusOK, &outdatedInstancesResponse{
+		ServiceInstances: outdatedInstances,
+	})
+}
+
+func (c *Controller) startUpgrade(req *web.Request) (*web.Response, error) {
+	request := &upgradeRequest{}
+	if len(req.Body) != 0 {
+		if err := util.BytesToObject(req.Body, request); err != nil {
+			return nil, err
+		}
+	}
+	concurrency := defaultConcurrency
+	if request.Concurrency != nil {
+		concurrency = *request.Concurrency
+	}
+	if concurrency < 1 {
+		return nil, &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: fmt.Sprintf("concurrency %d must be positive", concurrency),
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+	if request.CanaryPercentage < 0 || request.CanaryPercentage > 100 {
+		return nil, &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: fmt.Sprintf("canary_percentage %d must be between 0 and 100", request.CanaryPercentage),
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+
+	upgrade, err := c.Upgrader.Start(req.Context(), &types.ServiceInstanceUpgrade{
+		ServicePlanID:    request.ServicePlanID,
+		BrokerID:         request.BrokerID,
+		Concurrency:      concurrency,
+		CanaryPercentage: request.CanaryPercentage,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return util.NewJSONResponseWithHeaders(http.StatusAccepted, upgrade, map[string]string{
+		"Location": fmt.Sprintf("%s/%s", web.ServiceInstanceUpgradesURL, upgrade.ID),
+	})
+}
+
+func (c *Controller) listUpgrades(req *web.Request) (*web.Response, error) {
+	upgrades, err := c.Upgrader.List(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	return util.NewJSONResponse(http.StatusOK, &upgradesResponse{
+		Upgrades: upgrades,
+	})
+}
+
+func (c *Controller) getUpgrade(req *web.Request) (*web.Response, error) {
+	upgrade, err := c.Upgrader.Get(req.Context(), req.PathParams[web.PathParamID])
+	if err != nil {
+		return nil, err
+	}
+	return util.NewJSONResponse(http.StatusOK, upgrade)
+}
+
+func (c *Controller) pauseUpgrade(req *web.Request) (*web.Response, error) {
+	upgrade, err := c.Upgrader.Pause(req.Context(), req.PathParams[web.PathParamID])
+	if err != nil {
+		return nil, err
+	}
+	return util.NewJSONResponse(http.StatusOK, upgrade)
+}
+
+func (c *Controller) resumeUpgrade(req *web.Request) (*web.Response, error) {
+	upgrade, err := c.Upgrader.Resume(req.Context(), req.PathParams[web.PathParamID])
+	if err != nil {
+		return nil, err
+	}
+	return util.NewJSONResponse(http.StatusOK, upgrade)
+}
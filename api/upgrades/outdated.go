@@ -0,0 +1,109 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package upgrades
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// OutdatedInstance is a service instance whose maintenance info version differs from the one of its plan
+type OutdatedInstance struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	ServicePlanID string `json:"service_plan_id"`
+	PlatformID    string `json:"platform_id"`
+	// Version is the maintenance info version of the instance. It is empty if the instance has no maintenance info.
+	Version string `json:"version,omitempty"`
+	// PlanVersion is the maintenance info version of the plan to which the instance can be upgraded
+	PlanVersion string `json:"plan_version"`
+
+	planMaintenanceInfo json.RawMessage
+}
+
+// ListOutdatedInstances returns the instances whose maintenance info version differs from the one of their plan.
+// Only plans with maintenance info are considered. The plans are limited to the plan and the broker if not empty.
+func ListOutdatedInstances(ctx context.Context, repository storage.Repository, planID, brokerID string) ([]*OutdatedInstance, error) {
+	var planCriteria []query.Criterion
+	if planID != "" {
+		planCriteria = append(planCriteria, query.ByField(query.EqualsOperator, "id", planID))
+	}
+	if brokerID != "" {
+		services, err := repository.List(ctx, types.ServiceOfferingType, query.ByField(query.EqualsOperator, "broker_id", brokerID))
+		if err != nil {
+			return nil, util.HandleStorageError(err, types.ServiceOfferingType.String())
+		}
+		if services.Len() == 0 {
+			return []*OutdatedInstance{}, nil
+		}
+		serviceIDs := make([]string, 0, services.Len())
+		for i := 0; i < services.Len(); i++ {
+			serviceIDs = append(serviceIDs, services.ItemAt(i).GetID())
+		}
+		planCriteria = append(planCriteria, query.ByField(query.InOperator, "service_offering_id", serviceIDs...))
+	}
+
+	plans, err := repository.List(ctx, types.ServicePlanType, planCriteria...)
+	if err != nil {
+		return nil, util.HandleStorageError(err, types.ServicePlanType.String())
+	}
+
+	outdatedInstances := make([]*OutdatedInstance, 0)
+	for i := 0; i < plans.Len(); i++ {
+		plan := plans.ItemAt(i).(*types.ServicePlan)
+		planMaintenanceInfo, err := types.ParseMaintenanceInfo(plan.MaintenanceInfo)
+		if err != nil {
+			log.C(ctx).Warnf("Ignoring invalid maintenance info of plan with id %s: %s", plan.ID, err)
+			continue
+		}
+		if planMaintenanceInfo == nil {
+			continue
+		}
+
+		instances, err := repository.List(ctx, types.ServiceInstanceType, query.ByField(query.EqualsOperator, "service_plan_id", plan.ID))
+		if err != nil {
+			return nil, util.HandleStorageError(err, types.ServiceInstanceType.String())
+		}
+		for j := 0; j < instances.Len(); j++ {
+			instance := instances.ItemAt(j).(*types.ServiceInstance)
+			version := ""
+			if maintenanceInfo, err := types.ParseMaintenanceInfo(instance.MaintenanceInfo); err == nil && maintenanceInfo != nil {
+				version = maintenanceInfo.Version
+			}
+			if version == planMaintenanceInfo.Version {
+				continue
+			}
+			outdatedInstances = append(outdatedInstances, &OutdatedInstance{
+				ID:                  instance.ID,
+				Name:                instance.Name,
+				ServicePlanID:       plan.ID,
+				PlatformID:          instance.PlatformID,
+				Version:             version,
+				PlanVersion:         planMaintenanceInfo.Version,
+				planMaintenanceInfo: plan.MaintenanceInfo,
+			})
+		}
+	}
+
+	return outdatedInstances, nil
+}
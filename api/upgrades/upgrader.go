@@ -0,0 +1,583 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package upgrades
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/Peripli/service-manager/operations"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// HeartbeatInterval is the interval in which a running upgrade updates its stored upgrade to show that it is alive
+const HeartbeatInterval = time.Minute
+
+// interruptedAfter is the time without heartbeat after which an upgrade in progress is considered interrupted
+const interruptedAfter = 5 * HeartbeatInterval
+
+var errNotInterrupted = errors.New("upgrade is not interrupted")
+
+// upgradeInstanceFunc upgrades a single service instance to its maintenance info
+type upgradeInstanceFunc func(ctx context.Context, upgrade *types.ServiceInstanceUpgrade, instance *types.UpgradedInstance) error
+
+// Upgrader runs the bulk upgrades of service instances. The upgrades are stored, so that they can be served, paused and
+// resumed by any Service Manager instance. The upgrades which are interrupted, e.g. by a restart, are resumed by the
+// operations maintainer.
+type Upgrader struct {
+	smCtx      context.Context
+	repository storage.TransactionalRepository
+	instances  storage.UpgradedInstanceStore
+	scheduler  *operations.Scheduler
+	settings   *operations.Settings
+	wg         *sync.WaitGroup
+
+	upgradeInstance   upgradeInstanceFunc
+	heartbeatInterval time.Duration
+	interruptedAfter  time.Duration
+}
+
+// NewUpgrader returns an upgrader which schedules the updates of the instances as operations with the provided scheduler
+func NewUpgrader(smCtx context.Context, repository storage.TransactionalRepository, instances storage.UpgradedInstanceStore, scheduler *operations.Scheduler, settings *operations.Settings, wg *sync.WaitGroup) *Upgrader {
+	upgrader := newUpgrader(smCtx, repository, instances, settings, wg)
+	upgrader.scheduler = scheduler
+	upgrader.upgradeInstance = upgrader.scheduleInstanceUpdate
+	return upgrader
+}
+
+func newUpgrader(smCtx context.Context, repository storage.TransactionalRepository, instances storage.UpgradedInstanceStore, settings *operations.Settings, wg *sync.WaitGroup) *Upgrader {
+	return &Upgrader{
+		smCtx:             smCtx,
+		repository:        repository,
+		instances:         instances,
+		settings:          settings,
+		wg:                wg,
+		heartbeatInterval: HeartbeatInterval,
+		interruptedAfter:  interruptedAfter,
+	}
+}
+
+// Start stores and starts the upgrade of the outdated instances of the SM platform which match the plan and broker of the upgrade
+func (u *Upgrader) Start(ctx context.Context, upgrade *types.ServiceInstanceUpgrade) (*types.ServiceInstanceUpgrade, error) {
+	outdatedInstances, err := ListOutdatedInstances(ctx, u.repository, upgrade.ServicePlanID, upgrade.BrokerID)
+	if err != nil {
+		return nil, err
+	}
+
+	UUID, err := uuid.NewV4()
+	if err != nil {
+		return nil, fmt.Errorf("could not generate GUID for upgrade: %s", err)
+	}
+	now := time.Now()
+	upgrade.ID = UUID.String()
+	upgrade.CreatedAt = now
+	upgrade.UpdatedAt = now
+	upgrade.Ready = true
+	upgrade.Paused = false
+	upgrade.Instances = make([]*types.UpgradedInstance, 0, len(outdatedInstances))
+	for _, outdatedInstance := range outdatedInstances {
+		// instances of other platforms are upgraded by their platforms
+		if outdatedInstance.PlatformID != types.SMPlatform {
+			continue
+		}
+		upgrade.Instances = append(upgrade.Instances, &types.UpgradedInstance{
+			InstanceID:      outdatedInstance.ID,
+			Version:         outdatedInstance.PlanVersion,
+			MaintenanceInfo: outdatedInstance.planMaintenanceInfo,
+		})
+	}
+	upgrade.LastOperation = &types.Operation{
+		Type:          types.UPDATE,
+		State:         types.IN_PROGRESS,
+		CorrelationID: log.CorrelationIDFromContext(ctx),
+	}
+	upgrade.LastOperation.Description = describeProgress(upgrade)
+
+	object, err := u.repository.Create(ctx, upgrade)
+	if err != nil {
+		return nil, util.HandleStorageError(err, types.ServiceInstanceUpgradeType.String())
+	}
+	created := object.(*types.ServiceInstanceUpgrade)
+	if err := u.instances.AddUpgradedInstances(ctx, created.ID, upgrade.Instances); err != nil {
+		// the upgrade is not started without its instances
+		byID := query.ByField(query.EqualsOperator, "id", created.ID)
+		if deleteErr := u.repository.Delete(ctx, types.ServiceInstanceUpgradeType, byID); deleteErr != nil {
+			log.C(ctx).Errorf("Could not delete upgrade %s without instances: %s", created.ID, deleteErr)
+		}
+		return nil, fmt.Errorf("could not store the instances of upgrade %s: %s", created.ID, err)
+	}
+	created.Instances = upgrade.Instances
+
+	log.C(ctx).Infof("Starting upgrade %s of %d service instances", created.ID, len(created.Instances))
+	// the upgrade outlives the request so it runs in the context of the Service Manager
+	runCtx := log.ContextWithLogger(u.smCtx, log.C(ctx))
+	u.wg.Add(1)
+	go u.run(runCtx, created.ID)
+
+	return created, nil
+}
+
+// Get returns the stored upgrade with the id
+func (u *Upgrader) Get(ctx context.Context, id string) (*types.ServiceInstanceUpgrade, error) {
+	object, err := u.repository.Get(ctx, types.ServiceInstanceUpgradeType, query.ByField(query.EqualsOperator, "id", id))
+	if err != nil {
+		return nil, util.HandleStorageError(err, types.ServiceInstanceUpgradeType.String())
+	}
+	upgrade := object.(*types.ServiceInstanceUpgrade)
+	if err := u.loadInstances(ctx, upgrade); err != nil {
+		return nil, err
+	}
+	return upgrade, nil
+}
+
+// List returns all stored upgrades starting with the oldest one
+func (u *Upgrader) List(ctx context.Context) ([]*types.ServiceInstanceUpgrade, error) {
+	objectList, err := u.repository.List(ctx, types.ServiceInstanceUpgradeType, query.OrderResultBy("created_at", query.AscOrder))
+	if err != nil {
+		return nil, util.HandleStorageError(err, types.ServiceInstanceUpgradeType.String())
+	}
+	upgrades := objectList.(*types.ServiceInstanceUpgrades).ServiceInstanceUpgrades
+	for _, upgrade := range upgrades {
+		if err := u.loadInstances(ctx, upgrade); err != nil {
+			return nil, err
+		}
+	}
+	return upgrades, nil
+}
+
+// loadInstances adds the stored instances to the upgrade. The progress of an upgrade in progress is described
+// by the states of its instances, as they are stored without updating the upgrade.
+func (u *Upgrader) loadInstances(ctx context.Context, upgrade *types.ServiceInstanceUpgrade) error {
+	instances, err := u.instances.ListUpgradedInstances(ctx, upgrade.ID)
+	if err != nil {
+		return fmt.Errorf("could not get the instances of upgrade %s: %s", upgrade.ID, err)
+	}
+	upgrade.Instances = instances
+	if upgrade.LastOperation.State == types.IN_PROGRESS {
+		upgrade.LastOperation.Description = describeProgress(upgrade)
+	}
+	return nil
+}
+
+// Pause stops scheduling the upgrades of further instances. The upgrades in progress are completed.
+func (u *Upgrader) Pause(ctx context.Context, id string) (*types.ServiceInstanceUpgrade, error) {
+	return u.update(ctx, id, func(upgrade *types.ServiceInstanceUpgrade) error {
+		if err := checkInProgress(upgrade); err != nil {
+			return err
+		}
+		upgrade.Paused = true
+		return nil
+	})
+}
+
+// Resume continues scheduling the upgrades of the instances of a paused upgrade
+func (u *Upgrader) Resume(ctx context.Context, id string) (*types.ServiceInstanceUpgrade, error) {
+	return u.update(ctx, id, func(upgrade *types.ServiceInstanceUpgrade) error {
+		if err := checkInProgress(upgrade); err != nil {
+			return err
+		}
+		upgrade.Paused = false
+		return nil
+	})
+}
+
+// RecoverInterruptedUpgrades resumes the upgrades in progress which are no longer run by any Service Manager instance,
+// e.g. because of a restart. The upgrades which were started before the reconciliation timeout of operations are failed instead.
+func (u *Upgrader) RecoverInterruptedUpgrades(ctx context.Context) {
+	interruptedBefore := time.Now().Add(-u.interruptedAfter)
+	objectList, err := u.repository.List(ctx, types.ServiceInstanceUpgradeType,
+		query.ByField(query.EqualsOperator, "state", string(types.IN_PROGRESS)),
+		query.ByField(query.LessThanOperator, "updated_at", util.ToRFCNanoFormat(interruptedBefore)))
+	if err != nil {
+		log.C(ctx).Errorf("Failed to fetch interrupted service instance upgrades: %s", err)
+		return
+	}
+
+	upgrades := objectList.(*types.ServiceInstanceUpgrades).ServiceInstanceUpgrades
+	for _, upgrade := range upgrades {
+		expired := upgrade.CreatedAt.Before(time.Now().Add(-u.settings.ReconciliationOperationTimeout))
+		// taking over the upgrade updates it, so that no other Service Manager instance recovers it as well
+		_, err := u.update(ctx, upgrade.ID, func(stored *types.ServiceInstanceUpgrade) error {
+			if stored.LastOperation.State != types.IN_PROGRESS || stored.UpdatedAt.After(interruptedBefore) {
+				return errNotInterrupted
+			}
+			if expired {
+				finishUpgrade(stored, "upgrade was interrupted")
+			}
+			return nil
+		})
+		if err == errNotInterrupted {
+			continue
+		}
+		if err != nil {
+			log.C(ctx).Errorf("Failed to recover interrupted upgrade %s: %s", upgrade.ID, err)
+			continue
+		}
+		if expired {
+			log.C(ctx).Errorf("Upgrade %s failed: it was interrupted and not recovered before the reconciliation timeout", upgrade.ID)
+			continue
+		}
+
+		log.C(ctx).Infof("Resuming interrupted upgrade %s", upgrade.ID)
+		u.wg.Add(1)
+		go u.run(u.smCtx, upgrade.ID)
+	}
+}
+
+// CleanupFinishedUpgrades deletes the upgrades which finished before the given time
+func (u *Upgrader) CleanupFinishedUpgrades(ctx context.Context, finishedBefore time.Time) {
+	criteria := []query.Criterion{
+		query.ByField(query.NotEqualsOperator, "state", string(types.IN_PROGRESS)),
+		query.ByField(query.LessThanOperator, "updated_at", util.ToRFCNanoFormat(finishedBefore)),
+	}
+	if err := u.repository.Delete(ctx, types.ServiceInstanceUpgradeType, criteria...); err != nil && err != util.ErrNotFoundInStorage {
+		log.C(ctx).Errorf("Failed to cleanup finished service instance upgrades: %s", err)
+	}
+}
+
+// update applies the change to the stored upgrade while its row is locked, so that the changes of the Service Manager
+// instances which run, pause and resume the upgrade do not override each other
+func (u *Upgrader) update(ctx context.Context, id string, change func(upgrade *types.ServiceInstanceUpgrade) error) (*types.ServiceInstanceUpgrade, error) {
+	var result *types.ServiceInstanceUpgrade
+	err := u.repository.InTransaction(ctx, func(ctx context.Context, repository storage.Repository) error {
+		object, err := repository.Get(ctx, types.ServiceInstanceUpgradeType,
+			query.ByField(query.EqualsOperator, "id", id),
+			query.LockResultForUpdate())
+		if err != nil {
+			return util.HandleStorageError(err, types.ServiceInstanceUpgradeType.String())
+		}
+		upgrade := object.(*types.ServiceInstanceUpgrade)
+		if err := u.loadInstances(ctx, upgrade); err != nil {
+			return err
+		}
+		if err := change(upgrade); err != nil {
+			return err
+		}
+		updated, err := repository.Update(ctx, upgrade, query.LabelChanges{})
+		if err != nil {
+			return util.HandleStorageError(err, types.ServiceInstanceUpgradeType.String())
+		}
+		result = updated.(*types.ServiceInstanceUpgrade)
+		result.Instances = upgrade.Instances
+		return nil
+	})
+	return result, err
+}
+
+func (u *Upgrader) run(ctx context.Context, id string) {
+	defer u.wg.Done()
+
+	heartbeatCtx, stopHeartbeat := context.WithCancel(ctx)
+	defer stopHeartbeat()
+	u.wg.Add(1)
+	go u.heartbeat(heartbeatCtx, id)
+
+	upgrade, err := u.Get(ctx, id)
+	if err != nil {
+		log.C(ctx).Errorf("Could not run upgrade %s: %s", id, err)
+		return
+	}
+
+	canaries := canaryCount(len(upgrade.Instances), upgrade.CanaryPercentage)
+	if canaries > 0 {
+		log.C(ctx).Infof("Upgrading %d canary service instances of upgrade %s", canaries, id)
+		if upgrade, err = u.upgradeInstances(ctx, upgrade, upgrade.Instances[:canaries]); err != nil {
+			log.C(ctx).Infof("Upgrade %s was interrupted: %s", id, err)
+			return
+		}
+		if failed := countInstances(upgrade, types.FAILED); failed > 0 {
+			u.finish(ctx, id, fmt.Sprintf("upgrade of %d canary service instances failed", failed))
+			return
+		}
+	}
+	if upgrade, err = u.upgradeInstances(ctx, upgrade, upgrade.Instances[canaries:]); err != nil {
+		// the upgrade stays in progress and is resumed by the operations maintainer
+		log.C(ctx).Infof("Upgrade %s was interrupted: %s", id, err)
+		return
+	}
+
+	failure := ""
+	if failed := countInstances(upgrade, types.FAILED); failed > 0 {
+		failure = fmt.Sprintf("upgrade of %d service instances failed", failed)
+	}
+	u.finish(ctx, id, failure)
+}
+
+// heartbeat updates the upgrade periodically, so that it is not considered interrupted while it is running
+func (u *Upgrader) heartbeat(ctx context.Context, id string) {
+	defer u.wg.Done()
+
+	ticker := time.NewTicker(u.heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := u.update(ctx, id, func(*types.ServiceInstanceUpgrade) error { return nil }); err != nil {
+				log.C(ctx).Warnf("Could not update heartbeat of upgrade %s: %s", id, err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// upgradeInstances upgrades the instances which are not upgraded yet with as many workers as the concurrency of the
+// upgrade allows and returns the stored upgrade afterwards
+func (u *Upgrader) upgradeInstances(ctx context.Context, upgrade *types.ServiceInstanceUpgrade, instances []*types.UpgradedInstance) (*types.ServiceInstanceUpgrade, error) {
+	jobs := make(chan *types.UpgradedInstance)
+	workers := &sync.WaitGroup{}
+	for i := 0; i < upgrade.Concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for instance := range jobs {
+				// the upgrades of instances are not started while the upgrade is paused
+				if u.waitWhilePaused(ctx, upgrade.ID) {
+					u.upgradeSingleInstance(ctx, upgrade, instance)
+				}
+			}
+		}()
+	}
+
+	for _, instance := range instances {
+		if instance.State == types.SUCCEEDED || instance.State == types.FAILED {
+			continue
+		}
+		select {
+		case jobs <- instance:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(jobs)
+	workers.Wait()
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return u.Get(ctx, upgrade.ID)
+}
+
+func (u *Upgrader) upgradeSingleInstance(ctx context.Context, upgrade *types.ServiceInstanceUpgrade, instance *types.UpgradedInstance) {
+	var err error
+	if instance.State == types.IN_PROGRESS && instance.OperationID != "" {
+		// the upgrade was interrupted while the instance was upgraded
+		err = u.awaitOperation(ctx, upgrade, instance)
+	} else {
+		if startErr := u.setInstanceState(ctx, upgrade.ID, instance, types.IN_PROGRESS, nil); startErr != nil {
+			log.C(ctx).Errorf("Could not start upgrade of service instance with id %s: %s", instance.InstanceID, startErr)
+			return
+		}
+		err = u.upgradeInstance(ctx, upgrade, instance)
+	}
+	if ctx.Err() != nil {
+		// the instance remains in progress and its upgrade is completed when the upgrade is resumed
+		return
+	}
+
+	state := types.SUCCEEDED
+	if err != nil {
+		log.C(ctx).Errorf("Upgrade of service instance with id %s to maintenance info version %s failed: %s", instance.InstanceID, instance.Version, err)
+		state = types.FAILED
+	} else {
+		log.C(ctx).Infof("Successfully upgraded service instance with id %s to maintenance info version %s", instance.InstanceID, instance.Version)
+	}
+	if storeErr := u.setInstanceState(ctx, upgrade.ID, instance, state, err); storeErr != nil {
+		log.C(ctx).Errorf("Could not store the upgrade state of service instance with id %s: %s", instance.InstanceID, storeErr)
+	}
+}
+
+// scheduleInstanceUpdate updates the maintenance info of the instance in an operation which is executed by the
+// interceptors of the instance updates, the same way as a PATCH of the instance
+func (u *Upgrader) scheduleInstanceUpdate(ctx context.Context, upgrade *types.ServiceInstanceUpgrade, instance *types.UpgradedInstance) error {
+	object, err := u.repository.Get(ctx, types.ServiceInstanceType, query.ByField(query.EqualsOperator, "id", instance.InstanceID))
+	if err != nil {
+		return util.HandleStorageError(err, types.ServiceInstanceType.String())
+	}
+	serviceInstance := object.(*types.ServiceInstance)
+	serviceInstance.MaintenanceInfo = instance.MaintenanceInfo
+
+	UUID, err := uuid.NewV4()
+	if err != nil {
+		return fmt.Errorf("could not generate GUID for %s: %s", types.OperationType, err)
+	}
+	operation := &types.Operation{
+		Base: types.Base{
+			ID:        UUID.String(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			Labels:    make(map[string][]string),
+			Ready:     true,
+		},
+		Type:          types.UPDATE,
+		State:         types.IN_PROGRESS,
+		ResourceID:    serviceInstance.ID,
+		ResourceType:  types.ServiceInstanceType,
+		PlatformID:    types.SMPlatform,
+		CorrelationID: upgrade.LastOperation.CorrelationID,
+	}
+	// the operation is stored with the upgrade first, so that a resumed upgrade awaits it instead of updating the instance again
+	instance.OperationID = operation.ID
+	if err := u.instances.UpdateUpgradedInstance(ctx, upgrade.ID, instance); err != nil {
+		return err
+	}
+
+	action := func(ctx context.Context, repository storage.Repository) (types.Object, error) {
+		object, err := repository.Update(ctx, serviceInstance, query.LabelChanges{})
+		return object, util.HandleStorageError(err, types.ServiceInstanceType.String())
+	}
+	_, err = u.scheduler.ScheduleSyncStorageAction(ctx, operation, action)
+	return err
+}
+
+// awaitOperation waits until the operation of an instance upgrade, which was started before the upgrade was interrupted,
+// is completed by the operations maintainer. The instance is upgraded again if the operation was never stored.
+func (u *Upgrader) awaitOperation(ctx context.Context, upgrade *types.ServiceInstanceUpgrade, instance *types.UpgradedInstance) error {
+	ticker := time.NewTicker(u.settings.PollingInterval)
+	defer ticker.Stop()
+	for {
+		object, err := u.repository.Get(ctx, types.OperationType, query.ByField(query.EqualsOperator, "id", instance.OperationID))
+		if err == util.ErrNotFoundInStorage {
+			return u.upgradeInstance(ctx, upgrade, instance)
+		}
+		if err != nil {
+			return util.HandleStorageError(err, types.OperationType.String())
+		}
+		operation := object.(*types.Operation)
+		switch operation.State {
+		case types.SUCCEEDED:
+			return nil
+		case types.FAILED:
+			return fmt.Errorf("operation %s failed: %s", operation.ID, operation.Errors)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// waitWhilePaused blocks while the stored upgrade is paused and returns false if the context is done in the meantime
+func (u *Upgrader) waitWhilePaused(ctx context.Context, id string) bool {
+	ticker := time.NewTicker(u.settings.PollingInterval)
+	defer ticker.Stop()
+	for {
+		upgrade, err := u.Get(ctx, id)
+		if err != nil {
+			log.C(ctx).Warnf("Could not check whether upgrade %s is paused: %s", id, err)
+		} else if !upgrade.Paused {
+			return true
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// setInstanceState stores the state of the instance without locking the upgrade, so that the instances are upgraded concurrently
+func (u *Upgrader) setInstanceState(ctx context.Context, id string, instance *types.UpgradedInstance, state types.OperationState, err error) error {
+	instance.State = state
+	if err != nil {
+		instance.Error = err.Error()
+	}
+	return u.instances.UpdateUpgradedInstance(ctx, id, instance)
+}
+
+// finish completes the upgrade which failed if there is a failure
+func (u *Upgrader) finish(ctx context.Context, id string, failure string) {
+	upgrade, err := u.update(ctx, id, func(upgrade *types.ServiceInstanceUpgrade) error {
+		finishUpgrade(upgrade, failure)
+		return nil
+	})
+	if err != nil {
+		log.C(ctx).Errorf("Could not complete upgrade %s: %s", id, err)
+		return
+	}
+	if failure == "" {
+		log.C(ctx).Infof("Upgrade %s finished: %s", id, upgrade.LastOperation.Description)
+		return
+	}
+	log.C(ctx).Errorf("Upgrade %s failed: %s", id, failure)
+}
+
+func finishUpgrade(upgrade *types.ServiceInstanceUpgrade, failure string) {
+	upgrade.Paused = false
+	upgrade.LastOperation.Description = describeProgress(upgrade)
+	if failure == "" {
+		upgrade.LastOperation.State = types.SUCCEEDED
+		return
+	}
+
+	upgrade.LastOperation.State = types.FAILED
+	upgradeErrors, err := json.Marshal(&util.HTTPError{
+		ErrorType:   "UpgradeFailed",
+		Description: failure,
+	})
+	if err == nil {
+		upgrade.LastOperation.Errors = upgradeErrors
+	}
+}
+
+func checkInProgress(upgrade *types.ServiceInstanceUpgrade) error {
+	if upgrade.LastOperation.State != types.IN_PROGRESS {
+		return &util.HTTPError{
+			ErrorType:   "Conflict",
+			Description: fmt.Sprintf("service instance upgrade with id %s is already %s", upgrade.ID, upgrade.LastOperation.State),
+			StatusCode:  http.StatusConflict,
+		}
+	}
+	return nil
+}
+
+// canaryCount returns the number of canary instances which is rounded up so that there is at least one canary
+// if the percentage is positive
+func canaryCount(total, percentage int) int {
+	return (total*percentage + 99) / 100
+}
+
+func countInstances(upgrade *types.ServiceInstanceUpgrade, state types.OperationState) int {
+	count := 0
+	for _, instance := range upgrade.Instances {
+		if instance.State == state {
+			count++
+		}
+	}
+	return count
+}
+
+func describeProgress(upgrade *types.ServiceInstanceUpgrade) string {
+	return fmt.Sprintf("%d of %d service instances upgraded, %d failed",
+		countInstances(upgrade, types.SUCCEEDED), len(upgrade.Instances), countInstances(upgrade, types.FAILED))
+}
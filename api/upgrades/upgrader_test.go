@@ -0,0 +1,424 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package upgrades
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Peripli/service-manager/operations"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestUpgrades(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Upgrades Suite")
+}
+
+// inMemoryUpgradedInstanceStore stores copies of the instances of upgrades like a round trip to the storage
+type inMemoryUpgradedInstanceStore struct {
+	mutex     sync.Mutex
+	instances map[string][]types.UpgradedInstance
+}
+
+func (s *inMemoryUpgradedInstanceStore) AddUpgradedInstances(_ context.Context, upgradeID string, instances []*types.UpgradedInstance) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, instance := range instances {
+		s.instances[upgradeID] = append(s.instances[upgradeID], *instance)
+	}
+	return nil
+}
+
+func (s *inMemoryUpgradedInstanceStore) ListUpgradedInstances(_ context.Context, upgradeID string) ([]*types.UpgradedInstance, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	result := make([]*types.UpgradedInstance, 0, len(s.instances[upgradeID]))
+	for _, instance := range s.instances[upgradeID] {
+		instanceCopy := instance
+		result = append(result, &instanceCopy)
+	}
+	return result, nil
+}
+
+func (s *inMemoryUpgradedInstanceStore) UpdateUpgradedInstance(_ context.Context, upgradeID string, instance *types.UpgradedInstance) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i := range s.instances[upgradeID] {
+		stored := &s.instances[upgradeID][i]
+		if stored.InstanceID == instance.InstanceID {
+			stored.OperationID = instance.OperationID
+			stored.State = instance.State
+			stored.Error = instance.Error
+			return nil
+		}
+	}
+	return util.ErrNotFoundInStorage
+}
+
+var _ = Describe("Upgrader", func() {
+	var repository *storagefakes.FakeStorage
+	var instanceStore *inMemoryUpgradedInstanceStore
+	var plans []*types.ServicePlan
+	var instances []*types.ServiceInstance
+	var storedUpgrades map[string]*types.ServiceInstanceUpgrade
+	var storeMutex sync.Mutex
+	var ctx context.Context
+	var cancel context.CancelFunc
+	var wg *sync.WaitGroup
+	var settings *operations.Settings
+	var upgrader *Upgrader
+
+	maintenanceInfo := func(version string) json.RawMessage {
+		return json.RawMessage(`{"version": "` + version + `"}`)
+	}
+
+	newInstance := func(id, platformID, version string) *types.ServiceInstance {
+		instance := &types.ServiceInstance{
+			Base:          types.Base{ID: id},
+			Name:          id,
+			ServicePlanID: "plan-id",
+			PlatformID:    platformID,
+		}
+		if version != "" {
+			instance.MaintenanceInfo = maintenanceInfo(version)
+		}
+		return instance
+	}
+
+	// copyUpgrade copies the upgrade like a round trip to the storage
+	copyUpgrade := func(upgrade *types.ServiceInstanceUpgrade) *types.ServiceInstanceUpgrade {
+		result := *upgrade
+		result.Instances = make([]*types.UpgradedInstance, 0, len(upgrade.Instances))
+		for _, instance := range upgrade.Instances {
+			instanceCopy := *instance
+			result.Instances = append(result.Instances, &instanceCopy)
+		}
+		lastOperation := *upgrade.LastOperation
+		result.LastOperation = &lastOperation
+		return &result
+	}
+
+	storeUpgrade := func(upgrade *types.ServiceInstanceUpgrade) types.Object {
+		storeMutex.Lock()
+		defer storeMutex.Unlock()
+		storedUpgrades[upgrade.ID] = copyUpgrade(upgrade)
+		return copyUpgrade(upgrade)
+	}
+
+	BeforeEach(func() {
+		plans = []*types.ServicePlan{
+			{Base: types.Base{ID: "plan-id"}, MaintenanceInfo: maintenanceInfo("2.0.0")},
+			{Base: types.Base{ID: "plan-without-maintenance-info-id"}},
+		}
+		instances = []*types.ServiceInstance{
+			newInstance("outdated-id", types.SMPlatform, "1.0.0"),
+			newInstance("up-to-date-id", types.SMPlatform, "2.0.0"),
+			newInstance("without-maintenance-info-id", types.SMPlatform, ""),
+			newInstance("other-platform-id", "platform-id", "1.0.0"),
+		}
+		storedUpgrades = make(map[string]*types.ServiceInstanceUpgrade)
+
+		repository = &storagefakes.FakeStorage{}
+		repository.ListStub = func(_ context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.ObjectList, error) {
+			switch objectType {
+			case types.ServicePlanType:
+				return &types.ServicePlans{ServicePlans: plans}, nil
+			case types.ServiceInstanceType:
+				result := &types.ServiceInstances{}
+				for _, instance := range instances {
+					if instance.ServicePlanID == criteria[0].RightOp[0] {
+						result.ServiceInstances = append(result.ServiceInstances, instance)
+					}
+				}
+				return result, nil
+			case types.ServiceInstanceUpgradeType:
+				storeMutex.Lock()
+				defer storeMutex.Unlock()
+				result := &types.ServiceInstanceUpgrades{}
+				for _, upgrade := range storedUpgrades {
+					if upgrade.LastOperation.State == types.IN_PROGRESS {
+						result.ServiceInstanceUpgrades = append(result.ServiceInstanceUpgrades, copyUpgrade(upgrade))
+					}
+				}
+				return result, nil
+			}
+			return nil, errors.New("unexpected object type")
+		}
+		repository.GetStub = func(_ context.Context, objectType types.ObjectType, criteria ...query.Criterion) (types.Object, error) {
+			if objectType != types.ServiceInstanceUpgradeType {
+				return nil, util.ErrNotFoundInStorage
+			}
+			storeMutex.Lock()
+			defer storeMutex.Unlock()
+			upgrade, found := storedUpgrades[criteria[0].RightOp[0]]
+			if !found {
+				return nil, util.ErrNotFoundInStorage
+			}
+			return copyUpgrade(upgrade), nil
+		}
+		repository.CreateStub = func(_ context.Context, object types.Object) (types.Object, error) {
+			return storeUpgrade(object.(*types.ServiceInstanceUpgrade)), nil
+		}
+		repository.UpdateStub = func(_ context.Context, object types.Object, _ query.LabelChanges, _ ...query.Criterion) (types.Object, error) {
+			object.SetUpdatedAt(time.Now())
+			return storeUpgrade(object.(*types.ServiceInstanceUpgrade)), nil
+		}
+		var txMutex sync.Mutex
+		repository.InTransactionStub = func(ctx context.Context, f func(ctx context.Context, storage storage.Repository) error) error {
+			txMutex.Lock()
+			defer txMutex.Unlock()
+			return f(ctx, repository)
+		}
+
+		ctx, cancel = context.WithCancel(context.Background())
+		wg = &sync.WaitGroup{}
+		settings = operations.DefaultSettings()
+		settings.PollingInterval = 10 * time.Millisecond
+		instanceStore = &inMemoryUpgradedInstanceStore{instances: make(map[string][]types.UpgradedInstance)}
+		upgrader = newUpgrader(ctx, repository, instanceStore, settings, wg)
+	})
+
+	AfterEach(func() {
+		cancel()
+		wg.Wait()
+	})
+
+	waitForUpgrade := func(id string) *types.ServiceInstanceUpgrade {
+		var upgrade *types.ServiceInstanceUpgrade
+		Eventually(func() types.OperationState {
+			var err error
+			upgrade, err = upgrader.Get(ctx, id)
+			Expect(err).ToNot(HaveOccurred())
+			return upgrade.LastOperation.State
+		}).ShouldNot(Equal(types.IN_PROGRESS))
+		return upgrade
+	}
+
+	Describe("ListOutdatedInstances", func() {
+		It("returns the instances whose maintenance info version differs from the one of their plan", func() {
+			outdatedInstances, err := ListOutdatedInstances(ctx, repository, "", "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(outdatedInstances).To(HaveLen(3))
+			Expect(outdatedInstances[0].ID).To(Equal("outdated-id"))
+			Expect(outdatedInstances[0].Version).To(Equal("1.0.0"))
+			Expect(outdatedInstances[0].PlanVersion).To(Equal("2.0.0"))
+			Expect(outdatedInstances[1].ID).To(Equal("without-maintenance-info-id"))
+			Expect(outdatedInstances[1].Version).To(BeEmpty())
+			Expect(outdatedInstances[2].ID).To(Equal("other-platform-id"))
+		})
+	})
+
+	It("upgrades the outdated instances of the SM platform", func() {
+		var mutex sync.Mutex
+		upgraded := make(map[string]string)
+		upgrader.upgradeInstance = func(_ context.Context, _ *types.ServiceInstanceUpgrade, instance *types.UpgradedInstance) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			upgraded[instance.InstanceID] = string(instance.MaintenanceInfo)
+			return nil
+		}
+
+		upgrade, err := upgrader.Start(ctx, &types.ServiceInstanceUpgrade{Concurrency: 1})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(upgrade.LastOperation.State).To(Equal(types.IN_PROGRESS))
+		Expect(repository.CreateCallCount()).To(Equal(1))
+
+		upgrade = waitForUpgrade(upgrade.ID)
+		Expect(upgrade.LastOperation.State).To(Equal(types.SUCCEEDED))
+		Expect(upgrade.LastOperation.Description).To(Equal("2 of 2 service instances upgraded, 0 failed"))
+		// the states of the instances are stored without updating the upgrade, which is only updated when it finishes
+		Expect(repository.UpdateCallCount()).To(Equal(1))
+		mutex.Lock()
+		defer mutex.Unlock()
+		Expect(upgraded).To(Equal(map[string]string{
+			"outdated-id":                 `{"version": "2.0.0"}`,
+			"without-maintenance-info-id": `{"version": "2.0.0"}`,
+		}))
+	})
+
+	It("does not upgrade more instances at the same time than the concurrency", func() {
+		for i := 0; i < 10; i++ {
+			instances = append(instances, newInstance(string(rune('a'+i)), types.SMPlatform, "1.0.0"))
+		}
+		var mutex sync.Mutex
+		running, maxRunning := 0, 0
+		upgrader.upgradeInstance = func(context.Context, *types.ServiceInstanceUpgrade, *types.UpgradedInstance) error {
+			mutex.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mutex.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mutex.Lock()
+			running--
+			mutex.Unlock()
+			return nil
+		}
+
+		upgrade, err := upgrader.Start(ctx, &types.ServiceInstanceUpgrade{Concurrency: 3})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(waitForUpgrade(upgrade.ID).LastOperation.State).To(Equal(types.SUCCEEDED))
+		mutex.Lock()
+		defer mutex.Unlock()
+		Expect(maxRunning).To(Equal(3))
+	})
+
+	It("stops the upgrade if a canary fails", func() {
+		upgrader.upgradeInstance = func(context.Context, *types.ServiceInstanceUpgrade, *types.UpgradedInstance) error {
+			return errors.New("broker error")
+		}
+
+		upgrade, err := upgrader.Start(ctx, &types.ServiceInstanceUpgrade{Concurrency: 1, CanaryPercentage: 10})
+		Expect(err).ToNot(HaveOccurred())
+		upgrade = waitForUpgrade(upgrade.ID)
+		Expect(upgrade.LastOperation.State).To(Equal(types.FAILED))
+		Expect(string(upgrade.LastOperation.Errors)).To(ContainSubstring("canary"))
+		Expect(upgrade.Instances[0].State).To(Equal(types.FAILED))
+		Expect(upgrade.Instances[0].Error).To(Equal("broker error"))
+		Expect(upgrade.Instances[1].State).To(BeEmpty())
+	})
+
+	It("pauses and resumes the upgrade with its stored state", func() {
+		started := make(chan struct{})
+		proceed := make(chan struct{})
+		upgrader.upgradeInstance = func(context.Context, *types.ServiceInstanceUpgrade, *types.UpgradedInstance) error {
+			started <- struct{}{}
+			<-proceed
+			return nil
+		}
+
+		upgrade, err := upgrader.Start(ctx, &types.ServiceInstanceUpgrade{Concurrency: 1})
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(started).Should(Receive())
+		// another Service Manager instance pauses and resumes the upgrade
+		otherUpgrader := newUpgrader(ctx, repository, instanceStore, settings, wg)
+		upgrade, err = otherUpgrader.Pause(ctx, upgrade.ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(upgrade.Paused).To(BeTrue())
+		proceed <- struct{}{}
+		Consistently(started, 100*time.Millisecond).ShouldNot(Receive())
+
+		upgrade, err = otherUpgrader.Resume(ctx, upgrade.ID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(upgrade.Paused).To(BeFalse())
+		Eventually(started).Should(Receive())
+		proceed <- struct{}{}
+		Expect(waitForUpgrade(upgrade.ID).LastOperation.State).To(Equal(types.SUCCEEDED))
+
+		_, err = otherUpgrader.Pause(ctx, upgrade.ID)
+		Expect(err).To(HaveOccurred())
+	})
+
+	Describe("RecoverInterruptedUpgrades", func() {
+		var interrupted *types.ServiceInstanceUpgrade
+
+		storeInterruptedUpgrade := func() {
+			storeUpgrade(interrupted)
+			Expect(instanceStore.AddUpgradedInstances(ctx, interrupted.ID, interrupted.Instances)).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			interrupted = &types.ServiceInstanceUpgrade{
+				Base: types.Base{
+					ID:        "upgrade-id",
+					CreatedAt: time.Now().Add(-time.Hour),
+					UpdatedAt: time.Now().Add(-time.Hour),
+				},
+				Concurrency: 1,
+				Instances: []*types.UpgradedInstance{
+					{InstanceID: "upgraded-id", State: types.SUCCEEDED},
+					{InstanceID: "interrupted-id", State: types.IN_PROGRESS},
+					{InstanceID: "pending-id"},
+				},
+				LastOperation: &types.Operation{State: types.IN_PROGRESS},
+			}
+		})
+
+		It("resumes the upgrades without heartbeat", func() {
+			storeInterruptedUpgrade()
+			var mutex sync.Mutex
+			var upgraded []string
+			upgrader.upgradeInstance = func(_ context.Context, _ *types.ServiceInstanceUpgrade, instance *types.UpgradedInstance) error {
+				mutex.Lock()
+				defer mutex.Unlock()
+				upgraded = append(upgraded, instance.InstanceID)
+				return nil
+			}
+
+			upgrader.RecoverInterruptedUpgrades(ctx)
+			upgrade := waitForUpgrade(interrupted.ID)
+			Expect(upgrade.LastOperation.State).To(Equal(types.SUCCEEDED))
+			Expect(upgrade.LastOperation.Description).To(Equal("3 of 3 service instances upgraded, 0 failed"))
+			mutex.Lock()
+			defer mutex.Unlock()
+			Expect(upgraded).To(Equal([]string{"interrupted-id", "pending-id"}))
+		})
+
+		It("does not resume the upgrades which are running", func() {
+			interrupted.UpdatedAt = time.Now()
+			storeInterruptedUpgrade()
+			var upgradedCount int32
+			upgrader.upgradeInstance = func(context.Context, *types.ServiceInstanceUpgrade, *types.UpgradedInstance) error {
+				atomic.AddInt32(&upgradedCount, 1)
+				return nil
+			}
+
+			upgrader.RecoverInterruptedUpgrades(ctx)
+			Consistently(func() int32 {
+				return atomic.LoadInt32(&upgradedCount)
+			}, 100*time.Millisecond).Should(BeZero())
+			upgrade, err := upgrader.Get(ctx, interrupted.ID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(upgrade.LastOperation.State).To(Equal(types.IN_PROGRESS))
+		})
+
+		It("fails the upgrades which were started before the reconciliation timeout", func() {
+			settings.ReconciliationOperationTimeout = time.Minute
+			storeInterruptedUpgrade()
+
+			upgrader.RecoverInterruptedUpgrades(ctx)
+			upgrade, err := upgrader.Get(ctx, interrupted.ID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(upgrade.LastOperation.State).To(Equal(types.FAILED))
+			Expect(string(upgrade.LastOperation.Errors)).To(ContainSubstring("interrupted"))
+		})
+	})
+
+	Describe("canaryCount", func() {
+		It("rounds up to at least one canary", func() {
+			Expect(canaryCount(10, 0)).To(Equal(0))
+			Expect(canaryCount(10, 1)).To(Equal(1))
+			Expect(canaryCount(10, 25)).To(Equal(3))
+			Expect(canaryCount(10, 100)).To(Equal(10))
+			Expect(canaryCount(0, 50)).To(Equal(0))
+		})
+	})
+})
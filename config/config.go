@@ -32,6 +32,7 @@ import (
 	"github.com/Peripli/service-manager/pkg/server"
 	"github.com/Peripli/service-manager/pkg/ws"
 	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/backup"
 	"github.com/spf13/pflag"
 )
 
@@ -46,6 +47,7 @@ type Settings struct {
 	HTTPClient   *httpclient.Settings
 	Health       *health.Settings
 	Multitenancy *multitenancy.Settings
+	Backup       *backup.Settings
 }
 
 // AddPFlags adds the SM config flags to the provided flag set
@@ -66,6 +68,7 @@ func DefaultSettings() *Settings {
 		HTTPClient:   httpclient.DefaultSettings(),
 		Health:       health.DefaultSettings(),
 		Multitenancy: multitenancy.DefaultSettings(),
+		Backup:       backup.DefaultSettings(),
 	}
 }
 
@@ -18,6 +18,9 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"strings"
 
 	"github.com/Peripli/service-manager/api/extensions/security"
 
@@ -35,6 +38,13 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	var command string
+	var commandArgs []string
+	if len(os.Args) > 1 && (os.Args[1] == sm.MigrateCommand || os.Args[1] == sm.ExportCommand || os.Args[1] == sm.ImportCommand) {
+		command = os.Args[1]
+		commandArgs, os.Args = splitCommandArgs(os.Args)
+	}
+
 	env, err := env.Default(ctx, config.AddPFlags)
 	if err != nil {
 		panic(err)
@@ -45,6 +55,14 @@ func main() {
 		panic(err)
 	}
 
+	if len(command) != 0 {
+		if err := runCommand(ctx, cfg, command, commandArgs); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	serviceManager, err := sm.New(ctx, cancel, env, cfg)
 	if err != nil {
 		panic(err)
@@ -55,3 +73,26 @@ func main() {
 
 	serviceManager.Build().Run()
 }
+
+// runCommand runs a command such as "migrate" instead of starting the Service Manager
+func runCommand(ctx context.Context, cfg *config.Settings, command string, args []string) error {
+	switch command {
+	case sm.ExportCommand:
+		return sm.Export(ctx, cfg, args, os.Stdout)
+	case sm.ImportCommand:
+		return sm.Import(ctx, cfg, args, os.Stdout)
+	default:
+		return sm.Migrate(ctx, cfg, args, os.Stdout)
+	}
+}
+
+// splitCommandArgs separates the arguments of a command such as "migrate up 2" from the
+// configuration flags which follow them, so that the flags are loaded as usual
+func splitCommandArgs(args []string) ([]string, []string) {
+	commandArgs := make([]string, 0)
+	i := 2
+	for ; i < len(args) && !strings.HasPrefix(args[i], "-"); i++ {
+		commandArgs = append(commandArgs, args[i])
+	}
+	return commandArgs, append([]string{args[0]}, args[i:]...)
+}
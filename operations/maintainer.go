@@ -50,8 +50,25 @@ type Maintainer struct {
 	settings *Settings
 	wg       *sync.WaitGroup
 
-	functors         []maintainerFunctor
-	operationLockers map[string]storage.Locker
+	functors          []maintainerFunctor
+	operationLockers  map[string]storage.Locker
+	lockerCreatorFunc storage.LockerCreatorFunc
+
+	partitionManager    storage.PartitionManager
+	partitionRetentions map[types.ObjectType]time.Duration
+
+	changeFeed     storage.ChangeFeed
+	changesKeepFor time.Duration
+
+	upgrades UpgradesMaintainer
+}
+
+// UpgradesMaintainer maintains the stored bulk upgrades of service instances
+type UpgradesMaintainer interface {
+	// RecoverInterruptedUpgrades resumes or fails the upgrades in progress which were interrupted, e.g. by a restart
+	RecoverInterruptedUpgrades(ctx context.Context)
+	// CleanupFinishedUpgrades deletes the upgrades which finished before the given time
+	CleanupFinishedUpgrades(ctx context.Context, finishedBefore time.Time)
 }
 
 // NewMaintainer constructs a Maintainer
@@ -105,10 +122,74 @@ func NewMaintainer(smCtx context.Context, repository storage.TransactionalReposi
 	}
 
 	maintainer.operationLockers = operationLockers
+	maintainer.lockerCreatorFunc = lockerCreatorFunc
 
 	return maintainer
 }
 
+// WithPartitionMaintenance makes the maintainer create the upcoming partitions of the tables of the object types and
+// drop their partitions which are older than the retention of the object type. It must be called before Run.
+func (om *Maintainer) WithPartitionMaintenance(partitionManager storage.PartitionManager, retentions map[types.ObjectType]time.Duration) *Maintainer {
+	om.partitionManager = partitionManager
+	om.partitionRetentions = retentions
+
+	functor := maintainerFunctor{
+		name:     "maintainPartitions",
+		execute:  om.maintainPartitions,
+		interval: om.settings.CleanupInterval,
+	}
+	om.operationLockers[functor.name] = om.lockerCreatorFunc(initialOperationsLockIndex + len(om.functors))
+	om.functors = append(om.functors, functor)
+	return om
+}
+
+// WithChangesCleanup makes the maintainer delete the changes of the change feed which are older than keepFor.
+// It must be called before Run.
+func (om *Maintainer) WithChangesCleanup(changeFeed storage.ChangeFeed, keepFor time.Duration) *Maintainer {
+	om.changeFeed = changeFeed
+	om.changesKeepFor = keepFor
+
+	functor := maintainerFunctor{
+		name:     "cleanupChanges",
+		execute:  om.cleanupChanges,
+		interval: om.settings.CleanupInterval,
+	}
+	om.operationLockers[functor.name] = om.lockerCreatorFunc(initialOperationsLockIndex + len(om.functors))
+	om.functors = append(om.functors, functor)
+	return om
+}
+
+// WithUpgradesMaintenance makes the maintainer recover the interrupted bulk upgrades of service instances every
+// recoveryInterval and delete the finished upgrades which are older than the lifespan of operations.
+// It must be called before Run.
+func (om *Maintainer) WithUpgradesMaintenance(upgrades UpgradesMaintainer, recoveryInterval time.Duration) *Maintainer {
+	om.upgrades = upgrades
+
+	functors := []maintainerFunctor{
+		{
+			name:     "recoverInterruptedUpgrades",
+			execute:  om.recoverInterruptedUpgrades,
+			interval: recoveryInterval,
+		},
+		{
+			name:     "cleanupFinishedUpgrades",
+			execute:  om.cleanupFinishedUpgrades,
+			interval: om.settings.CleanupInterval,
+		},
+	}
+	for _, functor := range functors {
+		om.operationLockers[functor.name] = om.lockerCreatorFunc(initialOperationsLockIndex + len(om.functors))
+		om.functors = append(om.functors, functor)
+	}
+	return om
+}
+
+// Scheduler returns the scheduler with which the maintainer reschedules operations, so that other
+// components can schedule operations without creating a scheduler of their own
+func (om *Maintainer) Scheduler() *Scheduler {
+	return om.scheduler
+}
+
 // Run starts the two recurring jobs responsible for cleaning up operations which are too old
 // and deleting orphan operations
 func (om *Maintainer) Run() {
@@ -205,6 +286,35 @@ func (om *Maintainer) cleanupInternalFailedOperations() {
 	log.D().Debug("Finished cleaning up failed internal operations")
 }
 
+// maintainPartitions creates the upcoming partitions and drops the expired partitions of the partitioned tables
+func (om *Maintainer) maintainPartitions() {
+	for objectType, retention := range om.partitionRetentions {
+		if err := om.partitionManager.MaintainPartitions(om.smCtx, objectType, retention); err != nil {
+			log.D().Errorf("Failed to maintain partitions of %s: %s", objectType, err)
+			continue
+		}
+		log.D().Debugf("Finished maintaining partitions of %s", objectType)
+	}
+}
+
+func (om *Maintainer) cleanupChanges() {
+	if err := om.changeFeed.DeleteChanges(om.smCtx, time.Now().Add(-om.changesKeepFor)); err != nil {
+		log.D().Errorf("Failed to cleanup changes: %s", err)
+		return
+	}
+	log.D().Debug("Finished cleaning up changes")
+}
+
+func (om *Maintainer) recoverInterruptedUpgrades() {
+	om.upgrades.RecoverInterruptedUpgrades(om.smCtx)
+	log.D().Debug("Finished recovering interrupted upgrades")
+}
+
+func (om *Maintainer) cleanupFinishedUpgrades() {
+	om.upgrades.CleanupFinishedUpgrades(om.smCtx, time.Now().Add(-om.settings.Lifespan))
+	log.D().Debug("Finished cleaning up finished upgrades")
+}
+
 // rescheduleUnprocessedOperations reschedules IN_PROGRESS operations which are reschedulable, not scheduled for deletion and no goroutine is processing at the moment
 func (om *Maintainer) rescheduleUnprocessedOperations() {
 	criteria := []query.Criterion{
@@ -243,13 +353,22 @@ func (om *Maintainer) rescheduleUnprocessedOperations() {
 				object, err := repository.Create(ctx, object)
 				return object, util.HandleStorageError(err, operation.ResourceType.String())
 			}
-			/* TODO: Uncomment and adapt once update flow is enabled
-			case types.UPDATE:
-				action = func(ctx context.Context, repository storage.Repository) (types.Object, error) {
-					object, err := repository.Update(ctx, objFromDB, labelChanges, criteria...)
-					return object, util.HandleStorageError(err, operation.ResourceType.String())
-				}
-			*/
+		case types.UPDATE:
+			// only the updates of service instances are resumed, the updates of other resources are rescheduled as before
+			if operation.ResourceType != types.ServiceInstanceType {
+				break
+			}
+			object, err := om.repository.Get(om.smCtx, operation.ResourceType, query.ByField(query.EqualsOperator, "id", operation.ResourceID))
+			if err != nil {
+				logger.Warnf("Failed to fetch resource with ID (%s) for operation with ID (%s): %s", operation.ResourceID, operation.ID, err)
+				break
+			}
+
+			// the updated values are already stored so updating the resource with them resumes the update
+			action = func(ctx context.Context, repository storage.Repository) (types.Object, error) {
+				object, err := repository.Update(ctx, object, query.LabelChanges{})
+				return object, util.HandleStorageError(err, operation.ResourceType.String())
+			}
 		case types.DELETE:
 			byID := query.ByField(query.EqualsOperator, "id", operation.ResourceID)
 
@@ -0,0 +1,271 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package brokerclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/httpclient"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// tokenExpiryDelta is the time before the expiry of an OAuth2 token after which it is refreshed
+const tokenExpiryDelta = 30 * time.Second
+
+// BrokerAuthenticator authenticates the calls of the Service Manager to brokers with the credentials of the brokers.
+// The transports with the client certificates of the brokers are reused across calls and the OAuth2 tokens
+// of the brokers are cached until shortly before they expire.
+type BrokerAuthenticator struct {
+	doRequest util.DoRequestFunc
+	settings  *httpclient.Settings
+	now       func() time.Time
+
+	mutex      sync.Mutex
+	transports map[string]*brokerTransport
+	tokens     map[string]*brokerToken
+}
+
+type brokerTransport struct {
+	fingerprint string
+	transport   *http.Transport
+}
+
+type brokerToken struct {
+	mutex       sync.Mutex
+	fingerprint string
+	accessToken string
+	expiresAt   time.Time
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// NewBrokerAuthenticator returns a BrokerAuthenticator which sends the requests of brokers without client certificates
+// and the token requests with doRequestFunc. The transports of brokers with client certificates are configured with settings.
+func NewBrokerAuthenticator(doRequestFunc util.DoRequestFunc, settings *httpclient.Settings) *BrokerAuthenticator {
+	return &BrokerAuthenticator{
+		doRequest:  doRequestFunc,
+		settings:   settings,
+		now:        time.Now,
+		transports: make(map[string]*brokerTransport),
+		tokens:     make(map[string]*brokerToken),
+	}
+}
+
+// TLSConfig returns the TLS configuration with the client certificate of the broker or nil if it has none
+func (a *BrokerAuthenticator) TLSConfig(broker *types.ServiceBroker) (*tls.Config, error) {
+	transport, err := a.certificateTransport(broker)
+	if err != nil || transport == nil {
+		return nil, err
+	}
+	return transport.TLSClientConfig, nil
+}
+
+// Transport returns the transport of the calls to the broker, which presents the client certificate of the broker if it has one
+func (a *BrokerAuthenticator) Transport(broker *types.ServiceBroker) (http.RoundTripper, error) {
+	transport, err := a.certificateTransport(broker)
+	if err != nil {
+		return nil, err
+	}
+	if transport == nil {
+		return http.DefaultTransport, nil
+	}
+	return transport, nil
+}
+
+// Authorization returns the value of the Authorization header of the calls to the broker or an empty string if it has
+// neither basic nor OAuth2 credentials. The OAuth2 token is requested from the token endpoint if it is not cached.
+func (a *BrokerAuthenticator) Authorization(ctx context.Context, broker *types.ServiceBroker) (string, error) {
+	credentials := broker.Credentials
+	switch {
+	case credentials == nil:
+		return "", nil
+	case credentials.OAuth2 != nil:
+		token, err := a.Token(ctx, broker)
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	case credentials.Basic != nil:
+		userInfo := credentials.Basic.Username + ":" + credentials.Basic.Password
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(userInfo)), nil
+	default:
+		return "", nil
+	}
+}
+
+// DoRequestFunc returns a function which sends requests to the broker with its credentials
+func (a *BrokerAuthenticator) DoRequestFunc(broker *types.ServiceBroker) (util.DoRequestFunc, error) {
+	doRequest := a.doRequest
+	transport, err := a.certificateTransport(broker)
+	if err != nil {
+		return nil, err
+	}
+	if transport != nil {
+		doRequest = (&http.Client{Transport: transport}).Do
+	}
+
+	return func(request *http.Request) (*http.Response, error) {
+		authorization, err := a.Authorization(request.Context(), broker)
+		if err != nil {
+			return nil, err
+		}
+		if authorization != "" {
+			request.Header.Set("Authorization", authorization)
+		}
+		response, err := doRequest(request)
+		if err == nil && response.StatusCode == http.StatusUnauthorized {
+			a.InvalidateToken(broker)
+		}
+		return response, err
+	}, nil
+}
+
+// Token returns the OAuth2 token of the broker. The token is requested with the client credentials grant
+// if it is not cached or expires soon.
+func (a *BrokerAuthenticator) Token(ctx context.Context, broker *types.ServiceBroker) (string, error) {
+	oauth2 := broker.Credentials.OAuth2
+	fingerprint := fingerprintOf(oauth2.TokenURL, oauth2.ClientID, oauth2.ClientSecret, strings.Join(oauth2.Scopes, " "))
+
+	a.mutex.Lock()
+	token, found := a.tokens[broker.ID]
+	if !found {
+		token = &brokerToken{}
+		a.tokens[broker.ID] = token
+	}
+	a.mutex.Unlock()
+
+	// tokens of different brokers are requested concurrently
+	token.mutex.Lock()
+	defer token.mutex.Unlock()
+	if token.fingerprint == fingerprint && a.now().Add(tokenExpiryDelta).Before(token.expiresAt) {
+		return token.accessToken, nil
+	}
+
+	log.C(ctx).Debugf("Requesting OAuth2 token of broker with name %s from %s", broker.Name, oauth2.TokenURL)
+	response, err := a.requestToken(ctx, oauth2)
+	if err != nil {
+		return "", &util.HTTPError{
+			ErrorType:   "BrokerAuthenticationError",
+			Description: fmt.Sprintf("could not get OAuth2 token of broker %s: %s", broker.Name, err),
+			StatusCode:  http.StatusBadGateway,
+		}
+	}
+
+	token.fingerprint = fingerprint
+	token.accessToken = response.AccessToken
+	// tokens without expiry are not cached
+	token.expiresAt = a.now().Add(time.Duration(response.ExpiresIn) * time.Second)
+	return token.accessToken, nil
+}
+
+// InvalidateToken removes the cached OAuth2 token of the broker, e.g. after the broker rejected it
+func (a *BrokerAuthenticator) InvalidateToken(broker *types.ServiceBroker) {
+	if broker.Credentials == nil || broker.Credentials.OAuth2 == nil {
+		return
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	delete(a.tokens, broker.ID)
+}
+
+func (a *BrokerAuthenticator) requestToken(ctx context.Context, oauth2 *types.OAuth2) (*tokenResponse, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(oauth2.Scopes) != 0 {
+		form.Set("scope", strings.Join(oauth2.Scopes, " "))
+	}
+	request, err := http.NewRequest(http.MethodPost, oauth2.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.SetBasicAuth(url.QueryEscape(oauth2.ClientID), url.QueryEscape(oauth2.ClientSecret))
+
+	response, err := a.doRequest(request.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint responded with %s: %s", response.Status, util.HandleResponseError(response))
+	}
+
+	token := &tokenResponse{}
+	if err := util.BodyToObject(response.Body, token); err != nil {
+		return nil, err
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("token endpoint responded without access token")
+	}
+	if token.TokenType != "" && !strings.EqualFold(token.TokenType, "bearer") {
+		return nil, fmt.Errorf("token endpoint responded with unsupported token type %s", token.TokenType)
+	}
+	return token, nil
+}
+
+// certificateTransport returns the transport with the client certificate of the broker or nil if it has none
+func (a *BrokerAuthenticator) certificateTransport(broker *types.ServiceBroker) (*http.Transport, error) {
+	if broker.Credentials == nil || broker.Credentials.TLS == nil {
+		return nil, nil
+	}
+	tlsCredentials := broker.Credentials.TLS
+	fingerprint := fingerprintOf(tlsCredentials.Certificate, tlsCredentials.Key)
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if cached, found := a.transports[broker.ID]; found {
+		if cached.fingerprint == fingerprint {
+			return cached.transport, nil
+		}
+		cached.transport.CloseIdleConnections()
+	}
+
+	certificate, err := tls.X509KeyPair([]byte(tlsCredentials.Certificate), []byte(tlsCredentials.Key))
+	if err != nil {
+		return nil, fmt.Errorf("invalid client certificate of broker %s: %s", broker.Name, err)
+	}
+	transport := httpclient.NewTransport(a.settings, certificate)
+	a.transports[broker.ID] = &brokerTransport{
+		fingerprint: fingerprint,
+		transport:   transport,
+	}
+	return transport, nil
+}
+
+// fingerprintOf returns a hash of the credentials, so that changed credentials are detected without keeping them
+func fingerprintOf(credentials ...string) string {
+	hash := sha256.New()
+	for _, credential := range credentials {
+		// the length prevents collisions of different splits of the same string
+		fmt.Fprintf(hash, "%d:%s", len(credential), credential)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
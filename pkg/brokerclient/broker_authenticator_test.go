@@ -0,0 +1,204 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package brokerclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/httpclient"
+	"github.com/Peripli/service-manager/pkg/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BrokerAuthenticator", func() {
+	var authenticator *BrokerAuthenticator
+	var broker *types.ServiceBroker
+	var now time.Time
+	ctx := context.TODO()
+
+	BeforeEach(func() {
+		now = time.Now()
+		authenticator = NewBrokerAuthenticator(http.DefaultClient.Do, httpclient.DefaultSettings())
+		authenticator.now = func() time.Time { return now }
+		broker = &types.ServiceBroker{
+			Base: types.Base{ID: "broker-id"},
+			Name: "broker",
+			Credentials: &types.Credentials{
+				Basic: &types.Basic{
+					Username: "username",
+					Password: "password",
+				},
+			},
+		}
+	})
+
+	It("authorizes with the basic credentials of the broker", func() {
+		authorization, err := authenticator.Authorization(ctx, broker)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(authorization).To(Equal("Basic dXNlcm5hbWU6cGFzc3dvcmQ="))
+	})
+
+	Context("when the broker has OAuth2 credentials", func() {
+		var tokenServer *httptest.Server
+		var tokenRequests int
+		var tokenStatus int
+
+		BeforeEach(func() {
+			tokenRequests = 0
+			tokenStatus = http.StatusOK
+			tokenServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tokenRequests++
+				Expect(r.ParseForm()).To(Succeed())
+				Expect(r.PostForm.Get("grant_type")).To(Equal("client_credentials"))
+				Expect(r.PostForm.Get("scope")).To(Equal("broker.read broker.write"))
+				clientID, clientSecret, _ := r.BasicAuth()
+				Expect(clientID).To(Equal("client-id"))
+				Expect(clientSecret).To(Equal("client-secret"))
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tokenStatus)
+				_, err := w.Write([]byte(`{"access_token": "token", "token_type": "bearer", "expires_in": 3600}`))
+				Expect(err).ToNot(HaveOccurred())
+			}))
+			broker.Credentials = &types.Credentials{
+				OAuth2: &types.OAuth2{
+					TokenURL:     tokenServer.URL,
+					ClientID:     "client-id",
+					ClientSecret: "client-secret",
+					Scopes:       []string{"broker.read", "broker.write"},
+				},
+			}
+		})
+
+		AfterEach(func() {
+			tokenServer.Close()
+		})
+
+		It("authorizes with the cached token of the broker", func() {
+			authorization, err := authenticator.Authorization(ctx, broker)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(authorization).To(Equal("Bearer token"))
+
+			_, err = authenticator.Authorization(ctx, broker)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tokenRequests).To(Equal(1))
+		})
+
+		It("refreshes the token before it expires", func() {
+			_, err := authenticator.Token(ctx, broker)
+			Expect(err).ToNot(HaveOccurred())
+
+			now = now.Add(time.Hour - tokenExpiryDelta)
+			_, err = authenticator.Token(ctx, broker)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tokenRequests).To(Equal(2))
+		})
+
+		It("requests a new token after the token is invalidated", func() {
+			_, err := authenticator.Token(ctx, broker)
+			Expect(err).ToNot(HaveOccurred())
+
+			authenticator.InvalidateToken(broker)
+			_, err = authenticator.Token(ctx, broker)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tokenRequests).To(Equal(2))
+		})
+
+		It("invalidates the token if the broker rejects it", func() {
+			brokerServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.Header.Get("Authorization")).To(Equal("Bearer token"))
+				w.WriteHeader(http.StatusUnauthorized)
+			}))
+			defer brokerServer.Close()
+
+			doRequest, err := authenticator.DoRequestFunc(broker)
+			Expect(err).ToNot(HaveOccurred())
+			for i := 0; i < 2; i++ {
+				request, err := http.NewRequest(http.MethodGet, brokerServer.URL, nil)
+				Expect(err).ToNot(HaveOccurred())
+				response, err := doRequest(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(response.StatusCode).To(Equal(http.StatusUnauthorized))
+			}
+			Expect(tokenRequests).To(Equal(2))
+		})
+
+		It("returns an error if the token endpoint fails", func() {
+			tokenStatus = http.StatusUnauthorized
+			_, err := authenticator.Authorization(ctx, broker)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("could not get OAuth2 token of broker broker"))
+		})
+	})
+
+	Context("when the broker has a client certificate", func() {
+		BeforeEach(func() {
+			certificate, key := generateCertificate()
+			broker.Credentials.TLS = &types.TLS{
+				Certificate: certificate,
+				Key:         key,
+			}
+		})
+
+		It("reuses the transport with the client certificate", func() {
+			transport, err := authenticator.Transport(broker)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(transport.(*http.Transport).TLSClientConfig.Certificates).To(HaveLen(1))
+
+			sameTransport, err := authenticator.Transport(broker)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sameTransport).To(BeIdenticalTo(transport))
+		})
+
+		It("returns an error if the client certificate is invalid", func() {
+			broker.Credentials.TLS.Key = "invalid"
+			_, err := authenticator.Transport(broker)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
+
+func generateCertificate() (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "service-manager"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).ToNot(HaveOccurred())
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	Expect(err).ToNot(HaveOccurred())
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}))
+}
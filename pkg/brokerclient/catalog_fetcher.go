@@ -14,7 +14,7 @@
  * limitations under the License.
  */
 
-package osb
+package brokerclient
 
 import (
 	"context"
@@ -29,16 +29,39 @@ import (
 const brokerCatalogURL = "%s/v2/catalog"
 const brokerAPIVersionHeader = "X-Broker-API-Version"
 
-// CatalogFetcher creates a broker catalog fetcher that uses the provided request function to call the specified broker's catalog endpoint
-func CatalogFetcher(doRequestFunc util.DoRequestFunc, brokerAPIVersion string) func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error) {
+// CatalogFetcher creates a broker catalog fetcher that calls the specified broker's catalog endpoint with the credentials of the broker.
+// The calls are guarded by the circuit breakers unless they are nil.
+func CatalogFetcher(authenticator *BrokerAuthenticator, brokerAPIVersion string, breakers *CircuitBreakers) func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error) {
 	return func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error) {
 		log.C(ctx).Debugf("Attempting to fetch catalog from broker with name %s and URL %s", broker.Name, broker.BrokerURL)
-		requestWithBasicAuth := util.BasicAuthDecorator(broker.Credentials.Basic.Username, broker.Credentials.Basic.Password, doRequestFunc)
-		response, err := util.SendRequestWithHeaders(ctx, requestWithBasicAuth, http.MethodGet, fmt.Sprintf(brokerCatalogURL, broker.BrokerURL), map[string]string{}, nil, map[string]string{
+		doRequest, err := authenticator.DoRequestFunc(broker)
+		if err != nil {
+			return nil, &util.HTTPError{
+				ErrorType:   "BadRequest",
+				Description: err.Error(),
+				StatusCode:  http.StatusBadRequest,
+			}
+		}
+		if breakers != nil {
+			release, _, err := breakers.Acquire(ctx, broker)
+			if err != nil {
+				log.C(ctx).WithError(err).Errorf("Fetching catalog from broker with name %s failed fast", broker.Name)
+				return nil, err
+			}
+			var failed bool
+			defer func() {
+				release(failed)
+			}()
+			doRequest = guardedRequestFunc(doRequest, &failed)
+		}
+		response, err := util.SendRequestWithHeaders(ctx, doRequest, http.MethodGet, fmt.Sprintf(brokerCatalogURL, broker.BrokerURL), map[string]string{}, nil, map[string]string{
 			brokerAPIVersionHeader: brokerAPIVersion,
 		})
 		if err != nil {
 			log.C(ctx).WithError(err).Errorf("Error while forwarding request to service broker %s", broker.Name)
+			if httpErr, ok := err.(*util.HTTPError); ok {
+				return nil, httpErr
+			}
 			return nil, &util.HTTPError{
 				ErrorType:   "ServiceBrokerErr",
 				Description: fmt.Sprintf("could not reach service broker %s at %s", broker.Name, broker.BrokerURL),
@@ -65,3 +88,12 @@ func CatalogFetcher(doRequestFunc util.DoRequestFunc, brokerAPIVersion string) f
 		return responseBytes, nil
 	}
 }
+
+// guardedRequestFunc records in failed whether the request failed or the broker responded with a server error
+func guardedRequestFunc(doRequestFunc util.DoRequestFunc, failed *bool) util.DoRequestFunc {
+	return func(request *http.Request) (*http.Response, error) {
+		response, err := doRequestFunc(request)
+		*failed = err != nil || response.StatusCode >= http.StatusInternalServerError
+		return response, err
+	}
+}
@@ -14,7 +14,7 @@
  * limitations under the License.
  */
 
-package osb_test
+package brokerclient_test
 
 import (
 	"context"
@@ -22,7 +22,8 @@ import (
 	"fmt"
 	"net/http"
 
-	"github.com/Peripli/service-manager/api/osb"
+	"github.com/Peripli/service-manager/pkg/brokerclient"
+	"github.com/Peripli/service-manager/pkg/httpclient"
 
 	"github.com/Peripli/service-manager/pkg/util"
 
@@ -77,7 +78,7 @@ var _ = Describe("Catalog CatalogFetcher", func() {
 	}
 
 	newFetcher := func(t testCase) func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error) {
-		return osb.CatalogFetcher(common.DoHTTP(t.reaction, t.expectations), version)
+		return brokerclient.CatalogFetcher(brokerclient.NewBrokerAuthenticator(common.DoHTTP(t.reaction, t.expectations), httpclient.DefaultSettings()), version, nil)
 	}
 
 	basicAuth := func(username, password string) string {
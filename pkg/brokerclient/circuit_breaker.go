@@ -0,0 +1,297 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package brokerclient
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+const (
+	// FailureThresholdLabelKey is the label of a broker which overrides the number of consecutive failed calls
+	// after which calls to the broker fail fast
+	FailureThresholdLabelKey = "osb_failure_threshold"
+	// RateLimitLabelKey is the label of a broker which overrides the maximum number of calls per second to the broker
+	RateLimitLabelKey = "osb_rate_limit"
+
+	unavailableErrorType = "ServiceBrokerUnavailable"
+)
+
+// BreakerState is the state of the circuit breaker of a broker
+type BreakerState string
+
+const (
+	// BreakerClosed indicates that calls to the broker are let through
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen indicates that calls to the broker fail fast
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen indicates that a limited number of probing calls to the broker are let through
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// CircuitBreakerSettings configures the circuit breakers and rate limits of the outbound calls to brokers
+type CircuitBreakerSettings struct {
+	FailureThreshold int           `mapstructure:"failure_threshold" description:"number of consecutive failed calls to a broker after which calls to it fail fast. 0 disables the circuit breaker"`
+	OpenTimeout      time.Duration `mapstructure:"open_timeout" description:"time for which calls to a broker fail fast before probing calls are let through"`
+	HalfOpenRequests int           `mapstructure:"half_open_requests" description:"maximum number of concurrent probing calls to a broker after the open timeout"`
+	RateLimit        int           `mapstructure:"rate_limit" description:"maximum number of calls per second to a broker. 0 disables rate limiting"`
+	RateLimitBurst   int           `mapstructure:"rate_limit_burst" description:"maximum number of calls to a broker which may exceed the rate limit at once. If 0, the rate limit is used"`
+}
+
+// DefaultCircuitBreakerSettings returns the default values for the circuit breakers of brokers
+func DefaultCircuitBreakerSettings() *CircuitBreakerSettings {
+	return &CircuitBreakerSettings{
+		FailureThreshold: 5,
+		OpenTimeout:      30 * time.Second,
+		HalfOpenRequests: 1,
+		RateLimit:        0,
+		RateLimitBurst:   0,
+	}
+}
+
+// Validate validates the circuit breaker settings
+func (s *CircuitBreakerSettings) Validate() error {
+	if s.FailureThreshold < 0 {
+		return fmt.Errorf("validate Settings: FailureThreshold must not be negative")
+	}
+	if s.FailureThreshold > 0 && s.OpenTimeout <= 0 {
+		return fmt.Errorf("validate Settings: OpenTimeout must be positive")
+	}
+	if s.FailureThreshold > 0 && s.HalfOpenRequests <= 0 {
+		return fmt.Errorf("validate Settings: HalfOpenRequests must be positive")
+	}
+	if s.RateLimit < 0 || s.RateLimitBurst < 0 {
+		return fmt.Errorf("validate Settings: RateLimit and RateLimitBurst must not be negative")
+	}
+	return nil
+}
+
+// BreakerStatus is a snapshot of the circuit breaker of a broker
+type BreakerStatus struct {
+	Broker   string
+	URL      string
+	State    BreakerState
+	Failures int
+	Since    time.Time
+}
+
+// CircuitBreakers guards the outbound calls to brokers. The calls to a broker fail fast after a number of consecutive
+// failures until a probing call succeeds and are limited to a number of calls per second. The breakers are local to
+// the Service Manager instance and are identified by the URL of the broker.
+type CircuitBreakers struct {
+	settings *CircuitBreakerSettings
+	mutex    sync.Mutex
+	breakers map[string]*circuitBreaker
+	now      func() time.Time
+}
+
+type circuitBreaker struct {
+	name             string
+	failureThreshold int
+	rateLimit        int
+	state            BreakerState
+	failures         int
+	since            time.Time
+	probes           int
+	tokens           float64
+	refilledAt       time.Time
+}
+
+// NewCircuitBreakers returns circuit breakers configured with the settings unless overridden by the labels of a broker
+func NewCircuitBreakers(settings *CircuitBreakerSettings) *CircuitBreakers {
+	return &CircuitBreakers{
+		settings: settings,
+		breakers: make(map[string]*circuitBreaker),
+		now:      time.Now,
+	}
+}
+
+// Acquire reserves a call to the broker. If the call must fail fast, it returns an error and the time after which
+// a call might be let through. Otherwise the returned release function must be invoked with the outcome of the call.
+func (cb *CircuitBreakers) Acquire(ctx context.Context, broker *types.ServiceBroker) (func(failed bool), time.Duration, error) {
+	failureThreshold := cb.labelValue(ctx, broker, FailureThresholdLabelKey, cb.settings.FailureThreshold)
+	rateLimit := cb.labelValue(ctx, broker, RateLimitLabelKey, cb.settings.RateLimit)
+	return cb.acquire(broker.BrokerURL, broker.Name, func(breaker *circuitBreaker) {
+		breaker.failureThreshold = failureThreshold
+		breaker.rateLimit = rateLimit
+	})
+}
+
+// Statuses returns the status of the circuit breakers of the called brokers sorted by broker name
+func (cb *CircuitBreakers) Statuses() []*BreakerStatus {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+	statuses := make([]*BreakerStatus, 0, len(cb.breakers))
+	for url, breaker := range cb.breakers {
+		statuses = append(statuses, &BreakerStatus{
+			Broker:   breaker.name,
+			URL:      url,
+			State:    breaker.state,
+			Failures: breaker.failures,
+			Since:    breaker.since,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Broker < statuses[j].Broker
+	})
+	return statuses
+}
+
+// acquire reserves a call to the broker with the url. The configure function, if any, updates the name and the
+// per broker settings of the breaker.
+func (cb *CircuitBreakers) acquire(url, name string, configure func(breaker *circuitBreaker)) (func(failed bool), time.Duration, error) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	now := cb.now()
+	breaker, found := cb.breakers[url]
+	if !found {
+		breaker = &circuitBreaker{
+			name:             name,
+			failureThreshold: cb.settings.FailureThreshold,
+			rateLimit:        cb.settings.RateLimit,
+			state:            BreakerClosed,
+			since:            now,
+		}
+		cb.breakers[url] = breaker
+	}
+	if configure != nil {
+		breaker.name = name
+		configure(breaker)
+	}
+
+	if breaker.state == BreakerOpen {
+		if wait := breaker.since.Add(cb.settings.OpenTimeout).Sub(now); wait > 0 {
+			return nil, wait, unavailableError(name, "circuit breaker is open", wait)
+		}
+		breaker.state = BreakerHalfOpen
+		breaker.since = now
+		breaker.probes = 0
+	}
+	probe := breaker.state == BreakerHalfOpen
+	if probe && breaker.probes >= cb.settings.HalfOpenRequests {
+		return nil, cb.settings.OpenTimeout, unavailableError(name, "circuit breaker is half-open and probing", cb.settings.OpenTimeout)
+	}
+	if wait := cb.takeToken(breaker, now); wait > 0 {
+		return nil, wait, unavailableError(name, "rate limit is exceeded", wait)
+	}
+	if probe {
+		breaker.probes++
+	}
+
+	return func(failed bool) {
+		cb.release(breaker, probe, failed)
+	}, 0, nil
+}
+
+func (cb *CircuitBreakers) release(breaker *circuitBreaker, probe, failed bool) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if probe {
+		breaker.probes--
+	}
+	if !failed {
+		breaker.failures = 0
+		if probe && breaker.state == BreakerHalfOpen {
+			breaker.state = BreakerClosed
+			breaker.since = cb.now()
+		}
+		return
+	}
+
+	breaker.failures++
+	if breaker.state == BreakerHalfOpen ||
+		(breaker.state == BreakerClosed && breaker.failureThreshold > 0 && breaker.failures >= breaker.failureThreshold) {
+		breaker.state = BreakerOpen
+		breaker.since = cb.now()
+		breaker.probes = 0
+	}
+}
+
+// takeToken consumes a call of the rate limit of the breaker or returns the time after which a call is available.
+// It must be called under mutex.Lock.
+func (cb *CircuitBreakers) takeToken(breaker *circuitBreaker, now time.Time) time.Duration {
+	if breaker.rateLimit <= 0 {
+		return 0
+	}
+	burst := float64(cb.rateLimitBurst(breaker.rateLimit))
+	if breaker.refilledAt.IsZero() {
+		breaker.tokens = burst
+		breaker.refilledAt = now
+	}
+	breaker.tokens = math.Min(burst, breaker.tokens+now.Sub(breaker.refilledAt).Seconds()*float64(breaker.rateLimit))
+	breaker.refilledAt = now
+	if breaker.tokens >= 1 {
+		breaker.tokens--
+		return 0
+	}
+	return time.Duration((1 - breaker.tokens) / float64(breaker.rateLimit) * float64(time.Second))
+}
+
+func (cb *CircuitBreakers) rateLimitBurst(rateLimit int) int {
+	if cb.settings.RateLimitBurst > 0 {
+		return cb.settings.RateLimitBurst
+	}
+	return rateLimit
+}
+
+func (cb *CircuitBreakers) labelValue(ctx context.Context, broker *types.ServiceBroker, key string, defaultValue int) int {
+	values := broker.Labels[key]
+	if len(values) == 0 {
+		return defaultValue
+	}
+	value, err := strconv.Atoi(values[0])
+	if err != nil || value < 0 {
+		log.C(ctx).Errorf("Invalid value %s of label %s of broker %s. Using the default value %d", values[0], key, broker.Name, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
+func unavailableError(broker, reason string, retryAfter time.Duration) error {
+	return &util.HTTPError{
+		ErrorType:   unavailableErrorType,
+		Description: fmt.Sprintf("service broker %s is unavailable: %s. Retry after %d seconds", broker, reason, RetryAfterSeconds(retryAfter)),
+		StatusCode:  http.StatusServiceUnavailable,
+		Headers: map[string]string{
+			"Retry-After": strconv.Itoa(RetryAfterSeconds(retryAfter)),
+		},
+	}
+}
+
+// IsUnavailableError returns whether the error is returned by a call to a broker which failed fast because the circuit
+// breaker of the broker did not let it through. Such errors should be reported as they are so that clients know when to retry
+func IsUnavailableError(err error) bool {
+	httpError, ok := err.(*util.HTTPError)
+	return ok && httpError.ErrorType == unavailableErrorType
+}
+
+// RetryAfterSeconds returns the value of the Retry-After header for the duration rounded up to whole seconds
+func RetryAfterSeconds(retryAfter time.Duration) int {
+	return int(math.Ceil(retryAfter.Seconds()))
+}
@@ -0,0 +1,147 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package brokerclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/httpclient"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CircuitBreakers", func() {
+	var breakers *CircuitBreakers
+	var broker *types.ServiceBroker
+	var now time.Time
+	ctx := context.TODO()
+
+	call := func(failed bool) error {
+		release, _, err := breakers.Acquire(ctx, broker)
+		if err == nil {
+			release(failed)
+		}
+		return err
+	}
+
+	BeforeEach(func() {
+		now = time.Now()
+		breakers = NewCircuitBreakers(&CircuitBreakerSettings{
+			FailureThreshold: 2,
+			OpenTimeout:      time.Minute,
+			HalfOpenRequests: 1,
+		})
+		breakers.now = func() time.Time { return now }
+		broker = &types.ServiceBroker{
+			Base:      types.Base{ID: "broker-id"},
+			Name:      "broker",
+			BrokerURL: "http://broker.com",
+		}
+	})
+
+	It("fails fast after the failure threshold is reached", func() {
+		Expect(call(true)).To(Succeed())
+		Expect(call(true)).To(Succeed())
+
+		_, retryAfter, err := breakers.Acquire(ctx, broker)
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusServiceUnavailable))
+		Expect(err.(*util.HTTPError).Headers).To(HaveKeyWithValue("Retry-After", "60"))
+		Expect(IsUnavailableError(err)).To(BeTrue())
+		Expect(retryAfter).To(Equal(time.Minute))
+	})
+
+	It("resets the failures after a successful call", func() {
+		Expect(call(true)).To(Succeed())
+		Expect(call(false)).To(Succeed())
+		Expect(call(true)).To(Succeed())
+		Expect(call(false)).To(Succeed())
+	})
+
+	It("lets a probing call through after the open timeout and closes if it succeeds", func() {
+		Expect(call(true)).To(Succeed())
+		Expect(call(true)).To(Succeed())
+		now = now.Add(time.Minute)
+
+		release, _, err := breakers.Acquire(ctx, broker)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(breakers.Statuses()[0].State).To(Equal(BreakerHalfOpen))
+		Expect(call(false)).To(HaveOccurred())
+
+		release(false)
+		Expect(breakers.Statuses()[0].State).To(Equal(BreakerClosed))
+		Expect(call(false)).To(Succeed())
+	})
+
+	It("opens again if the probing call fails", func() {
+		Expect(call(true)).To(Succeed())
+		Expect(call(true)).To(Succeed())
+		now = now.Add(time.Minute)
+
+		Expect(call(true)).To(Succeed())
+		Expect(breakers.Statuses()[0].State).To(Equal(BreakerOpen))
+		Expect(call(false)).To(HaveOccurred())
+	})
+
+	It("uses the failure threshold of the broker label", func() {
+		broker.Labels = types.Labels{FailureThresholdLabelKey: {"1"}}
+		Expect(call(true)).To(Succeed())
+		Expect(call(false)).To(HaveOccurred())
+	})
+
+	It("limits the rate of calls of brokers with a rate limit", func() {
+		broker.Labels = types.Labels{RateLimitLabelKey: {"2"}}
+		Expect(call(false)).To(Succeed())
+		Expect(call(false)).To(Succeed())
+
+		_, retryAfter, err := breakers.Acquire(ctx, broker)
+		Expect(err).To(HaveOccurred())
+		Expect(retryAfter).To(Equal(500 * time.Millisecond))
+
+		now = now.Add(500 * time.Millisecond)
+		Expect(call(false)).To(Succeed())
+	})
+
+	It("fails fast calls of broker clients with the failure threshold of the broker label", func() {
+		brokerRequests := 0
+		brokerServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			brokerRequests++
+			w.WriteHeader(http.StatusInternalServerError)
+			_, err := w.Write([]byte(`{}`))
+			Expect(err).ToNot(HaveOccurred())
+		}))
+		defer brokerServer.Close()
+		broker.BrokerURL = brokerServer.URL
+		broker.Labels = types.Labels{FailureThresholdLabelKey: {"1"}}
+		client, err := NewBrokerClientProvider(false, 10, breakers, NewBrokerAuthenticator(http.DefaultClient.Do, httpclient.DefaultSettings()))(broker)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = client.GetCatalog()
+		Expect(IsUnavailableError(err)).To(BeFalse())
+		_, err = client.GetCatalog()
+		Expect(IsUnavailableError(err)).To(BeTrue())
+		Expect(err.(*util.HTTPError).StatusCode).To(Equal(http.StatusServiceUnavailable))
+		Expect(err.(*util.HTTPError).Headers).To(HaveKeyWithValue("Retry-After", "60"))
+		Expect(brokerRequests).To(Equal(1))
+	})
+})
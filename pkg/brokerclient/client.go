@@ -0,0 +1,240 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+// Package brokerclient contains the clients which call the brokers through their OSB API
+package brokerclient
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	osbc "github.com/kubernetes-sigs/go-open-service-broker-client/v2"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// BrokerClientFunc creates an OSB client which calls the broker with its credentials
+type BrokerClientFunc func(broker *types.ServiceBroker) (osbc.Client, error)
+
+// NewBrokerClientProvider provides a function which constructs an OSB client for a broker. The clients authenticate with
+// the credentials of the broker, i.e. basic credentials, a client certificate or OAuth2 tokens which are refreshed before
+// they expire. The calls of the clients are guarded by the circuit breakers unless they are nil. The clients implement LastOperationPoller.
+func NewBrokerClientProvider(skipSsl bool, timeout int, breakers *CircuitBreakers, authenticator *BrokerAuthenticator) BrokerClientFunc {
+	return func(broker *types.ServiceBroker) (osbc.Client, error) {
+		tlsConfig, err := authenticator.TLSConfig(broker)
+		if err != nil {
+			return nil, err
+		}
+		doRequest, err := authenticator.DoRequestFunc(broker)
+		if err != nil {
+			return nil, err
+		}
+
+		configuration := &osbc.ClientConfiguration{
+			Name:                broker.Name + " broker client",
+			EnableAlphaFeatures: true,
+			URL:                 broker.BrokerURL,
+			APIVersion:          osbc.LatestAPIVersion(),
+			TLSConfig:           tlsConfig,
+			TimeoutSeconds:      timeout,
+			Insecure:            skipSsl,
+		}
+		if broker.Credentials != nil && broker.Credentials.Basic != nil {
+			configuration.AuthConfig = &osbc.AuthConfig{
+				BasicAuthConfig: &osbc.BasicAuthConfig{
+					Username: broker.Credentials.Basic.Username,
+					Password: broker.Credentials.Basic.Password,
+				},
+			}
+		}
+
+		return &brokerClient{
+			broker:        broker,
+			configuration: configuration,
+			breakers:      breakers,
+			authenticator: authenticator,
+			url:           broker.BrokerURL,
+			name:          broker.Name,
+			apiVersion:    configuration.APIVersion.HeaderValue(),
+			doRequest:     doRequest,
+		}, nil
+	}
+}
+
+// brokerClient is an OSB client whose calls fail fast if the circuit breaker of the broker does not let them through.
+// If the broker has OAuth2 credentials, the underlying client is recreated whenever the token of the broker changes.
+type brokerClient struct {
+	broker        *types.ServiceBroker
+	configuration *osbc.ClientConfiguration
+	breakers      *CircuitBreakers
+	authenticator *BrokerAuthenticator
+	url           string
+	name          string
+	apiVersion    string
+	doRequest     util.DoRequestFunc
+
+	mutex  sync.Mutex
+	client osbc.Client
+	token  string
+}
+
+// authenticatedClient returns the underlying client, which is created with the current token of the broker if it uses OAuth2
+func (bc *brokerClient) authenticatedClient() (osbc.Client, error) {
+	bc.mutex.Lock()
+	defer bc.mutex.Unlock()
+
+	if bc.broker.Credentials != nil && bc.broker.Credentials.OAuth2 != nil {
+		token, err := bc.authenticator.Token(context.Background(), bc.broker)
+		if err != nil {
+			return nil, err
+		}
+		if token != bc.token {
+			bc.client = nil
+			bc.token = token
+			bc.configuration.AuthConfig = &osbc.AuthConfig{
+				BearerConfig: &osbc.BearerConfig{
+					Token: token,
+				},
+			}
+		}
+	}
+
+	if bc.client == nil {
+		client, err := osbc.NewClient(bc.configuration)
+		if err != nil {
+			return nil, err
+		}
+		bc.client = client
+	}
+	return bc.client, nil
+}
+
+// callClient calls f with the authenticated underlying client
+func (bc *brokerClient) callClient(f func(client osbc.Client) error) error {
+	client, err := bc.authenticatedClient()
+	if err != nil {
+		return err
+	}
+	return bc.call(func() error {
+		return f(client)
+	})
+}
+
+func (bc *brokerClient) call(f func() error) error {
+	if bc.breakers == nil {
+		return bc.rejectToken(f())
+	}
+	release, _, err := bc.breakers.Acquire(context.Background(), bc.broker)
+	if err != nil {
+		return err
+	}
+	err = f()
+	release(isBrokerFailure(err))
+	return bc.rejectToken(err)
+}
+
+// rejectToken invalidates the OAuth2 token of the broker if the broker rejected it with the error
+func (bc *brokerClient) rejectToken(err error) error {
+	if httpError, ok := osbc.IsHTTPError(err); ok && httpError.StatusCode == http.StatusUnauthorized && bc.authenticator != nil {
+		bc.authenticator.InvalidateToken(bc.broker)
+	}
+	return err
+}
+
+// isBrokerFailure returns whether the error of an OSB call indicates that the broker is unhealthy
+func isBrokerFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	if httpError, ok := osbc.IsHTTPError(err); ok {
+		return httpError.StatusCode >= 500
+	}
+	return true
+}
+
+func (bc *brokerClient) GetCatalog() (response *osbc.CatalogResponse, err error) {
+	err = bc.callClient(func(client osbc.Client) (err error) {
+		response, err = client.GetCatalog()
+		return
+	})
+	return
+}
+
+func (bc *brokerClient) ProvisionInstance(r *osbc.ProvisionRequest) (response *osbc.ProvisionResponse, err error) {
+	err = bc.callClient(func(client osbc.Client) (err error) {
+		response, err = client.ProvisionInstance(r)
+		return
+	})
+	return
+}
+
+func (bc *brokerClient) UpdateInstance(r *osbc.UpdateInstanceRequest) (response *osbc.UpdateInstanceResponse, err error) {
+	err = bc.callClient(func(client osbc.Client) (err error) {
+		response, err = client.UpdateInstance(r)
+		return
+	})
+	return
+}
+
+func (bc *brokerClient) DeprovisionInstance(r *osbc.DeprovisionRequest) (response *osbc.DeprovisionResponse, err error) {
+	err = bc.callClient(func(client osbc.Client) (err error) {
+		response, err = client.DeprovisionInstance(r)
+		return
+	})
+	return
+}
+
+func (bc *brokerClient) PollLastOperation(r *osbc.LastOperationRequest) (response *osbc.LastOperationResponse, err error) {
+	err = bc.callClient(func(client osbc.Client) (err error) {
+		response, err = client.PollLastOperation(r)
+		return
+	})
+	return
+}
+
+func (bc *brokerClient) PollBindingLastOperation(r *osbc.BindingLastOperationRequest) (response *osbc.LastOperationResponse, err error) {
+	err = bc.callClient(func(client osbc.Client) (err error) {
+		response, err = client.PollBindingLastOperation(r)
+		return
+	})
+	return
+}
+
+func (bc *brokerClient) Bind(r *osbc.BindRequest) (response *osbc.BindResponse, err error) {
+	err = bc.callClient(func(client osbc.Client) (err error) {
+		response, err = client.Bind(r)
+		return
+	})
+	return
+}
+
+func (bc *brokerClient) Unbind(r *osbc.UnbindRequest) (response *osbc.UnbindResponse, err error) {
+	err = bc.callClient(func(client osbc.Client) (err error) {
+		response, err = client.Unbind(r)
+		return
+	})
+	return
+}
+
+func (bc *brokerClient) GetBinding(r *osbc.GetBindingRequest) (response *osbc.GetBindingResponse, err error) {
+	err = bc.callClient(func(client osbc.Client) (err error) {
+		response, err = client.GetBinding(r)
+		return
+	})
+	return
+}
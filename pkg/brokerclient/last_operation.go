@@ -0,0 +1,120 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package brokerclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	osbc "github.com/kubernetes-sigs/go-open-service-broker-client/v2"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// LastOperationResponse is the response of a last operation request including the hints of OSB API 2.15
+// which the OSB client does not support
+type LastOperationResponse struct {
+	osbc.LastOperationResponse
+	// InstanceUsable reports whether the instance can still be used after a failed update or deprovisioning
+	InstanceUsable *bool `json:"instance_usable,omitempty"`
+	// UpdateRepeatable reports whether a failed update can be repeated
+	UpdateRepeatable *bool `json:"update_repeatable,omitempty"`
+	// RetryAfter is the time the broker asked to wait before polling again, if any
+	RetryAfter time.Duration `json:"-"`
+}
+
+// LastOperationPoller polls the last operations of instances and bindings including the hints of OSB API 2.15
+type LastOperationPoller interface {
+	PollLastOperationWithHints(ctx context.Context, r *osbc.LastOperationRequest) (*LastOperationResponse, error)
+	PollBindingLastOperationWithHints(ctx context.Context, r *osbc.BindingLastOperationRequest) (*LastOperationResponse, error)
+}
+
+var _ LastOperationPoller = &brokerClient{}
+
+func (bc *brokerClient) PollLastOperationWithHints(ctx context.Context, r *osbc.LastOperationRequest) (response *LastOperationResponse, err error) {
+	path := fmt.Sprintf("/v2/service_instances/%s/last_operation", r.InstanceID)
+	err = bc.call(func() (err error) {
+		response, err = bc.pollLastOperation(ctx, path, r.ServiceID, r.PlanID, r.OperationKey)
+		return
+	})
+	return
+}
+
+func (bc *brokerClient) PollBindingLastOperationWithHints(ctx context.Context, r *osbc.BindingLastOperationRequest) (response *LastOperationResponse, err error) {
+	path := fmt.Sprintf("/v2/service_instances/%s/service_bindings/%s/last_operation", r.InstanceID, r.BindingID)
+	err = bc.call(func() (err error) {
+		response, err = bc.pollLastOperation(ctx, path, r.ServiceID, r.PlanID, r.OperationKey)
+		return
+	})
+	return
+}
+
+func (bc *brokerClient) pollLastOperation(ctx context.Context, path string, serviceID, planID *string, operationKey *osbc.OperationKey) (*LastOperationResponse, error) {
+	params := make(map[string]string)
+	if serviceID != nil {
+		params["service_id"] = *serviceID
+	}
+	if planID != nil {
+		params["plan_id"] = *planID
+	}
+	if operationKey != nil {
+		params["operation"] = string(*operationKey)
+	}
+	response, err := util.SendRequestWithHeaders(ctx, bc.doRequest, http.MethodGet, bc.url+path, params, nil, map[string]string{
+		brokerAPIVersionHeader: bc.apiVersion,
+	})
+	if err != nil {
+		return nil, err
+	}
+	body, err := util.BodyToBytes(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error getting content from body of response with status %s: %s", response.Status, err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		statusCodeError := &osbc.HTTPStatusCodeError{StatusCode: response.StatusCode}
+		if description := gjson.GetBytes(body, "description"); description.Exists() {
+			descriptionValue := description.String()
+			statusCodeError.Description = &descriptionValue
+		}
+		return nil, statusCodeError
+	}
+
+	lastOperation := &LastOperationResponse{}
+	if err := json.Unmarshal(body, lastOperation); err != nil {
+		return nil, fmt.Errorf("could not parse last operation response of broker %s: %s", bc.name, err)
+	}
+	lastOperation.RetryAfter = parseRetryAfter(response.Header.Get("Retry-After"))
+	return lastOperation, nil
+}
+
+// parseRetryAfter returns the duration of a Retry-After header value in seconds or as HTTP date, or 0 if it is invalid
+func parseRetryAfter(value string) time.Duration {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
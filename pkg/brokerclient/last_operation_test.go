@@ -0,0 +1,115 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package brokerclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	osbc "github.com/kubernetes-sigs/go-open-service-broker-client/v2"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LastOperationPoller", func() {
+	var brokerServer *httptest.Server
+	var requestQuery url.Values
+	var responseStatus int
+	var responseBody string
+	var retryAfter string
+	var client *brokerClient
+
+	serviceID := "service-id"
+	planID := "plan-id"
+	operationKey := osbc.OperationKey("operation")
+
+	BeforeEach(func() {
+		responseStatus = http.StatusOK
+		responseBody = `{"state": "failed", "description": "update failed", "instance_usable": true, "update_repeatable": false}`
+		retryAfter = ""
+		brokerServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/v2/service_instances/instance-id/last_operation"))
+			requestQuery = r.URL.Query()
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(responseStatus)
+			_, err := w.Write([]byte(responseBody))
+			Expect(err).ToNot(HaveOccurred())
+		}))
+		client = &brokerClient{
+			url:        brokerServer.URL,
+			name:       "broker",
+			apiVersion: "2.15",
+			doRequest:  http.DefaultClient.Do,
+		}
+	})
+
+	AfterEach(func() {
+		brokerServer.Close()
+	})
+
+	poll := func() (*LastOperationResponse, error) {
+		return client.PollLastOperationWithHints(context.TODO(), &osbc.LastOperationRequest{
+			InstanceID:   "instance-id",
+			ServiceID:    &serviceID,
+			PlanID:       &planID,
+			OperationKey: &operationKey,
+		})
+	}
+
+	It("sends the plan and service ids and returns the hints of the broker", func() {
+		response, err := poll()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requestQuery.Get("service_id")).To(Equal(serviceID))
+		Expect(requestQuery.Get("plan_id")).To(Equal(planID))
+		Expect(requestQuery.Get("operation")).To(Equal(string(operationKey)))
+
+		Expect(response.State).To(Equal(osbc.StateFailed))
+		Expect(*response.Description).To(Equal("update failed"))
+		Expect(*response.InstanceUsable).To(BeTrue())
+		Expect(*response.UpdateRepeatable).To(BeFalse())
+		Expect(response.RetryAfter).To(BeZero())
+	})
+
+	It("returns the Retry-After of the broker", func() {
+		retryAfter = "120"
+		response, err := poll()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.RetryAfter).To(Equal(2 * time.Minute))
+	})
+
+	It("returns an HTTP error if the broker fails", func() {
+		responseStatus = http.StatusGone
+		responseBody = `{"description": "gone"}`
+		_, err := poll()
+		Expect(osbc.IsGoneError(err)).To(BeTrue())
+	})
+
+	Describe("parseRetryAfter", func() {
+		It("parses seconds and HTTP dates", func() {
+			Expect(parseRetryAfter("5")).To(Equal(5 * time.Second))
+			Expect(parseRetryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))).To(BeNumerically(">", 59*time.Minute))
+			Expect(parseRetryAfter("")).To(BeZero())
+			Expect(parseRetryAfter("soon")).To(BeZero())
+		})
+	})
+})
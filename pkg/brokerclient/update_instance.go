@@ -0,0 +1,125 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package brokerclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/tidwall/gjson"
+
+	osbc "github.com/kubernetes-sigs/go-open-service-broker-client/v2"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// UpdateInstanceRequest is an update service instance request including the maintenance info of OSB API 2.15
+// which the OSB client does not support
+type UpdateInstanceRequest struct {
+	osbc.UpdateInstanceRequest
+	// MaintenanceInfo is the maintenance info to which the instance is upgraded, if any
+	MaintenanceInfo *types.MaintenanceInfo
+	// PreviousMaintenanceInfo is the maintenance info of the instance before the update, if any
+	PreviousMaintenanceInfo *types.MaintenanceInfo
+}
+
+// InstanceUpdater updates instances including their maintenance info
+type InstanceUpdater interface {
+	UpdateInstanceWithMaintenanceInfo(ctx context.Context, r *UpdateInstanceRequest) (*osbc.UpdateInstanceResponse, error)
+}
+
+var _ InstanceUpdater = &brokerClient{}
+
+type updateInstanceBody struct {
+	ServiceID       string                 `json:"service_id"`
+	PlanID          *string                `json:"plan_id,omitempty"`
+	Parameters      map[string]interface{} `json:"parameters,omitempty"`
+	Context         map[string]interface{} `json:"context,omitempty"`
+	MaintenanceInfo *types.MaintenanceInfo `json:"maintenance_info,omitempty"`
+	PreviousValues  *updatePreviousValues  `json:"previous_values,omitempty"`
+}
+
+type updatePreviousValues struct {
+	ServiceID       string                 `json:"service_id,omitempty"`
+	PlanID          string                 `json:"plan_id,omitempty"`
+	MaintenanceInfo *types.MaintenanceInfo `json:"maintenance_info,omitempty"`
+}
+
+func (bc *brokerClient) UpdateInstanceWithMaintenanceInfo(ctx context.Context, r *UpdateInstanceRequest) (response *osbc.UpdateInstanceResponse, err error) {
+	err = bc.call(func() (err error) {
+		response, err = bc.updateInstance(ctx, r)
+		return
+	})
+	return
+}
+
+func (bc *brokerClient) updateInstance(ctx context.Context, r *UpdateInstanceRequest) (*osbc.UpdateInstanceResponse, error) {
+	body := &updateInstanceBody{
+		ServiceID:       r.ServiceID,
+		PlanID:          r.PlanID,
+		Parameters:      r.Parameters,
+		Context:         r.Context,
+		MaintenanceInfo: r.MaintenanceInfo,
+	}
+	if r.PreviousValues != nil || r.PreviousMaintenanceInfo != nil {
+		body.PreviousValues = &updatePreviousValues{
+			MaintenanceInfo: r.PreviousMaintenanceInfo,
+		}
+		if r.PreviousValues != nil {
+			body.PreviousValues.ServiceID = r.PreviousValues.ServiceID
+			body.PreviousValues.PlanID = r.PreviousValues.PlanID
+		}
+	}
+
+	path := fmt.Sprintf("/v2/service_instances/%s", r.InstanceID)
+	params := map[string]string{
+		"accepts_incomplete": strconv.FormatBool(r.AcceptsIncomplete),
+	}
+	response, err := util.SendRequestWithHeaders(ctx, bc.doRequest, http.MethodPatch, bc.url+path, params, body, map[string]string{
+		brokerAPIVersionHeader: bc.apiVersion,
+		"Content-Type":         "application/json",
+	})
+	if err != nil {
+		return nil, err
+	}
+	responseBody, err := util.BodyToBytes(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error getting content from body of response with status %s: %s", response.Status, err)
+	}
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		return &osbc.UpdateInstanceResponse{}, nil
+	case http.StatusAccepted:
+		updateResponse := &osbc.UpdateInstanceResponse{Async: true}
+		if operation := gjson.GetBytes(responseBody, "operation"); operation.Exists() {
+			operationKey := osbc.OperationKey(operation.String())
+			updateResponse.OperationKey = &operationKey
+		}
+		return updateResponse, nil
+	default:
+		statusCodeError := &osbc.HTTPStatusCodeError{StatusCode: response.StatusCode}
+		if description := gjson.GetBytes(responseBody, "description"); description.Exists() {
+			descriptionValue := description.String()
+			statusCodeError.Description = &descriptionValue
+		}
+		return nil, statusCodeError
+	}
+}
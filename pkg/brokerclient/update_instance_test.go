@@ -0,0 +1,121 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package brokerclient
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	osbc "github.com/kubernetes-sigs/go-open-service-broker-client/v2"
+
+	"github.com/Peripli/service-manager/pkg/types"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("InstanceUpdater", func() {
+	var brokerServer *httptest.Server
+	var requestQuery url.Values
+	var requestBody map[string]interface{}
+	var responseStatus int
+	var responseBody string
+	var client *brokerClient
+
+	planID := "plan-id"
+
+	BeforeEach(func() {
+		responseStatus = http.StatusAccepted
+		responseBody = `{"operation": "upgrade"}`
+		brokerServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Method).To(Equal(http.MethodPatch))
+			Expect(r.URL.Path).To(Equal("/v2/service_instances/instance-id"))
+			Expect(r.Header.Get(brokerAPIVersionHeader)).To(Equal("2.15"))
+			requestQuery = r.URL.Query()
+			body, err := ioutil.ReadAll(r.Body)
+			Expect(err).ToNot(HaveOccurred())
+			requestBody = make(map[string]interface{})
+			Expect(json.Unmarshal(body, &requestBody)).To(Succeed())
+			w.WriteHeader(responseStatus)
+			_, err = w.Write([]byte(responseBody))
+			Expect(err).ToNot(HaveOccurred())
+		}))
+		client = &brokerClient{
+			url:        brokerServer.URL,
+			name:       "broker",
+			apiVersion: "2.15",
+			doRequest:  http.DefaultClient.Do,
+		}
+	})
+
+	AfterEach(func() {
+		brokerServer.Close()
+	})
+
+	update := func() (*osbc.UpdateInstanceResponse, error) {
+		return client.UpdateInstanceWithMaintenanceInfo(context.TODO(), &UpdateInstanceRequest{
+			UpdateInstanceRequest: osbc.UpdateInstanceRequest{
+				InstanceID:        "instance-id",
+				AcceptsIncomplete: true,
+				ServiceID:         "service-id",
+				PlanID:            &planID,
+				PreviousValues: &osbc.PreviousValues{
+					PlanID:    planID,
+					ServiceID: "service-id",
+				},
+			},
+			MaintenanceInfo:         &types.MaintenanceInfo{Version: "2.0.0"},
+			PreviousMaintenanceInfo: &types.MaintenanceInfo{Version: "1.0.0"},
+		})
+	}
+
+	It("sends the maintenance info and returns the operation of the broker", func() {
+		response, err := update()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(requestQuery.Get("accepts_incomplete")).To(Equal("true"))
+		Expect(requestBody["service_id"]).To(Equal("service-id"))
+		Expect(requestBody["plan_id"]).To(Equal(planID))
+		Expect(requestBody["maintenance_info"]).To(HaveKeyWithValue("version", "2.0.0"))
+		Expect(requestBody["previous_values"]).To(HaveKeyWithValue("maintenance_info", HaveKeyWithValue("version", "1.0.0")))
+
+		Expect(response.Async).To(BeTrue())
+		Expect(string(*response.OperationKey)).To(Equal("upgrade"))
+	})
+
+	It("returns a synchronous response if the broker completes the update", func() {
+		responseStatus = http.StatusOK
+		responseBody = `{}`
+		response, err := update()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.Async).To(BeFalse())
+		Expect(response.OperationKey).To(BeNil())
+	})
+
+	It("returns an HTTP error if the broker rejects the update", func() {
+		responseStatus = http.StatusUnprocessableEntity
+		responseBody = `{"error": "MaintenanceInfoConflict", "description": "outdated"}`
+		_, err := update()
+		httpErr, ok := osbc.IsHTTPError(err)
+		Expect(ok).To(BeTrue())
+		Expect(httpErr.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+		Expect(*httpErr.Description).To(Equal("outdated"))
+	})
+})
@@ -35,6 +35,9 @@ const StorageIndicatorName = "storage"
 // PlatformsIndicatorName is the name of platforms indicator
 const PlatformsIndicatorName = "platforms"
 
+// BrokersIndicatorName is the name of brokers indicator
+const BrokersIndicatorName = "brokers"
+
 // indicatorNames is a list of names of indicators which will be registered with default settings
 // as part of default health settings, this will allow binding them as part of environment.
 // If an indicator is registered but not specified in this list, it will be configured with
@@ -44,6 +47,7 @@ const PlatformsIndicatorName = "platforms"
 var indicatorNames = [...]string{
 	StorageIndicatorName,
 	PlatformsIndicatorName,
+	BrokersIndicatorName,
 }
 
 // Settings type to be loaded from the environment
@@ -57,6 +61,9 @@ func DefaultSettings() *Settings {
 	for _, name := range indicatorNames {
 		defaultIndicatorSettings[name] = DefaultIndicatorSettings()
 	}
+	// an unavailable broker does not affect the availability of the Service Manager
+	defaultIndicatorSettings[BrokersIndicatorName].Fatal = false
+	defaultIndicatorSettings[BrokersIndicatorName].FailuresThreshold = 0
 	return &Settings{
 		Indicators: defaultIndicatorSettings,
 	}
@@ -63,12 +63,28 @@ func (s *Settings) Validate() error {
 // Configure configures the default http client and transport
 func Configure(settings *Settings) {
 	transport := http.DefaultTransport.(*http.Transport)
+	configureTransport(transport, settings)
 
+	http.DefaultClient.Transport = transport
+}
+
+// NewTransport returns a new transport which is configured like the default transport and presents the client certificates
+func NewTransport(settings *Settings, certificates ...tls.Certificate) *http.Transport {
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		MaxIdleConns:          100,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	configureTransport(transport, settings)
+	transport.TLSClientConfig.Certificates = certificates
+
+	return transport
+}
+
+func configureTransport(transport *http.Transport, settings *Settings) {
 	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: settings.SkipSSLValidation}
 	transport.ResponseHeaderTimeout = settings.ResponseHeaderTimeout
 	transport.TLSHandshakeTimeout = settings.TLSHandshakeTimeout
 	transport.IdleConnTimeout = settings.IdleConnTimeout
 	transport.DialContext = (&net.Dialer{Timeout: settings.DialTimeout}).DialContext
-
-	http.DefaultClient.Transport = transport
 }
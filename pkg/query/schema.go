@@ -0,0 +1,140 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// FieldType is the type of the values of an entity field
+type FieldType string
+
+const (
+	// StringField denotes a field with text values
+	StringField FieldType = "string"
+	// IntegerField denotes a field with whole number values
+	IntegerField FieldType = "integer"
+	// BooleanField denotes a field with true or false values
+	BooleanField FieldType = "boolean"
+	// DatetimeField denotes a field with RFC3339 timestamp values
+	DatetimeField FieldType = "datetime"
+	// JSONField denotes a field with JSON values
+	JSONField FieldType = "json"
+)
+
+// Field describes a single field of an entity
+type Field struct {
+	// Name is the name of the field as used in field queries
+	Name string `json:"name"`
+	// Type is the type of the field values
+	Type FieldType `json:"type"`
+	// Nullable is true if the field may have no value
+	Nullable bool `json:"nullable"`
+	// Queryable is true if the field can be used in field queries and for ordering
+	Queryable bool `json:"queryable"`
+}
+
+// Schema describes the fields of an entity
+type Schema struct {
+	Fields []Field `json:"fields"`
+}
+
+// Field returns the field with the given name
+func (s *Schema) Field(name string) (Field, bool) {
+	for _, field := range s.Fields {
+		if field.Name == name {
+			return field, true
+		}
+	}
+	return Field{}, false
+}
+
+// QueryableFields returns the names of the fields which can be used in field queries
+func (s *Schema) QueryableFields() []string {
+	names := make([]string, 0, len(s.Fields))
+	for _, field := range s.Fields {
+		if field.Queryable {
+			names = append(names, field.Name)
+		}
+	}
+	return names
+}
+
+// Validate verifies that the field query and order by criteria reference queryable fields
+// of the schema and that the right operands are valid values for the type of the fields
+func (s *Schema) Validate(criteria ...Criterion) error {
+	for _, criterion := range criteria {
+		switch {
+		case criterion.Type == FieldQuery:
+			field, err := s.queryableField(criterion.LeftOp, "unsupported field query key: %s")
+			if err != nil {
+				return err
+			}
+			if err := field.validateCriterion(criterion); err != nil {
+				return err
+			}
+		case criterion.Type == ResultQuery && criterion.LeftOp == OrderBy && len(criterion.RightOp) > 0:
+			if _, err := s.queryableField(criterion.RightOp[0], "unsupported entity field for order by: %s"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) queryableField(name, errorTemplate string) (Field, error) {
+	field, found := s.Field(name)
+	if !found || !field.Queryable {
+		message := fmt.Sprintf(errorTemplate, name)
+		return Field{}, &util.UnsupportedQueryError{Message: fmt.Sprintf("%s. Supported fields are: %s", message, strings.Join(s.QueryableFields(), ", "))}
+	}
+	return field, nil
+}
+
+func (f Field) validateCriterion(criterion Criterion) error {
+	if criterion.Operator.IsNumeric() && (f.Type == BooleanField || f.Type == JSONField) {
+		return &util.UnsupportedQueryError{Message: fmt.Sprintf("operator %s is not supported for %s field %s", criterion.Operator, f.Type, f.Name)}
+	}
+	for _, value := range criterion.RightOp {
+		// empty multivariate operands such as "in ()" match no values and need no conversion
+		if value == "" && criterion.Operator.Type() == MultivariateOperator {
+			continue
+		}
+		if !f.isValidValue(value) {
+			return &util.UnsupportedQueryError{Message: fmt.Sprintf("invalid value '%s' for %s field %s", value, f.Type, f.Name)}
+		}
+	}
+	return nil
+}
+
+func (f Field) isValidValue(value string) bool {
+	switch f.Type {
+	case IntegerField:
+		_, err := strconv.ParseInt(value, 10, 64)
+		return err == nil
+	case BooleanField:
+		return value == "true" || value == "false"
+	case DatetimeField:
+		return isDateTime(value)
+	default:
+		return true
+	}
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	. "github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+var _ = Describe("Schema", func() {
+	schema := &Schema{
+		Fields: []Field{
+			{Name: "id", Type: StringField, Queryable: true},
+			{Name: "created_at", Type: DatetimeField, Queryable: true},
+			{Name: "ready", Type: BooleanField, Queryable: true},
+			{Name: "maximum_polling_duration", Type: IntegerField, Queryable: true},
+			{Name: "description", Type: StringField, Nullable: true, Queryable: true},
+			{Name: "context", Type: JSONField, Queryable: true},
+			{Name: "password", Type: StringField},
+		},
+	}
+
+	parse := func(expression string) []Criterion {
+		criteria, err := Parse(FieldQuery, expression)
+		Expect(err).ToNot(HaveOccurred())
+		return criteria
+	}
+
+	Describe("Field", func() {
+		It("returns the field with the given name", func() {
+			field, found := schema.Field("ready")
+			Expect(found).To(BeTrue())
+			Expect(field.Type).To(Equal(BooleanField))
+		})
+
+		It("returns false for unknown fields", func() {
+			_, found := schema.Field("unknown")
+			Expect(found).To(BeFalse())
+		})
+	})
+
+	Describe("QueryableFields", func() {
+		It("returns only the fields which can be queried", func() {
+			Expect(schema.QueryableFields()).To(Equal([]string{"id", "created_at", "ready", "maximum_polling_duration", "description", "context"}))
+		})
+	})
+
+	Describe("Validate", func() {
+		DescribeTable("accepts valid criteria",
+			func(expression string) {
+				Expect(schema.Validate(parse(expression)...)).To(Succeed())
+			},
+			Entry("string field", "id eq 'abc'"),
+			Entry("boolean field", "ready eq true"),
+			Entry("quoted boolean value", "ready ne 'false'"),
+			Entry("integer field", "maximum_polling_duration gt 10"),
+			Entry("datetime field", "created_at lt 2020-01-01T10:00:00Z"),
+			Entry("datetime field with fractional seconds", "created_at ge '2020-01-01T10:00:00.123456Z'"),
+			Entry("multivariate operator", "maximum_polling_duration in (1, 2, 3)"),
+			Entry("empty multivariate operator", "maximum_polling_duration in ()"),
+			Entry("nullable operator", "description en 'abc'"),
+			Entry("JSON field", `context eq '{"a":"b"}'`),
+		)
+
+		DescribeTable("rejects invalid criteria",
+			func(expression string, expectedMessage string) {
+				err := schema.Validate(parse(expression)...)
+				Expect(err).To(HaveOccurred())
+				Expect(err).To(BeAssignableToTypeOf(&util.UnsupportedQueryError{}))
+				Expect(err.Error()).To(ContainSubstring(expectedMessage))
+			},
+			Entry("unknown field", "unknown eq 'abc'", "unsupported field query key: unknown. Supported fields are: id, created_at, ready"),
+			Entry("non-queryable field", "password eq 'abc'", "unsupported field query key: password"),
+			Entry("invalid boolean value", "ready eq 'yes'", "invalid value 'yes' for boolean field ready"),
+			Entry("invalid integer value", "maximum_polling_duration eq 'ten'", "invalid value 'ten' for integer field maximum_polling_duration"),
+			Entry("invalid integer value in multivariate operator", "maximum_polling_duration in (1, 'two')", "invalid value 'two' for integer field"),
+			Entry("invalid datetime value", "created_at eq '2020-01-01'", "invalid value '2020-01-01' for datetime field created_at"),
+			Entry("numeric operator on boolean field", "ready gt 1", "operator gt is not supported for boolean field ready"),
+			Entry("numeric operator on JSON field", "context lt 1", "operator lt is not supported for json field context"),
+		)
+
+		It("ignores label criteria", func() {
+			criteria, err := Parse(LabelQuery, "unknown eq 'yes'")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(schema.Validate(criteria...)).To(Succeed())
+		})
+
+		It("accepts ordering by queryable fields", func() {
+			Expect(schema.Validate(OrderResultBy("created_at", DescOrder))).To(Succeed())
+		})
+
+		It("rejects ordering by unknown fields", func() {
+			err := schema.Validate(OrderResultBy("unknown", AscOrder))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unsupported entity field for order by: unknown"))
+		})
+	})
+})
@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -46,6 +47,8 @@ const (
 	LabelQuery CriterionType = "labelQuery"
 	// ResultQuery is used to further process result
 	ResultQuery CriterionType = "resultQuery"
+	// SearchQuery denotes that a full-text search should be executed on the entity's searchable fields and labels
+	SearchQuery CriterionType = "searchQuery"
 )
 
 // OperatorType represents the type of the query operator
@@ -73,6 +76,10 @@ const (
 	OrderBy string = "orderBy"
 	// Limit should be used as a left operand in Criterion to signify the
 	Limit string = "limit"
+	// Search should be used as a left operand in Criterion to signify the text of a full-text search
+	Search string = "search"
+	// Lock should be used as a left operand in Criterion to signify that the selected rows are locked for update
+	Lock string = "lock"
 )
 
 var (
@@ -85,6 +92,8 @@ var (
 	}
 	// CriteriaTypes returns the supported query criteria types
 	CriteriaTypes = []CriterionType{FieldQuery, LabelQuery}
+
+	searchTermRegexp = regexp.MustCompile(`[\p{L}\p{N}_]+`)
 )
 
 // Operator is a query operator
@@ -132,6 +141,16 @@ func LimitResultBy(limit int) Criterion {
 	return NewCriterion(Limit, NoOperator, []string{limitString}, ResultQuery)
 }
 
+// LockResultForUpdate constructs a new criterion which locks the selected rows for update until the end of the transaction
+func LockResultForUpdate() Criterion {
+	return NewCriterion(Lock, NoOperator, []string{"update"}, ResultQuery)
+}
+
+// ByText constructs a new criterion for full-text search of the given text
+func ByText(text string) Criterion {
+	return NewCriterion(Search, NoOperator, []string{text}, SearchQuery)
+}
+
 func NewCriterion(leftOp string, operator Operator, rightOp []string, criteriaType CriterionType) Criterion {
 	return Criterion{LeftOp: leftOp, Operator: operator, RightOp: rightOp, Type: criteriaType}
 }
@@ -162,6 +181,13 @@ func (c Criterion) Validate() error {
 		return nil
 	}
 
+	if c.Type == SearchQuery {
+		if len(SearchTerms(c.RightOp[0])) == 0 {
+			return &util.UnsupportedQueryError{Message: fmt.Sprintf("search text \"%s\" does not contain any words", c.RightOp[0])}
+		}
+		return nil
+	}
+
 	if len(c.RightOp) > 1 && c.Operator.Type() == UnivariateOperator {
 		return &util.UnsupportedQueryError{Message: fmt.Sprintf("multiple values %s received for single value operation %s", c.RightOp, c.Operator)}
 	}
@@ -292,8 +318,14 @@ func isDateTime(str string) bool {
 	return err == nil
 }
 
+// SearchTerms returns the lower case words of the text which are matched by a full-text search
+func SearchTerms(text string) []string {
+	return searchTermRegexp.FindAllString(strings.ToLower(text), -1)
+}
+
 func validateWholeCriteria(criteria ...Criterion) error {
 	isLimited := false
+	isSearched := false
 	for _, criterion := range criteria {
 		if criterion.LeftOp == Limit {
 			if isLimited {
@@ -301,6 +333,12 @@ func validateWholeCriteria(criteria ...Criterion) error {
 			}
 			isLimited = true
 		}
+		if criterion.Type == SearchQuery {
+			if isSearched {
+				return &util.UnsupportedQueryError{Message: "zero/one search criterion expected but multiple provided"}
+			}
+			isSearched = true
+		}
 	}
 	return nil
 }
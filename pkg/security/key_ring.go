@@ -0,0 +1,125 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package security
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+)
+
+// keyIDDelimiter encloses the ID of the key which is prefixed on ciphertexts produced by a KeyRing
+const keyIDDelimiter = '$'
+
+// Key is a version of an encryption key
+type Key struct {
+	ID    string
+	Value []byte
+}
+
+// KeyRing encrypts data with the active version of an encryption key and decrypts data encrypted with any of the
+// versions of the key. The ID of the active key is prefixed on the produced ciphertexts so that the key used for
+// decryption can be identified. Ciphertexts without key ID, such as those produced before keys were versioned,
+// are decrypted by trying each of the keys.
+type KeyRing struct {
+	encrypter Encrypter
+	keys      []Key
+}
+
+// NewKeyRing creates a KeyRing which uses the specified encrypter with the provided keys. The first key is the active one.
+func NewKeyRing(encrypter Encrypter, activeKey Key, keys ...Key) *KeyRing {
+	return &KeyRing{
+		encrypter: encrypter,
+		keys:      append([]Key{activeKey}, keys...),
+	}
+}
+
+// ActiveKeyID returns the ID of the key used for encryption
+func (k *KeyRing) ActiveKeyID() string {
+	return k.keys[0].ID
+}
+
+// Encrypt encrypts the plaintext with the active key and prefixes the ciphertext with the ID of the key
+func (k *KeyRing) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	activeKey := k.keys[0]
+	ciphertext, err := k.encrypter.Encrypt(ctx, plaintext, activeKey.Value)
+	if err != nil {
+		return nil, err
+	}
+	if activeKey.ID == "" {
+		return ciphertext, nil
+	}
+	return append(keyIDPrefix(activeKey.ID), ciphertext...), nil
+}
+
+// Decrypt decrypts the ciphertext with the key whose ID is prefixed on it or, if there is no such key, with any of the keys
+func (k *KeyRing) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	if keyID, encrypted, found := splitKeyID(ciphertext); found {
+		for _, key := range k.keys {
+			if key.ID == keyID {
+				if plaintext, err := k.encrypter.Decrypt(ctx, encrypted, key.Value); err == nil {
+					return plaintext, nil
+				}
+				break
+			}
+		}
+	}
+
+	var err error
+	for _, key := range k.keys {
+		var plaintext []byte
+		if plaintext, err = k.encrypter.Decrypt(ctx, ciphertext, key.Value); err == nil {
+			return plaintext, nil
+		}
+	}
+	return nil, fmt.Errorf("could not decrypt data with any of the %d encryption keys: %s", len(k.keys), err)
+}
+
+// IsEncryptedWithActiveKey returns true if the ciphertext is prefixed with the ID of the active key
+func (k *KeyRing) IsEncryptedWithActiveKey(ciphertext []byte) bool {
+	keyID, _, found := splitKeyID(ciphertext)
+	return found && keyID == k.ActiveKeyID()
+}
+
+// IsEncryptedWithUnknownKey returns true if the ciphertext is prefixed with the ID of a key which is not in the KeyRing
+func (k *KeyRing) IsEncryptedWithUnknownKey(ciphertext []byte) bool {
+	keyID, _, found := splitKeyID(ciphertext)
+	if !found {
+		return false
+	}
+	for _, key := range k.keys {
+		if key.ID == keyID {
+			return false
+		}
+	}
+	return true
+}
+
+func keyIDPrefix(keyID string) []byte {
+	return []byte(string(keyIDDelimiter) + keyID + string(keyIDDelimiter))
+}
+
+func splitKeyID(ciphertext []byte) (string, []byte, bool) {
+	if len(ciphertext) == 0 || ciphertext[0] != keyIDDelimiter {
+		return "", nil, false
+	}
+	end := bytes.IndexByte(ciphertext[1:], keyIDDelimiter)
+	if end <= 0 {
+		return "", nil, false
+	}
+	return string(ciphertext[1 : end+1]), ciphertext[end+2:], true
+}
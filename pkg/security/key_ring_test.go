@@ -0,0 +1,116 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package security_test
+
+import (
+	"context"
+	"crypto/rand"
+	"log"
+
+	"github.com/Peripli/service-manager/pkg/security"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Key Ring", func() {
+	var oldKey, newKey security.Key
+	var encrypter *security.AESEncrypter
+	plaintext := []byte("plaintext")
+
+	generateKey := func(id string) security.Key {
+		value := make([]byte, 32)
+		if _, err := rand.Read(value); err != nil {
+			log.Panicf("Could not generate encryption key: %v", err)
+		}
+		return security.Key{ID: id, Value: value}
+	}
+
+	BeforeEach(func() {
+		encrypter = &security.AESEncrypter{}
+		oldKey = generateKey("1")
+		newKey = generateKey("2")
+	})
+
+	Context("Encrypt", func() {
+		It("prefixes the ciphertext with the ID of the active key", func() {
+			keyRing := security.NewKeyRing(encrypter, newKey, oldKey)
+			ciphertext, err := keyRing.Encrypt(context.TODO(), plaintext)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(ciphertext)).To(HavePrefix("$2$"))
+			Expect(keyRing.IsEncryptedWithActiveKey(ciphertext)).To(BeTrue())
+		})
+
+		It("does not prefix the ciphertext when the active key has no ID", func() {
+			keyRing := security.NewKeyRing(encrypter, security.Key{Value: newKey.Value})
+			ciphertext, err := keyRing.Encrypt(context.TODO(), plaintext)
+			Expect(err).ToNot(HaveOccurred())
+			decrypted, err := encrypter.Decrypt(context.TODO(), ciphertext, newKey.Value)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decrypted).To(Equal(plaintext))
+		})
+	})
+
+	Context("Decrypt", func() {
+		It("decrypts data encrypted with a previous key", func() {
+			ciphertext, err := security.NewKeyRing(encrypter, oldKey).Encrypt(context.TODO(), plaintext)
+			Expect(err).ToNot(HaveOccurred())
+
+			keyRing := security.NewKeyRing(encrypter, newKey, oldKey)
+			Expect(keyRing.IsEncryptedWithActiveKey(ciphertext)).To(BeFalse())
+			decrypted, err := keyRing.Decrypt(context.TODO(), ciphertext)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decrypted).To(Equal(plaintext))
+		})
+
+		It("decrypts data encrypted without key ID", func() {
+			ciphertext, err := encrypter.Encrypt(context.TODO(), plaintext, oldKey.Value)
+			Expect(err).ToNot(HaveOccurred())
+
+			keyRing := security.NewKeyRing(encrypter, newKey, oldKey)
+			Expect(keyRing.IsEncryptedWithActiveKey(ciphertext)).To(BeFalse())
+			decrypted, err := keyRing.Decrypt(context.TODO(), ciphertext)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decrypted).To(Equal(plaintext))
+		})
+
+		It("fails when none of the keys can decrypt the data", func() {
+			ciphertext, err := security.NewKeyRing(encrypter, generateKey("3")).Encrypt(context.TODO(), plaintext)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = security.NewKeyRing(encrypter, newKey, oldKey).Decrypt(context.TODO(), ciphertext)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("IsEncryptedWithUnknownKey", func() {
+		It("returns true only for data prefixed with the ID of a key which is not in the key ring", func() {
+			keyRing := security.NewKeyRing(encrypter, newKey, oldKey)
+
+			unknown, err := security.NewKeyRing(encrypter, generateKey("3")).Encrypt(context.TODO(), plaintext)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keyRing.IsEncryptedWithUnknownKey(unknown)).To(BeTrue())
+
+			known, err := security.NewKeyRing(encrypter, oldKey).Encrypt(context.TODO(), plaintext)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keyRing.IsEncryptedWithUnknownKey(known)).To(BeFalse())
+
+			withoutKeyID, err := encrypter.Encrypt(context.TODO(), plaintext, oldKey.Value)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keyRing.IsEncryptedWithUnknownKey(withoutKeyID)).To(BeFalse())
+		})
+	})
+})
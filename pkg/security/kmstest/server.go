@@ -0,0 +1,152 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package kmstest provides a local stand-in for a key management service implementing the Vault transit API
+package kmstest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/Peripli/service-manager/pkg/security"
+)
+
+// Server is a key management service which wraps data with in-memory keys
+type Server struct {
+	*httptest.Server
+
+	Token     string
+	MountPath string
+
+	mutex    sync.Mutex
+	keys     map[string][]byte
+	requests map[string]int
+}
+
+// NewServer starts a key management service which accepts requests authenticated with the specified token
+func NewServer(token string) *Server {
+	s := &Server{
+		Token:     token,
+		MountPath: "transit",
+		keys:      make(map[string][]byte),
+		requests:  make(map[string]int),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Requests returns the number of encrypt or decrypt requests received by the server
+func (s *Server) Requests(operation string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.requests[operation]
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeErrors(w, http.StatusMethodNotAllowed, "unsupported method")
+		return
+	}
+	if r.Header.Get("X-Vault-Token") != s.Token {
+		writeErrors(w, http.StatusForbidden, "permission denied")
+		return
+	}
+
+	segments := strings.Split(strings.TrimPrefix(r.URL.Path, "/v1/"+s.MountPath+"/"), "/")
+	if len(segments) != 2 {
+		writeErrors(w, http.StatusNotFound, "unsupported path")
+		return
+	}
+	operation, keyName := segments[0], segments[1]
+
+	request := struct {
+		Plaintext  string `json:"plaintext"`
+		Ciphertext string `json:"ciphertext"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeErrors(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mutex.Lock()
+	s.requests[operation]++
+	key := s.key(keyName)
+	s.mutex.Unlock()
+
+	encrypter := &security.AESEncrypter{}
+	switch operation {
+	case "encrypt":
+		plaintext, err := base64.StdEncoding.DecodeString(request.Plaintext)
+		if err != nil {
+			writeErrors(w, http.StatusBadRequest, "plaintext must be base64 encoded")
+			return
+		}
+		ciphertext, err := encrypter.Encrypt(context.Background(), plaintext, key)
+		if err != nil {
+			writeErrors(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeData(w, "ciphertext", security.VaultCiphertextPrefix+"v1:"+base64.StdEncoding.EncodeToString(ciphertext))
+	case "decrypt":
+		ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(request.Ciphertext, security.VaultCiphertextPrefix+"v1:"))
+		if err != nil {
+			writeErrors(w, http.StatusBadRequest, "invalid ciphertext")
+			return
+		}
+		plaintext, err := encrypter.Decrypt(context.Background(), ciphertext, key)
+		if err != nil {
+			writeErrors(w, http.StatusBadRequest, "cipher: message authentication failed")
+			return
+		}
+		writeData(w, "plaintext", base64.StdEncoding.EncodeToString(plaintext))
+	default:
+		writeErrors(w, http.StatusNotFound, "unsupported operation")
+	}
+}
+
+func (s *Server) key(name string) []byte {
+	key, found := s.keys[name]
+	if !found {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic(err)
+		}
+		s.keys[name] = key
+	}
+	return key
+}
+
+func writeData(w http.ResponseWriter, field, value string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": map[string]string{field: value},
+	})
+}
+
+func writeErrors(w http.ResponseWriter, status int, errors ...string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": errors,
+	})
+}
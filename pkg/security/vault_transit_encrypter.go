@@ -0,0 +1,189 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package security
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// VaultCiphertextPrefix is the prefix of the ciphertexts produced by the Vault transit secrets engine
+const VaultCiphertextPrefix = "vault:"
+
+// VaultTransitConfig configures a VaultTransitEncrypter
+type VaultTransitConfig struct {
+	// Address is the base URL of the key management service
+	Address string
+	// Token authenticates the requests to the key management service
+	Token string
+	// MountPath is the path on which the transit secrets engine is mounted
+	MountPath string
+	// KeyName is the name of the key in the key management service which wraps the data
+	KeyName string
+	// Timeout is the timeout of the requests to the key management service
+	Timeout time.Duration
+	// CacheTTL is how long unwrapped data is cached to avoid requests to the key management service
+	CacheTTL time.Duration
+	// Fallback decrypts ciphertexts which are not wrapped by the key management service, e.g. the ones
+	// encrypted before the key management service was configured. Optional.
+	Fallback Encrypter
+}
+
+// VaultTransitEncrypter is an Encrypter which wraps data with a key managed by an external key management service
+// implementing the Vault transit API. The key never leaves the key management service, so the key passed to
+// Encrypt and Decrypt is only used for decryption with the fallback encrypter.
+type VaultTransitEncrypter struct {
+	config    VaultTransitConfig
+	doRequest util.DoRequestFunc
+
+	mutex sync.Mutex
+	cache map[string]cachedPlaintext
+}
+
+type cachedPlaintext struct {
+	plaintext []byte
+	expiresAt time.Time
+}
+
+type vaultEncryptRequest struct {
+	Plaintext string `json:"plaintext"`
+}
+
+type vaultDecryptRequest struct {
+	Ciphertext string `json:"ciphertext"`
+}
+
+type vaultResponse struct {
+	Data struct {
+		Ciphertext string `json:"ciphertext"`
+		Plaintext  string `json:"plaintext"`
+	} `json:"data"`
+	Errors []string `json:"errors"`
+}
+
+// NewVaultTransitEncrypter creates a VaultTransitEncrypter with the specified configuration
+func NewVaultTransitEncrypter(config VaultTransitConfig) *VaultTransitEncrypter {
+	client := &http.Client{
+		Timeout: config.Timeout,
+	}
+	return &VaultTransitEncrypter{
+		config:    config,
+		doRequest: client.Do,
+		cache:     make(map[string]cachedPlaintext),
+	}
+}
+
+// Encrypt wraps the plaintext with the key in the key management service
+func (e *VaultTransitEncrypter) Encrypt(ctx context.Context, plaintext []byte, _ []byte) ([]byte, error) {
+	response := &vaultResponse{}
+	if err := e.send(ctx, "encrypt", &vaultEncryptRequest{
+		Plaintext: base64.StdEncoding.EncodeToString(plaintext),
+	}, response); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(response.Data.Ciphertext, VaultCiphertextPrefix) {
+		return nil, fmt.Errorf("key management service returned malformed ciphertext")
+	}
+
+	ciphertext := []byte(response.Data.Ciphertext)
+	e.store(ciphertext, plaintext)
+	return ciphertext, nil
+}
+
+// Decrypt unwraps the ciphertext with the key in the key management service. Ciphertexts which are not wrapped
+// by the key management service are decrypted with the provided key by the fallback encrypter.
+func (e *VaultTransitEncrypter) Decrypt(ctx context.Context, ciphertext []byte, key []byte) ([]byte, error) {
+	if !bytes.HasPrefix(ciphertext, []byte(VaultCiphertextPrefix)) {
+		if e.config.Fallback == nil || len(key) == 0 {
+			return nil, fmt.Errorf("ciphertext is not wrapped by the key management service")
+		}
+		return e.config.Fallback.Decrypt(ctx, ciphertext, key)
+	}
+
+	if plaintext, found := e.load(ciphertext); found {
+		return plaintext, nil
+	}
+
+	response := &vaultResponse{}
+	if err := e.send(ctx, "decrypt", &vaultDecryptRequest{
+		Ciphertext: string(ciphertext),
+	}, response); err != nil {
+		return nil, err
+	}
+	plaintext, err := base64.StdEncoding.DecodeString(response.Data.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("key management service returned malformed plaintext: %s", err)
+	}
+
+	e.store(ciphertext, plaintext)
+	return plaintext, nil
+}
+
+func (e *VaultTransitEncrypter) send(ctx context.Context, operation string, body interface{}, result *vaultResponse) error {
+	url := fmt.Sprintf("%s/v1/%s/%s/%s", strings.TrimRight(e.config.Address, "/"), strings.Trim(e.config.MountPath, "/"), operation, e.config.KeyName)
+	response, err := util.SendRequestWithHeaders(ctx, e.doRequest, http.MethodPost, url, nil, body, map[string]string{
+		"X-Vault-Token": e.config.Token,
+		"Content-Type":  "application/json",
+	})
+	if err != nil {
+		return fmt.Errorf("could not reach key management service: %s", err)
+	}
+	if err := util.BodyToObject(response.Body, result); err != nil {
+		return fmt.Errorf("could not parse response of key management service with status %d: %s", response.StatusCode, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("key management service could not %s data: status %d: %s", operation, response.StatusCode, strings.Join(result.Errors, ", "))
+	}
+	return nil
+}
+
+func (e *VaultTransitEncrypter) load(ciphertext []byte) ([]byte, bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	cached, found := e.cache[string(ciphertext)]
+	if !found {
+		return nil, false
+	}
+	if time.Now().After(cached.expiresAt) {
+		delete(e.cache, string(ciphertext))
+		return nil, false
+	}
+	return cached.plaintext, true
+}
+
+func (e *VaultTransitEncrypter) store(ciphertext, plaintext []byte) {
+	if e.config.CacheTTL <= 0 {
+		return
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.cache[string(ciphertext)] = cachedPlaintext{
+		plaintext: plaintext,
+		expiresAt: time.Now().Add(e.config.CacheTTL),
+	}
+}
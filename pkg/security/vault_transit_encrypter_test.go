@@ -0,0 +1,113 @@
+package security_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/pkg/security/kmstest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Vault Transit Encrypter", func() {
+	const token = "kms-token"
+
+	var kmsServer *kmstest.Server
+	var config security.VaultTransitConfig
+	var encrypter *security.VaultTransitEncrypter
+	plaintext := []byte("plaintext")
+
+	BeforeEach(func() {
+		kmsServer = kmstest.NewServer(token)
+		config = security.VaultTransitConfig{
+			Address:   kmsServer.URL,
+			Token:     token,
+			MountPath: kmsServer.MountPath,
+			KeyName:   "sm",
+			Timeout:   5 * time.Second,
+		}
+	})
+
+	JustBeforeEach(func() {
+		encrypter = security.NewVaultTransitEncrypter(config)
+	})
+
+	AfterEach(func() {
+		kmsServer.Close()
+	})
+
+	Context("when the key management service is available", func() {
+		It("wraps and unwraps data", func() {
+			ciphertext, err := encrypter.Encrypt(context.TODO(), plaintext, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(ciphertext)).To(HavePrefix(security.VaultCiphertextPrefix))
+			Expect(ciphertext).ToNot(ContainSubstring(string(plaintext)))
+
+			decrypted, err := encrypter.Decrypt(context.TODO(), ciphertext, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decrypted).To(Equal(plaintext))
+			Expect(kmsServer.Requests("decrypt")).To(Equal(1))
+		})
+	})
+
+	Context("when caching is enabled", func() {
+		BeforeEach(func() {
+			config.CacheTTL = time.Minute
+		})
+
+		It("does not call the key management service to unwrap known data", func() {
+			ciphertext, err := encrypter.Encrypt(context.TODO(), plaintext, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			for i := 0; i < 2; i++ {
+				decrypted, err := encrypter.Decrypt(context.TODO(), ciphertext, nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(decrypted).To(Equal(plaintext))
+			}
+			Expect(kmsServer.Requests("decrypt")).To(Equal(0))
+		})
+	})
+
+	Context("when the token is invalid", func() {
+		BeforeEach(func() {
+			config.Token = "invalid"
+		})
+
+		It("returns an error", func() {
+			_, err := encrypter.Encrypt(context.TODO(), plaintext, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("permission denied"))
+		})
+	})
+
+	Context("when the ciphertext is not wrapped by the key management service", func() {
+		var aesCiphertext []byte
+		var aesKey []byte
+
+		BeforeEach(func() {
+			aesKey = []byte("ejHjRNHbS0NaqARSRvnweVV9zcmhQEa8")
+			var err error
+			aesCiphertext, err = (&security.AESEncrypter{}).Encrypt(context.TODO(), plaintext, aesKey)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns an error if there is no fallback", func() {
+			_, err := encrypter.Decrypt(context.TODO(), aesCiphertext, aesKey)
+			Expect(err).To(HaveOccurred())
+		})
+
+		Context("and a fallback is configured", func() {
+			BeforeEach(func() {
+				config.Fallback = &security.AESEncrypter{}
+			})
+
+			It("decrypts it with the fallback", func() {
+				decrypted, err := encrypter.Decrypt(context.TODO(), aesCiphertext, aesKey)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(decrypted).To(Equal(plaintext))
+				Expect(kmsServer.Requests("decrypt")).To(Equal(0))
+			})
+		})
+	})
+})
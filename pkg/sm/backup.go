@@ -0,0 +1,148 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package sm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/Peripli/service-manager/config"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/backup"
+	"github.com/Peripli/service-manager/storage/postgres"
+)
+
+const (
+	// ExportCommand is the name of the command which exports the Service Manager data to an archive instead of starting the Service Manager
+	ExportCommand = "export"
+
+	// ImportCommand is the name of the command which imports the Service Manager data from an archive instead of starting the Service Manager
+	ImportCommand = "import"
+)
+
+// ExportUsage describes the arguments of the export command
+const ExportUsage = `usage: export FILE [flags]
+
+writes the brokers, platforms, visibilities, instances and bindings to FILE encrypted with the backup.encryption_key`
+
+// ImportUsage describes the arguments of the import command
+const ImportUsage = `usage: import FILE [flags]
+
+creates the brokers, platforms, visibilities, instances and bindings from FILE decrypted with the backup.encryption_key
+in a single transaction. Set backup.remap_ids to assign new ids to the imported resources.`
+
+// Export runs the export command with the specified arguments against the configured storage and writes its output to out
+func Export(ctx context.Context, cfg *config.Settings, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return fmt.Errorf("missing archive file\n%s", ExportUsage)
+	}
+	return runBackupCommand(ctx, cfg, func(ctx context.Context, repository storage.TransactionalRepository, key []byte) error {
+		archive, err := (&backup.Exporter{Repository: repository}).Export(ctx)
+		if err != nil {
+			return fmt.Errorf("could not export resources: %s", err)
+		}
+
+		file, err := os.OpenFile(args[0], os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return fmt.Errorf("could not create archive file: %s", err)
+		}
+		if err := backup.WriteArchive(ctx, file, archive, &security.AESEncrypter{}, key); err != nil {
+			if closeErr := file.Close(); closeErr != nil {
+				log.C(ctx).WithError(closeErr).Error("could not close archive file")
+			}
+			return fmt.Errorf("could not write archive file: %s", err)
+		}
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("could not write archive file: %s", err)
+		}
+		fmt.Fprintf(out, "Exported %d resources to %s\n", len(archive.Resources), args[0])
+		return nil
+	})
+}
+
+// Import runs the import command with the specified arguments against the configured storage and writes its output to out
+func Import(ctx context.Context, cfg *config.Settings, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return fmt.Errorf("missing archive file\n%s", ImportUsage)
+	}
+	return runBackupCommand(ctx, cfg, func(ctx context.Context, repository storage.TransactionalRepository, key []byte) error {
+		file, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("could not open archive file: %s", err)
+		}
+		defer func() {
+			if err := file.Close(); err != nil {
+				log.C(ctx).WithError(err).Error("could not close archive file")
+			}
+		}()
+		archive, err := backup.ReadArchive(ctx, file, &security.AESEncrypter{}, key)
+		if err != nil {
+			return err
+		}
+
+		importer := &backup.Importer{
+			Repository: repository,
+			RemapIDs:   cfg.Backup.RemapIDs,
+		}
+		if err := importer.Import(ctx, archive); err != nil {
+			return fmt.Errorf("could not import resources: %s", err)
+		}
+		fmt.Fprintf(out, "Imported %d resources from %s\n", len(archive.Resources), args[0])
+		return nil
+	})
+}
+
+// runBackupCommand opens the configured storage with the encryption of secrets and runs the command with it
+func runBackupCommand(ctx context.Context, cfg *config.Settings, command func(context.Context, storage.TransactionalRepository, []byte) error) error {
+	if err := cfg.Storage.Validate(); err != nil {
+		return fmt.Errorf("error validating storage configuration: %s", err)
+	}
+	if err := cfg.Backup.Validate(); err != nil {
+		return fmt.Errorf("error validating backup configuration: %s", err)
+	}
+	ctx, err := log.Configure(ctx, cfg.Log)
+	if err != nil {
+		return fmt.Errorf("error configuring logging: %s", err)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	waitGroup := &sync.WaitGroup{}
+	defer waitGroup.Wait()
+	defer cancel()
+
+	smStorage := &postgres.Storage{
+		ConnectFunc: func(driver string, url string) (*sql.DB, error) {
+			return sql.Open(driver, url)
+		},
+	}
+	encryptionKeys := storage.NewEncryptionKeys(&security.AESEncrypter{}, storage.NewKeyEncrypter(cfg.Storage.KMS), smStorage, postgres.EncryptingLocker(smStorage))
+	repository, err := storage.InitializeWithSafeTermination(ctx, smStorage, cfg.Storage, waitGroup, storage.EncryptingDecorator(ctx, encryptionKeys))
+	if err != nil {
+		return fmt.Errorf("error opening storage: %s", err)
+	}
+	// the imported resources must have the unique keys of the constraints enforced by the Service Manager
+	if err := smStorage.DeclareUniqueConstraints(ctx, uniqueConstraintsOf(cfg)...); err != nil {
+		return fmt.Errorf("could not declare unique constraints: %s", err)
+	}
+
+	return command(ctx, repository, []byte(cfg.Backup.EncryptionKey))
+}
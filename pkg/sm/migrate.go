@@ -0,0 +1,155 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package sm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/Peripli/service-manager/config"
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/storage/postgres"
+)
+
+// MigrateCommand is the name of the command which manages the database schema migrations instead of starting the Service Manager
+const MigrateCommand = "migrate"
+
+// MigrateUsage describes the arguments of the migrate command
+const MigrateUsage = `usage: migrate <command> [flags]
+
+commands:
+  status          print the current version of the database schema and the pending migrations
+  up [N]          apply the next N pending migrations or all of them if N is omitted
+  down N          revert the last N applied migrations
+  dry-run [N]     print the SQL of the next N pending migrations or all of them if N is omitted without applying them
+  force VERSION   set the version of the database schema and clear its dirty state without applying any migrations`
+
+// Migrator manages the database schema migrations
+type Migrator interface {
+	Status() (*postgres.MigrationStatus, error)
+	Up(n int) error
+	Down(n int) error
+	Force(version int) error
+	DryRun(writer io.Writer, n int) error
+	Close() error
+}
+
+// Migrate runs the migrate command with the specified arguments against the configured storage and writes its output to out
+func Migrate(ctx context.Context, cfg *config.Settings, args []string, out io.Writer) error {
+	if err := cfg.Storage.Validate(); err != nil {
+		return fmt.Errorf("error validating storage configuration: %s", err)
+	}
+	ctx, err := log.Configure(ctx, cfg.Log)
+	if err != nil {
+		return fmt.Errorf("error configuring logging: %s", err)
+	}
+
+	migrator, err := postgres.OpenMigrator(cfg.Storage)
+	if err != nil {
+		return fmt.Errorf("could not create migrator: %s", err)
+	}
+	defer func() {
+		if err := migrator.Close(); err != nil {
+			log.C(ctx).WithError(err).Error("could not close migrator")
+		}
+	}()
+
+	return RunMigrateCommand(ctx, migrator, args, out)
+}
+
+// RunMigrateCommand runs the migrate command with the specified arguments using the migrator and writes its output to out
+func RunMigrateCommand(ctx context.Context, migrator Migrator, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing migrate command\n%s", MigrateUsage)
+	}
+	command, number, err := parseMigrateArgs(args)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "status":
+		return printMigrationStatus(migrator, out)
+	case "up":
+		if err := migrator.Up(number); err != nil {
+			return fmt.Errorf("could not apply migrations: %s", err)
+		}
+		log.C(ctx).Info("Successfully applied migrations")
+		return printMigrationStatus(migrator, out)
+	case "down":
+		if len(args) < 2 {
+			return fmt.Errorf("missing number of migrations to revert\n%s", MigrateUsage)
+		}
+		if err := migrator.Down(number); err != nil {
+			return fmt.Errorf("could not revert migrations: %s", err)
+		}
+		log.C(ctx).Infof("Successfully reverted %d migrations", number)
+		return printMigrationStatus(migrator, out)
+	case "dry-run":
+		return migrator.DryRun(out, number)
+	case "force":
+		if len(args) < 2 {
+			return fmt.Errorf("missing version to force\n%s", MigrateUsage)
+		}
+		if err := migrator.Force(number); err != nil {
+			return fmt.Errorf("could not force version %d: %s", number, err)
+		}
+		log.C(ctx).Infof("Successfully forced database schema version %d", number)
+		return printMigrationStatus(migrator, out)
+	default:
+		return fmt.Errorf("unknown migrate command %s\n%s", command, MigrateUsage)
+	}
+}
+
+func parseMigrateArgs(args []string) (string, int, error) {
+	if len(args) > 2 {
+		return "", 0, fmt.Errorf("too many arguments for migrate command %s\n%s", args[0], MigrateUsage)
+	}
+	if len(args) == 1 {
+		return args[0], 0, nil
+	}
+	number, err := strconv.Atoi(args[1])
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid argument %s for migrate command %s: must be a number", args[1], args[0])
+	}
+	return args[0], number, nil
+}
+
+func printMigrationStatus(migrator Migrator, out io.Writer) error {
+	status, err := migrator.Status()
+	if err != nil {
+		return fmt.Errorf("could not get migration status: %s", err)
+	}
+
+	state := "up to date"
+	if status.Dirty {
+		state = "dirty"
+	} else if len(status.Pending) > 0 {
+		state = fmt.Sprintf("%d pending migrations", len(status.Pending))
+	}
+	if _, err := fmt.Fprintf(out, "version: %d (%s)\n", status.Version, state); err != nil {
+		return err
+	}
+	for _, version := range status.Pending {
+		if _, err := fmt.Fprintf(out, "pending: %d\n", version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -42,8 +42,12 @@ import (
 	"github.com/Peripli/service-manager/storage/interceptors"
 
 	"github.com/Peripli/service-manager/api"
+	"github.com/Peripli/service-manager/api/changes"
+	"github.com/Peripli/service-manager/api/configuration"
 	"github.com/Peripli/service-manager/api/healthcheck"
+	"github.com/Peripli/service-manager/api/upgrades"
 	"github.com/Peripli/service-manager/config"
+	"github.com/Peripli/service-manager/pkg/brokerclient"
 	"github.com/Peripli/service-manager/pkg/log"
 	"github.com/Peripli/service-manager/pkg/server"
 	"github.com/Peripli/service-manager/pkg/util"
@@ -52,7 +56,6 @@ import (
 
 	"github.com/Peripli/service-manager/api/filters"
 	"github.com/Peripli/service-manager/pkg/web"
-	osbc "github.com/kubernetes-sigs/go-open-service-broker-client/v2"
 )
 
 // ServiceManagerBuilder type is an extension point that allows adding additional filters, plugins and
@@ -60,24 +63,31 @@ import (
 type ServiceManagerBuilder struct {
 	*web.API
 
-	Storage             *storage.InterceptableTransactionalRepository
-	Notificator         storage.Notificator
-	NotificationCleaner *storage.NotificationCleaner
-	OperationMaintainer *operations.Maintainer
-	OSBClientProvider   osbc.CreateFunc
-	ctx                 context.Context
-	wg                  *sync.WaitGroup
-	cfg                 *config.Settings
-	securityBuilder     *SecurityBuilder
+	Storage                *storage.InterceptableTransactionalRepository
+	Notificator            storage.Notificator
+	NotificationCleaner    *storage.NotificationCleaner
+	ChangeFeed             *postgres.ChangeFeed
+	OperationMaintainer    *operations.Maintainer
+	EncryptionKeys         *storage.EncryptionKeys
+	CredentialsReencrypter *storage.CredentialsReencrypter
+	OSBClientProvider      brokerclient.BrokerClientFunc
+	ctx                    context.Context
+	wg                     *sync.WaitGroup
+	cfg                    *config.Settings
+	securityBuilder        *SecurityBuilder
+	schemas                storage.SchemaProvider
 }
 
 // ServiceManager  struct
 type ServiceManager struct {
-	ctx                 context.Context
-	wg                  *sync.WaitGroup
-	Server              *server.Server
-	Notificator         storage.Notificator
-	NotificationCleaner *storage.NotificationCleaner
+	ctx                    context.Context
+	wg                     *sync.WaitGroup
+	Server                 *server.Server
+	Notificator            storage.Notificator
+	NotificationCleaner    *storage.NotificationCleaner
+	ChangeFeed             *postgres.ChangeFeed
+	EncryptionKeys         *storage.EncryptionKeys
+	CredentialsReencrypter *storage.CredentialsReencrypter
 }
 
 // New returns service-manager Server with default setup
@@ -106,7 +116,8 @@ func New(ctx context.Context, cancel context.CancelFunc, e env.Environment, cfg
 	}
 
 	// Decorate the storage with credentials encryption/decryption
-	encryptingDecorator := storage.EncryptingDecorator(ctx, &security.AESEncrypter{}, smStorage, postgres.EncryptingLocker(smStorage))
+	encryptionKeys := storage.NewEncryptionKeys(&security.AESEncrypter{}, storage.NewKeyEncrypter(cfg.Storage.KMS), smStorage, postgres.EncryptingLocker(smStorage))
+	encryptingDecorator := storage.EncryptingDecorator(ctx, encryptionKeys)
 
 	// Initialize the storage with graceful termination
 	var transactionalRepository storage.TransactionalRepository
@@ -115,6 +126,19 @@ func New(ctx context.Context, cancel context.CancelFunc, e env.Environment, cfg
 		return nil, fmt.Errorf("error opening storage: %s", err)
 	}
 
+	// Re-encrypt credentials which are not yet encrypted with the active encryption key
+	credentialsReencrypter := &storage.CredentialsReencrypter{
+		Repository: smStorage,
+		Keys:       encryptionKeys,
+		Locker:     postgres.ReencryptionLocker(smStorage),
+	}
+
+	// Enforce the uniqueness of the names of instances and bindings in the database
+	uniqueConstraints := uniqueConstraintsOf(cfg)
+	if err := smStorage.DeclareUniqueConstraints(ctx, uniqueConstraints...); err != nil {
+		return nil, fmt.Errorf("could not declare unique constraints: %s", err)
+	}
+
 	// Wrap the repository with logic that runs interceptors
 	interceptableRepository := storage.NewInterceptableTransactionalRepository(transactionalRepository)
 
@@ -126,21 +150,49 @@ func New(ctx context.Context, cancel context.CancelFunc, e env.Environment, cfg
 		return nil, fmt.Errorf("could not create notificator: %v", err)
 	}
 
+	osbCircuitBreakers := brokerclient.NewCircuitBreakers(cfg.API.OSBCircuitBreaker)
+	brokerAuthenticator := brokerclient.NewBrokerAuthenticator(http.DefaultClient.Do, cfg.HTTPClient)
+
 	apiOptions := &api.Options{
-		Repository:        interceptableRepository,
-		APISettings:       cfg.API,
-		OperationSettings: cfg.Operations,
-		WSSettings:        cfg.WebSocket,
-		Notificator:       pgNotificator,
-		WaitGroup:         waitGroup,
+		Repository:          interceptableRepository,
+		Schemas:             smStorage,
+		APISettings:         cfg.API,
+		OperationSettings:   cfg.Operations,
+		WSSettings:          cfg.WebSocket,
+		Notificator:         pgNotificator,
+		WaitGroup:           waitGroup,
+		OSBCircuitBreakers:  osbCircuitBreakers,
+		BrokerAuthenticator: brokerAuthenticator,
 	}
 	API, err := api.New(ctx, e, apiOptions)
 	if err != nil {
 		return nil, fmt.Errorf("error creating core api: %s", err)
 	}
 
+	API.RegisterControllers(configuration.NewEncryptionKeysController(ctx, encryptionKeys, credentialsReencrypter, waitGroup))
+
+	postgresLockerCreatorFunc := func(advisoryIndex int) storage.Locker {
+		return &postgres.Locker{Storage: smStorage, AdvisoryIndex: advisoryIndex}
+	}
+	operationMaintainer := operations.NewMaintainer(ctx, interceptableRepository, postgresLockerCreatorFunc, cfg.Operations, waitGroup)
+
+	upgrader := upgrades.NewUpgrader(ctx, interceptableRepository, smStorage, operationMaintainer.Scheduler(), cfg.Operations, waitGroup)
+	API.RegisterControllers(&upgrades.Controller{
+		Repository: interceptableRepository,
+		Upgrader:   upgrader,
+	})
+
+	changeFeed := postgres.NewChangeFeed(smStorage, cfg.Storage)
+	API.RegisterControllers(&changes.Controller{
+		ChangeFeed:      changeFeed,
+		MaxWait:         cfg.Server.RequestTimeout / 2,
+		DefaultPageSize: cfg.API.DefaultPageSize,
+		MaxPageSize:     cfg.API.MaxPageSize,
+	})
+
 	securityBuilder, securityFilters := NewSecurityBuilder()
 	API.RegisterFiltersAfter(filters.LoggingFilterName, securityFilters...)
+	API.RegisterFiltersAfter(filters.LoggingFilterName, &filters.StatementTimeout{Timeout: cfg.Server.RequestTimeout})
 
 	storageHealthIndicator, err := storage.NewSQLHealthIndicator(storage.PingFunc(smStorage.PingContext))
 	if err != nil {
@@ -149,45 +201,74 @@ func New(ctx context.Context, cancel context.CancelFunc, e env.Environment, cfg
 
 	API.SetIndicator(storageHealthIndicator)
 	API.SetIndicator(healthcheck.NewPlatformIndicator(ctx, interceptableRepository, nil))
+	catalogFetcher := brokerclient.CatalogFetcher(brokerAuthenticator, cfg.API.OSBVersion, osbCircuitBreakers)
+	var brokersFailuresThreshold int64
+	if brokersIndicatorSettings, found := cfg.Health.Indicators[health.BrokersIndicatorName]; found {
+		brokersFailuresThreshold = brokersIndicatorSettings.FailuresThreshold
+	}
+	API.SetIndicator(healthcheck.NewBrokersIndicator(ctx, transactionalRepository, smStorage, postgres.BrokersHealthLocker(smStorage), catalogFetcher, osbCircuitBreakers, brokersFailuresThreshold))
 
 	notificationCleaner := &storage.NotificationCleaner{
 		Storage:  interceptableRepository,
 		Settings: *cfg.Storage,
 	}
 
-	postgresLockerCreatorFunc := func(advisoryIndex int) storage.Locker {
-		return &postgres.Locker{Storage: smStorage, AdvisoryIndex: advisoryIndex}
-	}
-
-	operationMaintainer := operations.NewMaintainer(ctx, interceptableRepository, postgresLockerCreatorFunc, cfg.Operations, waitGroup)
-	osbClientProvider := osb.NewBrokerClientProvider(cfg.HTTPClient.SkipSSLValidation, int(cfg.HTTPClient.ResponseHeaderTimeout.Seconds()))
+	operationMaintainer.
+		WithPartitionMaintenance(smStorage, map[types.ObjectType]time.Duration{
+			types.OperationType:    cfg.Operations.Lifespan,
+			types.NotificationType: cfg.Storage.Notification.KeepFor,
+		}).
+		WithChangesCleanup(changeFeed, cfg.Storage.Changes.KeepFor).
+		WithUpgradesMaintenance(upgrader, upgrades.HeartbeatInterval)
+	osbClientProvider := brokerclient.NewBrokerClientProvider(cfg.HTTPClient.SkipSSLValidation, int(cfg.HTTPClient.ResponseHeaderTimeout.Seconds()), osbCircuitBreakers, brokerAuthenticator)
 
 	smb := &ServiceManagerBuilder{
-		API:                 API,
-		Storage:             interceptableRepository,
-		Notificator:         pgNotificator,
-		NotificationCleaner: notificationCleaner,
-		OperationMaintainer: operationMaintainer,
-		ctx:                 ctx,
-		wg:                  waitGroup,
-		cfg:                 cfg,
-		securityBuilder:     securityBuilder,
-		OSBClientProvider:   osbClientProvider,
+		API:                    API,
+		Storage:                interceptableRepository,
+		Notificator:            pgNotificator,
+		NotificationCleaner:    notificationCleaner,
+		ChangeFeed:             changeFeed,
+		OperationMaintainer:    operationMaintainer,
+		EncryptionKeys:         encryptionKeys,
+		CredentialsReencrypter: credentialsReencrypter,
+		ctx:                    ctx,
+		wg:                     waitGroup,
+		cfg:                    cfg,
+		securityBuilder:        securityBuilder,
+		OSBClientProvider:      osbClientProvider,
+		schemas:                smStorage,
 	}
 
 	smb.RegisterPlugins(osb.NewCatalogFilterByVisibilityPlugin(interceptableRepository))
 	smb.RegisterPluginsBefore(osb.CheckInstanceOwnerhipPluginName, osb.NewStoreServiceInstancesPlugin(interceptableRepository))
 	smb.RegisterPluginsBefore(osb.StoreServiceInstancePluginName, osb.NewCheckVisibilityPlugin(interceptableRepository))
 	smb.RegisterPlugins(osb.NewCheckPlatformIDPlugin(interceptableRepository))
+	smb.RegisterPlugins(osb.NewTransformationPlugin(interceptableRepository))
+	smb.RegisterPlugins(osb.NewParametersSchemaPlugin(interceptableRepository))
+
+	// Label definitions are checked before any other interceptor of the resource is invoked
+	for _, objectType := range types.LabelDefinitionResourceTypes {
+		smb.
+			WithCreateAroundTxInterceptorProvider(objectType, &interceptors.LabelDefinitionsCreateInterceptorProvider{
+				IgnoredKeys: []string{cfg.Multitenancy.LabelKey},
+				Repository:  interceptableRepository,
+			}).Register().
+			WithUpdateAroundTxInterceptorProvider(objectType, &interceptors.LabelDefinitionsUpdateInterceptorProvider{
+				IgnoredKeys: []string{cfg.Multitenancy.LabelKey},
+				Repository:  interceptableRepository,
+			}).Register()
+	}
 
 	// Register default interceptors that represent the core SM business logic
 	smb.
 		WithCreateInterceptorProvider(types.ServiceBrokerType, &interceptors.BrokerCreateCatalogInterceptorProvider{
-			CatalogFetcher: osb.CatalogFetcher(http.DefaultClient.Do, cfg.API.OSBVersion),
+			CatalogFetcher:          catalogFetcher,
+			StrictCatalogValidation: cfg.API.StrictCatalogValidation,
 		}).Register().
 		WithUpdateInterceptorProvider(types.ServiceBrokerType, &interceptors.BrokerUpdateCatalogInterceptorProvider{
-			CatalogFetcher: osb.CatalogFetcher(http.DefaultClient.Do, cfg.API.OSBVersion),
-			CatalogLoader:  catalog.Load,
+			CatalogFetcher:          catalogFetcher,
+			CatalogLoader:           catalog.Load,
+			StrictCatalogValidation: cfg.API.StrictCatalogValidation,
 		}).Register().
 		WithDeleteInterceptorProvider(types.ServiceBrokerType, &interceptors.BrokerDeleteCatalogInterceptorProvider{
 			CatalogLoader: catalog.Load,
@@ -208,16 +289,17 @@ func New(ctx context.Context, cancel context.CancelFunc, e env.Environment, cfg
 	}
 
 	smb.
-		WithCreateAroundTxInterceptorProvider(types.ServiceInstanceType, &interceptors.UniqueInstanceNameCreateInterceptorProvider{
-			TenantIdentifier: cfg.Multitenancy.LabelKey,
-			Repository:       interceptableRepository,
+		WithCreateAroundTxInterceptorProvider(types.ServiceInstanceType, &interceptors.UniqueConstraintsCreateInterceptorProvider{
+			Constraints: uniqueConstraints,
+			Repository:  interceptableRepository,
 		}).Register().
-		WithUpdateAroundTxInterceptorProvider(types.ServiceInstanceType, &interceptors.UniqueInstanceNameUpdateInterceptorProvider{
-			TenantIdentifier: cfg.Multitenancy.LabelKey,
-			Repository:       interceptableRepository,
+		WithUpdateAroundTxInterceptorProvider(types.ServiceInstanceType, &interceptors.UniqueConstraintsUpdateInterceptorProvider{
+			Constraints: uniqueConstraints,
+			Repository:  interceptableRepository,
 		}).Register().
-		WithCreateAroundTxInterceptorProvider(types.ServiceBindingType, &interceptors.UniqueBindingNameCreateInterceptorProvider{
-			Repository: interceptableRepository,
+		WithCreateAroundTxInterceptorProvider(types.ServiceBindingType, &interceptors.UniqueConstraintsCreateInterceptorProvider{
+			Constraints: uniqueConstraints,
+			Repository:  interceptableRepository,
 		}).Register().
 		WithCreateAroundTxInterceptorProvider(types.ServiceInstanceType, &interceptors.ServiceInstanceCreateInterceptorProvider{
 			BaseSMAAPInterceptorProvider: baseSMAAPInterceptorProvider,
@@ -260,11 +342,14 @@ func (smb *ServiceManagerBuilder) Build() *ServiceManager {
 	}
 
 	return &ServiceManager{
-		ctx:                 smb.ctx,
-		wg:                  smb.wg,
-		Server:              srv,
-		Notificator:         smb.Notificator,
-		NotificationCleaner: smb.NotificationCleaner,
+		ctx:                    smb.ctx,
+		wg:                     smb.wg,
+		Server:                 srv,
+		Notificator:            smb.Notificator,
+		NotificationCleaner:    smb.NotificationCleaner,
+		ChangeFeed:             smb.ChangeFeed,
+		EncryptionKeys:         smb.EncryptionKeys,
+		CredentialsReencrypter: smb.CredentialsReencrypter,
 	}
 }
 
@@ -322,6 +407,11 @@ func (sm *ServiceManager) Run() {
 	if err := sm.NotificationCleaner.Start(sm.ctx, sm.wg); err != nil {
 		log.C(sm.ctx).WithError(err).Panicf("could not start Service Manager notification cleaner")
 	}
+	if err := sm.ChangeFeed.Start(sm.ctx, sm.wg); err != nil {
+		log.C(sm.ctx).WithError(err).Panicf("could not start Service Manager change feed")
+	}
+	sm.EncryptionKeys.Start(sm.ctx, sm.wg)
+	sm.CredentialsReencrypter.Start(sm.ctx, sm.wg)
 
 	sm.Server.Run(sm.ctx, sm.wg)
 
@@ -503,6 +593,12 @@ func (smb *ServiceManagerBuilder) EnableMultitenancy(labelKey string, extractTen
 
 	multitenancyFilters := filters.NewMultitenancyFilters(labelKey, extractTenantFunc)
 	smb.RegisterFiltersAfter(filters.ProtectedLabelsFilterName, multitenancyFilters...)
+	smb.ReplaceFilter(filters.CriteriaFilterName, &filters.SelectionCriteria{
+		Repository:     smb.Storage,
+		Schemas:        smb.schemas,
+		TenantLabelKey: labelKey,
+		ExtractTenant:  extractTenantFunc,
+	})
 	smb.RegisterFilters(
 		filters.NewServiceInstanceVisibilityFilter(smb.Storage, labelKey),
 		filters.NewServiceBindingVisibilityFilter(smb.Storage, labelKey),
@@ -524,3 +620,23 @@ func (smb *ServiceManagerBuilder) EnableMultitenancy(labelKey string, extractTen
 func (smb *ServiceManagerBuilder) Security() *SecurityBuilder {
 	return smb.securityBuilder.Reset()
 }
+
+// uniqueConstraintsOf returns the unique constraints of the resources enforced in the database
+func uniqueConstraintsOf(cfg *config.Settings) []storage.UniqueConstraint {
+	return []storage.UniqueConstraint{
+		{
+			Name:        "service_instance_name_per_tenant",
+			ObjectType:  types.ServiceInstanceType,
+			Fields:      []string{"name"},
+			ScopeLabel:  cfg.Multitenancy.LabelKey,
+			Conditions:  map[string]string{"platform_id": types.SMPlatform},
+			Description: "instance with same name exists for the current tenant",
+		},
+		{
+			Name:        "service_binding_name_per_instance",
+			ObjectType:  types.ServiceBindingType,
+			Fields:      []string{"service_instance_id", "name"},
+			Description: "binding with same name exists for the service instance",
+		},
+	}
+}
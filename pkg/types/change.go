@@ -0,0 +1,29 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package types
+
+import "time"
+
+// Change is a create, update or delete of a resource recorded by the storage
+type Change struct {
+	// Revision increases with every recorded change
+	Revision     int64             `json:"revision"`
+	ResourceType ObjectType        `json:"resource_type"`
+	ResourceID   string            `json:"resource_id"`
+	Operation    OperationCategory `json:"operation"`
+	CreatedAt    time.Time         `json:"created_at"`
+}
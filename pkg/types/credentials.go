@@ -18,9 +18,12 @@ package types
 
 import (
 	"crypto/rand"
+	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net/url"
 )
 
 // Basic basic credentials
@@ -29,9 +32,25 @@ type Basic struct {
 	Password string `json:"password,omitempty"`
 }
 
+// TLS client certificate credentials
+type TLS struct {
+	Certificate string `json:"client_certificate,omitempty"`
+	Key         string `json:"client_key,omitempty"`
+}
+
+// OAuth2 client credentials with which tokens are requested from the token endpoint
+type OAuth2 struct {
+	TokenURL     string   `json:"token_url,omitempty"`
+	ClientID     string   `json:"client_id,omitempty"`
+	ClientSecret string   `json:"client_secret,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+}
+
 // Credentials credentials
 type Credentials struct {
-	Basic *Basic `json:"basic,omitempty"`
+	Basic  *Basic  `json:"basic,omitempty"`
+	TLS    *TLS    `json:"tls,omitempty"`
+	OAuth2 *OAuth2 `json:"oauth2,omitempty"`
 }
 
 func (c *Credentials) MarshalJSON() ([]byte, error) {
@@ -45,14 +64,41 @@ func (c *Credentials) MarshalJSON() ([]byte, error) {
 
 // Validate implements InputValidator and verifies all mandatory fields are populated
 func (c *Credentials) Validate() error {
-	if c.Basic == nil {
+	if c.Basic == nil && c.TLS == nil && c.OAuth2 == nil {
 		return errors.New("missing broker credentials")
 	}
-	if c.Basic.Username == "" {
-		return errors.New("missing broker username")
+	if c.Basic != nil && c.OAuth2 != nil {
+		return errors.New("broker credentials cannot contain both basic and oauth2 credentials")
+	}
+	if c.Basic != nil {
+		if c.Basic.Username == "" {
+			return errors.New("missing broker username")
+		}
+		if c.Basic.Password == "" {
+			return errors.New("missing broker password")
+		}
+	}
+	if c.TLS != nil {
+		if c.TLS.Certificate == "" || c.TLS.Key == "" {
+			return errors.New("missing broker client certificate or key")
+		}
+		if _, err := tls.X509KeyPair([]byte(c.TLS.Certificate), []byte(c.TLS.Key)); err != nil {
+			return fmt.Errorf("invalid broker client certificate: %s", err)
+		}
 	}
-	if c.Basic.Password == "" {
-		return errors.New("missing broker password")
+	if c.OAuth2 != nil {
+		if c.OAuth2.TokenURL == "" {
+			return errors.New("missing broker oauth2 token url")
+		}
+		if tokenURL, err := url.Parse(c.OAuth2.TokenURL); err != nil || !tokenURL.IsAbs() {
+			return fmt.Errorf("invalid broker oauth2 token url %s", c.OAuth2.TokenURL)
+		}
+		if c.OAuth2.ClientID == "" {
+			return errors.New("missing broker oauth2 client id")
+		}
+		if c.OAuth2.ClientSecret == "" {
+			return errors.New("missing broker oauth2 client secret")
+		}
 	}
 	return nil
 }
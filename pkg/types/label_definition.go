@@ -0,0 +1,173 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package types
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// LabelCardinality defines how many values a label may have
+type LabelCardinality string
+
+const (
+	// SingleValued denotes a label which may have at most one value
+	SingleValued LabelCardinality = "single"
+	// MultiValued denotes a label which may have any number of values
+	MultiValued LabelCardinality = "multiple"
+)
+
+// LabelDefinitionResourceTypes are the object types whose labels can be constrained by label definitions
+var LabelDefinitionResourceTypes = []ObjectType{
+	ServiceBrokerType,
+	PlatformType,
+	VisibilityType,
+	ServiceInstanceType,
+	ServiceBindingType,
+}
+
+//go:generate smgen api LabelDefinition
+// LabelDefinition struct
+type LabelDefinition struct {
+	Base
+	ResourceType  ObjectType       `json:"resource_type"`
+	Key           string           `json:"key"`
+	Description   string           `json:"description,omitempty"`
+	AllowedValues []string         `json:"allowed_values,omitempty"`
+	Pattern       string           `json:"pattern,omitempty"`
+	Cardinality   LabelCardinality `json:"cardinality,omitempty"`
+	Required      bool             `json:"required"`
+}
+
+func (e *LabelDefinition) Equals(obj Object) bool {
+	if !Equals(e, obj) {
+		return false
+	}
+
+	definition := obj.(*LabelDefinition)
+	if e.ResourceType != definition.ResourceType ||
+		e.Key != definition.Key ||
+		e.Description != definition.Description ||
+		!reflect.DeepEqual(e.AllowedValues, definition.AllowedValues) ||
+		e.Pattern != definition.Pattern ||
+		e.Cardinality != definition.Cardinality ||
+		e.Required != definition.Required {
+		return false
+	}
+
+	return true
+}
+
+// Validate implements InputValidator and verifies all mandatory fields are populated
+func (e *LabelDefinition) Validate() error {
+	if util.HasRFC3986ReservedSymbols(e.ID) {
+		return fmt.Errorf("%s contains invalid character(s)", e.ID)
+	}
+	if e.ResourceType == "" {
+		return errors.New("missing label definition resource type")
+	}
+	if !isLabelDefinitionResourceType(e.ResourceType) {
+		return fmt.Errorf("label definitions are not supported for resource type %s", e.ResourceType)
+	}
+	if e.Key == "" {
+		return errors.New("missing label definition key")
+	}
+	if strings.ContainsAny(e.Key, " \n") {
+		return fmt.Errorf("label key \"%s\" cannot contain whitespaces", e.Key)
+	}
+	if e.Cardinality != "" && e.Cardinality != SingleValued && e.Cardinality != MultiValued {
+		return fmt.Errorf("unsupported label cardinality %s", e.Cardinality)
+	}
+	pattern, err := e.compilePattern()
+	if err != nil {
+		return err
+	}
+	for _, value := range e.AllowedValues {
+		if value == "" || strings.ContainsRune(value, '\n') {
+			return fmt.Errorf("invalid allowed value \"%s\"", value)
+		}
+		if pattern != nil && !pattern.MatchString(value) {
+			return fmt.Errorf("allowed value \"%s\" does not match pattern %s", value, e.Pattern)
+		}
+	}
+	if err := e.Labels.Validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ValidateValues verifies that the values of the defined label satisfy the definition.
+// Values should be nil if the label is not present.
+func (e *LabelDefinition) ValidateValues(values []string) error {
+	if len(values) == 0 {
+		if e.Required {
+			return fmt.Errorf("label %s is required", e.Key)
+		}
+		return nil
+	}
+	if e.Cardinality == SingleValued && len(values) > 1 {
+		return fmt.Errorf("label %s must have a single value", e.Key)
+	}
+	pattern, err := e.compilePattern()
+	if err != nil {
+		return err
+	}
+	for _, value := range values {
+		if len(e.AllowedValues) != 0 && !e.isAllowedValue(value) {
+			return fmt.Errorf("value \"%s\" of label %s is not allowed. Allowed values are: %s", value, e.Key, strings.Join(e.AllowedValues, ", "))
+		}
+		if pattern != nil && !pattern.MatchString(value) {
+			return fmt.Errorf("value \"%s\" of label %s does not match pattern %s", value, e.Key, e.Pattern)
+		}
+	}
+	return nil
+}
+
+func (e *LabelDefinition) compilePattern() (*regexp.Regexp, error) {
+	if e.Pattern == "" {
+		return nil, nil
+	}
+	pattern, err := regexp.Compile(e.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label value pattern %s: %s", e.Pattern, err)
+	}
+	return pattern, nil
+}
+
+func (e *LabelDefinition) isAllowedValue(value string) bool {
+	for _, allowedValue := range e.AllowedValues {
+		if allowedValue == value {
+			return true
+		}
+	}
+	return false
+}
+
+func isLabelDefinitionResourceType(objectType ObjectType) bool {
+	for _, resourceType := range LabelDefinitionResourceTypes {
+		if resourceType == objectType {
+			return true
+		}
+	}
+	return false
+}
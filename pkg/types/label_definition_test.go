@@ -0,0 +1,69 @@
+package types
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LabelDefinition", func() {
+	newDefinition := func() *LabelDefinition {
+		return &LabelDefinition{
+			ResourceType:  PlatformType,
+			Key:           "env",
+			AllowedValues: []string{"dev", "prod"},
+			Pattern:       "^[a-z]+$",
+			Cardinality:   SingleValued,
+			Required:      true,
+		}
+	}
+
+	Describe("Validate", func() {
+		It("accepts a valid definition", func() {
+			Expect(newDefinition().Validate()).To(Succeed())
+		})
+
+		DescribeTable("rejects invalid definitions",
+			func(modify func(definition *LabelDefinition), expectedMessage string) {
+				definition := newDefinition()
+				modify(definition)
+				err := definition.Validate()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(expectedMessage))
+			},
+			Entry("missing resource type", func(d *LabelDefinition) { d.ResourceType = "" }, "missing label definition resource type"),
+			Entry("unsupported resource type", func(d *LabelDefinition) { d.ResourceType = NotificationType }, "not supported for resource type"),
+			Entry("missing key", func(d *LabelDefinition) { d.Key = "" }, "missing label definition key"),
+			Entry("key with whitespaces", func(d *LabelDefinition) { d.Key = "my env" }, "cannot contain whitespaces"),
+			Entry("unsupported cardinality", func(d *LabelDefinition) { d.Cardinality = "many" }, "unsupported label cardinality many"),
+			Entry("invalid pattern", func(d *LabelDefinition) { d.Pattern = "[a-z" }, "invalid label value pattern"),
+			Entry("allowed value not matching pattern", func(d *LabelDefinition) { d.AllowedValues = []string{"Prod"} }, "does not match pattern"),
+		)
+	})
+
+	Describe("ValidateValues", func() {
+		It("accepts allowed values", func() {
+			Expect(newDefinition().ValidateValues([]string{"prod"})).To(Succeed())
+		})
+
+		It("accepts missing optional labels", func() {
+			definition := newDefinition()
+			definition.Required = false
+			Expect(definition.ValidateValues(nil)).To(Succeed())
+		})
+
+		DescribeTable("rejects invalid values",
+			func(modify func(definition *LabelDefinition), values []string, expectedMessage string) {
+				definition := newDefinition()
+				modify(definition)
+				err := definition.ValidateValues(values)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(expectedMessage))
+			},
+			Entry("missing required label", func(*LabelDefinition) {}, nil, "label env is required"),
+			Entry("multiple values of single valued label", func(*LabelDefinition) {}, []string{"dev", "prod"}, "label env must have a single value"),
+			Entry("value which is not allowed", func(*LabelDefinition) {}, []string{"production"}, "value \"production\" of label env is not allowed. Allowed values are: dev, prod"),
+			Entry("value not matching pattern", func(d *LabelDefinition) { d.AllowedValues = nil }, []string{"Prod"}, "value \"Prod\" of label env does not match pattern"),
+		)
+	})
+})
@@ -0,0 +1,65 @@
+// GENERATED. DO NOT MODIFY!
+
+package types
+
+import (
+	"encoding/json"
+
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+const LabelDefinitionType ObjectType = web.LabelDefinitionsURL
+
+type LabelDefinitions struct {
+	LabelDefinitions []*LabelDefinition `json:"label_definitions"`
+}
+
+func (e *LabelDefinitions) Add(object Object) {
+	e.LabelDefinitions = append(e.LabelDefinitions, object.(*LabelDefinition))
+}
+
+func (e *LabelDefinitions) ItemAt(index int) Object {
+	return e.LabelDefinitions[index]
+}
+
+func (e *LabelDefinitions) Len() int {
+	return len(e.LabelDefinitions)
+}
+
+func (e *LabelDefinition) GetType() ObjectType {
+	return LabelDefinitionType
+}
+
+// MarshalJSON override json serialization for http response
+func (e *LabelDefinition) MarshalJSON() ([]byte, error) {
+	type E LabelDefinition
+	toMarshal := struct {
+		*E
+		CreatedAt *string `json:"created_at,omitempty"`
+		UpdatedAt *string `json:"updated_at,omitempty"`
+		Labels    Labels  `json:"labels,omitempty"`
+	}{
+		E:      (*E)(e),
+		Labels: e.Labels,
+	}
+	if !e.CreatedAt.IsZero() {
+		str := util.ToRFCNanoFormat(e.CreatedAt)
+		toMarshal.CreatedAt = &str
+	}
+	if !e.UpdatedAt.IsZero() {
+		str := util.ToRFCNanoFormat(e.UpdatedAt)
+		toMarshal.UpdatedAt = &str
+	}
+	hasNoLabels := true
+	for key, values := range e.Labels {
+		if key != "" && len(values) != 0 {
+			hasNoLabels = false
+			break
+		}
+	}
+	if hasNoLabels {
+		toMarshal.Labels = nil
+	}
+	return json.Marshal(toMarshal)
+}
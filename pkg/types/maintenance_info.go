@@ -0,0 +1,140 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// semanticVersionPattern matches versions which follow Semantic Versioning 2.0.0
+var semanticVersionPattern = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+[0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*)?$`)
+
+// MaintenanceInfo is the maintenance information of a service plan as defined by OSB API 2.15
+type MaintenanceInfo struct {
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// ParseMaintenanceInfo parses the maintenance information of a service plan and verifies that its version is a semantic version.
+// It returns nil if the plan has no maintenance information.
+func ParseMaintenanceInfo(raw json.RawMessage) (*MaintenanceInfo, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	maintenanceInfo := &MaintenanceInfo{}
+	if err := json.Unmarshal(raw, maintenanceInfo); err != nil {
+		return nil, fmt.Errorf("maintenance_info is invalid: %s", err)
+	}
+	if maintenanceInfo.Version == "" {
+		return nil, errors.New("maintenance_info version missing")
+	}
+	if !semanticVersionPattern.MatchString(maintenanceInfo.Version) {
+		return nil, fmt.Errorf("maintenance_info version %s is not a semantic version", maintenanceInfo.Version)
+	}
+	return maintenanceInfo, nil
+}
+
+// CompareVersions compares two semantic versions by precedence and returns -1, 0 or 1 if the first version is lower,
+// equal or higher than the second one. Invalid versions are lower than valid ones.
+func CompareVersions(first, second string) int {
+	firstMatch := semanticVersionPattern.FindStringSubmatch(first)
+	secondMatch := semanticVersionPattern.FindStringSubmatch(second)
+	switch {
+	case firstMatch == nil && secondMatch == nil:
+		return 0
+	case firstMatch == nil:
+		return -1
+	case secondMatch == nil:
+		return 1
+	}
+
+	for i := 1; i <= 3; i++ {
+		if result := compareNumbers(firstMatch[i], secondMatch[i]); result != 0 {
+			return result
+		}
+	}
+	return comparePreReleases(firstMatch[4], secondMatch[4])
+}
+
+// comparePreReleases compares the pre-release parts of two versions. Versions without pre-release are higher.
+func comparePreReleases(first, second string) int {
+	switch {
+	case first == second:
+		return 0
+	case first == "":
+		return 1
+	case second == "":
+		return -1
+	}
+	firstIdentifiers := splitIdentifiers(first)
+	secondIdentifiers := splitIdentifiers(second)
+	for i := 0; i < len(firstIdentifiers) && i < len(secondIdentifiers); i++ {
+		_, firstErr := strconv.ParseUint(firstIdentifiers[i], 10, 64)
+		_, secondErr := strconv.ParseUint(secondIdentifiers[i], 10, 64)
+		var result int
+		switch {
+		case firstErr == nil && secondErr == nil:
+			result = compareNumbers(firstIdentifiers[i], secondIdentifiers[i])
+		case firstErr == nil:
+			// numeric identifiers have lower precedence than alphanumeric ones
+			result = -1
+		case secondErr == nil:
+			result = 1
+		case firstIdentifiers[i] < secondIdentifiers[i]:
+			result = -1
+		case firstIdentifiers[i] > secondIdentifiers[i]:
+			result = 1
+		}
+		if result != 0 {
+			return result
+		}
+	}
+	return compareNumbers(strconv.Itoa(len(firstIdentifiers)), strconv.Itoa(len(secondIdentifiers)))
+}
+
+// compareNumbers compares two decimal numbers without leading zeros of arbitrary length
+func compareNumbers(first, second string) int {
+	switch {
+	case len(first) < len(second):
+		return -1
+	case len(first) > len(second):
+		return 1
+	case first < second:
+		return -1
+	case first > second:
+		return 1
+	}
+	return 0
+}
+
+func splitIdentifiers(preRelease string) []string {
+	var identifiers []string
+	start := 0
+	for i := 0; i < len(preRelease); i++ {
+		if preRelease[i] == '.' {
+			identifiers = append(identifiers, preRelease[start:i])
+			start = i + 1
+		}
+	}
+	return append(identifiers, preRelease[start:])
+}
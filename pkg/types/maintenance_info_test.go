@@ -0,0 +1,53 @@
+package types
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MaintenanceInfo", func() {
+	Describe("ParseMaintenanceInfo", func() {
+		It("returns nil if there is no maintenance info", func() {
+			Expect(ParseMaintenanceInfo(nil)).To(BeNil())
+			Expect(ParseMaintenanceInfo(json.RawMessage(`null`))).To(BeNil())
+		})
+
+		It("parses maintenance info with a semantic version", func() {
+			maintenanceInfo, err := ParseMaintenanceInfo(json.RawMessage(`{"version": "1.2.3-beta.1+build", "description": "OS update"}`))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(maintenanceInfo).To(Equal(&MaintenanceInfo{Version: "1.2.3-beta.1+build", Description: "OS update"}))
+		})
+
+		DescribeTable("rejects invalid maintenance info",
+			func(raw, expectedMessage string) {
+				_, err := ParseMaintenanceInfo(json.RawMessage(raw))
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(expectedMessage))
+			},
+			Entry("not an object", `"1.0.0"`, "maintenance_info is invalid"),
+			Entry("missing version", `{"description": "OS update"}`, "maintenance_info version missing"),
+			Entry("version which is not semantic", `{"version": "1.0"}`, "is not a semantic version"),
+			Entry("version with leading zeros", `{"version": "1.01.0"}`, "is not a semantic version"),
+		)
+	})
+
+	DescribeTable("CompareVersions",
+		func(first, second string, expected int) {
+			Expect(CompareVersions(first, second)).To(Equal(expected))
+			Expect(CompareVersions(second, first)).To(Equal(-expected))
+		},
+		Entry("equal versions", "1.2.3", "1.2.3", 0),
+		Entry("versions which differ in build metadata", "1.2.3+a", "1.2.3+b", 0),
+		Entry("major versions", "2.0.0", "1.9.9", 1),
+		Entry("numeric minor versions", "1.10.0", "1.9.0", 1),
+		Entry("patch versions", "1.0.1", "1.0.0", 1),
+		Entry("release and pre-release", "1.0.0", "1.0.0-rc.1", 1),
+		Entry("numeric pre-release identifiers", "1.0.0-rc.11", "1.0.0-rc.2", 1),
+		Entry("alphanumeric and numeric pre-release identifiers", "1.0.0-alpha.beta", "1.0.0-alpha.1", 1),
+		Entry("pre-releases with more identifiers", "1.0.0-alpha.1", "1.0.0-alpha", 1),
+		Entry("valid and invalid versions", "1.0.0", "latest", 1),
+	)
+})
@@ -0,0 +1,165 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/xeipuuv/gojsonschema"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+const (
+	// InstanceCreateParametersSchema is the path of the schema of the provision parameters in the schemas of a plan
+	InstanceCreateParametersSchema = "service_instance.create.parameters"
+	// InstanceUpdateParametersSchema is the path of the schema of the update parameters in the schemas of a plan
+	InstanceUpdateParametersSchema = "service_instance.update.parameters"
+	// BindingCreateParametersSchema is the path of the schema of the bind parameters in the schemas of a plan
+	BindingCreateParametersSchema = "service_binding.create.parameters"
+
+	// Draft4SchemaURL is the JSON Schema draft required by the OSB API for plan schemas
+	Draft4SchemaURL = "http://json-schema.org/draft-04/schema#"
+)
+
+// ParametersSchemaPaths are the paths of all parameter schemas which a plan can define
+var ParametersSchemaPaths = []string{InstanceCreateParametersSchema, InstanceUpdateParametersSchema, BindingCreateParametersSchema}
+
+// ParametersSchema returns the parameters schema of the plan at the path or nil if the plan does not define it
+func (e *ServicePlan) ParametersSchema(path string) json.RawMessage {
+	schema := gjson.GetBytes(e.Schemas, path)
+	if !schema.Exists() || schema.Type == gjson.Null {
+		return nil
+	}
+	return json.RawMessage(schema.Raw)
+}
+
+// CompileParametersSchema verifies that the parameters schema is a valid JSON schema and compiles it.
+// Schemas with references to other documents are rejected, so that compiling a schema never fetches remote documents.
+func CompileParametersSchema(schema json.RawMessage) (*gojsonschema.Schema, error) {
+	var document interface{}
+	if err := json.Unmarshal(schema, &document); err != nil {
+		return nil, fmt.Errorf("schema is invalid JSON: %s", err)
+	}
+	if _, ok := document.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("schema is not a JSON object")
+	}
+	if err := verifyLocalReferences(document); err != nil {
+		return nil, err
+	}
+
+	loader := gojsonschema.NewSchemaLoader()
+	loader.Validate = true
+	compiled, err := loader.Compile(gojsonschema.NewGoLoader(document))
+	if err != nil {
+		return nil, fmt.Errorf("schema is not a valid JSON schema: %s", err)
+	}
+	return compiled, nil
+}
+
+// ParameterViolation is a violation of the parameters schema of a plan
+type ParameterViolation struct {
+	// Pointer is the JSON pointer of the violating value in the parameters
+	Pointer string `json:"pointer"`
+	Message string `json:"message"`
+}
+
+// ValidateParameters validates the parameters against the parameters schema of the plan at the path and returns the violations.
+// Parameters are not validated if they are missing or the plan does not define the schema.
+func (e *ServicePlan) ValidateParameters(path string, parameters json.RawMessage) ([]*ParameterViolation, error) {
+	schema := e.ParametersSchema(path)
+	if schema == nil || len(parameters) == 0 || string(parameters) == "null" {
+		return nil, nil
+	}
+	compiled, err := CompileParametersSchema(schema)
+	if err != nil {
+		return nil, fmt.Errorf("could not compile %s schema of plan %s: %s", path, e.ID, err)
+	}
+	result, err := compiled.Validate(gojsonschema.NewBytesLoader(parameters))
+	if err != nil {
+		return nil, fmt.Errorf("could not validate parameters against %s schema of plan %s: %s", path, e.ID, err)
+	}
+
+	var violations []*ParameterViolation
+	for _, resultError := range result.Errors() {
+		violations = append(violations, &ParameterViolation{
+			Pointer: jsonPointer(resultError.Context()),
+			Message: resultError.Description(),
+		})
+	}
+	return violations, nil
+}
+
+// NewParametersError returns the error of a request whose parameters violate the parameters schema of the plan
+func NewParametersError(violations []*ParameterViolation) error {
+	messages := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		pointer := violation.Pointer
+		if pointer == "" {
+			pointer = "/"
+		}
+		messages = append(messages, fmt.Sprintf("%s: %s", pointer, violation.Message))
+	}
+	return &util.HTTPError{
+		ErrorType:   "BadRequest",
+		Description: fmt.Sprintf("parameters do not match the schema of the plan: %s", strings.Join(messages, "; ")),
+		Details:     violations,
+		StatusCode:  http.StatusBadRequest,
+	}
+}
+
+// jsonPointer converts the context of a schema violation, e.g. (root).servers.0, to a JSON pointer, e.g. /servers/0
+func jsonPointer(context *gojsonschema.JsonContext) string {
+	if context == nil {
+		return ""
+	}
+	// keys are separated by NUL since they may contain dots
+	tokens := strings.Split(context.String("\x00"), "\x00")
+	pointer := ""
+	for _, token := range tokens[1:] {
+		token = strings.Replace(token, "~", "~0", -1)
+		token = strings.Replace(token, "/", "~1", -1)
+		pointer += "/" + token
+	}
+	return pointer
+}
+
+// verifyLocalReferences returns an error if the schema references documents other than itself
+func verifyLocalReferences(document interface{}) error {
+	switch value := document.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			if reference, ok := child.(string); ok && key == "$ref" && !strings.HasPrefix(reference, "#") {
+				return fmt.Errorf("schema references external document %s", reference)
+			}
+			if err := verifyLocalReferences(child); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, child := range value {
+			if err := verifyLocalReferences(child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package types
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Peripli/service-manager/pkg/util"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Plan schemas", func() {
+	Describe("ParametersSchema", func() {
+		plan := &ServicePlan{
+			Schemas: json.RawMessage(`{"service_instance": {"create": {"parameters": {"type": "object"}}, "update": {"parameters": null}}}`),
+		}
+
+		It("returns the schema at the path", func() {
+			Expect(plan.ParametersSchema(InstanceCreateParametersSchema)).To(MatchJSON(`{"type": "object"}`))
+		})
+
+		It("returns nil if the plan does not define the schema", func() {
+			Expect(plan.ParametersSchema(InstanceUpdateParametersSchema)).To(BeNil())
+			Expect(plan.ParametersSchema(BindingCreateParametersSchema)).To(BeNil())
+		})
+	})
+
+	Describe("CompileParametersSchema", func() {
+		It("compiles a valid schema", func() {
+			schema, err := CompileParametersSchema(json.RawMessage(`{
+				"$schema": "http://json-schema.org/draft-04/schema#",
+				"type": "object",
+				"properties": {"region": {"$ref": "#/definitions/region"}},
+				"definitions": {"region": {"type": "string"}}
+			}`))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(schema).ToNot(BeNil())
+		})
+
+		DescribeTable("rejects invalid schemas",
+			func(schema, expectedMessage string) {
+				_, err := CompileParametersSchema(json.RawMessage(schema))
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(expectedMessage))
+			},
+			Entry("invalid JSON", `{`, "schema is invalid JSON"),
+			Entry("not an object", `[]`, "schema is not a JSON object"),
+			Entry("invalid against the metaschema", `{"type": "unknown"}`, "schema is not a valid JSON schema"),
+			Entry("remote reference", `{"properties": {"region": {"$ref": "http://example.com/region.json"}}}`, "schema references external document http://example.com/region.json"),
+		)
+	})
+
+	Describe("ValidateParameters", func() {
+		plan := &ServicePlan{
+			Base: Base{ID: "plan-id"},
+			Schemas: json.RawMessage(`{"service_instance": {"create": {"parameters": {
+				"type": "object",
+				"required": ["region"],
+				"properties": {
+					"region": {"type": "string", "enum": ["eu", "us"]},
+					"servers": {"type": "array", "items": {"type": "object", "properties": {"a/b": {"type": "integer"}}}}
+				}
+			}}}}`),
+		}
+
+		It("accepts parameters which match the schema", func() {
+			violations, err := plan.ValidateParameters(InstanceCreateParametersSchema, json.RawMessage(`{"region": "eu"}`))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(violations).To(BeEmpty())
+		})
+
+		It("does not validate missing parameters or parameters of schemas which the plan does not define", func() {
+			Expect(plan.ValidateParameters(InstanceCreateParametersSchema, nil)).To(BeEmpty())
+			Expect(plan.ValidateParameters(BindingCreateParametersSchema, json.RawMessage(`{"any": true}`))).To(BeEmpty())
+		})
+
+		It("returns the violations with JSON pointers", func() {
+			violations, err := plan.ValidateParameters(InstanceCreateParametersSchema, json.RawMessage(`{"servers": [{"a/b": "one"}]}`))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(violations).To(HaveLen(2))
+			pointers := []string{violations[0].Pointer, violations[1].Pointer}
+			Expect(pointers).To(ConsistOf("", "/servers/0/a~1b"))
+		})
+
+		It("returns an error if the schema is invalid", func() {
+			invalidPlan := &ServicePlan{Schemas: json.RawMessage(`{"service_instance": {"create": {"parameters": {"type": "unknown"}}}}`)}
+			_, err := invalidPlan.ValidateParameters(InstanceCreateParametersSchema, json.RawMessage(`{}`))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("NewParametersError", func() {
+		It("returns a bad request error with the violations", func() {
+			violations := []*ParameterViolation{{Pointer: "", Message: "region is required"}, {Pointer: "/size", Message: "Invalid type"}}
+			err := NewParametersError(violations).(*util.HTTPError)
+			Expect(err.StatusCode).To(Equal(http.StatusBadRequest))
+			Expect(err.Description).To(ContainSubstring("/: region is required; /size: Invalid type"))
+			Expect(err.Details).To(Equal(violations))
+		})
+	})
+})
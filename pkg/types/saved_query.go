@@ -0,0 +1,96 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package types
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// SavedQueryResourceTypes are the object types which can be listed using a saved query
+var SavedQueryResourceTypes = []ObjectType{
+	ServiceBrokerType,
+	PlatformType,
+	VisibilityType,
+	ServiceOfferingType,
+	ServicePlanType,
+	ServiceInstanceType,
+	ServiceBindingType,
+}
+
+//go:generate smgen api SavedQuery
+// SavedQuery struct
+type SavedQuery struct {
+	Base
+	Name         string     `json:"name"`
+	ResourceType ObjectType `json:"resource_type"`
+	FieldQuery   string     `json:"field_query,omitempty"`
+	LabelQuery   string     `json:"label_query,omitempty"`
+	Owner        string     `json:"owner,omitempty"`
+}
+
+func (e *SavedQuery) Equals(obj Object) bool {
+	if !Equals(e, obj) {
+		return false
+	}
+
+	savedQuery := obj.(*SavedQuery)
+	if e.Name != savedQuery.Name ||
+		e.ResourceType != savedQuery.ResourceType ||
+		e.FieldQuery != savedQuery.FieldQuery ||
+		e.LabelQuery != savedQuery.LabelQuery ||
+		e.Owner != savedQuery.Owner {
+		return false
+	}
+
+	return true
+}
+
+// Validate implements InputValidator and verifies all mandatory fields are populated
+func (e *SavedQuery) Validate() error {
+	if util.HasRFC3986ReservedSymbols(e.ID) {
+		return fmt.Errorf("%s contains invalid character(s)", e.ID)
+	}
+	if e.Name == "" {
+		return errors.New("missing saved query name")
+	}
+	if util.HasRFC3986ReservedSymbols(e.Name) {
+		return fmt.Errorf("saved query name %s contains invalid character(s)", e.Name)
+	}
+	if e.ResourceType == "" {
+		return errors.New("missing saved query resource type")
+	}
+	if !isSavedQueryResourceType(e.ResourceType) {
+		return fmt.Errorf("saved queries are not supported for resource type %s", e.ResourceType)
+	}
+	if err := e.Labels.Validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func isSavedQueryResourceType(objectType ObjectType) bool {
+	for _, resourceType := range SavedQueryResourceTypes {
+		if resourceType == objectType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package types
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// SecretTag marks a field of an object as secret, e.g. `secret:"true"`. Secret fields of type json.RawMessage
+// are encrypted in the storage. Secret fields of any type are redacted in responses to clients which may not read them.
+const SecretTag = "secret"
+
+// encryptedFieldKey is the only key of the JSON object in which encrypted secret fields are stored
+const encryptedFieldKey = "sm_encrypted"
+
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// EncryptSecretFields encrypts the secret json.RawMessage fields of the object. The ciphertext is stored base64 encoded
+// in a JSON object with the single key sm_encrypted, so that the field still holds valid JSON.
+func EncryptSecretFields(ctx context.Context, obj Object, encryptionFunc func(context.Context, []byte) ([]byte, error)) error {
+	return transformSecretRawMessages(obj, func(name string, value json.RawMessage) (json.RawMessage, error) {
+		ciphertext, err := encryptionFunc(ctx, value)
+		if err != nil {
+			return nil, fmt.Errorf("could not encrypt field %s: %s", name, err)
+		}
+		return json.Marshal(map[string]string{
+			encryptedFieldKey: base64.StdEncoding.EncodeToString(ciphertext),
+		})
+	})
+}
+
+// DecryptSecretFields decrypts the secret json.RawMessage fields of the object. Fields which are not
+// encrypted, such as the ones stored before they were marked as secret, are left as they are.
+func DecryptSecretFields(ctx context.Context, obj Object, decryptionFunc func(context.Context, []byte) ([]byte, error)) error {
+	return transformSecretRawMessages(obj, func(name string, value json.RawMessage) (json.RawMessage, error) {
+		encoded, encrypted := encryptedValue(value)
+		if !encrypted {
+			return value, nil
+		}
+		ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode encrypted field %s: %s", name, err)
+		}
+		plaintext, err := decryptionFunc(ctx, ciphertext)
+		if err != nil {
+			return nil, fmt.Errorf("could not decrypt field %s: %s", name, err)
+		}
+		return plaintext, nil
+	})
+}
+
+// HasUnencryptedSecretFields returns true if any of the secret json.RawMessage fields of the object is not encrypted
+func HasUnencryptedSecretFields(obj Object) bool {
+	unencrypted := false
+	// the transformation leaves the fields as they are
+	_ = transformSecretRawMessages(obj, func(name string, value json.RawMessage) (json.RawMessage, error) {
+		_, encrypted := encryptedValue(value)
+		unencrypted = unencrypted || !encrypted
+		return value, nil
+	})
+	return unencrypted
+}
+
+// RedactSecretFields clears the secret fields of the object and returns true if the object has any secret fields
+func RedactSecretFields(obj Object) bool {
+	value, ok := structValue(obj)
+	if !ok {
+		return false
+	}
+
+	redacted := false
+	for i := 0; i < value.NumField(); i++ {
+		if isSecret(value.Type().Field(i)) {
+			field := value.Field(i)
+			field.Set(reflect.Zero(field.Type()))
+			redacted = true
+		}
+	}
+	return redacted
+}
+
+func transformSecretRawMessages(obj Object, transformationFunc func(name string, value json.RawMessage) (json.RawMessage, error)) error {
+	value, ok := structValue(obj)
+	if !ok {
+		return nil
+	}
+
+	for i := 0; i < value.NumField(); i++ {
+		structField := value.Type().Field(i)
+		if !isSecret(structField) || structField.Type != rawMessageType {
+			continue
+		}
+		field := value.Field(i)
+		if field.Len() == 0 {
+			continue
+		}
+		transformed, err := transformationFunc(structField.Name, field.Interface().(json.RawMessage))
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(transformed))
+	}
+	return nil
+}
+
+func structValue(obj Object) (reflect.Value, bool) {
+	value := reflect.ValueOf(obj)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return reflect.Value{}, false
+	}
+	value = value.Elem()
+	return value, value.Kind() == reflect.Struct
+}
+
+// encryptedValue returns the base64 encoded ciphertext of the value if it is an encrypted field,
+// i.e. a JSON object with the single key sm_encrypted holding a string
+func encryptedValue(value json.RawMessage) (string, bool) {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(value, &envelope); err != nil || len(envelope) != 1 {
+		return "", false
+	}
+	var encoded string
+	if err := json.Unmarshal(envelope[encryptedFieldKey], &encoded); err != nil {
+		return "", false
+	}
+	return encoded, true
+}
+
+func isSecret(field reflect.StructField) bool {
+	return field.Tag.Get(SecretTag) == "true"
+}
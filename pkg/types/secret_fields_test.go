@@ -0,0 +1,100 @@
+package types
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Secret fields", func() {
+	const instanceContext = `{"platform":"service-manager","password":"secret"}`
+
+	var instance *ServiceInstance
+
+	encrypt := func(_ context.Context, plaintext []byte) ([]byte, error) {
+		return append([]byte("encrypted:"), plaintext...), nil
+	}
+
+	decrypt := func(_ context.Context, ciphertext []byte) ([]byte, error) {
+		if !strings.HasPrefix(string(ciphertext), "encrypted:") {
+			return nil, errors.New("not encrypted")
+		}
+		return []byte(strings.TrimPrefix(string(ciphertext), "encrypted:")), nil
+	}
+
+	BeforeEach(func() {
+		instance = &ServiceInstance{
+			Name:       "instance",
+			Context:    json.RawMessage(instanceContext),
+			Parameters: map[string]interface{}{"password": "secret"},
+		}
+	})
+
+	Describe("EncryptSecretFields", func() {
+		It("stores the encrypted value in a JSON object", func() {
+			Expect(EncryptSecretFields(context.TODO(), instance, encrypt)).To(Succeed())
+
+			var envelope map[string]string
+			Expect(json.Unmarshal(instance.Context, &envelope)).To(Succeed())
+			Expect(envelope).To(HaveKey("sm_encrypted"))
+			Expect(string(instance.Context)).ToNot(ContainSubstring("secret"))
+			Expect(HasUnencryptedSecretFields(instance)).To(BeFalse())
+		})
+
+		It("encrypts the previous values which contain the previous context", func() {
+			instance.PreviousValues = json.RawMessage(`{"sm_context_key":` + instanceContext + `}`)
+			Expect(EncryptSecretFields(context.TODO(), instance, encrypt)).To(Succeed())
+			Expect(string(instance.PreviousValues)).ToNot(ContainSubstring("secret"))
+
+			Expect(DecryptSecretFields(context.TODO(), instance, decrypt)).To(Succeed())
+			Expect(string(instance.PreviousValues)).To(ContainSubstring(instanceContext))
+		})
+
+		It("does not encrypt fields which are not secret", func() {
+			Expect(EncryptSecretFields(context.TODO(), instance, encrypt)).To(Succeed())
+			Expect(instance.Name).To(Equal("instance"))
+		})
+	})
+
+	Describe("DecryptSecretFields", func() {
+		It("restores the encrypted value", func() {
+			Expect(EncryptSecretFields(context.TODO(), instance, encrypt)).To(Succeed())
+			Expect(DecryptSecretFields(context.TODO(), instance, decrypt)).To(Succeed())
+			Expect(string(instance.Context)).To(Equal(instanceContext))
+		})
+
+		It("leaves values stored before they were marked as secret", func() {
+			Expect(HasUnencryptedSecretFields(instance)).To(BeTrue())
+			Expect(DecryptSecretFields(context.TODO(), instance, decrypt)).To(Succeed())
+			Expect(string(instance.Context)).To(Equal(instanceContext))
+		})
+
+		It("leaves JSON strings which are not encrypted", func() {
+			instance.Context = json.RawMessage(`"aW52YWxpZA=="`)
+			Expect(HasUnencryptedSecretFields(instance)).To(BeTrue())
+			Expect(DecryptSecretFields(context.TODO(), instance, decrypt)).To(Succeed())
+			Expect(string(instance.Context)).To(Equal(`"aW52YWxpZA=="`))
+		})
+
+		It("returns an error if decryption fails", func() {
+			instance.Context = json.RawMessage(`{"sm_encrypted":"aW52YWxpZA=="}`)
+			Expect(DecryptSecretFields(context.TODO(), instance, decrypt)).ToNot(Succeed())
+		})
+	})
+
+	Describe("RedactSecretFields", func() {
+		It("clears the secret fields", func() {
+			Expect(RedactSecretFields(instance)).To(BeTrue())
+			Expect(instance.Context).To(BeNil())
+			Expect(instance.Name).To(Equal("instance"))
+		})
+
+		It("returns false for objects without secret fields", func() {
+			Expect(RedactSecretFields(&Platform{Name: "platform"})).To(BeFalse())
+		})
+	})
+})
@@ -39,12 +39,41 @@ type ServiceBroker struct {
 	BrokerURL   string       `json:"broker_url"`
 	Credentials *Credentials `json:"credentials,omitempty"`
 
+	// HealthStatus is the status of the broker determined by the brokers health indicator, if any
+	HealthStatus string `json:"health_status,omitempty"`
+	// HealthError is the error of the probes which determined that the broker is down
+	HealthError string `json:"health_error,omitempty"`
+	// CatalogWarnings are the findings of the validation of the catalog of the broker which did not prevent its registration
+	CatalogWarnings []*CatalogIssue `json:"catalog_warnings,omitempty"`
+
 	Catalog  json.RawMessage    `json:"-"`
 	Services []*ServiceOffering `json:"-"`
 
 	LastOperation *Operation `json:"last_operation,omitempty"`
 }
 
+// CatalogIssueSeverity denotes whether a catalog issue prevents the registration of a broker
+type CatalogIssueSeverity string
+
+const (
+	// CatalogError prevents the registration of the broker
+	CatalogError CatalogIssueSeverity = "error"
+	// CatalogWarning is stored with the broker unless catalog validation is strict
+	CatalogWarning CatalogIssueSeverity = "warning"
+)
+
+// CatalogIssue is a finding of the validation of a broker catalog
+type CatalogIssue struct {
+	Severity CatalogIssueSeverity `json:"severity"`
+	// Path is the path of the catalog element, e.g. services[0].plans[1].maintenance_info
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (i *CatalogIssue) String() string {
+	return fmt.Sprintf("%s: %s", i.Path, i.Message)
+}
+
 func (e *ServiceBroker) Sanitize() {
 	e.Credentials = nil
 }
@@ -58,14 +87,26 @@ func (e *ServiceBroker) Decrypt(ctx context.Context, decryptionFunc func(context
 }
 
 func (e *ServiceBroker) transform(ctx context.Context, transformationFunc func(context.Context, []byte) ([]byte, error)) error {
-	if e.Credentials == nil || e.Credentials.Basic == nil {
+	if e.Credentials == nil {
 		return nil
 	}
-	transformedPassword, err := transformationFunc(ctx, []byte(e.Credentials.Basic.Password))
-	if err != nil {
-		return err
+	var secrets []*string
+	if e.Credentials.Basic != nil {
+		secrets = append(secrets, &e.Credentials.Basic.Password)
+	}
+	if e.Credentials.TLS != nil {
+		secrets = append(secrets, &e.Credentials.TLS.Key)
+	}
+	if e.Credentials.OAuth2 != nil {
+		secrets = append(secrets, &e.Credentials.OAuth2.ClientSecret)
+	}
+	for _, secret := range secrets {
+		transformedSecret, err := transformationFunc(ctx, []byte(*secret))
+		if err != nil {
+			return err
+		}
+		*secret = string(transformedSecret)
 	}
-	e.Credentials.Basic.Password = string(transformedPassword)
 	return nil
 }
 
@@ -35,8 +35,8 @@ type ServiceInstance struct {
 	PlatformID      string                 `json:"platform_id"`
 	DashboardURL    string                 `json:"dashboard_url,omitempty"`
 	MaintenanceInfo json.RawMessage        `json:"maintenance_info,omitempty"`
-	Context         json.RawMessage        `json:"context,omitempty"`
-	PreviousValues  json.RawMessage        `json:"-"`
+	Context         json.RawMessage        `json:"context,omitempty" secret:"true"`
+	PreviousValues  json.RawMessage        `json:"-" secret:"true"`
 	Parameters      map[string]interface{} `json:"parameters,omitempty"`
 	Usable          bool                   `json:"usable"`
 
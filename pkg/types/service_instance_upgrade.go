@@ -0,0 +1,90 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// UpgradedInstance is the upgrade of a single service instance within a bulk upgrade. Its state is empty until the
+// upgrade of the instance is started.
+type UpgradedInstance struct {
+	InstanceID      string          `json:"instance_id"`
+	Version         string          `json:"version"`
+	MaintenanceInfo json.RawMessage `json:"maintenance_info"`
+	OperationID     string          `json:"operation_id,omitempty"`
+	State           OperationState  `json:"state,omitempty"`
+	Error           string          `json:"error,omitempty"`
+}
+
+//go:generate smgen api ServiceInstanceUpgrade
+// ServiceInstanceUpgrade is a bulk upgrade of outdated service instances to the maintenance info of their plans
+type ServiceInstanceUpgrade struct {
+	Base
+	ServicePlanID string `json:"service_plan_id,omitempty"`
+	BrokerID      string `json:"broker_id,omitempty"`
+	// Concurrency is the number of instances which are upgraded at the same time
+	Concurrency int `json:"concurrency"`
+	// CanaryPercentage is the percentage of the instances which are upgraded first. The other instances are upgraded
+	// only if all canaries are upgraded successfully.
+	CanaryPercentage int                 `json:"canary_percentage"`
+	Paused           bool                `json:"paused"`
+	Instances        []*UpgradedInstance `json:"instances"`
+	// LastOperation reports the state and the progress of the upgrade of all instances
+	LastOperation *Operation `json:"last_operation"`
+}
+
+func (e *ServiceInstanceUpgrade) Equals(obj Object) bool {
+	if !Equals(e, obj) {
+		return false
+	}
+
+	upgrade := obj.(*ServiceInstanceUpgrade)
+	if e.ServicePlanID != upgrade.ServicePlanID ||
+		e.BrokerID != upgrade.BrokerID ||
+		e.Concurrency != upgrade.Concurrency ||
+		e.CanaryPercentage != upgrade.CanaryPercentage ||
+		e.Paused != upgrade.Paused ||
+		!reflect.DeepEqual(e.Instances, upgrade.Instances) ||
+		!reflect.DeepEqual(e.LastOperation, upgrade.LastOperation) {
+		return false
+	}
+
+	return true
+}
+
+// Validate implements InputValidator and verifies all mandatory fields are populated
+func (e *ServiceInstanceUpgrade) Validate() error {
+	if util.HasRFC3986ReservedSymbols(e.ID) {
+		return fmt.Errorf("%s contains invalid character(s)", e.ID)
+	}
+	if e.Concurrency < 1 {
+		return fmt.Errorf("concurrency %d must be positive", e.Concurrency)
+	}
+	if e.CanaryPercentage < 0 || e.CanaryPercentage > 100 {
+		return fmt.Errorf("canary_percentage %d must be between 0 and 100", e.CanaryPercentage)
+	}
+	if e.LastOperation == nil {
+		return fmt.Errorf("missing last operation of upgrade %s", e.ID)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+// GENERATED. DO NOT MODIFY!
+
+package types
+
+import (
+	"encoding/json"
+
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+const ServiceInstanceUpgradeType ObjectType = web.ServiceInstanceUpgradesURL
+
+type ServiceInstanceUpgrades struct {
+	ServiceInstanceUpgrades []*ServiceInstanceUpgrade `json:"service_instance_upgrades"`
+}
+
+func (e *ServiceInstanceUpgrades) Add(object Object) {
+	e.ServiceInstanceUpgrades = append(e.ServiceInstanceUpgrades, object.(*ServiceInstanceUpgrade))
+}
+
+func (e *ServiceInstanceUpgrades) ItemAt(index int) Object {
+	return e.ServiceInstanceUpgrades[index]
+}
+
+func (e *ServiceInstanceUpgrades) Len() int {
+	return len(e.ServiceInstanceUpgrades)
+}
+
+func (e *ServiceInstanceUpgrade) GetType() ObjectType {
+	return ServiceInstanceUpgradeType
+}
+
+// MarshalJSON override json serialization for http response
+func (e *ServiceInstanceUpgrade) MarshalJSON() ([]byte, error) {
+	type E ServiceInstanceUpgrade
+	toMarshal := struct {
+		*E
+		CreatedAt *string `json:"created_at,omitempty"`
+		UpdatedAt *string `json:"updated_at,omitempty"`
+		Labels    Labels  `json:"labels,omitempty"`
+	}{
+		E:      (*E)(e),
+		Labels: e.Labels,
+	}
+	if !e.CreatedAt.IsZero() {
+		str := util.ToRFCNanoFormat(e.CreatedAt)
+		toMarshal.CreatedAt = &str
+	}
+	if !e.UpdatedAt.IsZero() {
+		str := util.ToRFCNanoFormat(e.UpdatedAt)
+		toMarshal.UpdatedAt = &str
+	}
+	hasNoLabels := true
+	for key, values := range e.Labels {
+		if key != "" && len(values) != 0 {
+			hasNoLabels = false
+			break
+		}
+	}
+	if hasNoLabels {
+		toMarshal.Labels = nil
+	}
+	return json.Marshal(toMarshal)
+}
@@ -0,0 +1,240 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+// TransformationOperation is an OSB operation whose requests and responses can be transformed
+type TransformationOperation string
+
+const (
+	// TransformProvision denotes provision requests and responses
+	TransformProvision TransformationOperation = "provision"
+	// TransformUpdate denotes update service instance requests and responses
+	TransformUpdate TransformationOperation = "update"
+	// TransformBind denotes bind requests and responses
+	TransformBind TransformationOperation = "bind"
+)
+
+// TransformationPhase denotes whether the request to the broker or the response of the broker is transformed
+type TransformationPhase string
+
+const (
+	// RequestPhase transforms the request before it is forwarded to the broker
+	RequestPhase TransformationPhase = "request"
+	// ResponsePhase transforms the response of the broker before it is returned to the platform
+	ResponsePhase TransformationPhase = "response"
+)
+
+// TransformationActionType is the type of a transformation action
+type TransformationActionType string
+
+const (
+	// SetAction sets the value of the path
+	SetAction TransformationActionType = "set"
+	// DefaultAction sets the value of the path if the path does not exist
+	DefaultAction TransformationActionType = "default"
+	// DeleteAction deletes the path
+	DeleteAction TransformationActionType = "delete"
+	// RenameAction moves the value of the path to another path
+	RenameAction TransformationActionType = "rename"
+)
+
+var transformationOperations = []TransformationOperation{TransformProvision, TransformUpdate, TransformBind}
+
+//go:generate smgen api TransformationRule
+// TransformationRule struct
+type TransformationRule struct {
+	Base
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+
+	// BrokerID, ServiceOfferingID, ServicePlanID and PlatformType select the requests to which the rule applies.
+	// Empty selectors match all requests.
+	BrokerID          string `json:"broker_id,omitempty"`
+	ServiceOfferingID string `json:"service_offering_id,omitempty"`
+	ServicePlanID     string `json:"service_plan_id,omitempty"`
+	PlatformType      string `json:"platform_type,omitempty"`
+
+	Operations []TransformationOperation `json:"operations"`
+	Phase      TransformationPhase       `json:"phase,omitempty"`
+	// Priority orders the rules which apply to a request in ascending order
+	Priority int                     `json:"priority"`
+	Actions  []*TransformationAction `json:"actions"`
+}
+
+// TransformationAction is a change of the JSON body of an OSB request or response. The paths use the gjson syntax, e.g. parameters.region
+type TransformationAction struct {
+	Type  TransformationActionType `json:"type"`
+	Path  string                   `json:"path"`
+	To    string                   `json:"to,omitempty"`
+	Value json.RawMessage          `json:"value,omitempty"`
+}
+
+func (e *TransformationRule) Equals(obj Object) bool {
+	if !Equals(e, obj) {
+		return false
+	}
+
+	rule := obj.(*TransformationRule)
+	if e.Name != rule.Name ||
+		e.Description != rule.Description ||
+		e.BrokerID != rule.BrokerID ||
+		e.ServiceOfferingID != rule.ServiceOfferingID ||
+		e.ServicePlanID != rule.ServicePlanID ||
+		e.PlatformType != rule.PlatformType ||
+		!reflect.DeepEqual(e.Operations, rule.Operations) ||
+		e.Phase != rule.Phase ||
+		e.Priority != rule.Priority ||
+		!reflect.DeepEqual(e.Actions, rule.Actions) {
+		return false
+	}
+
+	return true
+}
+
+// Validate implements InputValidator and verifies all mandatory fields are populated
+func (e *TransformationRule) Validate() error {
+	if util.HasRFC3986ReservedSymbols(e.ID) {
+		return fmt.Errorf("%s contains invalid character(s)", e.ID)
+	}
+	if e.Name == "" {
+		return errors.New("missing transformation rule name")
+	}
+	if len(e.Operations) == 0 {
+		return errors.New("missing transformation rule operations")
+	}
+	for _, operation := range e.Operations {
+		if !isTransformationOperation(operation) {
+			return fmt.Errorf("unsupported transformation operation %s", operation)
+		}
+	}
+	if e.Phase != "" && e.Phase != RequestPhase && e.Phase != ResponsePhase {
+		return fmt.Errorf("unsupported transformation phase %s", e.Phase)
+	}
+	if len(e.Actions) == 0 {
+		return errors.New("missing transformation rule actions")
+	}
+	for _, action := range e.Actions {
+		if err := action.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := e.Labels.Validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AppliesTo returns whether the rule applies to the operation in the phase
+func (e *TransformationRule) AppliesTo(operation TransformationOperation, phase TransformationPhase) bool {
+	rulePhase := e.Phase
+	if rulePhase == "" {
+		rulePhase = RequestPhase
+	}
+	if rulePhase != phase {
+		return false
+	}
+	for _, ruleOperation := range e.Operations {
+		if ruleOperation == operation {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply applies the actions of the rule to the JSON body in their order
+func (e *TransformationRule) Apply(body []byte) ([]byte, error) {
+	var err error
+	for _, action := range e.Actions {
+		if body, err = action.Apply(body); err != nil {
+			return nil, fmt.Errorf("could not apply transformation rule %s: %s", e.Name, err)
+		}
+	}
+	return body, nil
+}
+
+// Validate verifies that the action has the fields required by its type
+func (a *TransformationAction) Validate() error {
+	if a == nil {
+		return errors.New("missing transformation action")
+	}
+	if a.Path == "" {
+		return errors.New("missing transformation action path")
+	}
+	switch a.Type {
+	case SetAction, DefaultAction:
+		if len(a.Value) == 0 || !json.Valid(a.Value) {
+			return fmt.Errorf("%s action of path %s requires a valid JSON value", a.Type, a.Path)
+		}
+	case RenameAction:
+		if a.To == "" {
+			return fmt.Errorf("rename action of path %s requires a target path", a.Path)
+		}
+	case DeleteAction:
+	default:
+		return fmt.Errorf("unsupported transformation action type %s", a.Type)
+	}
+	return nil
+}
+
+// Apply applies the action to the JSON body
+func (a *TransformationAction) Apply(body []byte) ([]byte, error) {
+	switch a.Type {
+	case SetAction:
+		return sjson.SetRawBytes(body, a.Path, a.Value)
+	case DefaultAction:
+		if gjson.GetBytes(body, a.Path).Exists() {
+			return body, nil
+		}
+		return sjson.SetRawBytes(body, a.Path, a.Value)
+	case DeleteAction:
+		return sjson.DeleteBytes(body, a.Path)
+	case RenameAction:
+		value := gjson.GetBytes(body, a.Path)
+		if !value.Exists() {
+			return body, nil
+		}
+		body, err := sjson.SetRawBytes(body, a.To, []byte(value.Raw))
+		if err != nil {
+			return nil, err
+		}
+		return sjson.DeleteBytes(body, a.Path)
+	default:
+		return nil, fmt.Errorf("unsupported transformation action type %s", a.Type)
+	}
+}
+
+func isTransformationOperation(operation TransformationOperation) bool {
+	for _, transformationOperation := range transformationOperations {
+		if transformationOperation == operation {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package types
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TransformationRule", func() {
+	newRule := func() *TransformationRule {
+		return &TransformationRule{
+			Name:       "default-region",
+			Operations: []TransformationOperation{TransformProvision, TransformUpdate},
+			Actions: []*TransformationAction{
+				{Type: DefaultAction, Path: "parameters.region", Value: json.RawMessage(`"eu"`)},
+			},
+		}
+	}
+
+	Describe("Validate", func() {
+		It("accepts a valid rule", func() {
+			Expect(newRule().Validate()).To(Succeed())
+		})
+
+		DescribeTable("rejects invalid rules",
+			func(modify func(rule *TransformationRule), expectedMessage string) {
+				rule := newRule()
+				modify(rule)
+				err := rule.Validate()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(expectedMessage))
+			},
+			Entry("missing name", func(r *TransformationRule) { r.Name = "" }, "missing transformation rule name"),
+			Entry("missing operations", func(r *TransformationRule) { r.Operations = nil }, "missing transformation rule operations"),
+			Entry("unsupported operation", func(r *TransformationRule) { r.Operations = []TransformationOperation{"unbind"} }, "unsupported transformation operation unbind"),
+			Entry("unsupported phase", func(r *TransformationRule) { r.Phase = "before" }, "unsupported transformation phase before"),
+			Entry("missing actions", func(r *TransformationRule) { r.Actions = nil }, "missing transformation rule actions"),
+			Entry("missing action path", func(r *TransformationRule) { r.Actions[0].Path = "" }, "missing transformation action path"),
+			Entry("invalid action value", func(r *TransformationRule) { r.Actions[0].Value = json.RawMessage(`{`) }, "requires a valid JSON value"),
+			Entry("rename without target", func(r *TransformationRule) { r.Actions[0].Type = RenameAction }, "requires a target path"),
+			Entry("unsupported action type", func(r *TransformationRule) { r.Actions[0].Type = "copy" }, "unsupported transformation action type copy"),
+		)
+	})
+
+	Describe("AppliesTo", func() {
+		It("applies to requests of its operations by default", func() {
+			rule := newRule()
+			Expect(rule.AppliesTo(TransformProvision, RequestPhase)).To(BeTrue())
+			Expect(rule.AppliesTo(TransformBind, RequestPhase)).To(BeFalse())
+			Expect(rule.AppliesTo(TransformProvision, ResponsePhase)).To(BeFalse())
+		})
+
+		It("applies to responses if its phase is response", func() {
+			rule := newRule()
+			rule.Phase = ResponsePhase
+			Expect(rule.AppliesTo(TransformUpdate, ResponsePhase)).To(BeTrue())
+			Expect(rule.AppliesTo(TransformUpdate, RequestPhase)).To(BeFalse())
+		})
+	})
+
+	Describe("Apply", func() {
+		DescribeTable("applies the action",
+			func(action *TransformationAction, body, expectedBody string) {
+				rule := newRule()
+				rule.Actions = []*TransformationAction{action}
+				result, err := rule.Apply([]byte(body))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(MatchJSON(expectedBody))
+			},
+			Entry("set overrides the value",
+				&TransformationAction{Type: SetAction, Path: "parameters.size", Value: json.RawMessage(`{"disk": 10}`)},
+				`{"parameters": {"size": "small"}}`, `{"parameters": {"size": {"disk": 10}}}`),
+			Entry("default adds a missing value",
+				&TransformationAction{Type: DefaultAction, Path: "parameters.region", Value: json.RawMessage(`"eu"`)},
+				`{"service_id": "s"}`, `{"service_id": "s", "parameters": {"region": "eu"}}`),
+			Entry("default keeps an existing value",
+				&TransformationAction{Type: DefaultAction, Path: "parameters.region", Value: json.RawMessage(`"eu"`)},
+				`{"parameters": {"region": "us"}}`, `{"parameters": {"region": "us"}}`),
+			Entry("delete removes the value",
+				&TransformationAction{Type: DeleteAction, Path: "parameters.debug"},
+				`{"parameters": {"debug": true, "region": "us"}}`, `{"parameters": {"region": "us"}}`),
+			Entry("rename moves the value",
+				&TransformationAction{Type: RenameAction, Path: "parameters.zone", To: "parameters.region"},
+				`{"parameters": {"zone": "us"}}`, `{"parameters": {"region": "us"}}`),
+			Entry("rename ignores a missing value",
+				&TransformationAction{Type: RenameAction, Path: "parameters.zone", To: "parameters.region"},
+				`{"parameters": {}}`, `{"parameters": {}}`),
+		)
+
+		It("applies the actions in their order", func() {
+			rule := newRule()
+			rule.Actions = []*TransformationAction{
+				{Type: RenameAction, Path: "parameters.zone", To: "parameters.region"},
+				{Type: DefaultAction, Path: "parameters.region", Value: json.RawMessage(`"eu"`)},
+			}
+			result, err := rule.Apply([]byte(`{"parameters": {"zone": "us"}}`))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(MatchJSON(`{"parameters": {"region": "us"}}`))
+		})
+	})
+})
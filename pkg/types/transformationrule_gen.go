@@ -0,0 +1,65 @@
+// GENERATED. DO NOT MODIFY!
+
+package types
+
+import (
+	"encoding/json"
+
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/pkg/web"
+)
+
+const TransformationRuleType ObjectType = web.TransformationRulesURL
+
+type TransformationRules struct {
+	TransformationRules []*TransformationRule `json:"transformation_rules"`
+}
+
+func (e *TransformationRules) Add(object Object) {
+	e.TransformationRules = append(e.TransformationRules, object.(*TransformationRule))
+}
+
+func (e *TransformationRules) ItemAt(index int) Object {
+	return e.TransformationRules[index]
+}
+
+func (e *TransformationRules) Len() int {
+	return len(e.TransformationRules)
+}
+
+func (e *TransformationRule) GetType() ObjectType {
+	return TransformationRuleType
+}
+
+// MarshalJSON override json serialization for http response
+func (e *TransformationRule) MarshalJSON() ([]byte, error) {
+	type E TransformationRule
+	toMarshal := struct {
+		*E
+		CreatedAt *string `json:"created_at,omitempty"`
+		UpdatedAt *string `json:"updated_at,omitempty"`
+		Labels    Labels  `json:"labels,omitempty"`
+	}{
+		E:      (*E)(e),
+		Labels: e.Labels,
+	}
+	if !e.CreatedAt.IsZero() {
+		str := util.ToRFCNanoFormat(e.CreatedAt)
+		toMarshal.CreatedAt = &str
+	}
+	if !e.UpdatedAt.IsZero() {
+		str := util.ToRFCNanoFormat(e.UpdatedAt)
+		toMarshal.UpdatedAt = &str
+	}
+	hasNoLabels := true
+	for key, values := range e.Labels {
+		if key != "" && len(values) != 0 {
+			hasNoLabels = false
+			break
+		}
+	}
+	if hasNoLabels {
+		toMarshal.Labels = nil
+	}
+	return json.Marshal(toMarshal)
+}
@@ -29,7 +29,11 @@ import (
 type HTTPError struct {
 	ErrorType   string `json:"error,omitempty"`
 	Description string `json:"description,omitempty"`
-	StatusCode  int    `json:"-"`
+	// Details are the structured details of the error, e.g. the violations of a JSON schema
+	Details    interface{} `json:"details,omitempty"`
+	StatusCode int         `json:"-"`
+	// Headers are the headers of the response which reports the error, e.g. Retry-After
+	Headers map[string]string `json:"-"`
 }
 
 // Error HTTPError should implement error
@@ -50,6 +54,9 @@ func (uq *UnsupportedQueryError) Error() string {
 func WriteError(ctx context.Context, err error, writer http.ResponseWriter) {
 	logger := log.C(ctx)
 	respError := ToHTTPError(ctx, err)
+	for header, value := range respError.Headers {
+		writer.Header().Set(header, value)
+	}
 	sendErr := WriteJSON(writer, respError.StatusCode, respError)
 	if sendErr != nil {
 		logger.Errorf("Could not write error to response: %v", sendErr)
@@ -133,6 +140,48 @@ func (e *ErrForeignKeyViolation) Error() string {
 	return fmt.Sprintf("Could not delete entity %s due to existing reference entity %s", e.Entity, e.ReferenceEntity)
 }
 
+// ErrUniqueConstraintViolation represents a violation of a unique constraint of the storage that should be translated to a user-friendly http.StatusConflict
+type ErrUniqueConstraintViolation struct {
+	Constraint  string
+	Description string
+}
+
+func (e *ErrUniqueConstraintViolation) Error() string {
+	return fmt.Sprintf("unique constraint %s violated: %s", e.Constraint, e.Description)
+}
+
+// Reasons for which a notification consumer has to resynchronize instead of resuming from its last known revision
+const (
+	// ResyncReasonRevisionNotFound the revision of the consumer is not stored anymore, e.g. it was cleaned up
+	ResyncReasonRevisionNotFound = "revision_not_found"
+	// ResyncReasonRevisionAhead the revision of the consumer is greater than the last stored revision
+	ResyncReasonRevisionAhead = "revision_ahead"
+	// ResyncReasonTooManyMissed the consumer missed more notifications than its queue can hold
+	ResyncReasonTooManyMissed = "too_many_missed_notifications"
+	// ResyncReasonFailover the database failed over and the notifications after the last replicated revision were lost
+	ResyncReasonFailover = "database_failover"
+)
+
+// ErrNotificationResyncRequired represents an invalid notification revision of a consumer which has to resynchronize.
+// It must return http status GONE together with the reason.
+type ErrNotificationResyncRequired struct {
+	Reason string
+}
+
+func (e *ErrNotificationResyncRequired) Error() string {
+	return fmt.Sprintf("%s: %s", ErrInvalidNotificationRevision, e.Reason)
+}
+
+// ErrChangesDeleted represents a request for changes which were deleted from the storage. It must return http
+// status GONE, as the consumer has to resynchronize and request the changes since the revision.
+type ErrChangesDeleted struct {
+	Revision int64
+}
+
+func (e *ErrChangesDeleted) Error() string {
+	return fmt.Sprintf("changes up to revision %d were deleted", e.Revision)
+}
+
 // HandleStorageError handles storage errors by converting them to relevant HTTPErrors
 func HandleStorageError(err error, entityName string) error {
 	if err == nil {
@@ -175,12 +224,24 @@ func HandleStorageError(err error, entityName string) error {
 				Description: fmt.Sprintf("Error deleting entity %s: %s", entityName, err.Error()),
 				StatusCode:  http.StatusConflict,
 			}
+		case *ErrUniqueConstraintViolation:
+			return &HTTPError{
+				ErrorType:   "Conflict",
+				Description: e.Description,
+				StatusCode:  http.StatusConflict,
+			}
 		case *ErrBadRequestStorage:
 			return &HTTPError{
 				ErrorType:   "BadRequest",
 				Description: fmt.Sprintf("storage err: %s", e.Error()),
 				StatusCode:  http.StatusBadRequest,
 			}
+		case *ErrChangesDeleted:
+			return &HTTPError{
+				ErrorType:   "ResyncRequired",
+				Description: fmt.Sprintf("%s, resynchronize the resources and request the changes since revision %d", e.Error(), e.Revision),
+				StatusCode:  http.StatusGone,
+			}
 		default:
 			return err
 		}
@@ -67,6 +67,14 @@ var _ = Describe("Errors", func() {
 				Expect(responseRecorder.Code).To(Equal(http.StatusTeapot))
 				Expect(responseRecorder.Body.String()).To(ContainSubstring("test description"))
 			})
+
+			It("writes the headers of the error", func() {
+				testHTTPError.Headers = map[string]string{"Retry-After": "60"}
+				util.WriteError(ctx, testHTTPError, responseRecorder)
+
+				Expect(responseRecorder.Header().Get("Retry-After")).To(Equal("60"))
+				Expect(responseRecorder.Body.String()).ToNot(ContainSubstring("Retry-After"))
+			})
 		})
 		Context("With error as parameter", func() {
 			It("Writes to response writer the proper output", func() {
@@ -39,6 +39,12 @@ const (
 
 	// QueryParamLastOp is the value used to denote the query key used to convey a client's intent to retrieve also the last operation associated with the requested resource
 	QueryParamLastOp = "last_op"
+
+	// QueryParamView is the value used to denote the query key used to convey a client's intent to apply the criteria of a saved query when listing resources
+	QueryParamView = "view"
+
+	// QueryParamSearch is the value used to denote the query key used to convey a client's intent to full-text search the listed resources
+	QueryParamSearch = "q"
 )
 
 // API is the primary point for REST API registration
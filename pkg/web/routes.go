@@ -42,9 +42,36 @@ const (
 	// LoggingConfigURL is the Logging Configuration API URL path
 	LoggingConfigURL = ConfigURL + "/logging"
 
+	// EncryptionKeysURL is the Encryption Keys API URL path
+	EncryptionKeysURL = ConfigURL + "/encryption_keys"
+
 	// OperationsURL is the URL path fetch operations
 	OperationsURL = "/operations"
 
+	// SchemaURL is the URL path suffix to fetch the field schema of a resource
+	SchemaURL = "/schema"
+
 	// ProfileURL is the Configuration API base URL path
 	ProfileURL = "/" + apiVersion + "/profile"
+
+	// SavedQueriesURL is the URL path to manage saved queries
+	SavedQueriesURL = "/" + apiVersion + "/saved_queries"
+
+	// LabelDefinitionsURL is the URL path to manage label definitions
+	LabelDefinitionsURL = "/" + apiVersion + "/label_definitions"
+
+	// TransformationRulesURL is the URL path to manage the transformation rules of OSB requests and responses
+	TransformationRulesURL = "/" + apiVersion + "/transformation_rules"
+
+	// ChangesURL is the URL path to fetch the changes of all resources
+	ChangesURL = "/" + apiVersion + "/changes"
+
+	// OSBRecordingsURL is the URL path to inspect and replay the recorded OSB exchanges with brokers
+	OSBRecordingsURL = "/" + apiVersion + "/osb_recordings"
+
+	// OutdatedInstancesURL is the URL path to list the service instances whose maintenance info differs from their plan
+	OutdatedInstancesURL = "/" + apiVersion + "/outdated_service_instances"
+
+	// ServiceInstanceUpgradesURL is the URL path to manage the bulk upgrades of service instances
+	ServiceInstanceUpgradesURL = "/" + apiVersion + "/service_instance_upgrades"
 )
@@ -0,0 +1,158 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+// Package backup exports the Service Manager data to encrypted archives and imports them
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/pkg/types"
+)
+
+// FormatVersion is the version of the archive format written by the exporter
+const FormatVersion = 1
+
+// resourceTypes are the exported types in an order in which the referenced resources precede the referencing ones
+var resourceTypes = []types.ObjectType{
+	types.ServiceBrokerType,
+	types.ServiceOfferingType,
+	types.ServicePlanType,
+	types.PlatformType,
+	types.VisibilityType,
+	types.ServiceInstanceType,
+	types.ServiceBindingType,
+}
+
+var objectProviders = map[types.ObjectType]func() types.Object{
+	types.ServiceBrokerType:   func() types.Object { return &types.ServiceBroker{} },
+	types.ServiceOfferingType: func() types.Object { return &types.ServiceOffering{} },
+	types.ServicePlanType:     func() types.Object { return &types.ServicePlan{} },
+	types.PlatformType:        func() types.Object { return &types.Platform{} },
+	types.VisibilityType:      func() types.Object { return &types.Visibility{} },
+	types.ServiceInstanceType: func() types.Object { return &types.ServiceInstance{} },
+	types.ServiceBindingType:  func() types.Object { return &types.ServiceBinding{} },
+}
+
+// Archive contains the exported resources together with their labels
+type Archive struct {
+	FormatVersion int         `json:"format_version"`
+	CreatedAt     time.Time   `json:"created_at"`
+	Resources     []*Resource `json:"resources"`
+}
+
+// Resource is an exported resource
+type Resource struct {
+	Type   types.ObjectType `json:"type"`
+	Object json.RawMessage  `json:"object"`
+	// Catalog is the catalog of an exported broker which is not part of its JSON representation
+	Catalog json.RawMessage `json:"catalog,omitempty"`
+}
+
+// envelope is the unencrypted part of an archive file
+type envelope struct {
+	FormatVersion int `json:"format_version"`
+	// Data is the gzipped JSON of the archive encrypted with the backup encryption key
+	Data []byte `json:"data"`
+}
+
+// newResource creates the archived form of the object
+func newResource(obj types.Object) (*Resource, error) {
+	object, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	resource := &Resource{
+		Type:   obj.GetType(),
+		Object: object,
+	}
+	if broker, ok := obj.(*types.ServiceBroker); ok {
+		resource.Catalog = broker.Catalog
+	}
+	return resource, nil
+}
+
+// ToObject restores the archived object
+func (r *Resource) ToObject() (types.Object, error) {
+	provider, found := objectProviders[r.Type]
+	if !found {
+		return nil, fmt.Errorf("unsupported resource type %s", r.Type)
+	}
+	obj := provider()
+	if err := json.Unmarshal(r.Object, obj); err != nil {
+		return nil, fmt.Errorf("could not read resource of type %s: %s", r.Type, err)
+	}
+	if broker, ok := obj.(*types.ServiceBroker); ok {
+		broker.Catalog = r.Catalog
+	}
+	return obj, nil
+}
+
+// WriteArchive writes the archive encrypted with the key to the writer
+func WriteArchive(ctx context.Context, writer io.Writer, archive *Archive, encrypter security.Encrypter, key []byte) error {
+	buffer := &bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(buffer)
+	if err := json.NewEncoder(gzipWriter).Encode(archive); err != nil {
+		return err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return err
+	}
+	data, err := encrypter.Encrypt(ctx, buffer.Bytes(), key)
+	if err != nil {
+		return fmt.Errorf("could not encrypt archive: %s", err)
+	}
+	return json.NewEncoder(writer).Encode(&envelope{
+		FormatVersion: archive.FormatVersion,
+		Data:          data,
+	})
+}
+
+// ReadArchive reads an archive encrypted with the key from the reader
+func ReadArchive(ctx context.Context, reader io.Reader, encrypter security.Encrypter, key []byte) (*Archive, error) {
+	env := &envelope{}
+	if err := json.NewDecoder(reader).Decode(env); err != nil {
+		return nil, fmt.Errorf("could not read archive: %s", err)
+	}
+	if env.FormatVersion != FormatVersion {
+		return nil, fmt.Errorf("unsupported archive format version %d, expected %d", env.FormatVersion, FormatVersion)
+	}
+	data, err := encrypter.Decrypt(ctx, env.Data, key)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt archive. Verify the backup encryption key: %s", err)
+	}
+	gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	content, err := ioutil.ReadAll(gzipReader)
+	if err != nil {
+		return nil, err
+	}
+	archive := &Archive{}
+	if err := json.Unmarshal(content, archive); err != nil {
+		return nil, fmt.Errorf("could not read archive: %s", err)
+	}
+	return archive, nil
+}
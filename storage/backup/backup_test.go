@@ -0,0 +1,230 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package backup_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/backup"
+	"github.com/Peripli/service-manager/storage/storagefakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Backup", func() {
+	const key = "ejHjRNHbS0NaqARSRvnweVV9zcmhQEa8"
+
+	var ctx context.Context
+	var fakeStorage *storagefakes.FakeStorage
+	var existing map[types.ObjectType][]types.Object
+	var created []types.Object
+
+	newObjects := func() []types.Object {
+		return []types.Object{
+			&types.ServiceBroker{Base: types.Base{ID: "broker-id"}, Name: "broker", BrokerURL: "http://broker.com",
+				Credentials: &types.Credentials{Basic: &types.Basic{Username: "admin", Password: "secret"}},
+				Catalog:     json.RawMessage(`{"services":[]}`)},
+			&types.ServiceOffering{Base: types.Base{ID: "offering-id"}, Name: "offering", CatalogID: "offering-catalog-id", BrokerID: "broker-id"},
+			&types.ServicePlan{Base: types.Base{ID: "plan-id"}, Name: "plan", CatalogID: "plan-catalog-id", ServiceOfferingID: "offering-id"},
+			&types.Platform{Base: types.Base{ID: "platform-id"}, Name: "platform", Type: "kubernetes"},
+			&types.Visibility{Base: types.Base{ID: "visibility-id"}, PlatformID: "platform-id", ServicePlanID: "plan-id"},
+			&types.ServiceInstance{Base: types.Base{ID: "instance-id"}, Name: "instance", ServicePlanID: "plan-id", PlatformID: types.SMPlatform},
+			&types.ServiceBinding{Base: types.Base{ID: "binding-id"}, Name: "binding", ServiceInstanceID: "instance-id",
+				Credentials: json.RawMessage(`{"password":"secret"}`)},
+		}
+	}
+
+	listOf := func(objectType types.ObjectType) types.ObjectList {
+		var list types.ObjectList
+		switch objectType {
+		case types.ServiceBrokerType:
+			list = &types.ServiceBrokers{}
+		case types.ServiceOfferingType:
+			list = &types.ServiceOfferings{}
+		case types.ServicePlanType:
+			list = &types.ServicePlans{}
+		case types.PlatformType:
+			list = &types.Platforms{}
+		case types.VisibilityType:
+			list = &types.Visibilities{}
+		case types.ServiceInstanceType:
+			list = &types.ServiceInstances{}
+		case types.ServiceBindingType:
+			list = &types.ServiceBindings{}
+		}
+		for _, obj := range existing[objectType] {
+			list.Add(obj)
+		}
+		return list
+	}
+
+	exportArchive := func() *backup.Archive {
+		for _, obj := range newObjects() {
+			existing[obj.GetType()] = append(existing[obj.GetType()], obj)
+		}
+		archive, err := (&backup.Exporter{Repository: fakeStorage}).Export(ctx)
+		Expect(err).ToNot(HaveOccurred())
+		existing = make(map[types.ObjectType][]types.Object)
+		return archive
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		existing = make(map[types.ObjectType][]types.Object)
+		created = nil
+
+		fakeStorage = &storagefakes.FakeStorage{}
+		fakeStorage.InTransactionStub = func(ctx context.Context, f func(ctx context.Context, storage storage.Repository) error) error {
+			return f(ctx, fakeStorage)
+		}
+		fakeStorage.ListStub = func(_ context.Context, objectType types.ObjectType, _ ...query.Criterion) (types.ObjectList, error) {
+			return listOf(objectType), nil
+		}
+		fakeStorage.CountStub = func(_ context.Context, objectType types.ObjectType, criteria ...query.Criterion) (int, error) {
+			count := 0
+			for _, obj := range existing[objectType] {
+				if obj.GetID() == criteria[0].RightOp[0] {
+					count++
+				}
+			}
+			return count, nil
+		}
+		fakeStorage.CreateStub = func(_ context.Context, obj types.Object) (types.Object, error) {
+			created = append(created, obj)
+			return obj, nil
+		}
+	})
+
+	Describe("Export", func() {
+		It("exports the resources in the order of their references", func() {
+			archive := exportArchive()
+			Expect(archive.FormatVersion).To(Equal(backup.FormatVersion))
+			Expect(archive.Resources).To(HaveLen(7))
+			var exportedTypes []types.ObjectType
+			for _, resource := range archive.Resources {
+				exportedTypes = append(exportedTypes, resource.Type)
+			}
+			Expect(exportedTypes).To(Equal([]types.ObjectType{types.ServiceBrokerType, types.ServiceOfferingType, types.ServicePlanType,
+				types.PlatformType, types.VisibilityType, types.ServiceInstanceType, types.ServiceBindingType}))
+		})
+
+		It("reads the resources in a repeatable read only transaction", func() {
+			exportArchive()
+			Expect(fakeStorage.InTransactionCallCount()).To(Equal(1))
+			txCtx, _ := fakeStorage.InTransactionArgsForCall(0)
+			Expect(storage.TxOptionsFromContext(txCtx)).To(Equal(&sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}))
+		})
+
+		It("exports the catalogs and the secrets of the resources", func() {
+			archive := exportArchive()
+			broker, err := archive.Resources[0].ToObject()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(broker.(*types.ServiceBroker).Catalog)).To(Equal(`{"services":[]}`))
+			Expect(broker.(*types.ServiceBroker).Credentials.Basic.Password).To(Equal("secret"))
+		})
+
+		It("does not export the Service Manager platform", func() {
+			existing[types.PlatformType] = []types.Object{&types.Platform{Base: types.Base{ID: types.SMPlatform}, Name: types.SMPlatform}}
+			archive, err := (&backup.Exporter{Repository: fakeStorage}).Export(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(archive.Resources).To(BeEmpty())
+		})
+	})
+
+	Describe("Archive", func() {
+		It("is read with the key it is written with", func() {
+			archive := exportArchive()
+			buffer := &bytes.Buffer{}
+			Expect(backup.WriteArchive(ctx, buffer, archive, &security.AESEncrypter{}, []byte(key))).To(Succeed())
+			Expect(buffer.String()).ToNot(ContainSubstring("secret"))
+
+			result, err := backup.ReadArchive(ctx, bytes.NewReader(buffer.Bytes()), &security.AESEncrypter{}, []byte(key))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Resources).To(HaveLen(len(archive.Resources)))
+		})
+
+		It("is not read with another key", func() {
+			buffer := &bytes.Buffer{}
+			Expect(backup.WriteArchive(ctx, buffer, exportArchive(), &security.AESEncrypter{}, []byte(key))).To(Succeed())
+			_, err := backup.ReadArchive(ctx, buffer, &security.AESEncrypter{}, []byte("ejHjRNHbS0NaqARSRvnweVV9zcmhQEa9"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Import", func() {
+		It("creates the resources in a single transaction", func() {
+			Expect((&backup.Importer{Repository: fakeStorage}).Import(ctx, exportArchive())).To(Succeed())
+			Expect(fakeStorage.InTransactionCallCount()).To(Equal(1))
+			Expect(created).To(HaveLen(7))
+			Expect(created[6].GetID()).To(Equal("binding-id"))
+			Expect(created[6].(*types.ServiceBinding).Credentials).To(MatchJSON(`{"password":"secret"}`))
+		})
+
+		It("fails if a resource already exists", func() {
+			archive := exportArchive()
+			existing[types.PlatformType] = []types.Object{&types.Platform{Base: types.Base{ID: "platform-id"}}}
+			err := (&backup.Importer{Repository: fakeStorage}).Import(ctx, archive)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("already exists"))
+			Expect(created).To(BeEmpty())
+		})
+
+		It("fails if a reference is neither imported nor existing", func() {
+			archive := exportArchive()
+			archive.Resources = archive.Resources[len(archive.Resources)-1:]
+			err := (&backup.Importer{Repository: fakeStorage}).Import(ctx, archive)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("instance-id"))
+			Expect(created).To(BeEmpty())
+		})
+
+		It("accepts references to existing resources", func() {
+			archive := exportArchive()
+			archive.Resources = archive.Resources[len(archive.Resources)-1:]
+			existing[types.ServiceInstanceType] = []types.Object{&types.ServiceInstance{Base: types.Base{ID: "instance-id"}}}
+			Expect((&backup.Importer{Repository: fakeStorage}).Import(ctx, archive)).To(Succeed())
+			Expect(created).To(HaveLen(1))
+		})
+
+		It("assigns new ids and updates the references when remapping ids", func() {
+			archive := exportArchive()
+			for _, obj := range newObjects() {
+				existing[obj.GetType()] = append(existing[obj.GetType()], obj)
+			}
+			Expect((&backup.Importer{Repository: fakeStorage, RemapIDs: true}).Import(ctx, archive)).To(Succeed())
+			Expect(created).To(HaveLen(7))
+			ids := make(map[types.ObjectType]string)
+			for _, obj := range created {
+				Expect(obj.GetID()).ToNot(HaveSuffix("-id"))
+				ids[obj.GetType()] = obj.GetID()
+			}
+			Expect(created[1].(*types.ServiceOffering).BrokerID).To(Equal(ids[types.ServiceBrokerType]))
+			Expect(created[4].(*types.Visibility).PlatformID).To(Equal(ids[types.PlatformType]))
+			Expect(created[5].(*types.ServiceInstance).ServicePlanID).To(Equal(ids[types.ServicePlanType]))
+			Expect(created[5].(*types.ServiceInstance).PlatformID).To(Equal(types.SMPlatform))
+			Expect(created[6].(*types.ServiceBinding).ServiceInstanceID).To(Equal(ids[types.ServiceInstanceType]))
+		})
+	})
+})
@@ -0,0 +1,77 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// Exporter reads the resources of the Service Manager into an archive
+type Exporter struct {
+	// Repository must decrypt the secrets of the resources, so that they can be encrypted with the target key on import
+	Repository storage.TransactionalRepository
+}
+
+// Export reads all resources in a single read only transaction, so that the archive is consistent
+func (e *Exporter) Export(ctx context.Context) (*Archive, error) {
+	archive := &Archive{
+		FormatVersion: FormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		Resources:     make([]*Resource, 0),
+	}
+	// all resources are read from the same snapshot, as the default isolation level takes a snapshot per statement
+	txCtx := storage.ContextWithTxOptions(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	err := e.Repository.InTransaction(txCtx, func(ctx context.Context, repository storage.Repository) error {
+		for _, objectType := range resourceTypes {
+			objects, err := repository.List(ctx, objectType)
+			if err != nil {
+				return fmt.Errorf("could not list resources of type %s: %s", objectType, err)
+			}
+			count := 0
+			for i := 0; i < objects.Len(); i++ {
+				obj := objects.ItemAt(i)
+				if isBuiltIn(obj) {
+					continue
+				}
+				resource, err := newResource(obj)
+				if err != nil {
+					return fmt.Errorf("could not export %s with id %s: %s", objectType, obj.GetID(), err)
+				}
+				archive.Resources = append(archive.Resources, resource)
+				count++
+			}
+			log.C(ctx).Infof("Exported %d resources of type %s", count, objectType)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// isBuiltIn returns whether the object is created by the Service Manager itself and therefore exists in every environment
+func isBuiltIn(obj types.Object) bool {
+	return obj.GetType() == types.PlatformType && obj.GetID() == types.SMPlatform
+}
@@ -0,0 +1,187 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package backup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/gofrs/uuid"
+)
+
+// Importer creates the resources of an archive in the Service Manager
+type Importer struct {
+	// Repository must encrypt the secrets of the resources with the key of the target Service Manager
+	Repository storage.TransactionalRepository
+	// RemapIDs assigns new ids to the imported resources and updates the references between them
+	RemapIDs bool
+}
+
+// reference is a field of a resource which contains the id of another resource
+type reference struct {
+	Type types.ObjectType
+	ID   *string
+}
+
+// Import creates the resources of the archive in a single transaction. The references of all resources are validated
+// before any of them is created.
+func (i *Importer) Import(ctx context.Context, archive *Archive) error {
+	if archive.FormatVersion != FormatVersion {
+		return fmt.Errorf("unsupported archive format version %d, expected %d", archive.FormatVersion, FormatVersion)
+	}
+	objects, err := readObjects(archive)
+	if err != nil {
+		return err
+	}
+	if i.RemapIDs {
+		if err := remapIDs(objects); err != nil {
+			return err
+		}
+	}
+
+	return i.Repository.InTransaction(ctx, func(ctx context.Context, repository storage.Repository) error {
+		if err := validate(ctx, repository, objects); err != nil {
+			return err
+		}
+		counts := make(map[types.ObjectType]int)
+		for _, obj := range objects {
+			if _, err := repository.Create(ctx, obj); err != nil {
+				return fmt.Errorf("could not import %s with id %s: %s", obj.GetType(), obj.GetID(), err)
+			}
+			counts[obj.GetType()]++
+		}
+		for _, objectType := range resourceTypes {
+			log.C(ctx).Infof("Imported %d resources of type %s", counts[objectType], objectType)
+		}
+		return nil
+	})
+}
+
+// readObjects restores the objects of the archive in an order in which the referenced objects precede the referencing ones
+func readObjects(archive *Archive) ([]types.Object, error) {
+	order := make(map[types.ObjectType]int, len(resourceTypes))
+	for index, objectType := range resourceTypes {
+		order[objectType] = index
+	}
+	objects := make([]types.Object, 0, len(archive.Resources))
+	for _, resource := range archive.Resources {
+		obj, err := resource.ToObject()
+		if err != nil {
+			return nil, err
+		}
+		if isBuiltIn(obj) {
+			continue
+		}
+		objects = append(objects, obj)
+	}
+	sort.SliceStable(objects, func(a, b int) bool {
+		return order[objects[a].GetType()] < order[objects[b].GetType()]
+	})
+	return objects, nil
+}
+
+// remapIDs assigns new ids to the objects and updates the references to them
+func remapIDs(objects []types.Object) error {
+	ids := make(map[string]string, len(objects))
+	for _, obj := range objects {
+		UUID, err := uuid.NewV4()
+		if err != nil {
+			return fmt.Errorf("could not generate GUID: %s", err)
+		}
+		ids[obj.GetID()] = UUID.String()
+		obj.SetID(UUID.String())
+	}
+	for _, obj := range objects {
+		for _, ref := range referencesOf(obj) {
+			if newID, found := ids[*ref.ID]; found {
+				*ref.ID = newID
+			}
+		}
+	}
+	return nil
+}
+
+// validate verifies that none of the objects exists and that all references resolve to an imported or existing object
+func validate(ctx context.Context, repository storage.Repository, objects []types.Object) error {
+	imported := make(map[types.ObjectType]map[string]bool)
+	for _, obj := range objects {
+		if imported[obj.GetType()] == nil {
+			imported[obj.GetType()] = make(map[string]bool)
+		}
+		imported[obj.GetType()][obj.GetID()] = true
+	}
+
+	for _, obj := range objects {
+		exists, err := existsInTarget(ctx, repository, obj.GetType(), obj.GetID())
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("%s with id %s already exists. Enable the remapping of ids to import the archive as a copy", obj.GetType(), obj.GetID())
+		}
+		for _, ref := range referencesOf(obj) {
+			if imported[ref.Type][*ref.ID] || (ref.Type == types.PlatformType && *ref.ID == types.SMPlatform) {
+				continue
+			}
+			exists, err := existsInTarget(ctx, repository, ref.Type, *ref.ID)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return fmt.Errorf("%s with id %s references %s with id %s which is neither imported nor existing", obj.GetType(), obj.GetID(), ref.Type, *ref.ID)
+			}
+		}
+	}
+	return nil
+}
+
+func existsInTarget(ctx context.Context, repository storage.Repository, objectType types.ObjectType, id string) (bool, error) {
+	count, err := repository.Count(ctx, objectType, query.ByField(query.EqualsOperator, "id", id))
+	if err != nil {
+		return false, fmt.Errorf("could not check if %s with id %s exists: %s", objectType, id, err)
+	}
+	return count != 0, nil
+}
+
+// referencesOf returns the non-empty references of the object to other resources
+func referencesOf(obj types.Object) []reference {
+	var references []reference
+	switch o := obj.(type) {
+	case *types.ServiceOffering:
+		references = []reference{{types.ServiceBrokerType, &o.BrokerID}}
+	case *types.ServicePlan:
+		references = []reference{{types.ServiceOfferingType, &o.ServiceOfferingID}}
+	case *types.Visibility:
+		references = []reference{{types.PlatformType, &o.PlatformID}, {types.ServicePlanType, &o.ServicePlanID}}
+	case *types.ServiceInstance:
+		references = []reference{{types.ServicePlanType, &o.ServicePlanID}, {types.PlatformType, &o.PlatformID}}
+	case *types.ServiceBinding:
+		references = []reference{{types.ServiceInstanceType, &o.ServiceInstanceID}}
+	}
+	result := make([]reference, 0, len(references))
+	for _, ref := range references {
+		if len(*ref.ID) != 0 {
+			result = append(result, ref)
+		}
+	}
+	return result
+}
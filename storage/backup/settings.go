@@ -0,0 +1,41 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package backup
+
+import "fmt"
+
+// Settings configures the export and import of the Service Manager data
+type Settings struct {
+	EncryptionKey string `mapstructure:"encryption_key" description:"key used to encrypt exported archives and decrypt imported archives"`
+	RemapIDs      bool   `mapstructure:"remap_ids" description:"whether to assign new ids to the imported resources, e.g. when cloning an environment into one which already contains the resources"`
+}
+
+// DefaultSettings returns default values for the backup settings
+func DefaultSettings() *Settings {
+	return &Settings{
+		EncryptionKey: "",
+		RemapIDs:      false,
+	}
+}
+
+// Validate validates the backup settings
+func (s *Settings) Validate() error {
+	if len(s.EncryptionKey) != 32 {
+		return fmt.Errorf("validate Settings: BackupEncryptionKey must be exactly 32 symbols long but was %d symbols long", len(s.EncryptionKey))
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+const (
+	defaultReencryptionBatchSize = 100
+	defaultReencryptionInterval  = time.Minute
+)
+
+// ReencryptedTypes are the types of objects whose credentials or secret fields are re-encrypted after an encryption key rotation
+var ReencryptedTypes = []types.ObjectType{
+	types.ServiceBrokerType,
+	types.PlatformType,
+	types.ServiceInstanceType,
+	types.ServiceBindingType,
+}
+
+// CredentialsReencrypter re-encrypts the credentials of Secured objects and the secret fields of objects
+// which are not encrypted with the active encryption key
+type CredentialsReencrypter struct {
+	// Repository should provide access to the encrypted credentials, i.e. it should not be decorated with encryption
+	Repository TransactionalRepository
+	Keys       *EncryptionKeys
+	// Locker guarantees that only one Service Manager instance runs the re-encryption at a time
+	Locker    Locker
+	BatchSize int
+	// Interval is the time between re-encryption runs until no credentials are left which are not encrypted with the active encryption key
+	Interval time.Duration
+}
+
+// Start runs the re-encryption in a go routine. The re-encryption is repeated on an interval until a run finds
+// no credentials which are not encrypted with the active encryption key, e.g. because they were encrypted by another
+// Service Manager instance during the rotation or because the re-encryption was running in another instance.
+func (cr *CredentialsReencrypter) Start(ctx context.Context, group *sync.WaitGroup) {
+	interval := cr.Interval
+	if interval <= 0 {
+		interval = defaultReencryptionInterval
+	}
+	util.StartInWaitGroupWithContext(ctx, func(ctx context.Context) {
+		for {
+			done, err := cr.Run(ctx)
+			if err != nil {
+				log.C(ctx).WithError(err).Error("could not re-encrypt credentials")
+			} else if done {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+	}, group)
+}
+
+// Run re-encrypts the credentials of all Secured objects which are not encrypted with the active encryption key
+// and returns true if all credentials were already encrypted with it. If the re-encryption is already running in
+// another Service Manager instance, Run returns false without re-encrypting.
+func (cr *CredentialsReencrypter) Run(ctx context.Context) (bool, error) {
+	if err := cr.Locker.TryLock(ctx); err != nil {
+		log.C(ctx).WithError(err).Info("Skipping re-encryption of credentials as it could not be locked")
+		return false, nil
+	}
+	defer func() {
+		if err := cr.Locker.Unlock(ctx); err != nil {
+			log.C(ctx).WithError(err).Error("error while unlocking credentials re-encryption")
+		}
+	}()
+
+	activeKeyID := cr.Keys.ActiveKeyID()
+	log.C(ctx).Infof("Re-encrypting credentials with encryption key %s", activeKeyID)
+	total := 0
+	for _, objectType := range ReencryptedTypes {
+		count, err := cr.reencryptType(ctx, objectType)
+		if err != nil {
+			return false, fmt.Errorf("could not re-encrypt credentials of %s: %s", objectType, err)
+		}
+		log.C(ctx).Infof("Re-encrypted credentials of %d %s with encryption key %s", count, objectType, activeKeyID)
+		total += count
+	}
+	return total == 0, nil
+}
+
+func (cr *CredentialsReencrypter) reencryptType(ctx context.Context, objectType types.ObjectType) (int, error) {
+	batchSize := cr.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultReencryptionBatchSize
+	}
+
+	var lastPagingSequence int64
+	count := 0
+	for {
+		batchLen := 0
+		err := cr.Repository.InTransaction(ctx, func(ctx context.Context, repository Repository) error {
+			objectList, err := repository.List(ctx, objectType,
+				query.ByField(query.GreaterThanOperator, "paging_sequence", strconv.FormatInt(lastPagingSequence, 10)),
+				query.OrderResultBy("paging_sequence", query.AscOrder),
+				query.LimitResultBy(batchSize),
+				// the objects are locked until they are updated so that concurrent changes are not overridden
+				query.LockResultForUpdate())
+			if err != nil {
+				return err
+			}
+			batchLen = objectList.Len()
+			for i := 0; i < objectList.Len(); i++ {
+				obj := objectList.ItemAt(i)
+				reencrypted, err := cr.reencrypt(ctx, repository, obj)
+				if err != nil {
+					return fmt.Errorf("could not re-encrypt credentials of %s with id %s: %s", objectType, obj.GetID(), err)
+				}
+				if reencrypted {
+					count++
+				}
+				lastPagingSequence = obj.GetPagingSequence()
+			}
+			return nil
+		})
+		if err != nil {
+			return count, err
+		}
+		if batchLen < batchSize {
+			return count, nil
+		}
+	}
+}
+
+func (cr *CredentialsReencrypter) reencrypt(ctx context.Context, repository Repository, obj types.Object) (bool, error) {
+	// secret fields stored before they were marked as secret are encrypted for the first time
+	stale := types.HasUnencryptedSecretFields(obj)
+	if err := decryptObject(ctx, obj, func(ctx context.Context, ciphertext []byte) ([]byte, error) {
+		stale = stale || !cr.Keys.IsEncryptedWithActiveKey(ciphertext)
+		return cr.Keys.Decrypt(ctx, ciphertext)
+	}); err != nil {
+		return false, err
+	}
+	if !stale {
+		return false, nil
+	}
+
+	if err := encryptObject(ctx, obj, cr.Keys.Encrypt); err != nil {
+		return false, err
+	}
+	if _, err := repository.Update(ctx, obj, nil); err != nil {
+		return false, err
+	}
+	return true, nil
+}
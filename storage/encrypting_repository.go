@@ -2,12 +2,8 @@ package storage
 
 import (
 	"context"
-	"crypto/rand"
-	"fmt"
 	"time"
 
-	"github.com/Peripli/service-manager/pkg/log"
-
 	"github.com/Peripli/service-manager/pkg/security"
 
 	"github.com/Peripli/service-manager/pkg/query"
@@ -36,54 +32,51 @@ type KeyStore interface {
 
 	// SetEncryptionKey sets the provided encryption key in the KeyStore after applying the specified transformation function
 	SetEncryptionKey(ctx context.Context, key []byte, transformationFunc func(context.Context, []byte, []byte) ([]byte, error)) error
+
+	// GetEncryptionKeys returns all versions of the encryption key, the active one first, after applying the specified transformation function
+	GetEncryptionKeys(ctx context.Context, transformationFunc func(context.Context, []byte, []byte) ([]byte, error)) ([]security.Key, error)
+
+	// GetActiveEncryptionKeyID returns the ID of the active version of the encryption key
+	GetActiveEncryptionKeyID(ctx context.Context) (string, error)
+
+	// AddEncryptionKey stores a new version of the encryption key after applying the specified transformation function.
+	// The new version becomes the active one and its ID is returned.
+	AddEncryptionKey(ctx context.Context, key []byte, transformationFunc func(context.Context, []byte, []byte) ([]byte, error)) (string, error)
+
+	// ReencryptEncryptionKeys applies the decryption and encryption functions to the versions of the encryption key which
+	// are not encrypted with the current layer one encryption key and returns the number of re-encrypted versions
+	ReencryptEncryptionKeys(ctx context.Context, decryptionFunc, encryptionFunc func(context.Context, []byte, []byte) ([]byte, error)) (int, error)
 }
 
 // EncryptingDecorator creates a TransactionalRepositoryDecorator that can be used to add encrypting/decrypting logic to a TransactionalRepository
-func EncryptingDecorator(ctx context.Context, encrypter security.Encrypter, keyStore KeyStore, locker Locker) TransactionalRepositoryDecorator {
+func EncryptingDecorator(ctx context.Context, keys *EncryptionKeys) TransactionalRepositoryDecorator {
 	return func(next TransactionalRepository) (TransactionalRepository, error) {
 		ctx, cancelFunc := context.WithTimeout(ctx, 2*time.Second)
 		defer cancelFunc()
 
-		if err := locker.Lock(ctx); err != nil {
+		if err := keys.Load(ctx); err != nil {
 			return nil, err
 		}
-		defer func() {
-			if err := locker.Unlock(ctx); err != nil {
-				log.C(ctx).WithError(err).Error("error while unlocking keystore")
-			}
-		}()
-
-		encryptionKey, err := keyStore.GetEncryptionKey(ctx, encrypter.Decrypt)
-		if err != nil {
-			return nil, err
-		}
-
-		if len(encryptionKey) == 0 {
-			logger := log.C(ctx)
-			logger.Info("No encryption key is present. Generating new one...")
-			newEncryptionKey := make([]byte, 32)
-			if _, err = rand.Read(newEncryptionKey); err != nil {
-				return nil, fmt.Errorf("could not generate encryption key: %v", err)
-			}
-
-			encryptionKey = newEncryptionKey
-			if err = keyStore.SetEncryptionKey(ctx, newEncryptionKey, encrypter.Encrypt); err != nil {
-				return nil, err
-			}
-			logger.Info("Successfully generated new encryption key")
-		}
 
-		return NewEncryptingRepository(next, encrypter, encryptionKey)
+		return &TransactionalEncryptingRepository{
+			encryptingRepository: &encryptingRepository{
+				repository: next,
+				keys:       keys,
+			},
+			repository: next,
+		}, nil
 	}
 }
 
-//NewEncryptingRepository creates a new TransactionalEncryptingRepository using the specified encrypter and encryption key
+// NewEncryptingRepository creates a new TransactionalEncryptingRepository using the specified encrypter and encryption key
 func NewEncryptingRepository(repository TransactionalRepository, encrypter security.Encrypter, key []byte) (*TransactionalEncryptingRepository, error) {
 	encryptingRepository := &TransactionalEncryptingRepository{
 		encryptingRepository: &encryptingRepository{
-			repository:    repository,
-			encrypter:     encrypter,
-			encryptionKey: key,
+			repository: repository,
+			keys: &EncryptionKeys{
+				encrypter: encrypter,
+				keyRing:   security.NewKeyRing(encrypter, security.Key{Value: key}),
+			},
 		},
 		repository: repository,
 	}
@@ -93,12 +86,10 @@ func NewEncryptingRepository(repository TransactionalRepository, encrypter secur
 
 type encryptingRepository struct {
 	repository Repository
-	encrypter  security.Encrypter
-
-	encryptionKey []byte
+	keys       *EncryptionKeys
 }
 
-//TransactionalEncryptingRepository is a TransactionalRepository with that also encrypts credentials of Secured objects
+// TransactionalEncryptingRepository is a TransactionalRepository with that also encrypts credentials of Secured objects
 // before storing in the database them and decrypts credentials of Secured objects when reading them from the database
 type TransactionalEncryptingRepository struct {
 	*encryptingRepository
@@ -196,30 +187,39 @@ func (er *encryptingRepository) Delete(ctx context.Context, objectType types.Obj
 }
 
 func (er *encryptingRepository) encrypt(ctx context.Context, obj types.Object) error {
+	return encryptObject(ctx, obj, er.keys.Encrypt)
+}
+
+func (er *encryptingRepository) decrypt(ctx context.Context, obj types.Object) error {
+	return decryptObject(ctx, obj, er.keys.Decrypt)
+}
+
+// encryptObject encrypts the credentials of Secured objects and the secret fields of the object
+func encryptObject(ctx context.Context, obj types.Object, encryptionFunc func(context.Context, []byte) ([]byte, error)) error {
 	if securedObject, isSecured := obj.(types.Secured); isSecured {
-		return securedObject.Encrypt(ctx, func(ctx context.Context, bytes []byte) ([]byte, error) {
-			return er.encrypter.Encrypt(ctx, bytes, er.encryptionKey)
-		})
+		if err := securedObject.Encrypt(ctx, encryptionFunc); err != nil {
+			return err
+		}
 	}
-	return nil
+	return types.EncryptSecretFields(ctx, obj, encryptionFunc)
 }
 
-func (er *encryptingRepository) decrypt(ctx context.Context, obj types.Object) error {
+// decryptObject decrypts the credentials of Secured objects and the secret fields of the object
+func decryptObject(ctx context.Context, obj types.Object, decryptionFunc func(context.Context, []byte) ([]byte, error)) error {
 	if securedObject, isSecured := obj.(types.Secured); isSecured {
-		return securedObject.Decrypt(ctx, func(ctx context.Context, bytes []byte) ([]byte, error) {
-			return er.encrypter.Decrypt(ctx, bytes, er.encryptionKey)
-		})
+		if err := securedObject.Decrypt(ctx, decryptionFunc); err != nil {
+			return err
+		}
 	}
-	return nil
+	return types.DecryptSecretFields(ctx, obj, decryptionFunc)
 }
 
 // InTransaction wraps repository passed in the transaction to also encypt/decrypt credentials
 func (er *TransactionalEncryptingRepository) InTransaction(ctx context.Context, f func(ctx context.Context, storage Repository) error) error {
 	return er.repository.InTransaction(ctx, func(ctx context.Context, storage Repository) error {
 		return f(ctx, &encryptingRepository{
-			repository:    storage,
-			encrypter:     er.encrypter,
-			encryptionKey: er.encryptionKey,
+			repository: storage,
+			keys:       er.keys,
 		})
 	})
 }
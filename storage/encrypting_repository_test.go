@@ -2,6 +2,7 @@ package storage_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -161,6 +162,28 @@ var _ = Describe("Encrypting Repository", func() {
 		})
 	})
 
+	Describe("Create object with secret fields", func() {
+		It("encrypts the secret fields", func() {
+			instance := &types.ServiceInstance{
+				Base:    types.Base{ID: "id"},
+				Context: json.RawMessage(`{"password":"secret"}`),
+			}
+			var storedContext string
+			fakeRepository.CreateStub = func(ctx context.Context, obj types.Object) (types.Object, error) {
+				storedContext = string(obj.(*types.ServiceInstance).Context)
+				return obj, nil
+			}
+
+			returnedObj, err := repository.Create(ctx, instance)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(storedContext).ToNot(ContainSubstring("secret"))
+			Expect(fakeEncrypter.EncryptCallCount() - encryptCallsCountBeforeOp).To(Equal(1))
+			Expect(fakeEncrypter.DecryptCallCount() - decryptCallsCountBeforeOp).To(Equal(1))
+			Expect(string(returnedObj.(*types.ServiceInstance).Context)).To(Equal(`{"password":"secret"}`))
+		})
+	})
+
 	Describe("List", func() {
 		Context("when decrypting fails", func() {
 			It("returns an error", func() {
@@ -0,0 +1,234 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/pkg/util"
+)
+
+const defaultEncryptionKeysRefreshInterval = time.Minute
+
+// EncryptionKeys manages the versions of the encryption key used to encrypt the credentials of Secured objects.
+// Credentials are encrypted with the active version and can be decrypted with any of the versions, so that
+// credentials encrypted before a key rotation remain readable until they are re-encrypted.
+type EncryptionKeys struct {
+	encrypter    security.Encrypter
+	keyEncrypter security.Encrypter
+	keyStore     KeyStore
+	locker       Locker
+	// RefreshInterval is the time between checks whether another Service Manager instance has activated a newer key version
+	RefreshInterval time.Duration
+
+	mutex   sync.RWMutex
+	keyRing *security.KeyRing
+}
+
+// NewEncryptionKeys creates EncryptionKeys which encrypt data with the encrypter and are stored in the specified
+// KeyStore wrapped by the keyEncrypter. The locker guards the generation of new key versions across Service Manager instances.
+func NewEncryptionKeys(encrypter, keyEncrypter security.Encrypter, keyStore KeyStore, locker Locker) *EncryptionKeys {
+	return &EncryptionKeys{
+		encrypter:    encrypter,
+		keyEncrypter: keyEncrypter,
+		keyStore:     keyStore,
+		locker:       locker,
+	}
+}
+
+// NewKeyEncrypter returns the Encrypter which wraps the encryption keys in the KeyStore. If a key management
+// service is configured, the keys are wrapped by it, otherwise they are encrypted with the storage encryption key.
+func NewKeyEncrypter(settings *KMSSettings) security.Encrypter {
+	if settings == nil || settings.Type == "" {
+		return &security.AESEncrypter{}
+	}
+	return security.NewVaultTransitEncrypter(security.VaultTransitConfig{
+		Address:   settings.Address,
+		Token:     settings.Token,
+		MountPath: settings.MountPath,
+		KeyName:   settings.KeyName,
+		Timeout:   settings.Timeout,
+		CacheTTL:  settings.CacheTTL,
+		// keys encrypted with a previous storage encryption key can still be read and re-wrapped by the key management service
+		Fallback: &security.AESEncrypter{},
+	})
+}
+
+// Load loads the versions of the encryption key from the KeyStore. If no key is present, a new one is generated.
+// Key versions which are still wrapped with a previous layer one encryption key are re-wrapped with the current one.
+func (k *EncryptionKeys) Load(ctx context.Context) error {
+	return k.withLock(ctx, func() error {
+		reencrypted, err := k.keyStore.ReencryptEncryptionKeys(ctx, k.keyEncrypter.Decrypt, k.keyEncrypter.Encrypt)
+		if err != nil {
+			return err
+		}
+		if reencrypted > 0 {
+			log.C(ctx).Infof("Re-encrypted %d encryption key versions with the current layer one encryption key", reencrypted)
+		}
+
+		encryptionKey, err := k.keyStore.GetEncryptionKey(ctx, k.keyEncrypter.Decrypt)
+		if err != nil {
+			return err
+		}
+		if len(encryptionKey) == 0 {
+			logger := log.C(ctx)
+			logger.Info("No encryption key is present. Generating new one...")
+			newEncryptionKey, err := generateEncryptionKey()
+			if err != nil {
+				return err
+			}
+			if err = k.keyStore.SetEncryptionKey(ctx, newEncryptionKey, k.keyEncrypter.Encrypt); err != nil {
+				return err
+			}
+			logger.Info("Successfully generated new encryption key")
+		}
+
+		return k.Refresh(ctx)
+	})
+}
+
+// Rotate generates a new version of the encryption key which becomes the active one and returns its ID
+func (k *EncryptionKeys) Rotate(ctx context.Context) (string, error) {
+	var keyID string
+	err := k.withLock(ctx, func() error {
+		newEncryptionKey, err := generateEncryptionKey()
+		if err != nil {
+			return err
+		}
+		if keyID, err = k.keyStore.AddEncryptionKey(ctx, newEncryptionKey, k.keyEncrypter.Encrypt); err != nil {
+			return err
+		}
+		return k.Refresh(ctx)
+	})
+	if err != nil {
+		return "", err
+	}
+	log.C(ctx).Infof("Successfully rotated encryption key. Active encryption key is %s", keyID)
+	return keyID, nil
+}
+
+// Refresh reloads the versions of the encryption key from the KeyStore so that key versions
+// generated by other Service Manager instances are taken into account
+func (k *EncryptionKeys) Refresh(ctx context.Context) error {
+	keys, err := k.keyStore.GetEncryptionKeys(ctx, k.keyEncrypter.Decrypt)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no encryption keys are present")
+	}
+
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.keyRing = security.NewKeyRing(k.encrypter, keys[0], keys[1:]...)
+	return nil
+}
+
+// ActiveKeyID returns the ID of the key version used for encryption
+func (k *EncryptionKeys) ActiveKeyID() string {
+	return k.currentKeyRing().ActiveKeyID()
+}
+
+// IsEncryptedWithActiveKey returns true if the ciphertext is encrypted with the active key version
+func (k *EncryptionKeys) IsEncryptedWithActiveKey(ciphertext []byte) bool {
+	return k.currentKeyRing().IsEncryptedWithActiveKey(ciphertext)
+}
+
+// Encrypt encrypts the plaintext with the active key version
+func (k *EncryptionKeys) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	return k.currentKeyRing().Encrypt(ctx, plaintext)
+}
+
+// Decrypt decrypts the ciphertext with the key version it was encrypted with. If the ciphertext is encrypted with
+// a key version which is not loaded, the key versions are reloaded as it was generated by another Service Manager
+// instance after the last refresh.
+func (k *EncryptionKeys) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	if k.keyStore != nil && k.currentKeyRing().IsEncryptedWithUnknownKey(ciphertext) {
+		log.C(ctx).Debug("Reloading encryption keys to decrypt data encrypted with an unknown encryption key")
+		if err := k.Refresh(ctx); err != nil {
+			return nil, fmt.Errorf("could not reload encryption keys: %s", err)
+		}
+	}
+	return k.currentKeyRing().Decrypt(ctx, ciphertext)
+}
+
+// Start reloads the versions of the encryption key on an interval in a go routine when another Service Manager
+// instance has activated a newer key version, so that it is used for encryption shortly after a key rotation
+func (k *EncryptionKeys) Start(ctx context.Context, group *sync.WaitGroup) {
+	interval := k.RefreshInterval
+	if interval <= 0 {
+		interval = defaultEncryptionKeysRefreshInterval
+	}
+	util.StartInWaitGroupWithContext(ctx, func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := k.refreshIfRotated(ctx); err != nil {
+					log.C(ctx).WithError(err).Error("could not reload encryption keys")
+				}
+			}
+		}
+	}, group)
+}
+
+func (k *EncryptionKeys) refreshIfRotated(ctx context.Context) error {
+	activeKeyID, err := k.keyStore.GetActiveEncryptionKeyID(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get active encryption key: %s", err)
+	}
+	if activeKeyID == k.ActiveKeyID() {
+		return nil
+	}
+	log.C(ctx).Infof("Reloading encryption keys as encryption key %s is active", activeKeyID)
+	return k.Refresh(ctx)
+}
+
+func (k *EncryptionKeys) currentKeyRing() *security.KeyRing {
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+	return k.keyRing
+}
+
+func (k *EncryptionKeys) withLock(ctx context.Context, f func() error) error {
+	if err := k.locker.Lock(ctx); err != nil {
+		return err
+	}
+	defer func() {
+		if err := k.locker.Unlock(ctx); err != nil {
+			log.C(ctx).WithError(err).Error("error while unlocking keystore")
+		}
+	}()
+	return f()
+}
+
+func generateEncryptionKey() ([]byte, error) {
+	encryptionKey := make([]byte, 32)
+	if _, err := rand.Read(encryptionKey); err != nil {
+		return nil, fmt.Errorf("could not generate encryption key: %v", err)
+	}
+	return encryptionKey, nil
+}
@@ -0,0 +1,165 @@
+package storage_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/security"
+	"github.com/Peripli/service-manager/storage"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type inMemoryKeyStore struct {
+	layerOneKey []byte
+	secrets     [][]byte
+}
+
+func (ks *inMemoryKeyStore) GetEncryptionKey(ctx context.Context, transformationFunc func(context.Context, []byte, []byte) ([]byte, error)) ([]byte, error) {
+	if len(ks.secrets) == 0 {
+		return []byte{}, nil
+	}
+	return transformationFunc(ctx, ks.secrets[len(ks.secrets)-1], ks.layerOneKey)
+}
+
+func (ks *inMemoryKeyStore) SetEncryptionKey(ctx context.Context, key []byte, transformationFunc func(context.Context, []byte, []byte) ([]byte, error)) error {
+	_, err := ks.AddEncryptionKey(ctx, key, transformationFunc)
+	return err
+}
+
+func (ks *inMemoryKeyStore) GetEncryptionKeys(ctx context.Context, transformationFunc func(context.Context, []byte, []byte) ([]byte, error)) ([]security.Key, error) {
+	var keys []security.Key
+	for i := len(ks.secrets) - 1; i >= 0; i-- {
+		key, err := transformationFunc(ctx, ks.secrets[i], ks.layerOneKey)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, security.Key{ID: strconv.Itoa(i + 1), Value: key})
+	}
+	return keys, nil
+}
+
+func (ks *inMemoryKeyStore) GetActiveEncryptionKeyID(ctx context.Context) (string, error) {
+	return strconv.Itoa(len(ks.secrets)), nil
+}
+
+func (ks *inMemoryKeyStore) AddEncryptionKey(ctx context.Context, key []byte, transformationFunc func(context.Context, []byte, []byte) ([]byte, error)) (string, error) {
+	secret, err := transformationFunc(ctx, key, ks.layerOneKey)
+	if err != nil {
+		return "", err
+	}
+	ks.secrets = append(ks.secrets, secret)
+	return strconv.Itoa(len(ks.secrets)), nil
+}
+
+func (ks *inMemoryKeyStore) ReencryptEncryptionKeys(ctx context.Context, decryptionFunc, encryptionFunc func(context.Context, []byte, []byte) ([]byte, error)) (int, error) {
+	return 0, nil
+}
+
+type noopLocker struct{}
+
+func (noopLocker) Lock(ctx context.Context) error    { return nil }
+func (noopLocker) TryLock(ctx context.Context) error { return nil }
+func (noopLocker) Unlock(ctx context.Context) error  { return nil }
+
+var _ = Describe("Encryption keys", func() {
+	var ctx context.Context
+	var keyStore *inMemoryKeyStore
+	var keys *storage.EncryptionKeys
+
+	BeforeEach(func() {
+		ctx = context.TODO()
+		keyStore = &inMemoryKeyStore{layerOneKey: []byte("ejHjRNHbS0NaqARSRvnweVV9zcmhQEa8")}
+		keys = storage.NewEncryptionKeys(&security.AESEncrypter{}, &security.AESEncrypter{}, keyStore, noopLocker{})
+	})
+
+	Describe("Load", func() {
+		Context("when no encryption key is present", func() {
+			It("generates one", func() {
+				Expect(keys.Load(ctx)).To(Succeed())
+				Expect(keyStore.secrets).To(HaveLen(1))
+				Expect(keys.ActiveKeyID()).To(Equal("1"))
+			})
+		})
+
+		Context("when an encryption key is present", func() {
+			It("does not generate a new one", func() {
+				Expect(keys.Load(ctx)).To(Succeed())
+				Expect(keys.Load(ctx)).To(Succeed())
+				Expect(keyStore.secrets).To(HaveLen(1))
+			})
+		})
+	})
+
+	Describe("Rotate", func() {
+		var ciphertext []byte
+
+		BeforeEach(func() {
+			Expect(keys.Load(ctx)).To(Succeed())
+
+			var err error
+			ciphertext, err = keys.Encrypt(ctx, []byte("secret"))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("activates a new encryption key", func() {
+			keyID, err := keys.Rotate(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keyID).To(Equal("2"))
+			Expect(keys.ActiveKeyID()).To(Equal("2"))
+			Expect(keys.IsEncryptedWithActiveKey(ciphertext)).To(BeFalse())
+
+			newCiphertext, err := keys.Encrypt(ctx, []byte("secret"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys.IsEncryptedWithActiveKey(newCiphertext)).To(BeTrue())
+		})
+
+		It("decrypts data encrypted with the previous encryption key", func() {
+			_, err := keys.Rotate(ctx)
+			Expect(err).ToNot(HaveOccurred())
+
+			plaintext, err := keys.Decrypt(ctx, ciphertext)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(plaintext).To(Equal([]byte("secret")))
+		})
+
+		Context("when the rotation is done by another instance", func() {
+			It("decrypts data encrypted with the new encryption key", func() {
+				otherKeys := storage.NewEncryptionKeys(&security.AESEncrypter{}, &security.AESEncrypter{}, keyStore, noopLocker{})
+				Expect(otherKeys.Load(ctx)).To(Succeed())
+				_, err := otherKeys.Rotate(ctx)
+				Expect(err).ToNot(HaveOccurred())
+				newCiphertext, err := otherKeys.Encrypt(ctx, []byte("new secret"))
+				Expect(err).ToNot(HaveOccurred())
+
+				plaintext, err := keys.Decrypt(ctx, newCiphertext)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(plaintext).To(Equal([]byte("new secret")))
+				Expect(keys.ActiveKeyID()).To(Equal("2"))
+			})
+
+			It("encrypts data with the new encryption key once the encryption keys are refreshed", func() {
+				otherKeys := storage.NewEncryptionKeys(&security.AESEncrypter{}, &security.AESEncrypter{}, keyStore, noopLocker{})
+				Expect(otherKeys.Load(ctx)).To(Succeed())
+				_, err := otherKeys.Rotate(ctx)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(keys.ActiveKeyID()).To(Equal("1"))
+
+				refreshCtx, cancel := context.WithCancel(ctx)
+				wg := &sync.WaitGroup{}
+				keys.RefreshInterval = 10 * time.Millisecond
+				keys.Start(refreshCtx, wg)
+				Eventually(keys.ActiveKeyID).Should(Equal("2"))
+				cancel()
+				wg.Wait()
+
+				newCiphertext, err := keys.Encrypt(ctx, []byte("new secret"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(keys.ActiveKeyID()).To(Equal("2"))
+				Expect(otherKeys.IsEncryptedWithActiveKey(newCiphertext)).To(BeTrue())
+			})
+		})
+	})
+})
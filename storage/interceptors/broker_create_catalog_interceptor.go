@@ -31,6 +31,8 @@ const BrokerCreateCatalogInterceptorName = "BrokerCreateCatalogInterceptor"
 
 type BrokerCreateCatalogInterceptorProvider struct {
 	CatalogFetcher func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error)
+	// StrictCatalogValidation rejects catalogs with warnings
+	StrictCatalogValidation bool
 }
 
 func (c *BrokerCreateCatalogInterceptorProvider) Name() string {
@@ -39,19 +41,21 @@ func (c *BrokerCreateCatalogInterceptorProvider) Name() string {
 
 func (c *BrokerCreateCatalogInterceptorProvider) Provide() storage.CreateInterceptor {
 	return &brokerCreateCatalogInterceptor{
-		CatalogFetcher: c.CatalogFetcher,
+		CatalogFetcher:          c.CatalogFetcher,
+		StrictCatalogValidation: c.StrictCatalogValidation,
 	}
 
 }
 
 type brokerCreateCatalogInterceptor struct {
-	CatalogFetcher func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error)
+	CatalogFetcher          func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error)
+	StrictCatalogValidation bool
 }
 
 func (c *brokerCreateCatalogInterceptor) AroundTxCreate(h storage.InterceptCreateAroundTxFunc) storage.InterceptCreateAroundTxFunc {
 	return func(ctx context.Context, obj types.Object) (types.Object, error) {
 		broker := obj.(*types.ServiceBroker)
-		if err := brokerCatalogAroundTx(ctx, broker, c.CatalogFetcher); err != nil {
+		if err := brokerCatalogAroundTx(ctx, broker, c.CatalogFetcher, c.StrictCatalogValidation); err != nil {
 			return nil, err
 		}
 
@@ -83,7 +87,9 @@ func (c *brokerCreateCatalogInterceptor) OnTxCreate(f storage.InterceptCreateOnT
 	}
 }
 
-func brokerCatalogAroundTx(ctx context.Context, broker *types.ServiceBroker, fetcher func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error)) error {
+// brokerCatalogAroundTx fetches and validates the catalog of the broker and constructs its services and plans.
+// The warnings of the catalog replace the catalog warnings of the broker unless strict validation rejects them.
+func brokerCatalogAroundTx(ctx context.Context, broker *types.ServiceBroker, fetcher func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error), strict bool) error {
 	catalogBytes, err := fetcher(ctx, broker)
 	if err != nil {
 		return err
@@ -96,6 +102,10 @@ func brokerCatalogAroundTx(ctx context.Context, broker *types.ServiceBroker, fet
 	if err := util.BytesToObject(catalogBytes, &catalogResponse); err != nil {
 		return err
 	}
+	broker.CatalogWarnings = nil
+	if err := validateCatalog(catalogResponse.Services).result(broker, strict); err != nil {
+		return err
+	}
 
 	for _, service := range catalogResponse.Services {
 		service.CatalogID = service.ID
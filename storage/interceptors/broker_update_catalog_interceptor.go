@@ -36,12 +36,15 @@ const BrokerUpdateCatalogInterceptorName = "BrokerUpdateCatalogInterceptor"
 type BrokerUpdateCatalogInterceptorProvider struct {
 	CatalogFetcher func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error)
 	CatalogLoader  func(ctx context.Context, brokerID string, repository storage.Repository) (*types.ServiceOfferings, error)
+	// StrictCatalogValidation rejects catalogs with warnings
+	StrictCatalogValidation bool
 }
 
 func (c *BrokerUpdateCatalogInterceptorProvider) Provide() storage.UpdateInterceptor {
 	return &brokerUpdateCatalogInterceptor{
-		CatalogFetcher: c.CatalogFetcher,
-		CatalogLoader:  c.CatalogLoader,
+		CatalogFetcher:          c.CatalogFetcher,
+		CatalogLoader:           c.CatalogLoader,
+		StrictCatalogValidation: c.StrictCatalogValidation,
 	}
 }
 
@@ -50,15 +53,16 @@ func (c *BrokerUpdateCatalogInterceptorProvider) Name() string {
 }
 
 type brokerUpdateCatalogInterceptor struct {
-	CatalogFetcher func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error)
-	CatalogLoader  func(ctx context.Context, brokerID string, repository storage.Repository) (*types.ServiceOfferings, error)
+	CatalogFetcher          func(ctx context.Context, broker *types.ServiceBroker) ([]byte, error)
+	CatalogLoader           func(ctx context.Context, brokerID string, repository storage.Repository) (*types.ServiceOfferings, error)
+	StrictCatalogValidation bool
 }
 
 // AroundTxUpdate fetches the broker catalog before the transaction, so it can be stored later on in the transaction
 func (c *brokerUpdateCatalogInterceptor) AroundTxUpdate(h storage.InterceptUpdateAroundTxFunc) storage.InterceptUpdateAroundTxFunc {
 	return func(ctx context.Context, obj types.Object, labelChanges ...*query.LabelChange) (types.Object, error) {
 		broker := obj.(*types.ServiceBroker)
-		if err := brokerCatalogAroundTx(ctx, broker, c.CatalogFetcher); err != nil {
+		if err := brokerCatalogAroundTx(ctx, broker, c.CatalogFetcher, c.StrictCatalogValidation); err != nil {
 			return nil, err
 		}
 
@@ -78,6 +82,15 @@ func (c *brokerUpdateCatalogInterceptor) OnTxUpdate(f storage.InterceptUpdateOnT
 
 		oldBroker.Services = existingServiceOfferingsWithServicePlans.ServiceOfferings
 
+		newBroker := newObj.(*types.ServiceBroker)
+		validation, err := validateCatalogUpdate(ctx, txStorage, existingServiceOfferingsWithServicePlans.ServiceOfferings, newBroker.Services)
+		if err != nil {
+			return nil, err
+		}
+		if err := validation.result(newBroker, c.StrictCatalogValidation); err != nil {
+			return nil, err
+		}
+
 		updatedObject, err := f(ctx, txStorage, oldObj, newObj, labelChanges...)
 		if err != nil {
 			return nil, err
@@ -0,0 +1,270 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// catalogValidation collects the issues found in a broker catalog
+type catalogValidation struct {
+	issues []*types.CatalogIssue
+	// conflicts are the errors caused by existing resources, e.g. removed plans which are still in use
+	conflicts []*types.CatalogIssue
+}
+
+func (v *catalogValidation) errorf(path, format string, args ...interface{}) {
+	v.add(types.CatalogError, path, format, args...)
+}
+
+func (v *catalogValidation) warnf(path, format string, args ...interface{}) {
+	v.add(types.CatalogWarning, path, format, args...)
+}
+
+func (v *catalogValidation) add(severity types.CatalogIssueSeverity, path, format string, args ...interface{}) {
+	v.issues = append(v.issues, &types.CatalogIssue{
+		Severity: severity,
+		Path:     path,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// result returns an error listing the errors of the catalog, or also its warnings in strict mode. The error is a conflict
+// if existing resources prevent applying the catalog. Otherwise the warnings of the catalog are added to the broker.
+func (v *catalogValidation) result(broker *types.ServiceBroker, strict bool) error {
+	var rejected []string
+	for _, issue := range v.conflicts {
+		rejected = append(rejected, issue.String())
+	}
+	for _, issue := range v.issues {
+		if issue.Severity == types.CatalogError || strict {
+			rejected = append(rejected, issue.String())
+		} else {
+			broker.CatalogWarnings = append(broker.CatalogWarnings, issue)
+		}
+	}
+	if len(v.conflicts) != 0 {
+		return &util.HTTPError{
+			ErrorType:   "ExistingReferenceEntity",
+			Description: fmt.Sprintf("catalog of broker with name %s cannot be applied: %s", broker.Name, strings.Join(rejected, "; ")),
+			StatusCode:  http.StatusConflict,
+		}
+	}
+	if len(rejected) != 0 {
+		return &util.HTTPError{
+			ErrorType:   "BadRequest",
+			Description: fmt.Sprintf("catalog of broker with name %s is invalid: %s", broker.Name, strings.Join(rejected, "; ")),
+			StatusCode:  http.StatusBadRequest,
+		}
+	}
+	return nil
+}
+
+// validateCatalog verifies that the services of a catalog comply with the OSB API. The services must still have
+// the IDs and names of the catalog.
+func validateCatalog(services []*types.ServiceOffering) *catalogValidation {
+	validation := &catalogValidation{}
+	serviceIDs := make(map[string]string)
+	serviceNames := make(map[string]string)
+	planIDs := make(map[string]string)
+
+	for i, service := range services {
+		servicePath := fmt.Sprintf("services[%d]", i)
+		if service == nil {
+			validation.errorf(servicePath, "service is null")
+			continue
+		}
+		validateUnique(validation, servicePath, "service", "id", service.ID, serviceIDs, types.CatalogError)
+		validateUnique(validation, servicePath, "service", "name", service.Name, serviceNames, types.CatalogWarning)
+		if service.Description == "" {
+			validation.warnf(servicePath+".description", "service %s has no description", service.Name)
+		}
+		if len(service.Plans) == 0 {
+			validation.warnf(servicePath+".plans", "service %s has no plans", service.Name)
+		}
+
+		servicePlanIDs := make(map[string]string)
+		planNames := make(map[string]string)
+		for j, plan := range service.Plans {
+			planPath := fmt.Sprintf("%s.plans[%d]", servicePath, j)
+			if plan == nil {
+				validation.errorf(planPath, "plan is null")
+				continue
+			}
+			// the OSB API requires globally unique plan IDs, but plans shared by several services are still supported
+			if validateUnique(validation, planPath, "plan", "id", plan.ID, servicePlanIDs, types.CatalogError) {
+				validateUnique(validation, planPath, "plan", "id", plan.ID, planIDs, types.CatalogWarning)
+			}
+			validateUnique(validation, planPath, "plan", "name", plan.Name, planNames, types.CatalogError)
+			if plan.Description == "" {
+				validation.warnf(planPath+".description", "plan %s has no description", plan.Name)
+			}
+			validatePlanSchemas(validation, planPath, plan)
+			if _, err := types.ParseMaintenanceInfo(plan.MaintenanceInfo); err != nil {
+				validation.errorf(planPath+".maintenance_info", "%s", err)
+			}
+		}
+	}
+	return validation
+}
+
+// validateUnique reports a missing value and a value which was already seen at another path and returns whether neither is the case
+func validateUnique(validation *catalogValidation, path, kind, field, value string, seen map[string]string, severity types.CatalogIssueSeverity) bool {
+	if value == "" {
+		validation.errorf(path+"."+field, "%s %s missing", kind, field)
+		return false
+	}
+	if otherPath, found := seen[value]; found {
+		validation.add(severity, path+"."+field, "%s %s %s is also used by %s", kind, field, value, otherPath)
+		return false
+	}
+	seen[value] = path
+	return true
+}
+
+func validatePlanSchemas(validation *catalogValidation, planPath string, plan *types.ServicePlan) {
+	if len(plan.Schemas) == 0 {
+		return
+	}
+	for _, schemaPath := range types.ParametersSchemaPaths {
+		schema := plan.ParametersSchema(schemaPath)
+		if schema == nil {
+			continue
+		}
+		path := planPath + ".schemas." + schemaPath
+		if _, err := types.CompileParametersSchema(schema); err != nil {
+			validation.errorf(path, "%s", err)
+			continue
+		}
+		if version := gjson.GetBytes(schema, `\$schema`); version.Exists() && version.String() != types.Draft4SchemaURL {
+			validation.warnf(path, "schema uses %s instead of JSON Schema draft 04 required by the OSB API", version.String())
+		}
+	}
+}
+
+// validateCatalogUpdate compares the updated services of a broker with its existing services. Plans which are removed
+// while service instances still use them are conflicts. Renamed IDs and downgraded maintenance info versions are warnings.
+func validateCatalogUpdate(ctx context.Context, repository storage.Repository, existingServices, services []*types.ServiceOffering) (*catalogValidation, error) {
+	validation := &catalogValidation{}
+	updatedServices := make(map[string]int)
+	for i, service := range services {
+		updatedServices[service.CatalogID] = i
+	}
+
+	for _, existingService := range existingServices {
+		i, found := updatedServices[existingService.CatalogID]
+		if !found {
+			if renamed := findServiceByName(services, existingService.CatalogName); renamed != -1 {
+				validation.warnf(fmt.Sprintf("services[%d].id", renamed), "service %s changed its id from %s to %s and is registered as a new service",
+					existingService.CatalogName, existingService.CatalogID, services[renamed].CatalogID)
+			}
+			for _, existingPlan := range existingService.Plans {
+				if err := validatePlanRemoval(ctx, repository, validation, "services", existingPlan); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		service := services[i]
+		updatedPlans := make(map[string]int)
+		for j, plan := range service.Plans {
+			updatedPlans[plan.CatalogID] = j
+		}
+		for _, existingPlan := range existingService.Plans {
+			plansPath := fmt.Sprintf("services[%d].plans", i)
+			j, found := updatedPlans[existingPlan.CatalogID]
+			if !found {
+				if renamed := findPlanByName(service.Plans, existingPlan.CatalogName); renamed != -1 {
+					validation.warnf(fmt.Sprintf("%s[%d].id", plansPath, renamed), "plan %s changed its id from %s to %s and is registered as a new plan",
+						existingPlan.CatalogName, existingPlan.CatalogID, service.Plans[renamed].CatalogID)
+				}
+				if err := validatePlanRemoval(ctx, repository, validation, plansPath, existingPlan); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			validateMaintenanceInfoUpdate(validation, fmt.Sprintf("%s[%d].maintenance_info", plansPath, j), existingPlan, service.Plans[j])
+		}
+	}
+	return validation, nil
+}
+
+// validatePlanRemoval reports a conflict if service instances still use the removed plan
+func validatePlanRemoval(ctx context.Context, repository storage.Repository, validation *catalogValidation, path string, plan *types.ServicePlan) error {
+	byPlanID := query.ByField(query.EqualsOperator, "service_plan_id", plan.ID)
+	instancesCount, err := repository.Count(ctx, types.ServiceInstanceType, byPlanID)
+	if err != nil {
+		return err
+	}
+	if instancesCount != 0 {
+		validation.conflicts = append(validation.conflicts, &types.CatalogIssue{
+			Severity: types.CatalogError,
+			Path:     path,
+			Message:  fmt.Sprintf("plan %s with id %s was removed but is used by %d service instance(s)", plan.CatalogName, plan.CatalogID, instancesCount),
+		})
+	}
+	return nil
+}
+
+// validateMaintenanceInfoUpdate reports a warning if the maintenance info version of the plan was downgraded
+func validateMaintenanceInfoUpdate(validation *catalogValidation, path string, existingPlan, plan *types.ServicePlan) {
+	existingMaintenanceInfo, err := types.ParseMaintenanceInfo(existingPlan.MaintenanceInfo)
+	if err != nil || existingMaintenanceInfo == nil {
+		return
+	}
+	maintenanceInfo, err := types.ParseMaintenanceInfo(plan.MaintenanceInfo)
+	if err != nil {
+		return
+	}
+	if maintenanceInfo == nil {
+		validation.warnf(path, "plan %s no longer has maintenance_info", plan.CatalogName)
+		return
+	}
+	if types.CompareVersions(maintenanceInfo.Version, existingMaintenanceInfo.Version) < 0 {
+		validation.warnf(path+".version", "maintenance_info version of plan %s was downgraded from %s to %s",
+			plan.CatalogName, existingMaintenanceInfo.Version, maintenanceInfo.Version)
+	}
+}
+
+func findServiceByName(services []*types.ServiceOffering, name string) int {
+	for i, service := range services {
+		if service.CatalogName == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func findPlanByName(plans []*types.ServicePlan, name string) int {
+	for i, plan := range plans {
+		if plan.CatalogName == name {
+			return i
+		}
+	}
+	return -1
+}
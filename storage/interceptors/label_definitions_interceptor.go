@@ -0,0 +1,201 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const (
+	LabelDefinitionsCreateInterceptorName = "LabelDefinitionsCreateInterceptor"
+	LabelDefinitionsUpdateInterceptorName = "LabelDefinitionsUpdateInterceptor"
+)
+
+// LabelDefinitionsCreateInterceptorProvider provides an interceptor that forbids creation of resources
+// with labels which do not satisfy the label definitions of the resource type
+type LabelDefinitionsCreateInterceptorProvider struct {
+	// IgnoredKeys are label keys managed by Service Manager such as the tenant label key which need no definition
+	IgnoredKeys []string
+	Repository  storage.Repository
+}
+
+func (c *LabelDefinitionsCreateInterceptorProvider) Name() string {
+	return LabelDefinitionsCreateInterceptorName
+}
+
+func (c *LabelDefinitionsCreateInterceptorProvider) Provide() storage.CreateAroundTxInterceptor {
+	return &labelDefinitionsInterceptor{
+		IgnoredKeys: c.IgnoredKeys,
+		Repository:  c.Repository,
+	}
+}
+
+// LabelDefinitionsUpdateInterceptorProvider provides an interceptor that forbids label changes
+// which do not satisfy the label definitions of the resource type
+type LabelDefinitionsUpdateInterceptorProvider struct {
+	// IgnoredKeys are label keys managed by Service Manager such as the tenant label key which need no definition
+	IgnoredKeys []string
+	Repository  storage.Repository
+}
+
+func (c *LabelDefinitionsUpdateInterceptorProvider) Name() string {
+	return LabelDefinitionsUpdateInterceptorName
+}
+
+func (c *LabelDefinitionsUpdateInterceptorProvider) Provide() storage.UpdateAroundTxInterceptor {
+	return &labelDefinitionsInterceptor{
+		IgnoredKeys: c.IgnoredKeys,
+		Repository:  c.Repository,
+	}
+}
+
+type labelDefinitionsInterceptor struct {
+	IgnoredKeys []string
+	Repository  storage.Repository
+}
+
+func (c *labelDefinitionsInterceptor) AroundTxCreate(h storage.InterceptCreateAroundTxFunc) storage.InterceptCreateAroundTxFunc {
+	return func(ctx context.Context, obj types.Object) (types.Object, error) {
+		labels := obj.GetLabels()
+		keys := make([]string, 0, len(labels))
+		for key := range labels {
+			keys = append(keys, key)
+		}
+		enforceRequired, err := c.isManagedBySM(ctx, obj)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.checkLabels(ctx, obj.GetType(), labels, keys, enforceRequired); err != nil {
+			return nil, err
+		}
+		return h(ctx, obj)
+	}
+}
+
+func (c *labelDefinitionsInterceptor) AroundTxUpdate(h storage.InterceptUpdateAroundTxFunc) storage.InterceptUpdateAroundTxFunc {
+	return func(ctx context.Context, newObj types.Object, labelChanges ...*query.LabelChange) (types.Object, error) {
+		if len(labelChanges) == 0 {
+			return h(ctx, newObj, labelChanges...)
+		}
+		oldObj, err := c.Repository.Get(ctx, newObj.GetType(), query.ByField(query.EqualsOperator, "id", newObj.GetID()))
+		if err != nil {
+			return nil, err
+		}
+		labels, _, _ := query.ApplyLabelChangesToLabels(labelChanges, oldObj.GetLabels())
+		keys := make([]string, 0, len(labelChanges))
+		for _, change := range labelChanges {
+			// labels which were added before their definitions can still be removed
+			if change.Operation == query.AddLabelOperation || change.Operation == query.AddLabelValuesOperation {
+				keys = append(keys, change.Key)
+			}
+		}
+		enforceRequired, err := c.isManagedBySM(ctx, oldObj)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.checkLabels(ctx, newObj.GetType(), labels, keys, enforceRequired); err != nil {
+			return nil, err
+		}
+		return h(ctx, newObj, labelChanges...)
+	}
+}
+
+// isManagedBySM returns whether the resource is managed via the Service Manager API. Service instances and bindings
+// which are managed by other platforms are created via the OSB API and can not be required to have any labels.
+func (c *labelDefinitionsInterceptor) isManagedBySM(ctx context.Context, obj types.Object) (bool, error) {
+	switch resource := obj.(type) {
+	case *types.ServiceInstance:
+		return resource.PlatformID == types.SMPlatform, nil
+	case *types.ServiceBinding:
+		instance, err := getInstanceByID(ctx, resource.ServiceInstanceID, c.Repository)
+		if err != nil {
+			return false, err
+		}
+		return instance.PlatformID == types.SMPlatform, nil
+	default:
+		return true, nil
+	}
+}
+
+// checkLabels verifies that the added label keys are defined and that the labels satisfy the
+// definitions of the resource type. Resource types without label definitions accept any labels.
+// Required labels are enforced only if enforceRequired is set.
+func (c *labelDefinitionsInterceptor) checkLabels(ctx context.Context, objectType types.ObjectType, labels types.Labels, addedKeys []string, enforceRequired bool) error {
+	list, err := c.Repository.List(ctx, types.LabelDefinitionType, query.ByField(query.EqualsOperator, "resource_type", string(objectType)))
+	if err != nil {
+		return fmt.Errorf("could not get label definitions for %s: %s", objectType, err)
+	}
+	if list.Len() == 0 {
+		return nil
+	}
+
+	definitions := make(map[string]*types.LabelDefinition, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		definition := list.ItemAt(i).(*types.LabelDefinition)
+		definitions[definition.Key] = definition
+	}
+
+	for _, key := range addedKeys {
+		if _, found := definitions[key]; !found && !c.isIgnored(key) {
+			return newLabelDefinitionError(fmt.Sprintf("label %s is not defined for %s. Defined labels are: %s", key, objectType, definedKeys(definitions)))
+		}
+	}
+	for key, definition := range definitions {
+		if len(labels[key]) == 0 && !enforceRequired {
+			continue
+		}
+		if err := definition.ValidateValues(labels[key]); err != nil {
+			return newLabelDefinitionError(err.Error())
+		}
+	}
+	return nil
+}
+
+func (c *labelDefinitionsInterceptor) isIgnored(key string) bool {
+	for _, ignoredKey := range c.IgnoredKeys {
+		if ignoredKey == key {
+			return true
+		}
+	}
+	return false
+}
+
+func definedKeys(definitions map[string]*types.LabelDefinition) string {
+	keys := make([]string, 0, len(definitions))
+	for key := range definitions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ", ")
+}
+
+func newLabelDefinitionError(description string) error {
+	return &util.HTTPError{
+		ErrorType:   "BadRequest",
+		Description: description,
+		StatusCode:  http.StatusBadRequest,
+	}
+}
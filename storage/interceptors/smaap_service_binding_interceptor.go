@@ -23,6 +23,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/Peripli/service-manager/pkg/brokerclient"
 	"github.com/Peripli/service-manager/pkg/log"
 
 	"github.com/Peripli/service-manager/operations"
@@ -84,7 +85,7 @@ func (c *ServiceBindingDeleteInterceptorProvider) Name() string {
 }
 
 type ServiceBindingInterceptor struct {
-	osbClientCreateFunc osbc.CreateFunc
+	osbClientCreateFunc brokerclient.BrokerClientFunc
 	repository          storage.TransactionalRepository
 	tenantKey           string
 	pollingInterval     time.Duration
@@ -157,24 +158,9 @@ func (i *ServiceBindingInterceptor) AroundTxCreate(f storage.InterceptCreateArou
 			log.C(ctx).Infof("Sending bind request %s to broker with name %s", logBindRequest(bindRequest), broker.Name)
 			bindResponse, err = osbClient.Bind(bindRequest)
 			if err != nil {
-				brokerError := &util.HTTPError{
-					ErrorType:   "BrokerError",
-					Description: fmt.Sprintf("Failed bind request %s: %s", logBindRequest(bindRequest), err),
-					StatusCode:  http.StatusBadGateway,
-				}
+				brokerError := brokerCallError(err, fmt.Sprintf("Failed bind request %s: %s", logBindRequest(bindRequest), err))
 				if shouldStartOrphanMitigation(err) {
-					// store the instance so that later on we can do orphan mitigation
-					_, err := f(ctx, obj)
-					if err != nil {
-						return nil, fmt.Errorf("broker error %s caused orphan mitigation which required storing the resource which failed with: %s", brokerError, err)
-					}
-
-					// mark the operation as deletion scheduled meaning orphan mitigation is required
-					operation.DeletionScheduled = time.Now()
-					operation.Reschedule = false
-					if _, err := i.repository.Update(ctx, operation, query.LabelChanges{}); err != nil {
-						return nil, fmt.Errorf("failed to update operation with id %s to schedule orphan mitigation after broker error %s: %s", operation.ID, brokerError, err)
-					}
+					return nil, i.scheduleOrphanMitigation(ctx, f, binding, operation, brokerError)
 				}
 				return nil, brokerError
 			}
@@ -192,6 +178,14 @@ func (i *ServiceBindingInterceptor) AroundTxCreate(f storage.InterceptCreateArou
 			if bindResponse.Async {
 				log.C(ctx).Infof("Successful asynchronous binding request %s to broker %s returned response %s",
 					logBindRequest(bindRequest), broker.Name, logBindResponse(bindResponse))
+				if !service.BindingsRetrievable {
+					// the credentials of the binding can only be fetched from brokers whose bindings are retrievable
+					return nil, i.scheduleOrphanMitigation(ctx, f, binding, operation, &util.HTTPError{
+						ErrorType:   "BrokerError",
+						Description: fmt.Sprintf("Failed bind request %s: broker %s responded asynchronously although bindings of service %s are not retrievable", logBindRequest(bindRequest), broker.Name, service.CatalogName),
+						StatusCode:  http.StatusBadGateway,
+					})
+				}
 				operation.Reschedule = true
 				if bindResponse.OperationKey != nil {
 					operation.ExternalID = string(*bindResponse.OperationKey)
@@ -212,7 +206,7 @@ func (i *ServiceBindingInterceptor) AroundTxCreate(f storage.InterceptCreateArou
 		}
 
 		if operation.Reschedule {
-			if err := i.pollServiceBinding(ctx, osbClient, binding, operation, broker.ID, service.CatalogID, plan.CatalogID, operation.ExternalID, true); err != nil {
+			if err := i.pollServiceBinding(ctx, osbClient, binding, operation, broker.ID, service.CatalogID, plan.CatalogID, types.CREATE, true); err != nil {
 				return nil, err
 			}
 		}
@@ -275,11 +269,7 @@ func (i *ServiceBindingInterceptor) deleteSingleBinding(ctx context.Context, bin
 					logUnbindRequest(unbindRequest), broker.Name)
 				return nil
 			}
-			brokerError := &util.HTTPError{
-				ErrorType:   "BrokerError",
-				Description: fmt.Sprintf("Failed unbind request %s: %s", logUnbindRequest(unbindRequest), err),
-				StatusCode:  http.StatusBadGateway,
-			}
+			brokerError := brokerCallError(err, fmt.Sprintf("Failed unbind request %s: %s", logUnbindRequest(unbindRequest), err))
 			if shouldStartOrphanMitigation(err) {
 				operation.DeletionScheduled = time.Now()
 				operation.Reschedule = false
@@ -295,6 +285,8 @@ func (i *ServiceBindingInterceptor) deleteSingleBinding(ctx context.Context, bin
 				logUnbindRequest(unbindRequest), broker.Name, logUnbindResponse(unbindResponse))
 			operation.Reschedule = true
 
+			// the unbind of an orphan mitigation must not be polled with the operation key of the failed bind
+			operation.ExternalID = ""
 			if unbindResponse.OperationKey != nil {
 				operation.ExternalID = string(*unbindResponse.OperationKey)
 			}
@@ -308,7 +300,7 @@ func (i *ServiceBindingInterceptor) deleteSingleBinding(ctx context.Context, bin
 	}
 
 	if operation.Reschedule {
-		if err := i.pollServiceBinding(ctx, osbClient, binding, operation, broker.ID, service.CatalogID, plan.CatalogID, operation.ExternalID, true); err != nil {
+		if err := i.pollServiceBinding(ctx, osbClient, binding, operation, broker.ID, service.CatalogID, plan.CatalogID, types.DELETE, true); err != nil {
 			return err
 		}
 	}
@@ -316,6 +308,23 @@ func (i *ServiceBindingInterceptor) deleteSingleBinding(ctx context.Context, bin
 	return nil
 }
 
+// scheduleOrphanMitigation stores the binding and marks its operation as deletion scheduled, so that the binding is
+// unbound from the broker after the broker error
+func (i *ServiceBindingInterceptor) scheduleOrphanMitigation(ctx context.Context, f storage.InterceptCreateAroundTxFunc, binding *types.ServiceBinding, operation *types.Operation, brokerError error) error {
+	// store the binding so that later on we can do orphan mitigation
+	if _, err := f(ctx, binding); err != nil {
+		return fmt.Errorf("broker error %s caused orphan mitigation which required storing the resource which failed with: %s", brokerError, err)
+	}
+
+	// mark the operation as deletion scheduled meaning orphan mitigation is required
+	operation.DeletionScheduled = time.Now()
+	operation.Reschedule = false
+	if _, err := i.repository.Update(ctx, operation, query.LabelChanges{}); err != nil {
+		return fmt.Errorf("failed to update operation with id %s to schedule orphan mitigation after broker error %s: %s", operation.ID, brokerError, err)
+	}
+	return brokerError
+}
+
 func (i *ServiceBindingInterceptor) isPlanBindable(service *types.ServiceOffering, plan *types.ServicePlan) bool {
 	if plan.Bindable != nil {
 		return *plan.Bindable
@@ -427,7 +436,9 @@ func prepareUnbindRequest(instance *types.ServiceInstance, binding *types.Servic
 	return unbindRequest
 }
 
-func (i *ServiceBindingInterceptor) pollServiceBinding(ctx context.Context, osbClient osbc.Client, binding *types.ServiceBinding, operation *types.Operation, brokerID, serviceCatalogID, planCatalogID, operationKey string, enableOrphanMitigation bool) error {
+// pollServiceBinding polls the last operation of the bind or unbind of the binding, which is denoted by category. The operation
+// may be of another category, e.g. the unbind of an orphan mitigation is polled with the failed create operation.
+func (i *ServiceBindingInterceptor) pollServiceBinding(ctx context.Context, osbClient osbc.Client, binding *types.ServiceBinding, operation *types.Operation, brokerID, serviceCatalogID, planCatalogID string, category types.OperationCategory, enableOrphanMitigation bool) error {
 	var key *osbc.OperationKey
 	if len(operation.ExternalID) != 0 {
 		opKey := osbc.OperationKey(operation.ExternalID)
@@ -444,20 +455,20 @@ func (i *ServiceBindingInterceptor) pollServiceBinding(ctx context.Context, osbC
 		OriginatingIdentity: nil,
 	}
 
-	ticker := time.NewTicker(i.pollingInterval)
-	defer ticker.Stop()
+	timer := time.NewTimer(i.pollingInterval)
+	defer timer.Stop()
 	for {
 		select {
 		case <-ctx.Done():
 			log.C(ctx).Errorf("Terminating poll last operation for binding with id %s and name %s due to context done event", binding.ID, binding.Name)
 			//operation should be kept in progress in this case
 			return nil
-		case <-ticker.C:
+		case <-timer.C:
 			log.C(ctx).Infof("Sending poll last operation request %s for binding with id %s and name %s",
 				logPollBindingRequest(pollingRequest), binding.ID, binding.Name)
-			pollingResponse, err := osbClient.PollBindingLastOperation(pollingRequest)
+			pollingResponse, err := pollBindingLastOperation(ctx, osbClient, pollingRequest)
 			if err != nil {
-				if osbc.IsGoneError(err) && operation.Type == types.DELETE {
+				if osbc.IsGoneError(err) && category == types.DELETE {
 					log.C(ctx).Infof("Successfully finished polling operation for binding with id %s and name %s", binding.ID, binding.Name)
 
 					operation.Reschedule = false
@@ -467,12 +478,8 @@ func (i *ServiceBindingInterceptor) pollServiceBinding(ctx context.Context, osbC
 					return nil
 				}
 
-				return &util.HTTPError{
-					ErrorType: "BrokerError",
-					Description: fmt.Sprintf("Failed poll last operation request %s for binding with id %s and name %s: %s",
-						logPollBindingRequest(pollingRequest), binding.ID, binding.Name, err),
-					StatusCode: http.StatusBadGateway,
-				}
+				return brokerCallError(err, fmt.Sprintf("Failed poll last operation request %s for binding with id %s and name %s: %s",
+					logPollBindingRequest(pollingRequest), binding.ID, binding.Name, err))
 			}
 
 			switch pollingResponse.State {
@@ -489,7 +496,7 @@ func (i *ServiceBindingInterceptor) pollServiceBinding(ctx context.Context, osbC
 				}
 
 				// for async creation of bindings, an extra fetching of the binding is required to get the credentials
-				if operation.Type == types.CREATE {
+				if category == types.CREATE {
 					bindingDetails, err := i.getBindingDetailsFromBroker(ctx, binding, operation, brokerID, osbClient)
 					if err != nil {
 						return err
@@ -504,7 +511,8 @@ func (i *ServiceBindingInterceptor) pollServiceBinding(ctx context.Context, osbC
 				log.C(ctx).Infof("Failed polling operation for binding with id %s and name %s with response %s",
 					binding.ID, binding.Name, logPollBindingResponse(pollingResponse))
 				operation.Reschedule = false
-				if enableOrphanMitigation {
+				// a failed unbind of an orphan mitigation keeps the time at which the orphan mitigation was scheduled
+				if enableOrphanMitigation && operation.DeletionScheduled.IsZero() {
 					operation.DeletionScheduled = time.Now()
 				}
 				if _, err := i.repository.Update(ctx, operation, query.LabelChanges{}); err != nil {
@@ -525,10 +533,23 @@ func (i *ServiceBindingInterceptor) pollServiceBinding(ctx context.Context, osbC
 			default:
 				log.C(ctx).Errorf("invalid state during poll last operation for binding with id %s and name %s: %s", binding.ID, binding.Name, pollingResponse.State)
 			}
+			timer.Reset(nextPollingInterval(i.pollingInterval, pollingResponse))
 		}
 	}
 }
 
+// pollBindingLastOperation polls the last operation of a binding with the hints of OSB API 2.15 if the client supports them
+func pollBindingLastOperation(ctx context.Context, osbClient osbc.Client, request *osbc.BindingLastOperationRequest) (*brokerclient.LastOperationResponse, error) {
+	if poller, ok := osbClient.(brokerclient.LastOperationPoller); ok {
+		return poller.PollBindingLastOperationWithHints(ctx, request)
+	}
+	response, err := osbClient.PollBindingLastOperation(request)
+	if err != nil {
+		return nil, err
+	}
+	return &brokerclient.LastOperationResponse{LastOperationResponse: *response}, nil
+}
+
 func (i *ServiceBindingInterceptor) getBindingDetailsFromBroker(ctx context.Context, binding *types.ServiceBinding, operation *types.Operation, brokerID string, osbClient osbc.Client) (*bindResponseDetails, error) {
 	getBindingRequest := &osbc.GetBindingRequest{
 		InstanceID: binding.ServiceInstanceID,
@@ -537,19 +558,13 @@ func (i *ServiceBindingInterceptor) getBindingDetailsFromBroker(ctx context.Cont
 	log.C(ctx).Infof("Sending get binding request %s to broker with id %s", logGetBindingRequest(getBindingRequest), brokerID)
 	bindingResponse, err := osbClient.GetBinding(getBindingRequest)
 	if err != nil {
-		brokerError := &util.HTTPError{
-			ErrorType:   "BrokerError",
-			Description: fmt.Sprintf("Failed get bind request %s after successfully finished polling: %s", logGetBindingRequest(getBindingRequest), err),
-			StatusCode:  http.StatusBadGateway,
-		}
-		if shouldStartOrphanMitigation(err) {
-			// mark the operation as deletion scheduled meaning orphan mitigation is required
-			operation.DeletionScheduled = time.Now()
-			operation.Reschedule = false
-			if _, err := i.repository.Update(ctx, operation, query.LabelChanges{}); err != nil {
-				return nil, fmt.Errorf("failed to update operation with id %s to schedule orphan mitigation after broker error %s: %s",
-					operation.ID, brokerError, err)
-			}
+		brokerError := brokerCallError(err, fmt.Sprintf("Failed get bind request %s after successfully finished polling: %s", logGetBindingRequest(getBindingRequest), err))
+		// the binding was created by the broker, so it is orphan mitigated regardless of the error
+		operation.DeletionScheduled = time.Now()
+		operation.Reschedule = false
+		if _, err := i.repository.Update(ctx, operation, query.LabelChanges{}); err != nil {
+			return nil, fmt.Errorf("failed to update operation with id %s to schedule orphan mitigation after broker error %s: %s",
+				operation.ID, brokerError, err)
 		}
 		return nil, brokerError
 	}
@@ -618,7 +633,7 @@ func logPollBindingRequest(request *osbc.BindingLastOperationRequest) string {
 		request.BindingID, request.InstanceID, strPtrToStr(request.PlanID), strPtrToStr(request.ServiceID), opKeyPtrToStr(request.OperationKey))
 }
 
-func logPollBindingResponse(response *osbc.LastOperationResponse) string {
+func logPollBindingResponse(response *brokerclient.LastOperationResponse) string {
 	return fmt.Sprintf("state: %s, description: %s", response.State, strPtrToStr(response.Description))
 }
 
@@ -22,9 +22,11 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/Peripli/service-manager/operations"
+	"github.com/Peripli/service-manager/pkg/brokerclient"
 
 	"github.com/Peripli/service-manager/pkg/util"
 
@@ -34,14 +36,26 @@ import (
 
 	"github.com/Peripli/service-manager/pkg/types"
 	osbc "github.com/kubernetes-sigs/go-open-service-broker-client/v2"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
 
 	"github.com/Peripli/service-manager/storage"
 )
 
 const ServiceInstanceCreateInterceptorProviderName = "ServiceInstanceCreateInterceptorProvider"
 
+const (
+	// smServicePlanIDKey and smContextKey are the keys of the previous plan and context of an updated instance
+	// as stored by the OSB API
+	smServicePlanIDKey = "sm_service_plan_id"
+	smContextKey       = "sm_context_key"
+)
+
+// instanceUpdateInProgressKey marks the context of an instance update which is already sent to the broker
+type instanceUpdateInProgressKey struct{}
+
 type BaseSMAAPInterceptorProvider struct {
-	OSBClientCreateFunc osbc.CreateFunc
+	OSBClientCreateFunc brokerclient.BrokerClientFunc
 	Repository          storage.TransactionalRepository
 	TenantKey           string
 	PollingInterval     time.Duration
@@ -106,7 +120,7 @@ func (c *ServiceInstanceDeleteInterceptorProvider) Name() string {
 }
 
 type ServiceInstanceInterceptor struct {
-	osbClientCreateFunc osbc.CreateFunc
+	osbClientCreateFunc brokerclient.BrokerClientFunc
 	repository          storage.TransactionalRepository
 	tenantKey           string
 	pollingInterval     time.Duration
@@ -140,11 +154,7 @@ func (i *ServiceInstanceInterceptor) AroundTxCreate(f storage.InterceptCreateAro
 			log.C(ctx).Infof("Sending provision request %s to broker with name %s", logProvisionRequest(provisionRequest), broker.Name)
 			provisionResponse, err = osbClient.ProvisionInstance(provisionRequest)
 			if err != nil {
-				brokerError := &util.HTTPError{
-					ErrorType:   "BrokerError",
-					Description: fmt.Sprintf("Failed provisioning request %s: %s", logProvisionRequest(provisionRequest), err),
-					StatusCode:  http.StatusBadGateway,
-				}
+				brokerError := brokerCallError(err, fmt.Sprintf("Failed provisioning request %s: %s", logProvisionRequest(provisionRequest), err))
 				if shouldStartOrphanMitigation(err) {
 					// store the instance so that later on we can do orphan mitigation
 					_, err := f(ctx, obj)
@@ -201,9 +211,134 @@ func (i *ServiceInstanceInterceptor) AroundTxCreate(f storage.InterceptCreateAro
 	}
 }
 
-// TODO Update of instances in SM is not yet implemented
 func (i *ServiceInstanceInterceptor) AroundTxUpdate(h storage.InterceptUpdateAroundTxFunc) storage.InterceptUpdateAroundTxFunc {
-	return h
+	return func(ctx context.Context, obj types.Object, labelChanges ...*query.LabelChange) (types.Object, error) {
+		instance := obj.(*types.ServiceInstance)
+
+		// updates which the interceptor itself does while updating the instance are not sent to the broker again
+		if inProgress, _ := ctx.Value(instanceUpdateInProgressKey{}).(bool); inProgress || instance.PlatformID != types.SMPlatform {
+			return h(ctx, obj, labelChanges...)
+		}
+
+		operation, found := operations.GetFromContext(ctx)
+		if !found || operation.Type != types.UPDATE || operation.ResourceID != instance.ID {
+			return h(ctx, obj, labelChanges...)
+		}
+		ctx = context.WithValue(ctx, instanceUpdateInProgressKey{}, true)
+
+		oldInstanceObject, err := i.repository.Get(ctx, types.ServiceInstanceType, query.ByField(query.EqualsOperator, "id", instance.ID))
+		if err != nil {
+			return nil, util.HandleStorageError(err, types.ServiceInstanceType.String())
+		}
+		oldInstance := oldInstanceObject.(*types.ServiceInstance)
+
+		oldMaintenanceInfo, err := types.ParseMaintenanceInfo(oldInstance.MaintenanceInfo)
+		if err != nil {
+			log.C(ctx).Warnf("Ignoring invalid maintenance info of instance with id %s: %s", instance.ID, err)
+		}
+		maintenanceInfo := oldMaintenanceInfo
+		if string(instance.MaintenanceInfo) != string(oldInstance.MaintenanceInfo) {
+			if maintenanceInfo, err = types.ParseMaintenanceInfo(instance.MaintenanceInfo); err != nil {
+				return nil, &util.HTTPError{
+					ErrorType:   "BadRequest",
+					Description: err.Error(),
+					StatusCode:  http.StatusBadRequest,
+				}
+			}
+		}
+		maintenanceInfoChanged := maintenanceInfo != nil && (oldMaintenanceInfo == nil || maintenanceInfo.Version != oldMaintenanceInfo.Version)
+
+		if !operation.Reschedule && !maintenanceInfoChanged && len(instance.Parameters) == 0 && instance.ServicePlanID == oldInstance.ServicePlanID {
+			log.C(ctx).Debugf("Update of instance with id %s does not change its plan, parameters or maintenance info and is not sent to the broker", instance.ID)
+			return h(ctx, obj, labelChanges...)
+		}
+
+		osbClient, broker, service, plan, err := preparePrerequisites(ctx, i.repository, i.osbClientCreateFunc, instance)
+		if err != nil {
+			return nil, err
+		}
+
+		if !operation.Reschedule {
+			if maintenanceInfoChanged {
+				planMaintenanceInfo, err := types.ParseMaintenanceInfo(plan.MaintenanceInfo)
+				if err != nil || planMaintenanceInfo == nil || planMaintenanceInfo.Version != maintenanceInfo.Version {
+					return nil, &util.HTTPError{
+						ErrorType:   "BadRequest",
+						Description: fmt.Sprintf("maintenance_info version %s does not match the maintenance_info of plan with id %s", maintenanceInfo.Version, plan.ID),
+						StatusCode:  http.StatusBadRequest,
+					}
+				}
+			}
+
+			oldPlan := plan
+			if oldInstance.ServicePlanID != instance.ServicePlanID {
+				oldPlanObject, err := i.repository.Get(ctx, types.ServicePlanType, query.ByField(query.EqualsOperator, "id", oldInstance.ServicePlanID))
+				if err != nil {
+					return nil, util.HandleStorageError(err, types.ServicePlanType.String())
+				}
+				oldPlan = oldPlanObject.(*types.ServicePlan)
+				if oldPlan.ServiceOfferingID != plan.ServiceOfferingID {
+					return nil, &util.HTTPError{
+						ErrorType:   "BadRequest",
+						Description: fmt.Sprintf("plan with id %s does not belong to the service offering of instance with id %s", plan.ID, instance.ID),
+						StatusCode:  http.StatusBadRequest,
+					}
+				}
+			}
+
+			updateRequest, err := prepareUpdateRequest(instance, service.CatalogID, plan.CatalogID, oldPlan.CatalogID, maintenanceInfo, oldMaintenanceInfo)
+			if err != nil {
+				return nil, fmt.Errorf("failed to prepare update request: %s", err)
+			}
+			log.C(ctx).Infof("Sending update request %s to broker with name %s", logUpdateRequest(updateRequest), broker.Name)
+			updateResponse, err := updateInstance(ctx, osbClient, updateRequest)
+			if err != nil {
+				return nil, brokerCallError(err, fmt.Sprintf("Failed update request %s: %s", logUpdateRequest(updateRequest), err))
+			}
+
+			// keep the previous values so that the instance can be reverted if the asynchronous update fails
+			if instance.PreviousValues, err = previousInstanceValues(oldInstance); err != nil {
+				return nil, err
+			}
+
+			if updateResponse.Async {
+				log.C(ctx).Infof("Successful asynchronous update request %s to broker %s returned response %s",
+					logUpdateRequest(updateRequest), broker.Name, logUpdateResponse(updateResponse))
+				operation.Reschedule = true
+				if updateResponse.OperationKey != nil {
+					operation.ExternalID = string(*updateResponse.OperationKey)
+				}
+
+				if _, err := i.repository.Update(ctx, operation, query.LabelChanges{}); err != nil {
+					return nil, fmt.Errorf("failed to update operation with id %s to mark that next execution should be a reschedule: %s", operation.ID, err)
+				}
+			} else {
+				log.C(ctx).Infof("Successful synchronous update %s to broker %s returned response %s",
+					logUpdateRequest(updateRequest), broker.Name, logUpdateResponse(updateResponse))
+			}
+
+			object, err := h(ctx, obj, labelChanges...)
+			if err != nil {
+				return nil, err
+			}
+			instance = object.(*types.ServiceInstance)
+		}
+
+		if operation.Reschedule {
+			if err := i.pollServiceInstance(ctx, osbClient, instance, operation, broker.ID, service.CatalogID, plan.CatalogID, operation.ExternalID, true); err != nil {
+				// an instance which is kept after the failed update gets its previous values back so that the update
+				// can be repeated, an instance which is neither usable nor repeatable is deleted by the orphan mitigation
+				if operation.DeletionScheduled.IsZero() {
+					if rollbackErr := i.rollbackInstanceUpdate(ctx, instance); rollbackErr != nil {
+						log.C(ctx).Errorf("Could not revert instance with id %s after failed update: %s", instance.ID, rollbackErr)
+					}
+				}
+				return nil, err
+			}
+		}
+
+		return instance, nil
+	}
 }
 
 func (i *ServiceInstanceInterceptor) AroundTxDelete(f storage.InterceptDeleteAroundTxFunc) storage.InterceptDeleteAroundTxFunc {
@@ -278,11 +413,7 @@ func (i *ServiceInstanceInterceptor) deleteSingleInstance(ctx context.Context, i
 					logDeprovisionRequest(deprovisionRequest), broker.Name)
 				return nil
 			}
-			brokerError := &util.HTTPError{
-				ErrorType:   "BrokerError",
-				Description: fmt.Sprintf("Failed deprovisioning request %s: %s", logDeprovisionRequest(deprovisionRequest), err),
-				StatusCode:  http.StatusBadGateway,
-			}
+			brokerError := brokerCallError(err, fmt.Sprintf("Failed deprovisioning request %s: %s", logDeprovisionRequest(deprovisionRequest), err))
 
 			if shouldStartOrphanMitigation(err) {
 				operation.DeletionScheduled = time.Now()
@@ -336,17 +467,17 @@ func (i *ServiceInstanceInterceptor) pollServiceInstance(ctx context.Context, os
 		OriginatingIdentity: nil,
 	}
 
-	ticker := time.NewTicker(i.pollingInterval)
-	defer ticker.Stop()
+	timer := time.NewTimer(i.pollingInterval)
+	defer timer.Stop()
 	for {
 		select {
 		case <-ctx.Done():
 			log.C(ctx).Errorf("Terminating poll last operation for instance with id %s and name %s due to context done event", instance.ID, instance.Name)
 			//operation should be kept in progress in this case
 			return nil
-		case <-ticker.C:
+		case <-timer.C:
 			log.C(ctx).Infof("Sending poll last operation request %s for instance with id %s and name %s", logPollInstanceRequest(pollingRequest), instance.ID, instance.Name)
-			pollingResponse, err := osbClient.PollLastOperation(pollingRequest)
+			pollingResponse, err := pollLastOperation(ctx, osbClient, pollingRequest)
 			if err != nil {
 				if osbc.IsGoneError(err) && operation.Type == types.DELETE {
 					log.C(ctx).Infof("Successfully finished polling operation for instance with id %s and name %s", instance.ID, instance.Name)
@@ -358,12 +489,8 @@ func (i *ServiceInstanceInterceptor) pollServiceInstance(ctx context.Context, os
 					return nil
 				}
 
-				return &util.HTTPError{
-					ErrorType: "BrokerError",
-					Description: fmt.Sprintf("Failed poll last operation request %s for instance with id %s and name %s: %s",
-						logPollInstanceRequest(pollingRequest), instance.ID, instance.Name, err),
-					StatusCode: http.StatusBadGateway,
-				}
+				return brokerCallError(err, fmt.Sprintf("Failed poll last operation request %s for instance with id %s and name %s: %s",
+					logPollInstanceRequest(pollingRequest), instance.ID, instance.Name, err))
 			}
 			switch pollingResponse.State {
 			case osbc.StateInProgress:
@@ -382,12 +509,18 @@ func (i *ServiceInstanceInterceptor) pollServiceInstance(ctx context.Context, os
 			case osbc.StateFailed:
 				log.C(ctx).Infof("Failed polling operation for instance with id %s and name %s with response %s", instance.ID, instance.Name, logPollInstanceResponse(pollingResponse))
 				operation.Reschedule = false
-				if enableOrphanMitigation {
+				if enableOrphanMitigation && shouldMitigateOrphanAfterFailedOperation(operation.Type, pollingResponse) {
 					operation.DeletionScheduled = time.Now()
 				}
 				if _, err := i.repository.Update(ctx, operation, query.LabelChanges{}); err != nil {
 					return fmt.Errorf("failed to update operation with id %s after failed of last operation for instance with id %s: %s", operation.ID, instance.ID, err)
 				}
+				if pollingResponse.InstanceUsable != nil && operation.Type != types.CREATE && instance.Usable != *pollingResponse.InstanceUsable {
+					instance.Usable = *pollingResponse.InstanceUsable
+					if _, err := i.repository.Update(ctx, instance, query.LabelChanges{}); err != nil {
+						return fmt.Errorf("failed to update usability of instance with id %s after failed last operation: %s", instance.ID, err)
+					}
+				}
 
 				errDescription := ""
 				if pollingResponse.Description != nil {
@@ -403,11 +536,12 @@ func (i *ServiceInstanceInterceptor) pollServiceInstance(ctx context.Context, os
 			default:
 				log.C(ctx).Errorf("invalid state during poll last operation for instance with id %s and name %s: %s. Continuing polling...", instance.ID, instance.Name, pollingResponse.State)
 			}
+			timer.Reset(nextPollingInterval(i.pollingInterval, pollingResponse))
 		}
 	}
 }
 
-func preparePrerequisites(ctx context.Context, repository storage.Repository, osbClientFunc osbc.CreateFunc, instance *types.ServiceInstance) (osbc.Client, *types.ServiceBroker, *types.ServiceOffering, *types.ServicePlan, error) {
+func preparePrerequisites(ctx context.Context, repository storage.Repository, osbClientFunc brokerclient.BrokerClientFunc, instance *types.ServiceInstance) (osbc.Client, *types.ServiceBroker, *types.ServiceOffering, *types.ServicePlan, error) {
 	planObject, err := repository.Get(ctx, types.ServicePlanType, query.ByField(query.EqualsOperator, "id", instance.ServicePlanID))
 	if err != nil {
 		return nil, nil, nil, nil, util.HandleStorageError(err, types.ServicePlanType.String())
@@ -425,18 +559,7 @@ func preparePrerequisites(ctx context.Context, repository storage.Repository, os
 		return nil, nil, nil, nil, util.HandleStorageError(err, types.ServiceBrokerType.String())
 	}
 	broker := brokerObject.(*types.ServiceBroker)
-	osbClient, err := osbClientFunc(&osbc.ClientConfiguration{
-		Name:                broker.Name + " broker client",
-		EnableAlphaFeatures: true,
-		URL:                 broker.BrokerURL,
-		APIVersion:          osbc.LatestAPIVersion(),
-		AuthConfig: &osbc.AuthConfig{
-			BasicAuthConfig: &osbc.BasicAuthConfig{
-				Username: broker.Credentials.Basic.Username,
-				Password: broker.Credentials.Basic.Password,
-			},
-		},
-	})
+	osbClient, err := osbClientFunc(broker)
 	if err != nil {
 		return nil, nil, nil, nil, err
 	}
@@ -485,6 +608,81 @@ func (i *ServiceInstanceInterceptor) prepareProvisionRequest(instance *types.Ser
 	return provisionRequest, nil
 }
 
+func prepareUpdateRequest(instance *types.ServiceInstance, serviceCatalogID, planCatalogID, oldPlanCatalogID string, maintenanceInfo, oldMaintenanceInfo *types.MaintenanceInfo) (*brokerclient.UpdateInstanceRequest, error) {
+	context := make(map[string]interface{})
+	if len(instance.Context) != 0 {
+		if err := json.Unmarshal(instance.Context, &context); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal already present OSB context: %s", err)
+		}
+	}
+
+	updateRequest := &brokerclient.UpdateInstanceRequest{
+		UpdateInstanceRequest: osbc.UpdateInstanceRequest{
+			InstanceID:        instance.ID,
+			AcceptsIncomplete: true,
+			ServiceID:         serviceCatalogID,
+			Parameters:        instance.Parameters,
+			Context:           context,
+			PreviousValues: &osbc.PreviousValues{
+				PlanID:    oldPlanCatalogID,
+				ServiceID: serviceCatalogID,
+			},
+			//TODO no OI for SM platform yet
+			OriginatingIdentity: nil,
+		},
+		PreviousMaintenanceInfo: oldMaintenanceInfo,
+	}
+	if planCatalogID != oldPlanCatalogID {
+		updateRequest.PlanID = &planCatalogID
+	}
+	if maintenanceInfo != nil && (oldMaintenanceInfo == nil || maintenanceInfo.Version != oldMaintenanceInfo.Version) {
+		updateRequest.MaintenanceInfo = maintenanceInfo
+	}
+
+	return updateRequest, nil
+}
+
+// previousInstanceValues returns the values of the instance which are reverted if an update fails
+// in the format of the previous values stored by the OSB API
+func previousInstanceValues(oldInstance *types.ServiceInstance) (json.RawMessage, error) {
+	previousValues := []byte("{}")
+	var err error
+	if previousValues, err = sjson.SetBytes(previousValues, smServicePlanIDKey, oldInstance.ServicePlanID); err != nil {
+		return nil, fmt.Errorf("failed to store previous plan of instance with id %s: %s", oldInstance.ID, err)
+	}
+	if len(oldInstance.Context) != 0 {
+		if previousValues, err = sjson.SetRawBytes(previousValues, smContextKey, oldInstance.Context); err != nil {
+			return nil, fmt.Errorf("failed to store previous context of instance with id %s: %s", oldInstance.ID, err)
+		}
+	}
+	if len(oldInstance.MaintenanceInfo) != 0 {
+		if previousValues, err = sjson.SetRawBytes(previousValues, "maintenance_info", oldInstance.MaintenanceInfo); err != nil {
+			return nil, fmt.Errorf("failed to store previous maintenance info of instance with id %s: %s", oldInstance.ID, err)
+		}
+	}
+	return previousValues, nil
+}
+
+// rollbackInstanceUpdate reverts the plan, context and maintenance info of an instance to their previous values
+func (i *ServiceInstanceInterceptor) rollbackInstanceUpdate(ctx context.Context, instance *types.ServiceInstance) error {
+	previousValues := instance.PreviousValues
+	if oldPlanID := gjson.GetBytes(previousValues, smServicePlanIDKey).String(); len(oldPlanID) != 0 {
+		instance.ServicePlanID = oldPlanID
+	}
+	if oldContext := gjson.GetBytes(previousValues, smContextKey).Raw; len(oldContext) != 0 {
+		instance.Context = []byte(oldContext)
+	}
+	instance.MaintenanceInfo = nil
+	if oldMaintenanceInfo := gjson.GetBytes(previousValues, "maintenance_info").Raw; len(oldMaintenanceInfo) != 0 {
+		instance.MaintenanceInfo = []byte(oldMaintenanceInfo)
+	}
+
+	if _, err := i.repository.Update(ctx, instance, query.LabelChanges{}); err != nil {
+		return util.HandleStorageError(err, types.ServiceInstanceType.String())
+	}
+	return nil
+}
+
 func prepareDeprovisionRequest(instance *types.ServiceInstance, serviceCatalogID, planCatalogID string) *osbc.DeprovisionRequest {
 	return &osbc.DeprovisionRequest{
 		InstanceID:        instance.ID,
@@ -496,6 +694,67 @@ func prepareDeprovisionRequest(instance *types.ServiceInstance, serviceCatalogID
 	}
 }
 
+// pollLastOperation polls the last operation of an instance with the hints of OSB API 2.15 if the client supports them
+func pollLastOperation(ctx context.Context, osbClient osbc.Client, request *osbc.LastOperationRequest) (*brokerclient.LastOperationResponse, error) {
+	if poller, ok := osbClient.(brokerclient.LastOperationPoller); ok {
+		return poller.PollLastOperationWithHints(ctx, request)
+	}
+	response, err := osbClient.PollLastOperation(request)
+	if err != nil {
+		return nil, err
+	}
+	return &brokerclient.LastOperationResponse{LastOperationResponse: *response}, nil
+}
+
+// updateInstance updates an instance including its maintenance info if the client supports OSB API 2.15,
+// otherwise only the plan and parameters of the instance are updated
+func updateInstance(ctx context.Context, osbClient osbc.Client, request *brokerclient.UpdateInstanceRequest) (*osbc.UpdateInstanceResponse, error) {
+	if updater, ok := osbClient.(brokerclient.InstanceUpdater); ok {
+		return updater.UpdateInstanceWithMaintenanceInfo(ctx, request)
+	}
+	return osbClient.UpdateInstance(&request.UpdateInstanceRequest)
+}
+
+// nextPollingInterval returns the polling interval unless the broker asked to wait longer with Retry-After
+func nextPollingInterval(pollingInterval time.Duration, response *brokerclient.LastOperationResponse) time.Duration {
+	if response.RetryAfter > pollingInterval {
+		return response.RetryAfter
+	}
+	return pollingInterval
+}
+
+// shouldMitigateOrphanAfterFailedOperation decides based on the hints of OSB API 2.15 whether the resource of a
+// failed asynchronous operation has to be deleted or can be kept. A failed creation is always mitigated. A failed
+// update deletes the instance only if the broker reports that it is neither usable nor can the update be repeated,
+// otherwise the instance is kept with the usability returned by the broker. A failed deletion is retried unless
+// the instance is still usable.
+func shouldMitigateOrphanAfterFailedOperation(operationType types.OperationCategory, response *brokerclient.LastOperationResponse) bool {
+	switch operationType {
+	case types.CREATE:
+		return true
+	case types.UPDATE:
+		return response.InstanceUsable != nil && !*response.InstanceUsable &&
+			response.UpdateRepeatable != nil && !*response.UpdateRepeatable
+	case types.DELETE:
+		return response.InstanceUsable == nil || !*response.InstanceUsable
+	default:
+		return false
+	}
+}
+
+// brokerCallError wraps the error of a call to a broker as a bad gateway error unless the call failed fast because the
+// broker is unavailable, in which case the error is kept so that the client is told when to retry
+func brokerCallError(err error, description string) error {
+	if brokerclient.IsUnavailableError(err) {
+		return err
+	}
+	return &util.HTTPError{
+		ErrorType:   "BrokerError",
+		Description: description,
+		StatusCode:  http.StatusBadGateway,
+	}
+}
+
 func shouldStartOrphanMitigation(err error) bool {
 	if httpError, ok := osbc.IsHTTPError(err); ok {
 		statusCode := httpError.StatusCode
@@ -522,6 +781,19 @@ func logProvisionResponse(response *osbc.ProvisionResponse) string {
 	return fmt.Sprintf("async: %t, dashboardURL: %s, operationKey: %s", response.Async, strPtrToStr(response.DashboardURL), opKeyPtrToStr(response.OperationKey))
 }
 
+func logUpdateRequest(request *brokerclient.UpdateInstanceRequest) string {
+	maintenanceInfoVersion := ""
+	if request.MaintenanceInfo != nil {
+		maintenanceInfoVersion = request.MaintenanceInfo.Version
+	}
+	return fmt.Sprintf("context: %+v, instanceID: %s, planID: %s, serviceID: %s, maintenanceInfoVersion: %s, acceptsIncomplete: %t",
+		request.Context, request.InstanceID, strPtrToStr(request.PlanID), request.ServiceID, maintenanceInfoVersion, request.AcceptsIncomplete)
+}
+
+func logUpdateResponse(response *osbc.UpdateInstanceResponse) string {
+	return fmt.Sprintf("async: %t, operationKey: %s", response.Async, opKeyPtrToStr(response.OperationKey))
+}
+
 func logDeprovisionRequest(request *osbc.DeprovisionRequest) string {
 	return fmt.Sprintf("instanceID: %s, planID: %s, serviceID: %s, acceptsIncomplete: %t",
 		request.InstanceID, request.PlanID, request.ServiceID, request.AcceptsIncomplete)
@@ -536,8 +808,17 @@ func logPollInstanceRequest(request *osbc.LastOperationRequest) string {
 		request.InstanceID, strPtrToStr(request.PlanID), strPtrToStr(request.ServiceID), opKeyPtrToStr(request.OperationKey))
 }
 
-func logPollInstanceResponse(response *osbc.LastOperationResponse) string {
-	return fmt.Sprintf("state: %s, description: %s", response.State, strPtrToStr(response.Description))
+func logPollInstanceResponse(response *brokerclient.LastOperationResponse) string {
+	return fmt.Sprintf("state: %s, description: %s, instance_usable: %s, update_repeatable: %s",
+		response.State, strPtrToStr(response.Description), boolPtrToStr(response.InstanceUsable), boolPtrToStr(response.UpdateRepeatable))
+}
+
+func boolPtrToStr(bPtr *bool) string {
+	if bPtr == nil {
+		return ""
+	}
+
+	return strconv.FormatBool(*bPtr)
 }
 
 func strPtrToStr(sPtr *string) string {
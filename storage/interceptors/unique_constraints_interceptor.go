@@ -0,0 +1,151 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package interceptors
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const (
+	UniqueConstraintsCreateInterceptorName = "UniqueConstraintsCreateInterceptor"
+	UniqueConstraintsUpdateInterceptorName = "UniqueConstraintsUpdateInterceptor"
+)
+
+// UniqueConstraintsCreateInterceptorProvider provides an interceptor that rejects the creation of objects violating
+// the unique constraints before any other interceptors, e.g. calls to brokers, are run. The constraints are guaranteed
+// by the storage, so the interceptor only fails early.
+type UniqueConstraintsCreateInterceptorProvider struct {
+	Constraints []storage.UniqueConstraint
+	Repository  storage.TransactionalRepository
+}
+
+func (c *UniqueConstraintsCreateInterceptorProvider) Name() string {
+	return UniqueConstraintsCreateInterceptorName
+}
+
+func (c *UniqueConstraintsCreateInterceptorProvider) Provide() storage.CreateAroundTxInterceptor {
+	return &uniqueConstraintsInterceptor{
+		Constraints: c.Constraints,
+		Repository:  c.Repository,
+	}
+}
+
+// UniqueConstraintsUpdateInterceptorProvider provides an interceptor that rejects the updates of objects violating
+// the unique constraints before any other interceptors are run
+type UniqueConstraintsUpdateInterceptorProvider struct {
+	Constraints []storage.UniqueConstraint
+	Repository  storage.TransactionalRepository
+}
+
+func (c *UniqueConstraintsUpdateInterceptorProvider) Name() string {
+	return UniqueConstraintsUpdateInterceptorName
+}
+
+func (c *UniqueConstraintsUpdateInterceptorProvider) Provide() storage.UpdateAroundTxInterceptor {
+	return &uniqueConstraintsInterceptor{
+		Constraints: c.Constraints,
+		Repository:  c.Repository,
+	}
+}
+
+type uniqueConstraintsInterceptor struct {
+	Constraints []storage.UniqueConstraint
+	Repository  storage.TransactionalRepository
+}
+
+func (c *uniqueConstraintsInterceptor) AroundTxCreate(h storage.InterceptCreateAroundTxFunc) storage.InterceptCreateAroundTxFunc {
+	return func(ctx context.Context, obj types.Object) (types.Object, error) {
+		for i := range c.Constraints {
+			if err := c.checkUnique(ctx, &c.Constraints[i], obj, obj.GetLabels()); err != nil {
+				return nil, err
+			}
+		}
+		return h(ctx, obj)
+	}
+}
+
+func (c *uniqueConstraintsInterceptor) AroundTxUpdate(h storage.InterceptUpdateAroundTxFunc) storage.InterceptUpdateAroundTxFunc {
+	return func(ctx context.Context, newObj types.Object, labelChanges ...*query.LabelChange) (types.Object, error) {
+		oldObj, err := c.Repository.Get(ctx, newObj.GetType(), query.ByField(query.EqualsOperator, "id", newObj.GetID()))
+		if err != nil {
+			return nil, util.HandleStorageError(err, string(newObj.GetType()))
+		}
+		labels, _, _ := query.ApplyLabelChangesToLabels(labelChanges, oldObj.GetLabels())
+		for i := range c.Constraints {
+			constraint := &c.Constraints[i]
+			if constraint.ObjectType != newObj.GetType() {
+				continue
+			}
+			changed, err := c.keyChanged(constraint, oldObj, newObj, labels)
+			if err != nil {
+				return nil, err
+			}
+			if !changed {
+				continue
+			}
+			if err := c.checkUnique(ctx, constraint, newObj, labels); err != nil {
+				return nil, err
+			}
+		}
+		return h(ctx, newObj, labelChanges...)
+	}
+}
+
+// keyChanged returns true if the update changes the values which are unique according to the constraint
+func (c *uniqueConstraintsInterceptor) keyChanged(constraint *storage.UniqueConstraint, oldObj, newObj types.Object, newLabels types.Labels) (bool, error) {
+	oldCriteria, err := constraint.ConflictCriteria(oldObj, oldObj.GetLabels())
+	if err != nil {
+		return false, err
+	}
+	newCriteria, err := constraint.ConflictCriteria(newObj, newLabels)
+	if err != nil {
+		return false, err
+	}
+	return !reflect.DeepEqual(oldCriteria, newCriteria), nil
+}
+
+func (c *uniqueConstraintsInterceptor) checkUnique(ctx context.Context, constraint *storage.UniqueConstraint, obj types.Object, labels types.Labels) error {
+	if constraint.ObjectType != obj.GetType() {
+		return nil
+	}
+	applies, err := constraint.AppliesTo(obj)
+	if err != nil || !applies {
+		return err
+	}
+	criteria, err := constraint.ConflictCriteria(obj, labels)
+	if err != nil {
+		return err
+	}
+	count, err := c.Repository.Count(ctx, obj.GetType(), criteria...)
+	if err != nil {
+		return fmt.Errorf("could not get count of %s: %s", obj.GetType(), err)
+	}
+	if count > 0 {
+		return util.HandleStorageError(&util.ErrUniqueConstraintViolation{
+			Constraint:  constraint.Name,
+			Description: constraint.Description,
+		}, string(obj.GetType()))
+	}
+	return nil
+}
@@ -51,23 +51,43 @@ var (
 
 // Settings type to be loaded from the environment
 type Settings struct {
-	URI                string                `mapstructure:"uri" description:"URI of the storage"`
-	MigrationsURL      string                `mapstructure:"migrations_url" description:"location of a directory containing sql migrations scripts"`
-	EncryptionKey      string                `mapstructure:"encryption_key" description:"key to use for encrypting database entries"`
-	SkipSSLValidation  bool                  `mapstructure:"skip_ssl_validation" description:"whether to skip ssl verification when connecting to the storage"`
-	MaxIdleConnections int                   `mapstructure:"max_idle_connections" description:"sets the maximum number of connections in the idle connection pool"`
-	Notification       *NotificationSettings `mapstructure:"notification"`
+	URI                    string                `mapstructure:"uri" description:"URI of the storage"`
+	MigrationsURL          string                `mapstructure:"migrations_url" description:"location of a directory containing sql migrations scripts"`
+	AutoMigrate            bool                  `mapstructure:"auto_migrate" description:"whether to apply the pending sql migrations on startup. If disabled, the migrations are applied with the migrate command"`
+	MigrationsLockTimeout  time.Duration         `mapstructure:"migrations_lock_timeout" description:"how long to wait for another instance to finish applying sql migrations"`
+	EncryptionKey          string                `mapstructure:"encryption_key" description:"key to use for encrypting database entries"`
+	PreviousEncryptionKeys []string              `mapstructure:"previous_encryption_keys" description:"keys previously used for encrypting database entries which are still accepted for decryption during key rotation"`
+	SkipSSLValidation      bool                  `mapstructure:"skip_ssl_validation" description:"whether to skip ssl verification when connecting to the storage"`
+	MaxIdleConnections     int                   `mapstructure:"max_idle_connections" description:"sets the maximum number of connections in the idle connection pool"`
+	MaxOpenConnections     int                   `mapstructure:"max_open_connections" description:"sets the maximum number of open connections to the storage. If 0, the number of open connections is not limited"`
+	ConnectionMaxLifetime  time.Duration         `mapstructure:"connection_max_lifetime" description:"sets the maximum amount of time a connection to the storage may be reused. If 0, connections are reused forever"`
+	StatementTimeout       time.Duration         `mapstructure:"statement_timeout" description:"maximum duration of any statement executed by the storage. If 0, statements are not limited"`
+	SlowQueryThreshold     time.Duration         `mapstructure:"slow_query_threshold" description:"statements which take longer than this duration are logged. If 0, slow statements are not logged"`
+	Notification           *NotificationSettings `mapstructure:"notification"`
+	Partitioning           *PartitioningSettings `mapstructure:"partitioning"`
+	Changes                *ChangesSettings      `mapstructure:"changes"`
+	KMS                    *KMSSettings          `mapstructure:"kms"`
 }
 
 // DefaultSettings returns default values for storage settings
 func DefaultSettings() *Settings {
 	return &Settings{
-		URI:                "",
-		MigrationsURL:      fmt.Sprintf("file://%s/postgres/migrations", basepath),
-		EncryptionKey:      "",
-		SkipSSLValidation:  false,
-		MaxIdleConnections: 5,
-		Notification:       DefaultNotificationSettings(),
+		URI:                    "",
+		MigrationsURL:          fmt.Sprintf("file://%s/postgres/migrations", basepath),
+		AutoMigrate:            true,
+		MigrationsLockTimeout:  10 * time.Minute,
+		EncryptionKey:          "",
+		PreviousEncryptionKeys: []string{},
+		SkipSSLValidation:      false,
+		MaxIdleConnections:     5,
+		MaxOpenConnections:     0,
+		ConnectionMaxLifetime:  0,
+		StatementTimeout:       0,
+		SlowQueryThreshold:     time.Second,
+		Notification:           DefaultNotificationSettings(),
+		Partitioning:           DefaultPartitioningSettings(),
+		Changes:                DefaultChangesSettings(),
+		KMS:                    DefaultKMSSettings(),
 	}
 }
 
@@ -79,12 +99,96 @@ func (s *Settings) Validate() error {
 	if len(s.MigrationsURL) == 0 {
 		return fmt.Errorf("validate Settings: StorageMigrationsURL missing")
 	}
-	if len(s.EncryptionKey) != 32 {
+	if s.KMS != nil && s.KMS.Type != "" {
+		if len(s.EncryptionKey) != 0 {
+			return fmt.Errorf("validate Settings: StorageEncryptionKey must not be set when a key management service is configured. Move it to StoragePreviousEncryptionKeys")
+		}
+		if err := s.KMS.Validate(); err != nil {
+			return err
+		}
+	} else if len(s.EncryptionKey) != 32 {
 		return fmt.Errorf("validate Settings: StorageEncryptionKey must be exactly 32 symbols long but was %d symbols long", len(s.EncryptionKey))
 	}
+	if s.MaxOpenConnections < 0 {
+		return fmt.Errorf("validate Settings: StorageMaxOpenConnections must not be negative")
+	}
+	if s.ConnectionMaxLifetime < 0 {
+		return fmt.Errorf("validate Settings: StorageConnectionMaxLifetime must not be negative")
+	}
+	if s.StatementTimeout < 0 {
+		return fmt.Errorf("validate Settings: StorageStatementTimeout must not be negative")
+	}
+	if s.SlowQueryThreshold < 0 {
+		return fmt.Errorf("validate Settings: StorageSlowQueryThreshold must not be negative")
+	}
+	for i, previousKey := range s.PreviousEncryptionKeys {
+		if len(previousKey) != 32 {
+			return fmt.Errorf("validate Settings: StoragePreviousEncryptionKeys[%d] must be exactly 32 symbols long but was %d symbols long", i, len(previousKey))
+		}
+	}
+	if s.Partitioning != nil {
+		if err := s.Partitioning.Validate(); err != nil {
+			return err
+		}
+	}
+	if s.Changes != nil {
+		if err := s.Changes.Validate(); err != nil {
+			return err
+		}
+	}
 	return s.Notification.Validate()
 }
 
+// KMSTypeVault denotes a key management service implementing the Vault transit API
+const KMSTypeVault = "vault"
+
+// KMSSettings configures an external key management service which wraps the encryption keys instead of the storage encryption key
+type KMSSettings struct {
+	Type      string        `mapstructure:"type" description:"type of the key management service which wraps the encryption keys. Supported types: vault. If empty, the encryption keys are encrypted with the storage encryption key"`
+	Address   string        `mapstructure:"address" description:"base URL of the key management service"`
+	Token     string        `mapstructure:"token" description:"token used to authenticate to the key management service"`
+	MountPath string        `mapstructure:"mount_path" description:"path on which the transit secrets engine is mounted"`
+	KeyName   string        `mapstructure:"key_name" description:"name of the key in the key management service which wraps the encryption keys"`
+	Timeout   time.Duration `mapstructure:"timeout" description:"timeout of the requests to the key management service"`
+	CacheTTL  time.Duration `mapstructure:"cache_ttl" description:"how long unwrapped encryption keys are cached"`
+}
+
+// DefaultKMSSettings returns default values for the key management service settings
+func DefaultKMSSettings() *KMSSettings {
+	return &KMSSettings{
+		Type:      "",
+		Address:   "",
+		Token:     "",
+		MountPath: "transit",
+		KeyName:   "",
+		Timeout:   10 * time.Second,
+		CacheTTL:  time.Hour,
+	}
+}
+
+// Validate validates the key management service settings
+func (s *KMSSettings) Validate() error {
+	if s.Type != KMSTypeVault {
+		return fmt.Errorf("validate Settings: unsupported StorageKMSType %s", s.Type)
+	}
+	if len(s.Address) == 0 {
+		return fmt.Errorf("validate Settings: StorageKMSAddress missing")
+	}
+	if len(s.Token) == 0 {
+		return fmt.Errorf("validate Settings: StorageKMSToken missing")
+	}
+	if len(s.MountPath) == 0 {
+		return fmt.Errorf("validate Settings: StorageKMSMountPath missing")
+	}
+	if len(s.KeyName) == 0 {
+		return fmt.Errorf("validate Settings: StorageKMSKeyName missing")
+	}
+	if s.Timeout <= 0 {
+		return fmt.Errorf("validate Settings: StorageKMSTimeout must be positive")
+	}
+	return nil
+}
+
 // NotificationSettings type to be loaded from the environment
 type NotificationSettings struct {
 	QueuesSize           int           `mapstructure:"queues_size" description:"maximum number of notifications queued for sending to a client"`
@@ -126,6 +230,53 @@ func (s *NotificationSettings) Validate() error {
 	return nil
 }
 
+// PartitioningSettings configures the maintenance of the tables which are partitioned by creation time
+type PartitioningSettings struct {
+	Interval   time.Duration `mapstructure:"interval" description:"time range covered by a partition of the operations and notifications tables"`
+	Premake    int           `mapstructure:"premake" description:"number of partitions to create in advance"`
+	ArchiveDir string        `mapstructure:"archive_dir" description:"directory in which expired partitions are archived as compressed NDJSON files before they are dropped. If empty, expired partitions are dropped without archiving"`
+}
+
+// DefaultPartitioningSettings returns default values for the partitioning settings
+func DefaultPartitioningSettings() *PartitioningSettings {
+	return &PartitioningSettings{
+		Interval:   24 * time.Hour,
+		Premake:    2,
+		ArchiveDir: "",
+	}
+}
+
+// Validate validates the partitioning settings
+func (s *PartitioningSettings) Validate() error {
+	if s.Interval < time.Hour {
+		return fmt.Errorf("validate Settings: StoragePartitioningInterval must be at least 1h but was %s", s.Interval)
+	}
+	if s.Premake < 1 {
+		return fmt.Errorf("validate Settings: StoragePartitioningPremake must be at least 1 but was %d", s.Premake)
+	}
+	return nil
+}
+
+// ChangesSettings configures the changes of the resources recorded by the storage
+type ChangesSettings struct {
+	KeepFor time.Duration `mapstructure:"keep_for" description:"the time to keep a recorded change in the storage"`
+}
+
+// DefaultChangesSettings returns default values for the changes settings
+func DefaultChangesSettings() *ChangesSettings {
+	return &ChangesSettings{
+		KeepFor: 24 * time.Hour,
+	}
+}
+
+// Validate validates the changes settings
+func (s *ChangesSettings) Validate() error {
+	if s.KeepFor <= 0 {
+		return fmt.Errorf("validate Settings: StorageChangesKeepFor must be positive but was %s", s.KeepFor)
+	}
+	return nil
+}
+
 // OpenCloser represents an openable and closeable storage
 type OpenCloser interface {
 	// Open initializes the storage, e.g. opens a connection to the underlying storage
@@ -150,6 +301,45 @@ func (mf PingFunc) PingContext(ctx context.Context) error {
 	return mf(ctx)
 }
 
+// PartitionManager manages the partitions of the tables which are partitioned by creation time
+type PartitionManager interface {
+	// MaintainPartitions creates the partitions of the table of the object type for the upcoming periods and
+	// drops the partitions which contain only objects created before the retention period
+	MaintainPartitions(ctx context.Context, objectType types.ObjectType, retention time.Duration) error
+}
+
+// BrokerHealthStore stores the health of the brokers determined by the brokers health indicator
+type BrokerHealthStore interface {
+	// UpdateBrokerHealth updates only the health status and error of the broker with the id if they differ from the stored ones
+	UpdateBrokerHealth(ctx context.Context, brokerID, status, healthError string) error
+}
+
+// UpgradedInstanceStore stores the upgrades of the single service instances of bulk upgrades apart from the bulk
+// upgrades, so that the upgrade of an instance is stored without rewriting the bulk upgrade
+type UpgradedInstanceStore interface {
+	// AddUpgradedInstances stores the instances of the bulk upgrade with the id in their order
+	AddUpgradedInstances(ctx context.Context, upgradeID string, instances []*types.UpgradedInstance) error
+	// ListUpgradedInstances returns the instances of the bulk upgrade with the id in the order in which they were added
+	ListUpgradedInstances(ctx context.Context, upgradeID string) ([]*types.UpgradedInstance, error)
+	// UpdateUpgradedInstance updates the operation, state and error of the instance of the bulk upgrade with the id
+	UpdateUpgradedInstance(ctx context.Context, upgradeID string, instance *types.UpgradedInstance) error
+}
+
+// ChangeFeed provides the creates, updates and deletes of all resources recorded by the storage
+type ChangeFeed interface {
+	// ListChanges returns at most limit changes with revisions greater than since in the order of their revisions.
+	// If resource types are specified, only their changes are returned. A change is returned only once all
+	// transactions started before its transaction have completed. If changes with revisions greater than since were
+	// deleted, util.ErrChangesDeleted is returned.
+	ListChanges(ctx context.Context, since int64, resourceTypes []types.ObjectType, limit int) ([]*types.Change, error)
+
+	// Changed returns a channel which is closed when new changes are recorded
+	Changed() <-chan struct{}
+
+	// DeleteChanges deletes the changes recorded before the specified time
+	DeleteChanges(ctx context.Context, before time.Time) error
+}
+
 type Repository interface {
 	// Create stores an object in SM DB
 	Create(ctx context.Context, obj types.Object) (types.Object, error)
@@ -173,6 +363,12 @@ type Repository interface {
 	Update(ctx context.Context, obj types.Object, labelChanges query.LabelChanges, criteria ...query.Criterion) (types.Object, error)
 }
 
+// SchemaProvider provides the field schemas of the stored entities
+type SchemaProvider interface {
+	// Schema returns the field schema of the entity which stores objects of the given type
+	Schema(objectType types.ObjectType) (*query.Schema, error)
+}
+
 // TransactionalRepository is a storage repository that can initiate a transaction
 type TransactionalRepository interface {
 	Repository
@@ -212,6 +408,12 @@ type NotificationQueue interface {
 	// Close closes the queue.
 	Close()
 
+	// CloseWithError closes the queue and records the error which caused the closing for the consumer
+	CloseWithError(err error)
+
+	// Err returns the error the queue was closed with, if any
+	Err() error
+
 	// ID returns unique queue identifier
 	ID() string
 }
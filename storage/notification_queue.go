@@ -48,6 +48,7 @@ type notificationQueue struct {
 	notificationsChannel chan *types.Notification
 	mutex                *sync.Mutex
 	id                   string
+	err                  error
 }
 
 // Enqueue adds a new notification for processing. If queue is full ErrQueueFull should be returned.
@@ -75,15 +76,29 @@ func (nq *notificationQueue) Channel() <-chan *types.Notification {
 // Any subsequent calls to Next or Enqueue will return ErrQueueClosed.
 // Any subsequent calls to Close does nothing.
 func (nq *notificationQueue) Close() {
+	nq.CloseWithError(nil)
+}
+
+// CloseWithError closes the queue like Close and records the error which caused the closing.
+// Any subsequent calls to CloseWithError do not change the recorded error.
+func (nq *notificationQueue) CloseWithError(err error) {
 	nq.mutex.Lock()
 	defer nq.mutex.Unlock()
 	if nq.isClosed {
 		return
 	}
 	nq.isClosed = true
+	nq.err = err
 	close(nq.notificationsChannel)
 }
 
+// Err returns the error the queue was closed with, if any
+func (nq *notificationQueue) Err() error {
+	nq.mutex.Lock()
+	defer nq.mutex.Unlock()
+	return nq.err
+}
+
 func (nq *notificationQueue) ID() string {
 	return nq.id
 }
@@ -17,6 +17,8 @@
 package storage_test
 
 import (
+	"errors"
+
 	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/Peripli/service-manager/storage"
 
@@ -80,6 +82,19 @@ var _ = Describe("NotificationQueue", func() {
 		})
 	})
 
+	Context("When queue is closed with error", func() {
+		It("Err should return the first error", func() {
+			notificationQueue := newQueue(1)
+			Expect(notificationQueue.Err()).ToNot(HaveOccurred())
+			expectedErr := errors.New("expected")
+			notificationQueue.CloseWithError(expectedErr)
+			notificationQueue.CloseWithError(errors.New("unexpected"))
+			Expect(notificationQueue.Err()).To(Equal(expectedErr))
+			_, ok := <-notificationQueue.Channel()
+			Expect(ok).To(BeFalse())
+		})
+	})
+
 	Context("When queue.Close is called twice", func() {
 		It("Should not panic", func() {
 			notificationQueue := newQueue(1)
@@ -21,6 +21,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
 	"reflect"
 	"strings"
 
@@ -41,10 +42,6 @@ type namedExecerContext interface {
 	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
 }
 
-type namedQuerierContext interface {
-	NamedQuery(query string, arg interface{}) (*sqlx.Rows, error)
-}
-
 type selecterContext interface {
 	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
 }
@@ -58,14 +55,13 @@ type getterContext interface {
 type pgDB interface {
 	prepareNamedContext
 	namedExecerContext
-	namedQuerierContext
 	selecterContext
 	getterContext
 	sqlx.ExtContext
 }
 
 func create(ctx context.Context, db pgDB, table string, resultDto interface{}, argsDto interface{}) error {
-	setTagType := getDBTags(argsDto, isAutoIncrementable)
+	setTagType := getDBTags(argsDto, isCalculatedInDB)
 	dbTags := make([]string, 0, len(setTagType))
 	for _, tagType := range setTagType {
 		dbTags = append(dbTags, tagType.Tag)
@@ -114,9 +110,21 @@ func update(ctx context.Context, db namedExecerContext, table string, dto interf
 	return checkRowsAffected(ctx, result)
 }
 
-func isAutoIncrementable(tagValue string) bool {
+func isCalculatedInDB(tagValue string) bool {
 	// auto_increment states that the value will be calculated in the DB
-	return strings.Contains(tagValue, "auto_increment")
+	// generated states that the value will be maintained by the DB, e.g. by a trigger
+	return strings.Contains(tagValue, "auto_increment") || isGenerated(tagValue)
+}
+
+func isGenerated(tagValue string) bool {
+	// the options of the db tag follow the column name, e.g. `db:"search_vector,generated"`
+	options := strings.Split(tagValue, ",")
+	for _, option := range options[1:] {
+		if strings.TrimSpace(option) == "generated" {
+			return true
+		}
+	}
+	return false
 }
 
 type tagType struct {
@@ -167,7 +175,7 @@ func getTags(fields []*structs.Field, set *[]tagType, predicate func(string) boo
 }
 
 func updateQuery(tableName string, structure interface{}) string {
-	dbTags := getDBTags(structure, isAutoIncrementable)
+	dbTags := getDBTags(structure, isCalculatedInDB)
 	set := make([]string, 0, len(dbTags))
 	for _, dbTag := range dbTags {
 		set = append(set, fmt.Sprintf("%s = :%s", dbTag.Tag, dbTag.Tag))
@@ -203,10 +211,35 @@ func checkIntegrityViolation(ctx context.Context, err error) error {
 	return err
 }
 
-func closeRows(ctx context.Context, rows *sqlx.Rows) {
-	if rows == nil {
-		return
+// rowsQuerierContext queries rows whose statement is released once they are closed
+type rowsQuerierContext interface {
+	QueryRowsContext(ctx context.Context, query string, args ...interface{}) (*rows, error)
+}
+
+// rows are the rows of a statement which cancel the context of the statement once they are closed
+type rows struct {
+	*sqlx.Rows
+	cancel context.CancelFunc
+}
+
+func (r *rows) Close() error {
+	defer r.cancel()
+	return r.Rows.Close()
+}
+
+// queryRows queries rows with QueryRowsContext if the db supports it, otherwise with QueryxContext
+func queryRows(ctx context.Context, db pgDB, query string, args ...interface{}) (*rows, error) {
+	if querier, ok := db.(rowsQuerierContext); ok {
+		return querier.QueryRowsContext(ctx, query, args...)
 	}
+	result, err := db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{Rows: result, cancel: func() {}}, nil
+}
+
+func closeRows(ctx context.Context, rows io.Closer) {
 	if err := rows.Close(); err != nil {
 		log.C(ctx).WithError(err).Error("Could not release connection")
 	}
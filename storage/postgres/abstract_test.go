@@ -64,6 +64,17 @@ var _ = Describe("Postgres Storage Abstract", func() {
 			})
 		})
 
+		Context("Called with structure with generated field", func() {
+			It("skips only fields with the generated option", func() {
+				type ts struct {
+					Field          string `db:"field,generated"`
+					GeneratedField string `db:"generated_field"`
+				}
+				query := updateQuery("n/a", ts{})
+				Expect(query).To(Equal("UPDATE n/a SET generated_field = :generated_field WHERE id = :id"))
+			})
+		})
+
 		Context("Called with structure with no fields", func() {
 			It("Should return proper query", func() {
 				type ts struct{}
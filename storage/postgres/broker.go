@@ -18,10 +18,13 @@ package postgres
 
 import (
 	"database/sql"
+	"encoding/json"
+	"strings"
 
 	"github.com/Peripli/service-manager/storage"
 	sqlxtypes "github.com/jmoiron/sqlx/types"
 
+	"github.com/Peripli/service-manager/pkg/log"
 	"github.com/Peripli/service-manager/pkg/types"
 )
 
@@ -32,10 +35,24 @@ type Broker struct {
 	Name        string             `db:"name"`
 	Description sql.NullString     `db:"description"`
 	BrokerURL   string             `db:"broker_url"`
-	Username    string             `db:"username"`
-	Password    string             `db:"password"`
+	Username    string             `db:"username" query:"-"`
+	Password    string             `db:"password" query:"-"`
 	Catalog     sqlxtypes.JSONText `db:"catalog"`
 
+	TLSClientCertificate string `db:"tls_client_certificate" query:"-"`
+	TLSClientKey         string `db:"tls_client_key" query:"-"`
+	OAuth2TokenURL       string `db:"oauth2_token_url" query:"-"`
+	OAuth2ClientID       string `db:"oauth2_client_id" query:"-"`
+	OAuth2ClientSecret   string `db:"oauth2_client_secret" query:"-"`
+	OAuth2Scopes         string `db:"oauth2_scopes" query:"-"`
+
+	HealthStatus string `db:"health_status"`
+	HealthError  string `db:"health_error"`
+
+	CatalogWarnings sqlxtypes.JSONText `db:"catalog_warnings"`
+
+	SearchVector sql.NullString `db:"search_vector,generated"`
+
 	Services []*ServiceOffering `db:"-"`
 }
 
@@ -44,6 +61,12 @@ func (e *Broker) ToObject() types.Object {
 	for _, service := range e.Services {
 		services = append(services, service.ToObject().(*types.ServiceOffering))
 	}
+	var catalogWarnings []*types.CatalogIssue
+	if len(e.CatalogWarnings) != 0 {
+		if err := json.Unmarshal(e.CatalogWarnings, &catalogWarnings); err != nil {
+			log.D().WithError(err).Errorf("could not unmarshal catalog warnings of broker with id %s", e.ID)
+		}
+	}
 	broker := &types.ServiceBroker{
 		Base: types.Base{
 			ID:             e.ID,
@@ -53,17 +76,15 @@ func (e *Broker) ToObject() types.Object {
 			PagingSequence: e.PagingSequence,
 			Ready:          e.Ready,
 		},
-		Name:        e.Name,
-		Description: e.Description.String,
-		BrokerURL:   e.BrokerURL,
-		Credentials: &types.Credentials{
-			Basic: &types.Basic{
-				Username: e.Username,
-				Password: e.Password,
-			},
-		},
-		Catalog:  getJSONRawMessage(e.Catalog),
-		Services: services,
+		Name:            e.Name,
+		Description:     e.Description.String,
+		BrokerURL:       e.BrokerURL,
+		Credentials:     e.credentials(),
+		HealthStatus:    e.HealthStatus,
+		HealthError:     e.HealthError,
+		CatalogWarnings: catalogWarnings,
+		Catalog:         getJSONRawMessage(e.Catalog),
+		Services:        services,
 	}
 	return broker
 }
@@ -80,6 +101,10 @@ func (*Broker) FromObject(object types.Object) (storage.Entity, bool) {
 			services = append(services, entity.(*ServiceOffering))
 		}
 	}
+	catalogWarnings, err := json.Marshal(broker.CatalogWarnings)
+	if err != nil {
+		log.D().WithError(err).Errorf("could not marshal catalog warnings of broker with id %s", broker.ID)
+	}
 	b := &Broker{
 		BaseEntity: BaseEntity{
 			ID:             broker.ID,
@@ -88,15 +113,58 @@ func (*Broker) FromObject(object types.Object) (storage.Entity, bool) {
 			PagingSequence: broker.PagingSequence,
 			Ready:          broker.Ready,
 		},
-		Name:        broker.Name,
-		Description: toNullString(broker.Description),
-		BrokerURL:   broker.BrokerURL,
-		Catalog:     getJSONText(broker.Catalog),
-		Services:    services,
+		Name:            broker.Name,
+		Description:     toNullString(broker.Description),
+		BrokerURL:       broker.BrokerURL,
+		Catalog:         getJSONText(broker.Catalog),
+		HealthStatus:    broker.HealthStatus,
+		HealthError:     broker.HealthError,
+		CatalogWarnings: catalogWarnings,
+		Services:        services,
 	}
-	if broker.Credentials != nil && broker.Credentials.Basic != nil {
-		b.Username = broker.Credentials.Basic.Username
-		b.Password = broker.Credentials.Basic.Password
+	if broker.Credentials != nil {
+		if broker.Credentials.Basic != nil {
+			b.Username = broker.Credentials.Basic.Username
+			b.Password = broker.Credentials.Basic.Password
+		}
+		if broker.Credentials.TLS != nil {
+			b.TLSClientCertificate = broker.Credentials.TLS.Certificate
+			b.TLSClientKey = broker.Credentials.TLS.Key
+		}
+		if broker.Credentials.OAuth2 != nil {
+			b.OAuth2TokenURL = broker.Credentials.OAuth2.TokenURL
+			b.OAuth2ClientID = broker.Credentials.OAuth2.ClientID
+			b.OAuth2ClientSecret = broker.Credentials.OAuth2.ClientSecret
+			b.OAuth2Scopes = strings.Join(broker.Credentials.OAuth2.Scopes, " ")
+		}
 	}
 	return b, true
 }
+
+// credentials returns the credentials of the broker which are stored
+func (e *Broker) credentials() *types.Credentials {
+	credentials := &types.Credentials{}
+	if e.Username != "" {
+		credentials.Basic = &types.Basic{
+			Username: e.Username,
+			Password: e.Password,
+		}
+	}
+	if e.TLSClientCertificate != "" {
+		credentials.TLS = &types.TLS{
+			Certificate: e.TLSClientCertificate,
+			Key:         e.TLSClientKey,
+		}
+	}
+	if e.OAuth2TokenURL != "" {
+		credentials.OAuth2 = &types.OAuth2{
+			TokenURL:     e.OAuth2TokenURL,
+			ClientID:     e.OAuth2ClientID,
+			ClientSecret: e.OAuth2ClientSecret,
+		}
+		if e.OAuth2Scopes != "" {
+			credentials.OAuth2.Scopes = strings.Fields(e.OAuth2Scopes)
+		}
+	}
+	return credentials
+}
@@ -0,0 +1,33 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package postgres
+
+import (
+	"context"
+	"fmt"
+)
+
+// UpdateBrokerHealth implements storage.BrokerHealthStore. Only the health columns are updated, so that the update
+// does not overwrite concurrent changes of the broker. The row is not updated if the stored health is the same, e.g. because
+// another Service Manager instance has already stored it, so that no change of the broker is recorded for it.
+func (ps *Storage) UpdateBrokerHealth(ctx context.Context, brokerID, status, healthError string) error {
+	ps.checkOpen()
+	statement := fmt.Sprintf(`UPDATE %s SET health_status = $1, health_error = $2
+		WHERE id = $3 AND (health_status IS DISTINCT FROM $1 OR health_error IS DISTINCT FROM $2)`, BrokerTable)
+	_, err := ps.pgDB.ExecContext(ctx, statement, status, healthError, brokerID)
+	return err
+}
@@ -0,0 +1,230 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+	notificationConnection "github.com/Peripli/service-manager/storage/postgres/notification_connection"
+
+	"github.com/lib/pq"
+)
+
+const (
+	changesChannel = "changes"
+	// changeRevisionsLockIndex serializes the assignments of the revisions of the changes
+	changeRevisionsLockIndex = 114
+)
+
+// assignRevisionsQuery assigns revisions to the changes of the transactions which started before the oldest
+// transaction in progress. These transactions have all completed, and the transactions which complete later have
+// higher transaction ids, so the revisions follow the order of the transaction ids.
+const assignRevisionsQuery = `UPDATE changes SET revision = assigned.revision
+FROM (SELECT pending.id, nextval('changes_revision_seq') AS revision
+      FROM (SELECT id FROM changes
+            WHERE revision IS NULL AND transaction_id < txid_snapshot_xmin(txid_current_snapshot())
+            ORDER BY transaction_id, id) pending) assigned
+WHERE changes.id = assigned.id`
+
+var _ storage.ChangeFeed = &ChangeFeed{}
+
+// change is the row of a change recorded by the triggers of the entity tables
+type change struct {
+	Revision     int64     `db:"revision"`
+	ResourceType string    `db:"resource_type"`
+	ResourceID   string    `db:"resource_id"`
+	Operation    string    `db:"operation"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+// ChangeFeed implements storage.ChangeFeed. The changes are recorded by triggers on the tables of the entities which
+// also notify the changes channel when the recording transactions commit. The revisions of the changes are assigned
+// by the readers once no older transaction is in progress, so the changes of transactions in progress always get
+// higher revisions than the visible changes, without serializing the recording transactions.
+type ChangeFeed struct {
+	storage           *Storage
+	connectionCreator notificationConnectionCreator
+	connection        notificationConnection.NotificationConnection
+	dbPingInterval    time.Duration
+
+	mutex   sync.Mutex
+	changed chan struct{}
+}
+
+// NewChangeFeed returns a ChangeFeed reading the changes recorded in the storage
+func NewChangeFeed(st *Storage, settings *storage.Settings) *ChangeFeed {
+	return &ChangeFeed{
+		storage: st,
+		connectionCreator: &notificationConnectionCreatorImpl{
+			skipSSLValidation:    settings.SkipSSLValidation,
+			storageURI:           settings.URI,
+			minReconnectInterval: settings.Notification.MinReconnectInterval,
+			maxReconnectInterval: settings.Notification.MaxReconnectInterval,
+		},
+		dbPingInterval: dbPingInterval,
+		changed:        make(chan struct{}),
+	}
+}
+
+// Start starts listening for the notifications of recorded changes. It must not be called concurrently.
+func (cf *ChangeFeed) Start(ctx context.Context, group *sync.WaitGroup) error {
+	if cf.connection != nil {
+		return fmt.Errorf("change feed already started")
+	}
+	cf.connection = cf.connectionCreator.NewConnection(func(isConnected bool, err error) {
+		if isConnected {
+			log.C(ctx).Info("DB connection for changes established")
+			// changes might have been recorded while the connection was closed
+			cf.notifyChanged()
+		} else {
+			log.C(ctx).WithError(err).Error("DB connection for changes closed")
+		}
+	})
+	util.StartInWaitGroupWithContext(ctx, cf.processNotifications, group)
+	util.StartInWaitGroupWithContext(ctx, func(c context.Context) {
+		<-c.Done()
+		log.C(c).Info("context cancelled, stopping change feed...")
+		if err := cf.connection.Close(); err != nil {
+			log.C(c).WithError(err).Error("Could not close db connection")
+		}
+	}, group)
+	return nil
+}
+
+func (cf *ChangeFeed) processNotifications(ctx context.Context) {
+	// blocks until the connection is established or closed
+	if err := cf.connection.Listen(changesChannel); err != nil && err != pq.ErrChannelAlreadyOpen {
+		log.C(ctx).WithError(err).Errorf("Could not listen to channel %s", changesChannel)
+		return
+	}
+	for {
+		select {
+		case _, ok := <-cf.connection.NotificationChannel():
+			if !ok {
+				log.C(ctx).Debugf("Stop listening channel %s", changesChannel)
+				return
+			}
+			cf.notifyChanged()
+		case <-time.After(cf.dbPingInterval):
+			if err := cf.connection.Ping(); err != nil {
+				log.C(ctx).WithError(err).Error("Pinging connection for changes failed")
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (cf *ChangeFeed) notifyChanged() {
+	cf.mutex.Lock()
+	defer cf.mutex.Unlock()
+	close(cf.changed)
+	cf.changed = make(chan struct{})
+}
+
+// Changed implements storage.ChangeFeed
+func (cf *ChangeFeed) Changed() <-chan struct{} {
+	cf.mutex.Lock()
+	defer cf.mutex.Unlock()
+	return cf.changed
+}
+
+// ListChanges implements storage.ChangeFeed
+func (cf *ChangeFeed) ListChanges(ctx context.Context, since int64, resourceTypes []types.ObjectType, limit int) ([]*types.Change, error) {
+	cf.storage.checkOpen()
+	if err := cf.assignRevisions(ctx); err != nil {
+		return nil, err
+	}
+	var deletedRevision int64
+	if err := cf.storage.pgDB.GetContext(ctx, &deletedRevision, "SELECT revision FROM changes_deleted_revision"); err != nil {
+		return nil, err
+	}
+	if since < deletedRevision {
+		return nil, &util.ErrChangesDeleted{Revision: deletedRevision}
+	}
+
+	sqlQuery := `SELECT revision, resource_type, resource_id, operation, created_at FROM changes WHERE revision > $1`
+	args := []interface{}{since}
+	if len(resourceTypes) != 0 {
+		typeNames := make([]string, 0, len(resourceTypes))
+		for _, resourceType := range resourceTypes {
+			typeNames = append(typeNames, resourceType.String())
+		}
+		sqlQuery += " AND resource_type = ANY($2)"
+		args = append(args, pq.Array(typeNames))
+	}
+	sqlQuery += fmt.Sprintf(" ORDER BY revision LIMIT %d", limit)
+
+	var rows []*change
+	if err := cf.storage.SelectContext(ctx, &rows, sqlQuery, args...); err != nil {
+		return nil, err
+	}
+	changes := make([]*types.Change, 0, len(rows))
+	for _, row := range rows {
+		changes = append(changes, &types.Change{
+			Revision:     row.Revision,
+			ResourceType: types.ObjectType(row.ResourceType),
+			ResourceID:   row.ResourceID,
+			Operation:    types.OperationCategory(row.Operation),
+			CreatedAt:    row.CreatedAt,
+		})
+	}
+	return changes, nil
+}
+
+func (cf *ChangeFeed) assignRevisions(ctx context.Context) error {
+	tx, err := cf.storage.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.C(ctx).WithError(err).Error("Could not rollback transaction")
+		}
+	}()
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", changeRevisionsLockIndex); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, assignRevisionsQuery); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteChanges implements storage.ChangeFeed. The changes are deleted up to the highest revision recorded before the
+// specified time, which is kept so that consumers requesting deleted changes can be told to resynchronize.
+func (cf *ChangeFeed) DeleteChanges(ctx context.Context, before time.Time) error {
+	cf.storage.checkOpen()
+	var deletedRevision sql.NullInt64
+	err := cf.storage.pgDB.GetContext(ctx, &deletedRevision, `WITH deleted AS (
+DELETE FROM changes WHERE revision <= (SELECT MAX(revision) FROM changes WHERE created_at < $1) RETURNING revision)
+UPDATE changes_deleted_revision SET revision = GREATEST(revision, (SELECT MAX(revision) FROM deleted)) RETURNING revision`, before)
+	if err != nil {
+		return err
+	}
+	log.C(ctx).Debugf("Deleted changes recorded before %s up to revision %d", before, deletedRevision.Int64)
+	return nil
+}
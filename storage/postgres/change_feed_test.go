@@ -0,0 +1,157 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+	notificationConnection "github.com/Peripli/service-manager/storage/postgres/notification_connection"
+	notificationConnectionFakes "github.com/Peripli/service-manager/storage/postgres/notification_connection/notification_connectionfakes"
+	"github.com/Peripli/service-manager/storage/postgres/postgresfakes"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ChangeFeed", func() {
+	var s *Storage
+	var mock sqlmock.Sqlmock
+	var feed *ChangeFeed
+
+	BeforeEach(func() {
+		mockdb, m, err := sqlmock.New()
+		Expect(err).ToNot(HaveOccurred())
+		mock = m
+
+		s = &Storage{
+			ConnectFunc: func(driver string, url string) (*sql.DB, error) {
+				return mockdb, nil
+			},
+		}
+		mock.ExpectQuery(`SELECT CURRENT_DATABASE()`).WillReturnRows(sqlmock.NewRows([]string{"mock"}).FromCSVString("mock"))
+		mock.ExpectQuery(`SELECT COUNT(1)*`).WillReturnRows(sqlmock.NewRows([]string{"mock"}).FromCSVString("1"))
+		mock.ExpectExec("SELECT pg_advisory_lock*").WithArgs(sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(`SELECT version, dirty FROM "schema_migrations" LIMIT 1`).WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).FromCSVString(latestMigration + ",false"))
+		mock.ExpectExec("SELECT pg_advisory_unlock*").WithArgs(sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+
+		settings := storage.DefaultSettings()
+		settings.EncryptionKey = "ejHjRNHbS0NaqARSRvnweVV9zcmhQEa8"
+		settings.URI = "sqlmock://sqlmock"
+		Expect(s.Open(settings)).To(Succeed())
+
+		feed = NewChangeFeed(s, settings)
+	})
+
+	AfterEach(func() {
+		Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+	})
+
+	Describe("ListChanges", func() {
+		expectRevisionsAssigned := func(deletedRevision int64) {
+			mock.ExpectBegin()
+			mock.ExpectExec("SELECT pg_advisory_xact_lock").WithArgs(changeRevisionsLockIndex).WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("(?s)UPDATE changes SET revision = assigned.revision(.+)transaction_id < txid_snapshot_xmin\\(txid_current_snapshot\\(\\)\\)\\s+ORDER BY transaction_id, id").
+				WillReturnResult(sqlmock.NewResult(0, 2))
+			mock.ExpectCommit()
+			mock.ExpectQuery("SELECT revision FROM changes_deleted_revision").
+				WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(deletedRevision))
+		}
+
+		It("returns the changes of the requested resource types", func() {
+			now := time.Now()
+			expectRevisionsAssigned(3)
+			mock.ExpectQuery("SELECT revision, resource_type, resource_id, operation, created_at FROM changes (.+) AND resource_type = ANY\\(\\$2\\) ORDER BY revision LIMIT 10").
+				WithArgs(int64(5), sqlmock.AnyArg()).
+				WillReturnRows(sqlmock.NewRows([]string{"revision", "resource_type", "resource_id", "operation", "created_at"}).
+					AddRow(6, types.ServiceInstanceType.String(), "instance-id", "create", now))
+
+			changes, err := feed.ListChanges(context.Background(), 5, []types.ObjectType{types.ServiceInstanceType}, 10)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(changes).To(HaveLen(1))
+			Expect(*changes[0]).To(Equal(types.Change{
+				Revision:     6,
+				ResourceType: types.ServiceInstanceType,
+				ResourceID:   "instance-id",
+				Operation:    types.CREATE,
+				CreatedAt:    now,
+			}))
+		})
+
+		It("returns an error when changes since the revision were deleted", func() {
+			expectRevisionsAssigned(7)
+
+			_, err := feed.ListChanges(context.Background(), 5, nil, 10)
+			Expect(err).To(Equal(&util.ErrChangesDeleted{Revision: 7}))
+		})
+	})
+
+	Describe("DeleteChanges", func() {
+		It("deletes the changes recorded before the specified time and keeps their highest revision", func() {
+			before := time.Now()
+			mock.ExpectQuery("(?s)DELETE FROM changes WHERE revision <= \\(SELECT MAX\\(revision\\) FROM changes WHERE created_at < \\$1\\)(.+)UPDATE changes_deleted_revision").
+				WithArgs(before).WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(3))
+			Expect(feed.DeleteChanges(context.Background(), before)).To(Succeed())
+		})
+	})
+
+	Describe("Changed", func() {
+		var ctx context.Context
+		var cancel context.CancelFunc
+		var wg *sync.WaitGroup
+		var notifications chan *pq.Notification
+
+		BeforeEach(func() {
+			ctx, cancel = context.WithCancel(context.Background())
+			wg = &sync.WaitGroup{}
+			notifications = make(chan *pq.Notification, 1)
+
+			connection := &notificationConnectionFakes.FakeNotificationConnection{}
+			connection.NotificationChannelReturns(notifications)
+			connectionCreator := &postgresfakes.FakeNotificationConnectionCreator{}
+			connectionCreator.NewConnectionStub = func(func(isRunning bool, err error)) notificationConnection.NotificationConnection {
+				return connection
+			}
+			feed.connectionCreator = connectionCreator
+			Expect(feed.Start(ctx, wg)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			cancel()
+			wg.Wait()
+		})
+
+		It("is closed when changes are notified", func() {
+			changed := feed.Changed()
+			Consistently(changed).ShouldNot(BeClosed())
+			notifications <- &pq.Notification{Channel: changesChannel, Extra: types.ServiceInstanceType.String()}
+			Eventually(changed).Should(BeClosed())
+			Expect(feed.Changed()).ToNot(BeClosed())
+		})
+
+		It("fails to start twice", func() {
+			Expect(feed.Start(ctx, wg)).ToNot(Succeed())
+		})
+	})
+})
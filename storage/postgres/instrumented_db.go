@@ -0,0 +1,109 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/storage"
+)
+
+// instrumentedDB is a pgDB which applies the statement timeout from the context to the executed statements
+// and logs the statements which take longer than the slow query threshold. Statements returning rows are
+// limited only when queried with QueryRowsContext or NamedQueryContext, as the rows are read after they return.
+type instrumentedDB struct {
+	pgDB
+	// statementTimeout limits the statements for which the context does not set a shorter statement timeout
+	statementTimeout   time.Duration
+	slowQueryThreshold time.Duration
+}
+
+func (db *instrumentedDB) NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error) {
+	ctx, cancel := db.withStatementTimeout(ctx)
+	defer cancel()
+	defer db.logSlowQuery(ctx, query, time.Now())
+	return db.pgDB.NamedExecContext(ctx, query, arg)
+}
+
+func (db *instrumentedDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	ctx, cancel := db.withStatementTimeout(ctx)
+	defer cancel()
+	defer db.logSlowQuery(ctx, query, time.Now())
+	return db.pgDB.SelectContext(ctx, dest, query, args...)
+}
+
+func (db *instrumentedDB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	ctx, cancel := db.withStatementTimeout(ctx)
+	defer cancel()
+	defer db.logSlowQuery(ctx, query, time.Now())
+	return db.pgDB.GetContext(ctx, dest, query, args...)
+}
+
+func (db *instrumentedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, cancel := db.withStatementTimeout(ctx)
+	defer cancel()
+	defer db.logSlowQuery(ctx, query, time.Now())
+	return db.pgDB.ExecContext(ctx, query, args...)
+}
+
+// QueryRowsContext queries rows with the statement timeout, which is released once the rows are closed
+func (db *instrumentedDB) QueryRowsContext(ctx context.Context, query string, args ...interface{}) (*rows, error) {
+	ctx, cancel := db.withStatementTimeout(ctx)
+	defer db.logSlowQuery(ctx, query, time.Now())
+	result, err := db.pgDB.QueryxContext(ctx, query, args...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &rows{Rows: result, cancel: cancel}, nil
+}
+
+// NamedQueryContext queries rows of a named statement with the statement timeout, which is released once the rows are closed
+func (db *instrumentedDB) NamedQueryContext(ctx context.Context, query string, arg interface{}) (*rows, error) {
+	boundQuery, args, err := db.pgDB.BindNamed(query, arg)
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryRowsContext(ctx, boundQuery, args...)
+}
+
+func (db *instrumentedDB) logSlowQuery(ctx context.Context, query string, start time.Time) {
+	if db.slowQueryThreshold <= 0 {
+		return
+	}
+	if duration := time.Since(start); duration >= db.slowQueryThreshold {
+		log.C(ctx).Warnf("Slow query took %s: %s", duration, query)
+	}
+}
+
+func (db *instrumentedDB) statementTimeoutFromContext(ctx context.Context) (time.Duration, bool) {
+	timeout, ok := storage.StatementTimeoutFromContext(ctx)
+	if db.statementTimeout > 0 && (!ok || db.statementTimeout < timeout) {
+		return db.statementTimeout, true
+	}
+	return timeout, ok
+}
+
+func (db *instrumentedDB) withStatementTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if timeout, ok := db.statementTimeoutFromContext(ctx); ok {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return ctx, func() {}
+}
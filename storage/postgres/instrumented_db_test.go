@@ -0,0 +1,177 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/storage/postgres/postgresfakes"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Instrumented DB", func() {
+	var fakeDB *postgresfakes.FakePgDB
+	var db *instrumentedDB
+	var output *bytes.Buffer
+	var ctx context.Context
+
+	BeforeEach(func() {
+		fakeDB = &postgresfakes.FakePgDB{}
+		db = &instrumentedDB{
+			pgDB:               fakeDB,
+			slowQueryThreshold: 50 * time.Millisecond,
+		}
+		output = &bytes.Buffer{}
+		logger := logrus.New()
+		logger.Out = output
+		ctx = log.ContextWithLogger(context.Background(), logrus.NewEntry(logger))
+	})
+
+	Describe("statement timeout", func() {
+		Context("when the context has a statement timeout", func() {
+			It("executes the statement with a deadline", func() {
+				_, err := db.ExecContext(storage.ContextWithStatementTimeout(ctx, time.Second), "DELETE FROM platforms")
+				Expect(err).ToNot(HaveOccurred())
+
+				statementCtx, _, _ := fakeDB.ExecContextArgsForCall(0)
+				deadline, ok := statementCtx.Deadline()
+				Expect(ok).To(BeTrue())
+				Expect(deadline).To(BeTemporally("~", time.Now().Add(time.Second), 500*time.Millisecond))
+			})
+
+			It("cancels statements returning rows when the timeout expires", func() {
+				_, err := db.QueryRowsContext(storage.ContextWithStatementTimeout(ctx, 10*time.Millisecond), "SELECT * FROM platforms")
+				Expect(err).ToNot(HaveOccurred())
+
+				statementCtx, _, _ := fakeDB.QueryxContextArgsForCall(0)
+				Expect(statementCtx.Err()).ToNot(HaveOccurred())
+				Eventually(statementCtx.Done()).Should(BeClosed())
+			})
+
+			It("releases the statement timeout when the rows are closed", func() {
+				mockDB, mock, err := sqlmock.New()
+				Expect(err).ToNot(HaveOccurred())
+				defer mockDB.Close()
+				mock.ExpectQuery("SELECT \\* FROM platforms").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("id"))
+				fakeDB.QueryxContextCalls(sqlx.NewDb(mockDB, "sqlmock").QueryxContext)
+
+				rows, err := db.QueryRowsContext(storage.ContextWithStatementTimeout(ctx, time.Hour), "SELECT * FROM platforms")
+				Expect(err).ToNot(HaveOccurred())
+				statementCtx, _, _ := fakeDB.QueryxContextArgsForCall(0)
+				Expect(statementCtx.Err()).ToNot(HaveOccurred())
+
+				Expect(rows.Close()).To(Succeed())
+				Expect(statementCtx.Err()).To(Equal(context.Canceled))
+			})
+		})
+
+		Context("when the context has no statement timeout", func() {
+			It("executes the statement without a deadline", func() {
+				_, err := db.ExecContext(ctx, "DELETE FROM platforms")
+				Expect(err).ToNot(HaveOccurred())
+
+				statementCtx, _, _ := fakeDB.ExecContextArgsForCall(0)
+				_, ok := statementCtx.Deadline()
+				Expect(ok).To(BeFalse())
+			})
+		})
+
+		Context("when a default statement timeout is configured", func() {
+			BeforeEach(func() {
+				db.statementTimeout = time.Second
+			})
+
+			It("executes statements without a statement timeout in the context with the default one", func() {
+				_, err := db.ExecContext(ctx, "DELETE FROM platforms")
+				Expect(err).ToNot(HaveOccurred())
+
+				statementCtx, _, _ := fakeDB.ExecContextArgsForCall(0)
+				deadline, ok := statementCtx.Deadline()
+				Expect(ok).To(BeTrue())
+				Expect(deadline).To(BeTemporally("~", time.Now().Add(time.Second), 500*time.Millisecond))
+			})
+
+			It("executes statements with the shorter statement timeout", func() {
+				_, err := db.ExecContext(storage.ContextWithStatementTimeout(ctx, time.Hour), "DELETE FROM platforms")
+				Expect(err).ToNot(HaveOccurred())
+
+				statementCtx, _, _ := fakeDB.ExecContextArgsForCall(0)
+				deadline, ok := statementCtx.Deadline()
+				Expect(ok).To(BeTrue())
+				Expect(deadline).To(BeTemporally("~", time.Now().Add(time.Second), 500*time.Millisecond))
+			})
+
+			It("cancels named queries when the timeout expires", func() {
+				db.statementTimeout = 10 * time.Millisecond
+				fakeDB.BindNamedCalls(func(query string, arg interface{}) (string, []interface{}, error) {
+					return sqlx.BindNamed(sqlx.DOLLAR, query, arg)
+				})
+				_, err := db.NamedQueryContext(ctx, "SELECT * FROM platforms WHERE id = :id", map[string]interface{}{"id": "id"})
+				Expect(err).ToNot(HaveOccurred())
+
+				statementCtx, query, args := fakeDB.QueryxContextArgsForCall(0)
+				Expect(query).To(Equal("SELECT * FROM platforms WHERE id = $1"))
+				Expect(args).To(ConsistOf("id"))
+				Eventually(statementCtx.Done()).Should(BeClosed())
+			})
+
+			It("cancels named queries when the context of the caller is cancelled", func() {
+				fakeDB.BindNamedCalls(func(query string, arg interface{}) (string, []interface{}, error) {
+					return sqlx.BindNamed(sqlx.DOLLAR, query, arg)
+				})
+				callerCtx, cancel := context.WithCancel(ctx)
+				_, err := db.NamedQueryContext(callerCtx, "SELECT * FROM platforms WHERE id = :id", map[string]interface{}{"id": "id"})
+				Expect(err).ToNot(HaveOccurred())
+
+				statementCtx, _, _ := fakeDB.QueryxContextArgsForCall(0)
+				Expect(statementCtx.Err()).ToNot(HaveOccurred())
+				cancel()
+				Expect(statementCtx.Err()).To(Equal(context.Canceled))
+			})
+		})
+	})
+
+	Describe("slow query logging", func() {
+		It("logs statements slower than the threshold", func() {
+			fakeDB.ExecContextCalls(func(context.Context, string, ...interface{}) (sql.Result, error) {
+				time.Sleep(60 * time.Millisecond)
+				return nil, nil
+			})
+			_, err := db.ExecContext(ctx, "DELETE FROM platforms WHERE id = $1", "id")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(output.String()).To(ContainSubstring("Slow query took"))
+			Expect(output.String()).To(ContainSubstring("DELETE FROM platforms WHERE id = $1"))
+		})
+
+		It("does not log statements faster than the threshold", func() {
+			_, err := db.ExecContext(ctx, "DELETE FROM platforms")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(output.String()).To(BeEmpty())
+		})
+	})
+})
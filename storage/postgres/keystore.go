@@ -19,8 +19,13 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/security"
+
 	"github.com/Peripli/service-manager/storage"
 
 	"github.com/Peripli/service-manager/pkg/types"
@@ -28,8 +33,10 @@ import (
 )
 
 const (
-	securityLockIndex = 111
-	SafeTable         = "safe"
+	securityLockIndex      = 111
+	reencryptionLockIndex  = 112
+	brokersHealthLockIndex = 115
+	SafeTable              = "safe"
 )
 
 // EncryptingLocker builds an encrypting storage.Locker with the pre-defined lock index
@@ -37,9 +44,20 @@ func EncryptingLocker(storage *Storage) storage.Locker {
 	return &Locker{Storage: storage, AdvisoryIndex: securityLockIndex}
 }
 
+// ReencryptionLocker builds a storage.Locker with the pre-defined lock index of the credentials re-encryption
+func ReencryptionLocker(storage *Storage) storage.Locker {
+	return &Locker{Storage: storage, AdvisoryIndex: reencryptionLockIndex}
+}
+
+// BrokersHealthLocker builds a storage.Locker with the pre-defined lock index of the instance which probes the brokers
+func BrokersHealthLocker(storage *Storage) storage.Locker {
+	return &Locker{Storage: storage, AdvisoryIndex: brokersHealthLockIndex}
+}
+
 // Safe represents a secret entity
 type Safe struct {
 	Secret    []byte    `db:"secret"`
+	Version   int       `db:"version"`
 	CreatedAt time.Time `db:"created_at"`
 	UpdatedAt time.Time `db:"updated_at"`
 }
@@ -76,12 +94,12 @@ func (s *Safe) LabelEntity() PostgresLabel {
 	return nil
 }
 
-// GetEncryptionKey returns the encryption key used to encrypt the credentials for brokers
+// GetEncryptionKey returns the active encryption key used to encrypt the credentials for brokers
 func (s *Storage) GetEncryptionKey(ctx context.Context, transformationFunc func(context.Context, []byte, []byte) ([]byte, error)) ([]byte, error) {
 	s.checkOpen()
 
 	safe := &Safe{}
-	if err := s.db.GetContext(ctx, safe, "SELECT * FROM safe"); err != nil {
+	if err := s.db.GetContext(ctx, safe, "SELECT * FROM safe ORDER BY version DESC LIMIT 1"); err != nil {
 		if err == sql.ErrNoRows {
 			return []byte{}, nil
 		}
@@ -89,7 +107,7 @@ func (s *Storage) GetEncryptionKey(ctx context.Context, transformationFunc func(
 	}
 	encryptedKey := []byte(safe.Secret)
 
-	return transformationFunc(ctx, encryptedKey, s.layerOneEncryptionKey)
+	return s.decryptSecret(ctx, encryptedKey, transformationFunc)
 }
 
 // SetEncryptionKey Sets the encryption key by encrypting it beforehand with the encryption key in the environment
@@ -103,9 +121,121 @@ func (s *Storage) SetEncryptionKey(ctx context.Context, key []byte, transformati
 
 	err = create(ctx, s.db, "safe", &Safe{}, Safe{
 		Secret:    bytes,
+		Version:   1,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	})
 
 	return err
 }
+
+// GetEncryptionKeys returns all versions of the encryption key, the active one first
+func (s *Storage) GetEncryptionKeys(ctx context.Context, transformationFunc func(context.Context, []byte, []byte) ([]byte, error)) ([]security.Key, error) {
+	s.checkOpen()
+
+	var safes []Safe
+	if err := s.db.SelectContext(ctx, &safes, "SELECT * FROM safe ORDER BY version DESC"); err != nil {
+		return nil, err
+	}
+
+	keys := make([]security.Key, 0, len(safes))
+	for _, safe := range safes {
+		key, err := s.decryptSecret(ctx, safe.Secret, transformationFunc)
+		if err != nil {
+			return nil, fmt.Errorf("could not decrypt encryption key with version %d: %s", safe.Version, err)
+		}
+		keys = append(keys, security.Key{
+			ID:    strconv.Itoa(safe.Version),
+			Value: key,
+		})
+	}
+	return keys, nil
+}
+
+// GetActiveEncryptionKeyID returns the ID of the active version of the encryption key
+func (s *Storage) GetActiveEncryptionKeyID(ctx context.Context) (string, error) {
+	s.checkOpen()
+
+	var version int
+	if err := s.db.GetContext(ctx, &version, "SELECT COALESCE(MAX(version), 0) FROM safe"); err != nil {
+		return "", err
+	}
+	return strconv.Itoa(version), nil
+}
+
+// AddEncryptionKey stores a new version of the encryption key by encrypting it beforehand with the encryption key in the environment
+func (s *Storage) AddEncryptionKey(ctx context.Context, key []byte, transformationFunc func(context.Context, []byte, []byte) ([]byte, error)) (string, error) {
+	s.checkOpen()
+
+	bytes, err := transformationFunc(ctx, key, s.layerOneEncryptionKey)
+	if err != nil {
+		return "", err
+	}
+
+	var version int
+	if err := s.db.GetContext(ctx, &version, "SELECT COALESCE(MAX(version), 0) + 1 FROM safe"); err != nil {
+		return "", err
+	}
+
+	err = create(ctx, s.db, "safe", &Safe{}, Safe{
+		Secret:    bytes,
+		Version:   version,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(version), nil
+}
+
+// ReencryptEncryptionKeys re-encrypts the versions of the encryption key which are encrypted with
+// one of the previous encryption keys in the environment with the current one
+func (s *Storage) ReencryptEncryptionKeys(ctx context.Context, decryptionFunc, encryptionFunc func(context.Context, []byte, []byte) ([]byte, error)) (int, error) {
+	s.checkOpen()
+
+	if len(s.previousLayerOneEncryptionKeys) == 0 {
+		return 0, nil
+	}
+
+	var safes []Safe
+	if err := s.db.SelectContext(ctx, &safes, "SELECT * FROM safe ORDER BY version"); err != nil {
+		return 0, err
+	}
+
+	reencrypted := 0
+	for _, safe := range safes {
+		if _, err := decryptionFunc(ctx, safe.Secret, s.layerOneEncryptionKey); err == nil {
+			continue
+		}
+		key, err := s.decryptSecret(ctx, safe.Secret, decryptionFunc)
+		if err != nil {
+			return reencrypted, fmt.Errorf("could not decrypt encryption key with version %d: %s", safe.Version, err)
+		}
+		bytes, err := encryptionFunc(ctx, key, s.layerOneEncryptionKey)
+		if err != nil {
+			return reencrypted, err
+		}
+		if _, err := s.db.ExecContext(ctx, "UPDATE safe SET secret = $1, updated_at = $2 WHERE version = $3", bytes, time.Now(), safe.Version); err != nil {
+			return reencrypted, err
+		}
+		log.C(ctx).Infof("Re-encrypted encryption key with version %d with the current layer one encryption key", safe.Version)
+		reencrypted++
+	}
+	return reencrypted, nil
+}
+
+// decryptSecret decrypts a secret with the current encryption key in the environment or,
+// if the secret is not yet re-encrypted after a rotation, with one of the previous ones
+func (s *Storage) decryptSecret(ctx context.Context, secret []byte, transformationFunc func(context.Context, []byte, []byte) ([]byte, error)) ([]byte, error) {
+	key, err := transformationFunc(ctx, secret, s.layerOneEncryptionKey)
+	if err == nil {
+		return key, nil
+	}
+	for _, previousKey := range s.previousLayerOneEncryptionKeys {
+		if key, previousErr := transformationFunc(ctx, secret, previousKey); previousErr == nil {
+			return key, nil
+		}
+	}
+	return nil, err
+}
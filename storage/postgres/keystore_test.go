@@ -59,7 +59,7 @@ var _ = Describe("Secured Storage", func() {
 		mock.ExpectQuery(`SELECT CURRENT_DATABASE()`).WillReturnRows(sqlmock.NewRows([]string{"mock"}).FromCSVString("mock"))
 		mock.ExpectQuery(`SELECT COUNT(1)*`).WillReturnRows(sqlmock.NewRows([]string{"mock"}).FromCSVString("1"))
 		mock.ExpectExec("SELECT pg_advisory_lock*").WithArgs(sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
-		mock.ExpectQuery(`SELECT version, dirty FROM "schema_migrations" LIMIT 1`).WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).FromCSVString("20200131152000,false"))
+		mock.ExpectQuery(`SELECT version, dirty FROM "schema_migrations" LIMIT 1`).WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).FromCSVString(latestMigration + ",false"))
 		mock.ExpectExec("SELECT pg_advisory_unlock*").WithArgs(sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
 		options := storage.DefaultSettings()
 		options.EncryptionKey = string(envEncryptionKey)
@@ -194,4 +194,155 @@ var _ = Describe("Secured Storage", func() {
 		})
 
 	})
+	Describe("GetEncryptionKeys", func() {
+		Context("When database returns error when selecting", func() {
+			expectedError := fmt.Errorf("expected error")
+
+			BeforeEach(func() {
+				mock.ExpectQuery("SELECT").WillReturnError(expectedError)
+			})
+
+			It("Should return error", func() {
+				keys, err := s.GetEncryptionKeys(context.TODO(), fakeEncrypter.Decrypt)
+				Expect(keys).To(BeNil())
+				Expect(err).To(Equal(expectedError))
+			})
+		})
+
+		Context("When encryption keys are found", func() {
+			BeforeEach(func() {
+				oldKey, _ := fakeEncrypter.Encrypt(context.TODO(), []byte("old"), envEncryptionKey)
+				newKey, _ := fakeEncrypter.Encrypt(context.TODO(), []byte("new"), envEncryptionKey)
+				rows := sqlmock.NewRows([]string{"secret", "version", "created_at", "updated_at"}).
+					AddRow(newKey, 2, time.Now(), time.Now()).
+					AddRow(oldKey, 1, time.Now(), time.Now())
+				mock.ExpectQuery("SELECT (.+) FROM safe ORDER BY version DESC").WillReturnRows(rows)
+			})
+
+			It("Should return the decrypted keys with the active one first", func() {
+				keys, err := s.GetEncryptionKeys(context.TODO(), fakeEncrypter.Decrypt)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(keys).To(Equal([]security.Key{
+					{ID: "2", Value: []byte("new")},
+					{ID: "1", Value: []byte("old")},
+				}))
+			})
+		})
+
+		Context("When an encryption key is encrypted with a previous environment encryption key", func() {
+			BeforeEach(func() {
+				previousEncryptionKey := make([]byte, 32)
+				_, err := rand.Read(previousEncryptionKey)
+				Expect(err).ToNot(HaveOccurred())
+				s.previousLayerOneEncryptionKeys = [][]byte{previousEncryptionKey}
+
+				key, _ := fakeEncrypter.Encrypt(context.TODO(), []byte("key"), previousEncryptionKey)
+				rows := sqlmock.NewRows([]string{"secret", "version", "created_at", "updated_at"}).
+					AddRow(key, 1, time.Now(), time.Now())
+				mock.ExpectQuery("SELECT").WillReturnRows(rows)
+			})
+
+			It("Should decrypt it with the previous key", func() {
+				keys, err := s.GetEncryptionKeys(context.TODO(), fakeEncrypter.Decrypt)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(keys).To(Equal([]security.Key{{ID: "1", Value: []byte("key")}}))
+			})
+		})
+
+		Context("When an encryption key cannot be decrypted", func() {
+			BeforeEach(func() {
+				rows := sqlmock.NewRows([]string{"secret", "version", "created_at", "updated_at"}).
+					AddRow([]byte("invalid"), 1, time.Now(), time.Now())
+				mock.ExpectQuery("SELECT").WillReturnRows(rows)
+			})
+
+			It("Should return error", func() {
+				_, err := s.GetEncryptionKeys(context.TODO(), fakeEncrypter.Decrypt)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("GetActiveEncryptionKeyID", func() {
+		Context("When there are stored versions", func() {
+			BeforeEach(func() {
+				mock.ExpectQuery("SELECT COALESCE").WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+			})
+
+			It("Should return the latest version", func() {
+				keyID, err := s.GetActiveEncryptionKeyID(context.TODO())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(keyID).To(Equal("2"))
+			})
+		})
+	})
+
+	Describe("AddEncryptionKey", func() {
+		Context("When selecting the latest version returns error", func() {
+			expectedError := fmt.Errorf("expected error")
+
+			BeforeEach(func() {
+				mock.ExpectQuery("SELECT COALESCE").WillReturnError(expectedError)
+			})
+
+			It("Should return error", func() {
+				_, err := s.AddEncryptionKey(context.TODO(), []byte("key"), fakeEncrypter.Encrypt)
+				Expect(err).To(Equal(expectedError))
+			})
+		})
+
+		Context("When there are stored versions", func() {
+			BeforeEach(func() {
+				mock.ExpectQuery("SELECT COALESCE").WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+				mock.ExpectPrepare("INSERT")
+				mock.ExpectQuery("INSERT").WillReturnRows(sqlmock.NewRows([]string{"secret", "version"}).AddRow([]byte("secret"), 3))
+			})
+
+			It("Should store the key as the next version", func() {
+				keyID, err := s.AddEncryptionKey(context.TODO(), []byte("key"), fakeEncrypter.Encrypt)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(keyID).To(Equal("3"))
+				Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("ReencryptEncryptionKeys", func() {
+		Context("When there are no previous environment encryption keys", func() {
+			It("Should not re-encrypt", func() {
+				count, err := s.ReencryptEncryptionKeys(context.TODO(), fakeEncrypter.Decrypt, fakeEncrypter.Encrypt)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(count).To(Equal(0))
+				Expect(fakeEncrypter.DecryptCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("When a key is encrypted with a previous environment encryption key", func() {
+			BeforeEach(func() {
+				previousEncryptionKey := make([]byte, 32)
+				_, err := rand.Read(previousEncryptionKey)
+				Expect(err).ToNot(HaveOccurred())
+				s.previousLayerOneEncryptionKeys = [][]byte{previousEncryptionKey}
+
+				currentKey, _ := fakeEncrypter.Encrypt(context.TODO(), []byte("current"), envEncryptionKey)
+				staleKey, _ := fakeEncrypter.Encrypt(context.TODO(), []byte("stale"), previousEncryptionKey)
+				rows := sqlmock.NewRows([]string{"secret", "version", "created_at", "updated_at"}).
+					AddRow(staleKey, 1, time.Now(), time.Now()).
+					AddRow(currentKey, 2, time.Now(), time.Now())
+				mock.ExpectQuery("SELECT").WillReturnRows(rows)
+				mock.ExpectExec("UPDATE safe").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+			})
+
+			It("Should re-encrypt it with the current environment encryption key", func() {
+				count, err := s.ReencryptEncryptionKeys(context.TODO(), fakeEncrypter.Decrypt, fakeEncrypter.Encrypt)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(count).To(Equal(1))
+				Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+
+				_, plaintext, encryptionKey := fakeEncrypter.EncryptArgsForCall(fakeEncrypter.EncryptCallCount() - 1)
+				Expect(plaintext).To(Equal([]byte("stale")))
+				Expect(encryptionKey).To(Equal(envEncryptionKey))
+			})
+		})
+	})
 })
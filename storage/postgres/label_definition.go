@@ -0,0 +1,84 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+
+	"github.com/Peripli/service-manager/storage"
+
+	"github.com/Peripli/service-manager/pkg/types"
+)
+
+// LabelDefinition entity
+//go:generate smgen storage LabelDefinition github.com/Peripli/service-manager/pkg/types
+type LabelDefinition struct {
+	BaseEntity
+	ResourceType  string         `db:"resource_type"`
+	Key           string         `db:"key"`
+	Description   sql.NullString `db:"description"`
+	AllowedValues pq.StringArray `db:"allowed_values"`
+	Pattern       sql.NullString `db:"pattern"`
+	Cardinality   sql.NullString `db:"cardinality"`
+	Required      bool           `db:"required"`
+}
+
+func (ld *LabelDefinition) ToObject() types.Object {
+	return &types.LabelDefinition{
+		Base: types.Base{
+			ID:             ld.ID,
+			CreatedAt:      ld.CreatedAt,
+			UpdatedAt:      ld.UpdatedAt,
+			Labels:         map[string][]string{},
+			PagingSequence: ld.PagingSequence,
+			Ready:          ld.Ready,
+		},
+		ResourceType:  types.ObjectType(ld.ResourceType),
+		Key:           ld.Key,
+		Description:   ld.Description.String,
+		AllowedValues: ld.AllowedValues,
+		Pattern:       ld.Pattern.String,
+		Cardinality:   types.LabelCardinality(ld.Cardinality.String),
+		Required:      ld.Required,
+	}
+}
+
+func (*LabelDefinition) FromObject(object types.Object) (storage.Entity, bool) {
+	definition, ok := object.(*types.LabelDefinition)
+	if !ok {
+		return nil, false
+	}
+
+	return &LabelDefinition{
+		BaseEntity: BaseEntity{
+			ID:             definition.ID,
+			CreatedAt:      definition.CreatedAt,
+			UpdatedAt:      definition.UpdatedAt,
+			PagingSequence: definition.PagingSequence,
+			Ready:          definition.Ready,
+		},
+		ResourceType:  string(definition.ResourceType),
+		Key:           definition.Key,
+		Description:   toNullString(definition.Description),
+		AllowedValues: definition.AllowedValues,
+		Pattern:       toNullString(definition.Pattern),
+		Cardinality:   toNullString(string(definition.Cardinality)),
+		Required:      definition.Required,
+	}, true
+}
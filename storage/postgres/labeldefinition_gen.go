@@ -0,0 +1,72 @@
+// GENERATED. DO NOT MODIFY!
+
+package postgres
+
+import (
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"database/sql"
+	"time"
+)
+
+var _ PostgresEntity = &LabelDefinition{}
+
+const LabelDefinitionTable = "label_definitions"
+
+func (*LabelDefinition) LabelEntity() PostgresLabel {
+	return &LabelDefinitionLabel{}
+}
+
+func (*LabelDefinition) TableName() string {
+	return LabelDefinitionTable
+}
+
+func (e *LabelDefinition) NewLabel(id, key, value string) storage.Label {
+	now := pq.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	return &LabelDefinitionLabel{
+		BaseLabelEntity: BaseLabelEntity{
+			ID:        sql.NullString{String: id, Valid: id != ""},
+			Key:       sql.NullString{String: key, Valid: key != ""},
+			Val:       sql.NullString{String: value, Valid: value != ""},
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		LabelDefinitionID: sql.NullString{String: e.ID, Valid: e.ID != ""},
+	}
+}
+
+func (e *LabelDefinition) RowsToList(rows *sqlx.Rows) (types.ObjectList, error) {
+	rowCreator := func() EntityLabelRow {
+		return &struct {
+			*LabelDefinition
+			LabelDefinitionLabel `db:"label_definition_labels"`
+		}{}
+	}
+	result := &types.LabelDefinitions{
+		LabelDefinitions: make([]*types.LabelDefinition, 0),
+	}
+	err := rowsToList(rows, rowCreator, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type LabelDefinitionLabel struct {
+	BaseLabelEntity
+	LabelDefinitionID sql.NullString `db:"label_definition_id"`
+}
+
+func (el LabelDefinitionLabel) LabelsTableName() string {
+	return "label_definition_labels"
+}
+
+func (el LabelDefinitionLabel) ReferenceColumn() string {
+	return "label_definition_id"
+}
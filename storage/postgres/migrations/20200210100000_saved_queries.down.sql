@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP INDEX IF EXISTS saved_queries_name_resource_type_index;
+DROP INDEX IF EXISTS saved_queries_paging_sequence_uindex;
+DROP TABLE IF EXISTS saved_query_labels;
+DROP TABLE IF EXISTS saved_queries;
+
+COMMIT;
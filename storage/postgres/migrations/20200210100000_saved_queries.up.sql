@@ -0,0 +1,36 @@
+BEGIN;
+
+CREATE TABLE saved_queries
+(
+  id              varchar(100) PRIMARY KEY,
+  name            varchar(255) NOT NULL,
+  resource_type   varchar(255) NOT NULL,
+  field_query     text,
+  label_query     text,
+  owner           varchar(255),
+
+  created_at      timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at      timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  paging_sequence BIGSERIAL,
+
+  ready           boolean NOT NULL DEFAULT '1'
+);
+
+CREATE TABLE saved_query_labels
+(
+  id             varchar(100) PRIMARY KEY,
+  key            varchar(255) NOT NULL CHECK (key <> ''),
+  val            varchar(255) NOT NULL CHECK (val <> ''),
+  saved_query_id varchar(100) NOT NULL REFERENCES saved_queries (id) ON DELETE CASCADE,
+  created_at     timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at     timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (key, val, saved_query_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS saved_queries_paging_sequence_uindex
+  on saved_queries (paging_sequence);
+
+CREATE INDEX IF NOT EXISTS saved_queries_name_resource_type_index
+  on saved_queries (name, resource_type);
+
+COMMIT;
@@ -0,0 +1,33 @@
+BEGIN;
+
+DROP TRIGGER IF EXISTS brokers_search_vector_update ON brokers;
+DROP INDEX IF EXISTS broker_labels_search_vector_idx;
+DROP INDEX IF EXISTS brokers_search_vector_idx;
+ALTER TABLE brokers DROP COLUMN IF EXISTS search_vector;
+
+DROP TRIGGER IF EXISTS platforms_search_vector_update ON platforms;
+DROP INDEX IF EXISTS platform_labels_search_vector_idx;
+DROP INDEX IF EXISTS platforms_search_vector_idx;
+ALTER TABLE platforms DROP COLUMN IF EXISTS search_vector;
+
+DROP TRIGGER IF EXISTS service_offerings_search_vector_update ON service_offerings;
+DROP INDEX IF EXISTS service_offering_labels_search_vector_idx;
+DROP INDEX IF EXISTS service_offerings_search_vector_idx;
+ALTER TABLE service_offerings DROP COLUMN IF EXISTS search_vector;
+
+DROP TRIGGER IF EXISTS service_plans_search_vector_update ON service_plans;
+DROP INDEX IF EXISTS service_plan_labels_search_vector_idx;
+DROP INDEX IF EXISTS service_plans_search_vector_idx;
+ALTER TABLE service_plans DROP COLUMN IF EXISTS search_vector;
+
+DROP TRIGGER IF EXISTS service_instances_search_vector_update ON service_instances;
+DROP INDEX IF EXISTS service_instance_labels_search_vector_idx;
+DROP INDEX IF EXISTS service_instances_search_vector_idx;
+ALTER TABLE service_instances DROP COLUMN IF EXISTS search_vector;
+
+DROP TRIGGER IF EXISTS service_bindings_search_vector_update ON service_bindings;
+DROP INDEX IF EXISTS service_binding_labels_search_vector_idx;
+DROP INDEX IF EXISTS service_bindings_search_vector_idx;
+ALTER TABLE service_bindings DROP COLUMN IF EXISTS search_vector;
+
+COMMIT;
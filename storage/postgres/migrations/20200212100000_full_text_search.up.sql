@@ -0,0 +1,45 @@
+BEGIN;
+
+ALTER TABLE brokers ADD COLUMN IF NOT EXISTS search_vector tsvector;
+UPDATE brokers SET search_vector = to_tsvector('pg_catalog.simple', coalesce(name, '') || ' ' || coalesce(description, ''));
+CREATE INDEX IF NOT EXISTS brokers_search_vector_idx ON brokers USING gin (search_vector);
+CREATE INDEX IF NOT EXISTS broker_labels_search_vector_idx ON broker_labels USING gin (to_tsvector('pg_catalog.simple', val));
+DROP TRIGGER IF EXISTS brokers_search_vector_update ON brokers;
+CREATE TRIGGER brokers_search_vector_update BEFORE INSERT OR UPDATE ON brokers FOR EACH ROW EXECUTE PROCEDURE tsvector_update_trigger(search_vector, 'pg_catalog.simple', name, description);
+
+ALTER TABLE platforms ADD COLUMN IF NOT EXISTS search_vector tsvector;
+UPDATE platforms SET search_vector = to_tsvector('pg_catalog.simple', coalesce(name, '') || ' ' || coalesce(description, ''));
+CREATE INDEX IF NOT EXISTS platforms_search_vector_idx ON platforms USING gin (search_vector);
+CREATE INDEX IF NOT EXISTS platform_labels_search_vector_idx ON platform_labels USING gin (to_tsvector('pg_catalog.simple', val));
+DROP TRIGGER IF EXISTS platforms_search_vector_update ON platforms;
+CREATE TRIGGER platforms_search_vector_update BEFORE INSERT OR UPDATE ON platforms FOR EACH ROW EXECUTE PROCEDURE tsvector_update_trigger(search_vector, 'pg_catalog.simple', name, description);
+
+ALTER TABLE service_offerings ADD COLUMN IF NOT EXISTS search_vector tsvector;
+UPDATE service_offerings SET search_vector = to_tsvector('pg_catalog.simple', coalesce(name, '') || ' ' || coalesce(description, '') || ' ' || coalesce(catalog_name, ''));
+CREATE INDEX IF NOT EXISTS service_offerings_search_vector_idx ON service_offerings USING gin (search_vector);
+CREATE INDEX IF NOT EXISTS service_offering_labels_search_vector_idx ON service_offering_labels USING gin (to_tsvector('pg_catalog.simple', val));
+DROP TRIGGER IF EXISTS service_offerings_search_vector_update ON service_offerings;
+CREATE TRIGGER service_offerings_search_vector_update BEFORE INSERT OR UPDATE ON service_offerings FOR EACH ROW EXECUTE PROCEDURE tsvector_update_trigger(search_vector, 'pg_catalog.simple', name, description, catalog_name);
+
+ALTER TABLE service_plans ADD COLUMN IF NOT EXISTS search_vector tsvector;
+UPDATE service_plans SET search_vector = to_tsvector('pg_catalog.simple', coalesce(name, '') || ' ' || coalesce(description, '') || ' ' || coalesce(catalog_name, ''));
+CREATE INDEX IF NOT EXISTS service_plans_search_vector_idx ON service_plans USING gin (search_vector);
+CREATE INDEX IF NOT EXISTS service_plan_labels_search_vector_idx ON service_plan_labels USING gin (to_tsvector('pg_catalog.simple', val));
+DROP TRIGGER IF EXISTS service_plans_search_vector_update ON service_plans;
+CREATE TRIGGER service_plans_search_vector_update BEFORE INSERT OR UPDATE ON service_plans FOR EACH ROW EXECUTE PROCEDURE tsvector_update_trigger(search_vector, 'pg_catalog.simple', name, description, catalog_name);
+
+ALTER TABLE service_instances ADD COLUMN IF NOT EXISTS search_vector tsvector;
+UPDATE service_instances SET search_vector = to_tsvector('pg_catalog.simple', coalesce(name, ''));
+CREATE INDEX IF NOT EXISTS service_instances_search_vector_idx ON service_instances USING gin (search_vector);
+CREATE INDEX IF NOT EXISTS service_instance_labels_search_vector_idx ON service_instance_labels USING gin (to_tsvector('pg_catalog.simple', val));
+DROP TRIGGER IF EXISTS service_instances_search_vector_update ON service_instances;
+CREATE TRIGGER service_instances_search_vector_update BEFORE INSERT OR UPDATE ON service_instances FOR EACH ROW EXECUTE PROCEDURE tsvector_update_trigger(search_vector, 'pg_catalog.simple', name);
+
+ALTER TABLE service_bindings ADD COLUMN IF NOT EXISTS search_vector tsvector;
+UPDATE service_bindings SET search_vector = to_tsvector('pg_catalog.simple', coalesce(name, ''));
+CREATE INDEX IF NOT EXISTS service_bindings_search_vector_idx ON service_bindings USING gin (search_vector);
+CREATE INDEX IF NOT EXISTS service_binding_labels_search_vector_idx ON service_binding_labels USING gin (to_tsvector('pg_catalog.simple', val));
+DROP TRIGGER IF EXISTS service_bindings_search_vector_update ON service_bindings;
+CREATE TRIGGER service_bindings_search_vector_update BEFORE INSERT OR UPDATE ON service_bindings FOR EACH ROW EXECUTE PROCEDURE tsvector_update_trigger(search_vector, 'pg_catalog.simple', name);
+
+COMMIT;
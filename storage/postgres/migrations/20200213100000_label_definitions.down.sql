@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP INDEX IF EXISTS label_definitions_paging_sequence_uindex;
+DROP TABLE IF EXISTS label_definition_labels;
+DROP TABLE IF EXISTS label_definitions;
+
+COMMIT;
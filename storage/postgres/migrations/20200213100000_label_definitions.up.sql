@@ -0,0 +1,36 @@
+BEGIN;
+
+CREATE TABLE label_definitions
+(
+  id              varchar(100) PRIMARY KEY,
+  resource_type   varchar(255) NOT NULL,
+  key             varchar(255) NOT NULL CHECK (key <> ''),
+  description     text,
+  allowed_values  text[],
+  pattern         text,
+  cardinality     varchar(10),
+  required        boolean NOT NULL DEFAULT '0',
+
+  created_at      timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at      timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  paging_sequence BIGSERIAL,
+
+  ready           boolean NOT NULL DEFAULT '1',
+  UNIQUE (resource_type, key)
+);
+
+CREATE TABLE label_definition_labels
+(
+  id                  varchar(100) PRIMARY KEY,
+  key                 varchar(255) NOT NULL CHECK (key <> ''),
+  val                 varchar(255) NOT NULL CHECK (val <> ''),
+  label_definition_id varchar(100) NOT NULL REFERENCES label_definitions (id) ON DELETE CASCADE,
+  created_at          timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at          timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (key, val, label_definition_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS label_definitions_paging_sequence_uindex
+  on label_definitions (paging_sequence);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE safe DROP CONSTRAINT IF EXISTS safe_version_unique;
+ALTER TABLE safe DROP COLUMN IF EXISTS version;
+
+COMMIT;
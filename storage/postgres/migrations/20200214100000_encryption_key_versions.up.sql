@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE safe ADD COLUMN version INTEGER;
+UPDATE safe SET version = 1;
+ALTER TABLE safe ALTER COLUMN version SET NOT NULL;
+ALTER TABLE safe ADD CONSTRAINT safe_version_unique UNIQUE (version);
+
+COMMIT;
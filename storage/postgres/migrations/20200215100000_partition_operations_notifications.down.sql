@@ -0,0 +1,65 @@
+BEGIN;
+
+-- the tables are partitioned only on PostgreSQL 11 and later
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_class WHERE relname = 'operations' AND relkind = 'p') THEN
+    RETURN;
+  END IF;
+
+  -- operations
+
+  ALTER TABLE operations RENAME TO operations_partitioned;
+  ALTER INDEX operations_paging_sequence_uindex RENAME TO operations_partitioned_paging_sequence_uindex;
+  ALTER SEQUENCE operations_paging_sequence_seq OWNED BY NONE;
+
+  CREATE TABLE operations
+  (
+    LIKE operations_partitioned INCLUDING DEFAULTS,
+    PRIMARY KEY (id)
+  );
+
+  CREATE UNIQUE INDEX operations_paging_sequence_uindex ON operations (paging_sequence);
+
+  INSERT INTO operations SELECT * FROM operations_partitioned;
+  ALTER SEQUENCE operations_paging_sequence_seq OWNED BY operations.paging_sequence;
+  DROP TABLE operations_partitioned;
+  DROP FUNCTION IF EXISTS register_operation_id();
+
+  -- notifications
+
+  ALTER TABLE notifications RENAME TO notifications_partitioned;
+  ALTER INDEX notifications_paging_sequence_uindex RENAME TO notifications_partitioned_paging_sequence_uindex;
+  ALTER SEQUENCE notifications_revision_seq OWNED BY NONE;
+  ALTER SEQUENCE notifications_paging_sequence_seq OWNED BY NONE;
+
+  CREATE TABLE notifications
+  (
+    LIKE notifications_partitioned INCLUDING DEFAULTS,
+    PRIMARY KEY (id),
+    FOREIGN KEY (platform_id) REFERENCES platforms (id) ON DELETE CASCADE
+  );
+
+  CREATE UNIQUE INDEX notifications_paging_sequence_uindex ON notifications (paging_sequence);
+
+  INSERT INTO notifications SELECT * FROM notifications_partitioned;
+  ALTER SEQUENCE notifications_revision_seq OWNED BY notifications.revision;
+  ALTER SEQUENCE notifications_paging_sequence_seq OWNED BY notifications.paging_sequence;
+  DROP TABLE notifications_partitioned;
+  DROP FUNCTION IF EXISTS register_notification_id();
+
+  CREATE TRIGGER notifications_broadcast
+    AFTER INSERT ON notifications
+    FOR EACH ROW EXECUTE PROCEDURE notify_sm();
+
+  DROP TABLE operation_ids CASCADE;
+  DROP TABLE notification_ids CASCADE;
+
+  DELETE FROM operation_labels WHERE operation_id NOT IN (SELECT id FROM operations);
+  DELETE FROM notification_labels WHERE notification_id NOT IN (SELECT id FROM notifications);
+  ALTER TABLE operation_labels ADD CONSTRAINT operation_labels_operation_id_fkey FOREIGN KEY (operation_id) REFERENCES operations (id) ON DELETE CASCADE;
+  ALTER TABLE notification_labels ADD CONSTRAINT notification_labels_notification_id_fkey FOREIGN KEY (notification_id) REFERENCES notifications (id) ON DELETE CASCADE;
+END
+$$;
+
+COMMIT;
@@ -0,0 +1,110 @@
+BEGIN;
+
+-- declarative partitioning with default partitions and primary keys requires PostgreSQL 11,
+-- on earlier versions the tables are not partitioned and expired rows are deleted by the regular cleanup
+DO $$
+BEGIN
+  IF current_setting('server_version_num')::integer < 110000 THEN
+    RAISE NOTICE 'Skipping partitioning of operations and notifications as it requires PostgreSQL 11';
+    RETURN;
+  END IF;
+
+  -- the primary keys of partitioned tables must contain the partition key, so the ids are kept unique
+  -- by registering them in separate tables which are also referenced by the labels instead
+  ALTER TABLE operation_labels DROP CONSTRAINT IF EXISTS operation_labels_operation_id_fkey;
+  ALTER TABLE notification_labels DROP CONSTRAINT IF EXISTS notification_labels_notification_id_fkey;
+
+  -- operations
+
+  ALTER TABLE operations RENAME TO operations_unpartitioned;
+  ALTER INDEX operations_paging_sequence_uindex RENAME TO operations_unpartitioned_paging_sequence_uindex;
+  ALTER SEQUENCE operations_paging_sequence_seq OWNED BY NONE;
+
+  CREATE TABLE operations
+  (
+    LIKE operations_unpartitioned INCLUDING DEFAULTS,
+    PRIMARY KEY (id, created_at)
+  ) PARTITION BY RANGE (created_at);
+
+  CREATE UNIQUE INDEX operations_paging_sequence_uindex ON operations (paging_sequence, created_at);
+  CREATE TABLE operations_default PARTITION OF operations DEFAULT;
+
+  INSERT INTO operations SELECT * FROM operations_unpartitioned;
+  ALTER SEQUENCE operations_paging_sequence_seq OWNED BY operations.paging_sequence;
+  DROP TABLE operations_unpartitioned;
+
+  CREATE TABLE operation_ids
+  (
+    id varchar(100) PRIMARY KEY
+  );
+
+  INSERT INTO operation_ids SELECT id FROM operations;
+  ALTER TABLE operation_labels ADD CONSTRAINT operation_labels_operation_id_fkey FOREIGN KEY (operation_id) REFERENCES operation_ids (id) ON DELETE CASCADE;
+
+  CREATE OR REPLACE FUNCTION register_operation_id() RETURNS TRIGGER AS $body$
+    BEGIN
+      IF TG_OP = 'INSERT' THEN
+        INSERT INTO operation_ids (id) VALUES (NEW.id);
+      ELSE
+        DELETE FROM operation_ids WHERE id = OLD.id;
+      END IF;
+      RETURN NULL;
+    END;
+  $body$ LANGUAGE plpgsql;
+
+  CREATE TRIGGER operations_register_id
+    AFTER INSERT OR DELETE ON operations
+    FOR EACH ROW EXECUTE PROCEDURE register_operation_id();
+
+  -- notifications
+
+  ALTER TABLE notifications RENAME TO notifications_unpartitioned;
+  ALTER INDEX notifications_paging_sequence_uindex RENAME TO notifications_unpartitioned_paging_sequence_uindex;
+  ALTER SEQUENCE notifications_revision_seq OWNED BY NONE;
+  ALTER SEQUENCE notifications_paging_sequence_seq OWNED BY NONE;
+
+  CREATE TABLE notifications
+  (
+    LIKE notifications_unpartitioned INCLUDING DEFAULTS,
+    PRIMARY KEY (id, created_at),
+    FOREIGN KEY (platform_id) REFERENCES platforms (id) ON DELETE CASCADE
+  ) PARTITION BY RANGE (created_at);
+
+  CREATE UNIQUE INDEX notifications_paging_sequence_uindex ON notifications (paging_sequence, created_at);
+  CREATE TABLE notifications_default PARTITION OF notifications DEFAULT;
+
+  INSERT INTO notifications SELECT * FROM notifications_unpartitioned;
+  ALTER SEQUENCE notifications_revision_seq OWNED BY notifications.revision;
+  ALTER SEQUENCE notifications_paging_sequence_seq OWNED BY notifications.paging_sequence;
+  DROP TABLE notifications_unpartitioned;
+
+  CREATE TRIGGER notifications_broadcast
+    AFTER INSERT ON notifications
+    FOR EACH ROW EXECUTE PROCEDURE notify_sm();
+
+  CREATE TABLE notification_ids
+  (
+    id varchar(100) PRIMARY KEY
+  );
+
+  INSERT INTO notification_ids SELECT id FROM notifications;
+  ALTER TABLE notification_labels ADD CONSTRAINT notification_labels_notification_id_fkey FOREIGN KEY (notification_id) REFERENCES notification_ids (id) ON DELETE CASCADE;
+
+  CREATE OR REPLACE FUNCTION register_notification_id() RETURNS TRIGGER AS $body$
+    BEGIN
+      IF TG_OP = 'INSERT' THEN
+        INSERT INTO notification_ids (id) VALUES (NEW.id);
+      ELSE
+        DELETE FROM notification_ids WHERE id = OLD.id;
+      END IF;
+      RETURN NULL;
+    END;
+  $body$ LANGUAGE plpgsql;
+
+  CREATE TRIGGER notifications_register_id
+    AFTER INSERT OR DELETE ON notifications
+    FOR EACH ROW EXECUTE PROCEDURE register_notification_id();
+END
+$$;
+
+COMMIT;
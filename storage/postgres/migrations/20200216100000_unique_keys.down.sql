@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS unique_keys;
+DROP FUNCTION IF EXISTS delete_unique_keys() CASCADE;
+
+COMMIT;
@@ -0,0 +1,20 @@
+BEGIN;
+
+CREATE TABLE unique_keys
+(
+  constraint_name varchar(100) NOT NULL,
+  unique_key      text         NOT NULL,
+  entity_id       varchar(100) NOT NULL,
+  PRIMARY KEY (constraint_name, unique_key)
+);
+
+CREATE INDEX unique_keys_entity_id_idx ON unique_keys (entity_id);
+
+CREATE OR REPLACE FUNCTION delete_unique_keys() RETURNS TRIGGER AS $$
+  BEGIN
+    DELETE FROM unique_keys WHERE entity_id = OLD.id;
+    RETURN NULL;
+  END;
+$$ LANGUAGE plpgsql;
+
+COMMIT;
@@ -0,0 +1,29 @@
+BEGIN;
+
+DROP TRIGGER IF EXISTS brokers_record_changes ON brokers;
+DROP TRIGGER IF EXISTS broker_labels_record_changes ON broker_labels;
+DROP TRIGGER IF EXISTS platforms_record_changes ON platforms;
+DROP TRIGGER IF EXISTS platform_labels_record_changes ON platform_labels;
+DROP TRIGGER IF EXISTS service_offerings_record_changes ON service_offerings;
+DROP TRIGGER IF EXISTS service_offering_labels_record_changes ON service_offering_labels;
+DROP TRIGGER IF EXISTS service_plans_record_changes ON service_plans;
+DROP TRIGGER IF EXISTS service_plan_labels_record_changes ON service_plan_labels;
+DROP TRIGGER IF EXISTS visibilities_record_changes ON visibilities;
+DROP TRIGGER IF EXISTS visibility_labels_record_changes ON visibility_labels;
+DROP TRIGGER IF EXISTS service_instances_record_changes ON service_instances;
+DROP TRIGGER IF EXISTS service_instance_labels_record_changes ON service_instance_labels;
+DROP TRIGGER IF EXISTS service_bindings_record_changes ON service_bindings;
+DROP TRIGGER IF EXISTS service_binding_labels_record_changes ON service_binding_labels;
+DROP TRIGGER IF EXISTS operations_record_changes ON operations;
+DROP TRIGGER IF EXISTS operation_labels_record_changes ON operation_labels;
+DROP TRIGGER IF EXISTS saved_queries_record_changes ON saved_queries;
+DROP TRIGGER IF EXISTS saved_query_labels_record_changes ON saved_query_labels;
+DROP TRIGGER IF EXISTS label_definitions_record_changes ON label_definitions;
+DROP TRIGGER IF EXISTS label_definition_labels_record_changes ON label_definition_labels;
+
+DROP FUNCTION IF EXISTS record_label_change();
+DROP FUNCTION IF EXISTS record_change();
+DROP TABLE IF EXISTS changes;
+DROP FUNCTION IF EXISTS assign_change_revision();
+
+COMMIT;
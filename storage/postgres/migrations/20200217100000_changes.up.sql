@@ -0,0 +1,160 @@
+BEGIN;
+
+CREATE SEQUENCE changes_revision_seq;
+
+CREATE TABLE changes
+(
+  id             bigserial    PRIMARY KEY,
+  revision       bigint       UNIQUE,
+  resource_type  varchar(100) NOT NULL,
+  resource_id    varchar(100) NOT NULL,
+  operation      varchar(20)  NOT NULL,
+  transaction_id bigint       NOT NULL DEFAULT txid_current(),
+  created_at     timestamptz  NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER SEQUENCE changes_revision_seq OWNED BY changes.revision;
+
+CREATE INDEX changes_resource_type_revision_idx ON changes (resource_type, revision);
+CREATE INDEX changes_transaction_id_resource_id_idx ON changes (transaction_id, resource_id);
+CREATE INDEX changes_created_at_idx ON changes (created_at);
+
+-- assign_change_revision assigns the revision of a change when its transaction commits. The revisions are assigned
+-- while holding a lock which is released only after the transaction has committed, so a transaction in progress never
+-- commits a change with a lower revision than the visible changes.
+CREATE OR REPLACE FUNCTION assign_change_revision() RETURNS TRIGGER AS $$
+  BEGIN
+    PERFORM pg_advisory_xact_lock(114);
+    UPDATE changes SET revision = nextval('changes_revision_seq') WHERE id = NEW.id;
+    RETURN NULL;
+  END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER changes_assign_revision
+  AFTER INSERT ON changes
+  DEFERRABLE INITIALLY DEFERRED
+  FOR EACH ROW EXECUTE PROCEDURE assign_change_revision();
+
+-- record_change records the change of a row of an entity table. Its argument is the type of the entity.
+CREATE OR REPLACE FUNCTION record_change() RETURNS TRIGGER AS $$
+  DECLARE
+    entity_id varchar(100);
+  BEGIN
+    IF TG_OP = 'DELETE' THEN
+      entity_id := OLD.id;
+    ELSE
+      entity_id := NEW.id;
+    END IF;
+    INSERT INTO changes (resource_type, resource_id, operation) VALUES (TG_ARGV[0], entity_id, lower(TG_OP));
+    PERFORM pg_notify('changes', TG_ARGV[0]);
+    RETURN NULL;
+  END;
+$$ LANGUAGE plpgsql;
+
+-- record_label_change records the change of the labels of an entity as an update of the entity, unless the entity
+-- has already been changed in the same transaction or no longer exists. Its arguments are the type of the entity,
+-- the column of the labels table referencing the entity and the table of the entity.
+CREATE OR REPLACE FUNCTION record_label_change() RETURNS TRIGGER AS $$
+  DECLARE
+    entity_id     varchar(100);
+    entity_exists boolean;
+  BEGIN
+    IF TG_OP = 'DELETE' THEN
+      entity_id := to_jsonb(OLD) ->> TG_ARGV[1];
+    ELSE
+      entity_id := to_jsonb(NEW) ->> TG_ARGV[1];
+    END IF;
+    IF EXISTS (SELECT 1 FROM changes WHERE transaction_id = txid_current() AND resource_id = entity_id) THEN
+      RETURN NULL;
+    END IF;
+    EXECUTE format('SELECT EXISTS (SELECT 1 FROM %I WHERE id = $1)', TG_ARGV[2]) INTO entity_exists USING entity_id;
+    IF NOT entity_exists THEN
+      RETURN NULL;
+    END IF;
+    INSERT INTO changes (resource_type, resource_id, operation) VALUES (TG_ARGV[0], entity_id, 'update');
+    PERFORM pg_notify('changes', TG_ARGV[0]);
+    RETURN NULL;
+  END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER brokers_record_changes
+  AFTER INSERT OR UPDATE OR DELETE ON brokers
+  FOR EACH ROW EXECUTE PROCEDURE record_change('/v1/service_brokers');
+
+CREATE TRIGGER broker_labels_record_changes
+  AFTER INSERT OR UPDATE OR DELETE ON broker_labels
+  FOR EACH ROW EXECUTE PROCEDURE record_label_change('/v1/service_brokers', 'broker_id', 'brokers');
+
+CREATE TRIGGER platforms_record_changes
+  AFTER INSERT OR UPDATE OR DELETE ON platforms
+  FOR EACH ROW EXECUTE PROCEDURE record_change('/v1/platforms');
+
+CREATE TRIGGER platform_labels_record_changes
+  AFTER INSERT OR UPDATE OR DELETE ON platform_labels
+  FOR EACH ROW EXECUTE PROCEDURE record_label_change('/v1/platforms', 'platform_id', 'platforms');
+
+CREATE TRIGGER service_offerings_record_changes
+  AFTER INSERT OR UPDATE OR DELETE ON service_offerings
+  FOR EACH ROW EXECUTE PROCEDURE record_change('/v1/service_offerings');
+
+CREATE TRIGGER service_offering_labels_record_changes
+  AFTER INSERT OR UPDATE OR DELETE ON service_offering_labels
+  FOR EACH ROW EXECUTE PROCEDURE record_label_change('/v1/service_offerings', 'service_offering_id', 'service_offerings');
+
+CREATE TRIGGER service_plans_record_changes
+  AFTER INSERT OR UPDATE OR DELETE ON service_plans
+  FOR EACH ROW EXECUTE PROCEDURE record_change('/v1/service_plans');
+
+CREATE TRIGGER service_plan_labels_record_changes
+  AFTER INSERT OR UPDATE OR DELETE ON service_plan_labels
+  FOR EACH ROW EXECUTE PROCEDURE record_label_change('/v1/service_plans', 'service_plan_id', 'service_plans');
+
+CREATE TRIGGER visibilities_record_changes
+  AFTER INSERT OR UPDATE OR DELETE ON visibilities
+  FOR EACH ROW EXECUTE PROCEDURE record_change('/v1/visibilities');
+
+CREATE TRIGGER visibility_labels_record_changes
+  AFTER INSERT OR UPDATE OR DELETE ON visibility_labels
+  FOR EACH ROW EXECUTE PROCEDURE record_label_change('/v1/visibilities', 'visibility_id', 'visibilities');
+
+CREATE TRIGGER service_instances_record_changes
+  AFTER INSERT OR UPDATE OR DELETE ON service_instances
+  FOR EACH ROW EXECUTE PROCEDURE record_change('/v1/service_instances');
+
+CREATE TRIGGER service_instance_labels_record_changes
+  AFTER INSERT OR UPDATE OR DELETE ON service_instance_labels
+  FOR EACH ROW EXECUTE PROCEDURE record_label_change('/v1/service_instances', 'service_instance_id', 'service_instances');
+
+CREATE TRIGGER service_bindings_record_changes
+  AFTER INSERT OR UPDATE OR DELETE ON service_bindings
+  FOR EACH ROW EXECUTE PROCEDURE record_change('/v1/service_bindings');
+
+CREATE TRIGGER service_binding_labels_record_changes
+  AFTER INSERT OR UPDATE OR DELETE ON service_binding_labels
+  FOR EACH ROW EXECUTE PROCEDURE record_label_change('/v1/service_bindings', 'service_binding_id', 'service_bindings');
+
+CREATE TRIGGER operations_record_changes
+  AFTER INSERT OR UPDATE OR DELETE ON operations
+  FOR EACH ROW EXECUTE PROCEDURE record_change('/operations');
+
+CREATE TRIGGER operation_labels_record_changes
+  AFTER INSERT OR UPDATE OR DELETE ON operation_labels
+  FOR EACH ROW EXECUTE PROCEDURE record_label_change('/operations', 'operation_id', 'operations');
+
+CREATE TRIGGER saved_queries_record_changes
+  AFTER INSERT OR UPDATE OR DELETE ON saved_queries
+  FOR EACH ROW EXECUTE PROCEDURE record_change('/v1/saved_queries');
+
+CREATE TRIGGER saved_query_labels_record_changes
+  AFTER INSERT OR UPDATE OR DELETE ON saved_query_labels
+  FOR EACH ROW EXECUTE PROCEDURE record_label_change('/v1/saved_queries', 'saved_query_id', 'saved_queries');
+
+CREATE TRIGGER label_definitions_record_changes
+  AFTER INSERT OR UPDATE OR DELETE ON label_definitions
+  FOR EACH ROW EXECUTE PROCEDURE record_change('/v1/label_definitions');
+
+CREATE TRIGGER label_definition_labels_record_changes
+  AFTER INSERT OR UPDATE OR DELETE ON label_definition_labels
+  FOR EACH ROW EXECUTE PROCEDURE record_label_change('/v1/label_definitions', 'label_definition_id', 'label_definitions');
+
+COMMIT;
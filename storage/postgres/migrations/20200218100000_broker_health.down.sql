@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE brokers DROP COLUMN health_error;
+ALTER TABLE brokers DROP COLUMN health_status;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE brokers ADD COLUMN health_status varchar(20) NOT NULL DEFAULT '';
+ALTER TABLE brokers ADD COLUMN health_error text NOT NULL DEFAULT '';
+
+COMMIT;
@@ -0,0 +1,10 @@
+BEGIN;
+
+ALTER TABLE brokers DROP COLUMN oauth2_scopes;
+ALTER TABLE brokers DROP COLUMN oauth2_client_secret;
+ALTER TABLE brokers DROP COLUMN oauth2_client_id;
+ALTER TABLE brokers DROP COLUMN oauth2_token_url;
+ALTER TABLE brokers DROP COLUMN tls_client_key;
+ALTER TABLE brokers DROP COLUMN tls_client_certificate;
+
+COMMIT;
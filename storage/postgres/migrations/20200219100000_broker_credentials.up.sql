@@ -0,0 +1,10 @@
+BEGIN;
+
+ALTER TABLE brokers ADD COLUMN tls_client_certificate text NOT NULL DEFAULT '';
+ALTER TABLE brokers ADD COLUMN tls_client_key text NOT NULL DEFAULT '';
+ALTER TABLE brokers ADD COLUMN oauth2_token_url text NOT NULL DEFAULT '';
+ALTER TABLE brokers ADD COLUMN oauth2_client_id varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE brokers ADD COLUMN oauth2_client_secret text NOT NULL DEFAULT '';
+ALTER TABLE brokers ADD COLUMN oauth2_scopes text NOT NULL DEFAULT '';
+
+COMMIT;
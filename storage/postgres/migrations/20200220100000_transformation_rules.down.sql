@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP INDEX IF EXISTS transformation_rules_paging_sequence_uindex;
+DROP TABLE IF EXISTS transformation_rule_labels;
+DROP TABLE IF EXISTS transformation_rules;
+
+COMMIT;
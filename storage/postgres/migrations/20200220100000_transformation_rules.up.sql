@@ -0,0 +1,46 @@
+BEGIN;
+
+CREATE TABLE transformation_rules
+(
+  id                  varchar(100) PRIMARY KEY,
+  name                varchar(255) NOT NULL UNIQUE CHECK (name <> ''),
+  description         text,
+  broker_id           varchar(100) REFERENCES brokers (id) ON DELETE CASCADE,
+  service_offering_id varchar(100) REFERENCES service_offerings (id) ON DELETE CASCADE,
+  service_plan_id     varchar(100) REFERENCES service_plans (id) ON DELETE CASCADE,
+  platform_type       varchar(255),
+  operations          text[] NOT NULL,
+  phase               varchar(10),
+  priority            integer NOT NULL DEFAULT 0,
+  actions             json NOT NULL,
+
+  created_at          timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at          timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  paging_sequence     BIGSERIAL,
+
+  ready               boolean NOT NULL DEFAULT '1'
+);
+
+CREATE TABLE transformation_rule_labels
+(
+  id                     varchar(100) PRIMARY KEY,
+  key                    varchar(255) NOT NULL CHECK (key <> ''),
+  val                    varchar(255) NOT NULL CHECK (val <> ''),
+  transformation_rule_id varchar(100) NOT NULL REFERENCES transformation_rules (id) ON DELETE CASCADE,
+  created_at             timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at             timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (key, val, transformation_rule_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS transformation_rules_paging_sequence_uindex
+  on transformation_rules (paging_sequence);
+
+CREATE TRIGGER transformation_rules_record_changes
+  AFTER INSERT OR UPDATE OR DELETE ON transformation_rules
+  FOR EACH ROW EXECUTE PROCEDURE record_change('/v1/transformation_rules');
+
+CREATE TRIGGER transformation_rule_labels_record_changes
+  AFTER INSERT OR UPDATE OR DELETE ON transformation_rule_labels
+  FOR EACH ROW EXECUTE PROCEDURE record_label_change('/v1/transformation_rules', 'transformation_rule_id', 'transformation_rules');
+
+COMMIT;
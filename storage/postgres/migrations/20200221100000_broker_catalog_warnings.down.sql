@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE brokers DROP COLUMN catalog_warnings;
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE brokers ADD COLUMN catalog_warnings json NOT NULL DEFAULT '[]';
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP INDEX IF EXISTS service_instance_upgrades_state_updated_at_index;
+DROP INDEX IF EXISTS service_instance_upgrades_paging_sequence_uindex;
+DROP TABLE IF EXISTS service_instance_upgrade_labels;
+DROP TABLE IF EXISTS service_instance_upgrades;
+
+COMMIT;
@@ -0,0 +1,43 @@
+BEGIN;
+
+-- unlike the other entity tables the changes of upgrades are not recorded, as running upgrades update their
+-- rows periodically to show that they are alive
+CREATE TABLE service_instance_upgrades
+(
+  id                varchar(100) PRIMARY KEY,
+  service_plan_id   varchar(100),
+  broker_id         varchar(100),
+  concurrency       integer NOT NULL CHECK (concurrency > 0),
+  canary_percentage integer NOT NULL DEFAULT 0 CHECK (canary_percentage BETWEEN 0 AND 100),
+  paused            boolean NOT NULL DEFAULT '0',
+  instances         json NOT NULL,
+  state             varchar(255) NOT NULL,
+  description       text,
+  errors            json DEFAULT '{}',
+  correlation_id    varchar(255),
+
+  created_at        timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at        timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  paging_sequence   BIGSERIAL,
+
+  ready             boolean NOT NULL DEFAULT '1'
+);
+
+CREATE TABLE service_instance_upgrade_labels
+(
+  id                          varchar(100) PRIMARY KEY,
+  key                         varchar(255) NOT NULL CHECK (key <> ''),
+  val                         varchar(255) NOT NULL CHECK (val <> ''),
+  service_instance_upgrade_id varchar(100) NOT NULL REFERENCES service_instance_upgrades (id) ON DELETE CASCADE,
+  created_at                  timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at                  timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (key, val, service_instance_upgrade_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS service_instance_upgrades_paging_sequence_uindex
+  on service_instance_upgrades (paging_sequence);
+
+CREATE INDEX IF NOT EXISTS service_instance_upgrades_state_updated_at_index
+  on service_instance_upgrades (state, updated_at);
+
+COMMIT;
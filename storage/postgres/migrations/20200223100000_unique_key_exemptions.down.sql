@@ -0,0 +1,12 @@
+BEGIN;
+
+CREATE OR REPLACE FUNCTION delete_unique_keys() RETURNS TRIGGER AS $$
+  BEGIN
+    DELETE FROM unique_keys WHERE entity_id = OLD.id;
+    RETURN NULL;
+  END;
+$$ LANGUAGE plpgsql;
+
+DROP TABLE IF EXISTS unique_key_exemptions;
+
+COMMIT;
@@ -0,0 +1,20 @@
+BEGIN;
+
+CREATE TABLE unique_key_exemptions
+(
+  constraint_name varchar(100) NOT NULL,
+  entity_id       varchar(100) NOT NULL,
+  PRIMARY KEY (constraint_name, entity_id)
+);
+
+CREATE INDEX unique_key_exemptions_entity_id_idx ON unique_key_exemptions (entity_id);
+
+CREATE OR REPLACE FUNCTION delete_unique_keys() RETURNS TRIGGER AS $$
+  BEGIN
+    DELETE FROM unique_keys WHERE entity_id = OLD.id;
+    DELETE FROM unique_key_exemptions WHERE entity_id = OLD.id;
+    RETURN NULL;
+  END;
+$$ LANGUAGE plpgsql;
+
+COMMIT;
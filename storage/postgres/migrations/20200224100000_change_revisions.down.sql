@@ -0,0 +1,27 @@
+BEGIN;
+
+DROP TABLE IF EXISTS changes_deleted_revision;
+DROP INDEX IF EXISTS changes_pending_idx;
+
+CREATE TRIGGER operations_record_changes
+  AFTER INSERT OR UPDATE OR DELETE ON operations
+  FOR EACH ROW EXECUTE PROCEDURE record_change('/operations');
+
+CREATE TRIGGER operation_labels_record_changes
+  AFTER INSERT OR UPDATE OR DELETE ON operation_labels
+  FOR EACH ROW EXECUTE PROCEDURE record_label_change('/operations', 'operation_id', 'operations');
+
+CREATE OR REPLACE FUNCTION assign_change_revision() RETURNS TRIGGER AS $$
+  BEGIN
+    PERFORM pg_advisory_xact_lock(114);
+    UPDATE changes SET revision = nextval('changes_revision_seq') WHERE id = NEW.id;
+    RETURN NULL;
+  END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER changes_assign_revision
+  AFTER INSERT ON changes
+  DEFERRABLE INITIALLY DEFERRED
+  FOR EACH ROW EXECUTE PROCEDURE assign_change_revision();
+
+COMMIT;
@@ -0,0 +1,23 @@
+BEGIN;
+
+-- the revisions of the changes are assigned by the readers of the change feed instead of a trigger which serialized
+-- all writing transactions on a global lock
+DROP TRIGGER IF EXISTS changes_assign_revision ON changes;
+DROP FUNCTION IF EXISTS assign_change_revision();
+
+-- operations are internal and change too often to be followed
+DROP TRIGGER IF EXISTS operations_record_changes ON operations;
+DROP TRIGGER IF EXISTS operation_labels_record_changes ON operation_labels;
+DELETE FROM changes WHERE resource_type = '/operations';
+
+CREATE INDEX changes_pending_idx ON changes (transaction_id, id) WHERE revision IS NULL;
+
+-- changes_deleted_revision holds the highest revision of the deleted changes
+CREATE TABLE changes_deleted_revision
+(
+  revision bigint NOT NULL
+);
+
+INSERT INTO changes_deleted_revision (revision) VALUES (0);
+
+COMMIT;
@@ -0,0 +1,23 @@
+BEGIN;
+
+ALTER TABLE service_instance_upgrades ADD COLUMN instances json NOT NULL DEFAULT '[]';
+
+UPDATE service_instance_upgrades u
+SET instances = i.instances
+FROM (SELECT service_instance_upgrade_id,
+             json_agg(json_strip_nulls(json_build_object(
+               'instance_id', instance_id,
+               'version', version,
+               'maintenance_info', maintenance_info,
+               'operation_id', operation_id,
+               'state', state,
+               'error', error)) ORDER BY position) AS instances
+      FROM service_instance_upgrade_instances
+      GROUP BY service_instance_upgrade_id) i
+WHERE u.id = i.service_instance_upgrade_id;
+
+ALTER TABLE service_instance_upgrades ALTER COLUMN instances DROP DEFAULT;
+
+DROP TABLE IF EXISTS service_instance_upgrade_instances;
+
+COMMIT;
@@ -0,0 +1,33 @@
+BEGIN;
+
+-- the instances of an upgrade are stored in their own rows, so that the upgrade of an instance is stored
+-- without rewriting and locking the whole upgrade
+CREATE TABLE service_instance_upgrade_instances
+(
+  service_instance_upgrade_id varchar(100) NOT NULL REFERENCES service_instance_upgrades (id) ON DELETE CASCADE,
+  position                    integer NOT NULL,
+  instance_id                 varchar(100) NOT NULL,
+  version                     varchar(255),
+  maintenance_info            json,
+  operation_id                varchar(100),
+  state                       varchar(255),
+  error                       text,
+  PRIMARY KEY (service_instance_upgrade_id, instance_id)
+);
+
+INSERT INTO service_instance_upgrade_instances
+  (service_instance_upgrade_id, position, instance_id, version, maintenance_info, operation_id, state, error)
+SELECT u.id,
+       i.position,
+       i.instance ->> 'instance_id',
+       i.instance ->> 'version',
+       i.instance -> 'maintenance_info',
+       i.instance ->> 'operation_id',
+       i.instance ->> 'state',
+       i.instance ->> 'error'
+FROM service_instance_upgrades u,
+     json_array_elements(u.instances) WITH ORDINALITY AS i(instance, position);
+
+ALTER TABLE service_instance_upgrades DROP COLUMN instances;
+
+COMMIT;
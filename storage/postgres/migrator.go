@@ -0,0 +1,253 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/golang-migrate/migrate"
+	migratepg "github.com/golang-migrate/migrate/database/postgres"
+	"github.com/golang-migrate/migrate/source"
+)
+
+// MigrationStatus describes the state of the database schema
+type MigrationStatus struct {
+	// Version is the version of the last applied migration or 0 if no migration is applied
+	Version uint
+	// Dirty is true if the last applied migration failed and the schema has to be fixed manually
+	Dirty bool
+	// Pending are the versions of the migrations which are not yet applied
+	Pending []uint
+}
+
+// UpToDate returns true if all migrations are applied and the schema is not dirty
+func (s *MigrationStatus) UpToDate() bool {
+	return !s.Dirty && len(s.Pending) == 0
+}
+
+// Migrator applies the database schema migrations. Migrations are executed while holding a PostgreSQL
+// advisory lock, so only one Service Manager instance migrates the database schema at a time.
+type Migrator struct {
+	migrate *migrate.Migrate
+	source  source.Driver
+	db      *sql.DB
+}
+
+// NewMigrator creates a Migrator for the database using the migrations at the specified URL.
+// The lockTimeout is how long to wait for other instances to finish migrating the database schema.
+func NewMigrator(db *sql.DB, migrationsURL string, lockTimeout time.Duration) (*Migrator, error) {
+	sourceDriver, err := source.Open(migrationsURL)
+	if err != nil {
+		return nil, err
+	}
+	databaseDriver, err := migratepg.WithInstance(db, &migratepg.Config{})
+	if err != nil {
+		return nil, err
+	}
+	m, err := migrate.NewWithInstance(migrationsURL, sourceDriver, postgresDriverName, databaseDriver)
+	if err != nil {
+		return nil, err
+	}
+	m.Log = migrateLogger{}
+	if lockTimeout > 0 {
+		m.LockTimeout = lockTimeout
+	}
+
+	return &Migrator{
+		migrate: m,
+		source:  sourceDriver,
+	}, nil
+}
+
+// OpenMigrator connects to the storage with the specified settings and creates a Migrator for it.
+// Closing the Migrator closes the connection.
+func OpenMigrator(settings *storage.Settings) (*Migrator, error) {
+	db, err := sql.Open(postgresDriverName, dataSourceName(settings))
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to PostgreSQL: %s", err)
+	}
+	migrator, err := NewMigrator(db, settings.MigrationsURL, settings.MigrationsLockTimeout)
+	if err != nil {
+		if closeErr := db.Close(); closeErr != nil {
+			log.D().WithError(closeErr).Error("could not close connection to PostgreSQL")
+		}
+		return nil, err
+	}
+	migrator.db = db
+	return migrator, nil
+}
+
+// Status returns the current state of the database schema
+func (m *Migrator) Status() (*MigrationStatus, error) {
+	version, dirty, err := m.migrate.Version()
+	applied := true
+	if err == migrate.ErrNilVersion {
+		applied = false
+	} else if err != nil {
+		return nil, err
+	}
+
+	pending, err := m.versionsAfter(version, applied)
+	if err != nil {
+		return nil, err
+	}
+	return &MigrationStatus{
+		Version: version,
+		Dirty:   dirty,
+		Pending: pending,
+	}, nil
+}
+
+// Up applies the next n pending migrations or all of them if n is not positive
+func (m *Migrator) Up(n int) error {
+	var err error
+	if n > 0 {
+		err = m.migrate.Steps(n)
+	} else {
+		err = m.migrate.Up()
+	}
+	if err == migrate.ErrNoChange {
+		log.D().Debug("Database schema already up to date")
+		return nil
+	}
+	return err
+}
+
+// Down reverts the last n applied migrations
+func (m *Migrator) Down(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("number of migrations to revert must be positive but was %d", n)
+	}
+	err := m.migrate.Steps(-n)
+	if err == migrate.ErrNoChange {
+		return nil
+	}
+	return err
+}
+
+// Force sets the version of the database schema without applying any migrations and clears its dirty state.
+// It is used to recover from a failed migration after the schema has been fixed manually.
+func (m *Migrator) Force(version int) error {
+	if version < -1 {
+		return fmt.Errorf("version must be at least -1 but was %d", version)
+	}
+	if version >= 0 {
+		if _, _, err := m.source.ReadUp(uint(version)); err != nil {
+			return fmt.Errorf("migration with version %d does not exist: %s", version, err)
+		}
+	}
+	return m.migrate.Force(version)
+}
+
+// DryRun writes the SQL of the next n pending migrations or all of them if n is not positive without applying them
+func (m *Migrator) DryRun(writer io.Writer, n int) error {
+	status, err := m.Status()
+	if err != nil {
+		return err
+	}
+	if status.Dirty {
+		return fmt.Errorf("database schema is dirty at version %d", status.Version)
+	}
+
+	pending := status.Pending
+	if n > 0 && n < len(pending) {
+		pending = pending[:n]
+	}
+	for _, version := range pending {
+		if err := m.writeUpMigration(writer, version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close releases the resources held by the Migrator
+func (m *Migrator) Close() error {
+	sourceErr, databaseErr := m.migrate.Close()
+	if sourceErr != nil {
+		return sourceErr
+	}
+	if databaseErr != nil {
+		return databaseErr
+	}
+	if m.db != nil {
+		return m.db.Close()
+	}
+	return nil
+}
+
+func (m *Migrator) versionsAfter(version uint, applied bool) ([]uint, error) {
+	var next uint
+	var err error
+	if applied {
+		next, err = m.source.Next(version)
+	} else {
+		next, err = m.source.First()
+	}
+
+	versions := make([]uint, 0)
+	for err == nil {
+		versions = append(versions, next)
+		next, err = m.source.Next(next)
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return versions, nil
+}
+
+func (m *Migrator) writeUpMigration(writer io.Writer, version uint) error {
+	body, identifier, err := m.source.ReadUp(version)
+	if err != nil {
+		return fmt.Errorf("could not read migration %d: %s", version, err)
+	}
+	defer func() {
+		if err := body.Close(); err != nil {
+			log.D().WithError(err).Errorf("could not close migration %d", version)
+		}
+	}()
+
+	content, err := ioutil.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("could not read migration %d: %s", version, err)
+	}
+	_, err = fmt.Fprintf(writer, "-- %d_%s\n%s\n", version, identifier, content)
+	return err
+}
+
+func dataSourceName(settings *storage.Settings) string {
+	params := make([]string, 0)
+	if settings.SkipSSLValidation {
+		params = append(params, "sslmode=disable")
+	}
+	if len(params) == 0 {
+		return settings.URI
+	}
+	separator := "?"
+	if strings.Contains(settings.URI, "?") {
+		separator = "&"
+	}
+	return settings.URI + separator + strings.Join(params, "&")
+}
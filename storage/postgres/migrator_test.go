@@ -0,0 +1,165 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package postgres
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+
+	"github.com/Peripli/service-manager/storage"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const (
+	latestMigration   = "20200225100000"
+	previousMigration = "20200224100000"
+)
+
+var _ = Describe("Migrator", func() {
+	var mockdb *sql.DB
+	var mock sqlmock.Sqlmock
+	var migrator *Migrator
+
+	expectDriverInitialization := func() {
+		mock.ExpectQuery(`SELECT CURRENT_DATABASE()`).WillReturnRows(sqlmock.NewRows([]string{"mock"}).FromCSVString("mock"))
+		mock.ExpectQuery(`SELECT COUNT(1)*`).WillReturnRows(sqlmock.NewRows([]string{"mock"}).FromCSVString("1"))
+	}
+
+	expectVersion := func(version string, dirty bool) {
+		row := version + ",false"
+		if dirty {
+			row = version + ",true"
+		}
+		mock.ExpectQuery(`SELECT version, dirty FROM "schema_migrations" LIMIT 1`).WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).FromCSVString(row))
+	}
+
+	BeforeEach(func() {
+		var err error
+		mockdb, mock, err = sqlmock.New()
+		Expect(err).ToNot(HaveOccurred())
+
+		expectDriverInitialization()
+		migrator, err = NewMigrator(mockdb, "file://migrations", 0)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+	})
+
+	Describe("Status", func() {
+		Context("when all migrations are applied", func() {
+			It("returns no pending migrations", func() {
+				expectVersion(latestMigration, false)
+				status, err := migrator.Status()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(status.Pending).To(BeEmpty())
+				Expect(status.UpToDate()).To(BeTrue())
+			})
+		})
+
+		Context("when migrations are pending", func() {
+			It("returns the pending migrations", func() {
+				expectVersion(previousMigration, false)
+				status, err := migrator.Status()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fmt.Sprint(status.Version)).To(Equal(previousMigration))
+				Expect(status.Pending).To(HaveLen(1))
+				Expect(fmt.Sprint(status.Pending[0])).To(Equal(latestMigration))
+				Expect(status.UpToDate()).To(BeFalse())
+			})
+		})
+
+		Context("when the last migration failed", func() {
+			It("returns a dirty status", func() {
+				expectVersion(latestMigration, true)
+				status, err := migrator.Status()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(status.Dirty).To(BeTrue())
+				Expect(status.UpToDate()).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("DryRun", func() {
+		It("writes the SQL of the pending migrations without applying them", func() {
+			expectVersion(previousMigration, false)
+			buffer := &bytes.Buffer{}
+			err := migrator.DryRun(buffer, 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(buffer.String()).To(ContainSubstring("-- " + latestMigration + "_"))
+		})
+
+		It("fails when the schema is dirty", func() {
+			expectVersion(previousMigration, true)
+			err := migrator.DryRun(&bytes.Buffer{}, 0)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("dirty"))
+		})
+	})
+
+	Describe("Down", func() {
+		It("fails when the number of migrations is not positive", func() {
+			err := migrator.Down(0)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Force", func() {
+		It("fails when the version does not exist", func() {
+			err := migrator.Force(1)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("does not exist"))
+		})
+	})
+
+	Describe("Storage Open", func() {
+		Context("when auto migration is disabled", func() {
+			var s *Storage
+			var options *storage.Settings
+
+			BeforeEach(func() {
+				s = &Storage{
+					ConnectFunc: func(driver string, url string) (*sql.DB, error) {
+						return mockdb, nil
+					},
+				}
+				options = storage.DefaultSettings()
+				options.EncryptionKey = "ejHjRNHbS0NaqARSRvnweVV9zcmhQEa8"
+				options.URI = "sqlmock://sqlmock"
+				options.AutoMigrate = false
+				expectDriverInitialization()
+			})
+
+			It("succeeds when the schema is up to date", func() {
+				expectVersion(latestMigration, false)
+				Expect(s.Open(options)).To(Succeed())
+			})
+
+			It("fails when migrations are pending", func() {
+				expectVersion(previousMigration, false)
+				err := s.Open(options)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("1 migrations are pending"))
+			})
+		})
+	})
+})
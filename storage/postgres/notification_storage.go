@@ -41,6 +41,9 @@ type notificationStorage interface {
 
 	// GetLastRevision returns the last received notification revision
 	GetLastRevision(ctx context.Context) (int64, error)
+
+	// GetServerIdentity returns an identifier of the database server which changes when the database fails over to another server
+	GetServerIdentity(ctx context.Context) (string, error)
 }
 
 // NewNotificationStorage returns new notification storage
@@ -71,6 +74,25 @@ func (ns *notificationStorageImpl) GetLastRevision(ctx context.Context) (int64,
 	return result[0].Revision, nil
 }
 
+func (ns *notificationStorageImpl) GetServerIdentity(ctx context.Context) (string, error) {
+	result := make([]string, 0, 1)
+	// replicas share the system identifier of the primary, but a replica promoted to primary switches to a new timeline.
+	// The timeline is taken from the name of the current WAL file, as the one of the last checkpoint changes only with
+	// the first checkpoint after the promotion. A server in recovery has no current WAL file and is identified by its start time.
+	sqlString := `SELECT system.system_identifier || '/' || CASE
+		WHEN pg_is_in_recovery() THEN 'recovery/' || pg_postmaster_start_time()
+		ELSE substr(pg_walfile_name(pg_current_wal_lsn()), 1, 8)
+	END AS identity FROM pg_control_system() system`
+	err := ns.storage.SelectContext(ctx, &result, sqlString)
+	if err != nil {
+		return "", fmt.Errorf("could not get database server identity from db %v", err)
+	}
+	if len(result) == 0 {
+		return "", errors.New("could not get database server identity from db")
+	}
+	return result[0], nil
+}
+
 func (ns *notificationStorageImpl) GetNotification(ctx context.Context, id string) (*types.Notification, error) {
 	byID := query.ByField(query.EqualsOperator, "id", id)
 	notificationObj, err := ns.storage.Get(ctx, types.NotificationType, byID)
@@ -42,8 +42,9 @@ const (
 )
 
 type Notificator struct {
-	isListening bool // To be used only under connectionMutex.Lock
-	isConnected int32
+	isListening    bool   // To be used only under connectionMutex.Lock
+	serverIdentity string // To be used only under connectionMutex.Lock
+	isConnected    int32
 
 	queueSize int
 
@@ -62,7 +63,10 @@ type Notificator struct {
 	ctx                 context.Context
 
 	lastKnownRevision int64
-	dbPingInterval    time.Duration
+	// failoverRevision is the last revision stored on the database server after the last detected failover. It is reset
+	// once notifications after it are stored.
+	failoverRevision int64
+	dbPingInterval   time.Duration
 }
 
 // NewNotificator returns new Notificator based on a given NotificatorStorage and desired queue size
@@ -90,6 +94,7 @@ func NewNotificator(st storage.Storage, settings *storage.Settings) (*Notificato
 		connectionCreator: connectionCreator,
 		stopProcessing:    func() {},
 		lastKnownRevision: types.InvalidRevision,
+		failoverRevision:  types.InvalidRevision,
 		dbPingInterval:    dbPingInterval,
 	}, nil
 }
@@ -129,12 +134,13 @@ func (n *Notificator) addConsumer(platform *types.Platform, queue storage.Notifi
 		if err != nil && err != pq.ErrChannelAlreadyOpen {
 			return types.InvalidRevision, fmt.Errorf("listen to %s channel failed %v", postgresChannel, err)
 		}
-		lastKnownRevision, err := n.storage.GetLastRevision(n.ctx)
+		// the revision is read from the database and not kept from the previous listening as the database might have failed over
+		lastKnownRevision, err := n.getLastCommittedRevision()
 		if err != nil {
 			if errUnlisten := n.connection.Unlisten(postgresChannel); errUnlisten != nil {
 				log.C(n.ctx).WithError(errUnlisten).Errorf("could not unlisten %s channel", postgresChannel)
 			}
-			return types.InvalidRevision, fmt.Errorf("getting last revision failed %v", err)
+			return types.InvalidRevision, err
 		}
 		atomic.StoreInt64(&n.lastKnownRevision, lastKnownRevision)
 		n.isListening = true
@@ -164,6 +170,10 @@ func (n *Notificator) RegisterConsumer(consumer *types.Platform, lastKnownRevisi
 	if err != nil {
 		return nil, types.InvalidRevision, err
 	}
+	if failoverRevision := atomic.LoadInt64(&n.failoverRevision); failoverRevision != types.InvalidRevision && lastKnownRevisionToSM > failoverRevision {
+		// notifications were stored on the new server, so consumers ahead of it are no longer caused by the failover
+		atomic.CompareAndSwapInt64(&n.failoverRevision, failoverRevision, types.InvalidRevision)
+	}
 	if lastKnownRevision == types.InvalidRevision || lastKnownRevision == lastKnownRevisionToSM {
 		return queue, lastKnownRevisionToSM, nil
 	}
@@ -176,7 +186,11 @@ func (n *Notificator) RegisterConsumer(consumer *types.Platform, lastKnownRevisi
 	}()
 	if lastKnownRevision > lastKnownRevisionToSM {
 		log.C(n.ctx).Debug("lastKnownRevision is grater than the one SM knows")
-		err = util.ErrInvalidNotificationRevision // important for defer logic
+		reason := util.ResyncReasonRevisionAhead
+		if atomic.LoadInt64(&n.failoverRevision) != types.InvalidRevision {
+			reason = util.ResyncReasonFailover
+		}
+		err = &util.ErrNotificationResyncRequired{Reason: reason} // important for defer logic
 		return nil, types.InvalidRevision, err
 	}
 	var queueWithMissedNotifications storage.NotificationQueue
@@ -201,7 +215,7 @@ func (n *Notificator) replaceQueueWithMissingNotificationsQueue(queue storage.No
 	if _, err := n.storage.GetNotificationByRevision(n.ctx, lastKnownRevision); err != nil {
 		if err == util.ErrNotFoundInStorage {
 			log.C(n.ctx).WithError(err).Debugf("Notification with revision %d not found in storage", lastKnownRevision)
-			return nil, util.ErrInvalidNotificationRevision
+			return nil, &util.ErrNotificationResyncRequired{Reason: util.ResyncReasonRevisionNotFound}
 		}
 		return nil, err
 	}
@@ -220,7 +234,7 @@ func (n *Notificator) replaceQueueWithMissingNotificationsQueue(queue storage.No
 
 	if n.queueSize < len(filteredMissedNotification) {
 		log.C(n.ctx).Debugf("Too many missed notifications %d", len(filteredMissedNotification))
-		return nil, util.ErrInvalidNotificationRevision
+		return nil, &util.ErrNotificationResyncRequired{Reason: util.ResyncReasonTooManyMissed}
 	}
 
 	queueWithMissedNotifications, err := storage.NewNotificationQueue(n.queueSize)
@@ -273,18 +287,56 @@ func (n *Notificator) RegisterFilter(f storage.ReceiversFilterFunc) {
 	n.notificationFilters = append(n.notificationFilters, f)
 }
 
-func (n *Notificator) closeAllConsumers() {
+func (n *Notificator) closeAllConsumers(err error) {
 	n.consumersMutex.Lock()
 	defer n.consumersMutex.Unlock()
 
 	platformConsumers := n.consumers.Clear()
 	for _, platformConsumers := range platformConsumers {
 		for _, queue := range platformConsumers {
-			queue.Close()
+			queue.CloseWithError(err)
 		}
 	}
 }
 
+// getLastCommittedRevision returns the last revision stored in the database. It must be called under connectionMutex.Lock.
+func (n *Notificator) getLastCommittedRevision() (int64, error) {
+	if _, err := n.detectFailover(); err != nil {
+		return types.InvalidRevision, err
+	}
+	lastRevision, err := n.storage.GetLastRevision(n.ctx)
+	if err != nil {
+		return types.InvalidRevision, fmt.Errorf("getting last revision failed %v", err)
+	}
+	return lastRevision, nil
+}
+
+// detectFailover returns whether the database server changed since the last check, e.g. because a replica was promoted.
+// The last revision stored on the new server is recorded as the failover revision, as the notifications after it might
+// have been lost. It must be called under connectionMutex.Lock.
+func (n *Notificator) detectFailover() (bool, error) {
+	identity, err := n.storage.GetServerIdentity(n.ctx)
+	if err != nil {
+		return false, fmt.Errorf("getting database server identity failed %v", err)
+	}
+	previousIdentity := n.serverIdentity
+	if previousIdentity == identity {
+		return false, nil
+	}
+	if len(previousIdentity) == 0 {
+		n.serverIdentity = identity
+		return false, nil
+	}
+	failoverRevision, err := n.storage.GetLastRevision(n.ctx)
+	if err != nil {
+		return false, fmt.Errorf("getting last revision failed %v", err)
+	}
+	n.serverIdentity = identity
+	atomic.StoreInt64(&n.failoverRevision, failoverRevision)
+	log.C(n.ctx).Warnf("Database server changed from %s to %s. Notifications after revision %d might have been lost", previousIdentity, identity, failoverRevision)
+	return true, nil
+}
+
 func (n *Notificator) setConnection(conn notificationConnection.NotificationConnection) {
 	n.connectionMutex.Lock()
 	defer n.connectionMutex.Unlock()
@@ -303,12 +355,14 @@ func (n *Notificator) processNotifications(notificationChannel <-chan *pq.Notifi
 			log.C(n.ctx).Errorf("recovered from panic while processing notifications: %s", err)
 		}
 	}()
+	// closeErr is passed to the consumers, so that they know whether they have to resync
+	var closeErr error
 	defer func() {
 		n.connectionMutex.Lock()
 		defer n.connectionMutex.Unlock()
 		n.isListening = false
 		n.stopProcessing() // closing processingContext if not already closed
-		n.closeAllConsumers()
+		n.closeAllConsumers(closeErr)
 		log.C(n.ctx).Debugf("Stop listening notification channel %s", postgresChannel)
 		if atomic.LoadInt32(&n.isConnected) == aTrue {
 			if err := n.connection.Unlisten(postgresChannel); err != nil {
@@ -346,6 +400,11 @@ func (n *Notificator) processNotifications(notificationChannel <-chan *pq.Notifi
 				log.C(n.ctx).WithError(err).Error("Pinging connection failed. Closing all consumers...")
 				return
 			}
+			if n.checkFailover() {
+				log.C(n.ctx).Error("Database failed over. Closing all consumers...")
+				closeErr = &util.ErrNotificationResyncRequired{Reason: util.ResyncReasonFailover}
+				return
+			}
 		case <-processingContext.Done():
 			log.C(n.ctx).Debug("Stopping processing of notifications. Closing consumers...")
 			return
@@ -353,6 +412,18 @@ func (n *Notificator) processNotifications(notificationChannel <-chan *pq.Notifi
 	}
 }
 
+// checkFailover detects failovers while listening, as the connection might not be interrupted, e.g. when connecting through a proxy
+func (n *Notificator) checkFailover() bool {
+	n.connectionMutex.Lock()
+	defer n.connectionMutex.Unlock()
+	failedOver, err := n.detectFailover()
+	if err != nil {
+		log.C(n.ctx).WithError(err).Error("Could not check for database failover")
+		return false
+	}
+	return failedOver
+}
+
 func getPayload(data string) (*notifyEventPayload, error) {
 	payload := &notifyEventPayload{}
 	if err := json.Unmarshal([]byte(data), payload); err != nil {
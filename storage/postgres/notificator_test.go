@@ -102,6 +102,7 @@ var _ = Describe("Notificator", func() {
 			connectionCreator: fakeConnectionCreator,
 			stopProcessing:    func() {},
 			lastKnownRevision: types.InvalidRevision,
+			failoverRevision:  types.InvalidRevision,
 			dbPingInterval:    time.Millisecond * 10,
 		}
 	}
@@ -364,6 +365,64 @@ var _ = Describe("Notificator", func() {
 			It("Should return error", func() {
 				expectRegisterConsumerFail(util.ErrInvalidNotificationRevision.Error(), defaultLastRevision+1)
 			})
+
+			It("Should return revision ahead as resync reason", func() {
+				_, _, err := testNotificator.RegisterConsumer(defaultPlatform, defaultLastRevision+1)
+				Expect(err).To(Equal(&util.ErrNotificationResyncRequired{Reason: util.ResyncReasonRevisionAhead}))
+			})
+		})
+
+		Context("When database server identity cannot be retrieved", func() {
+			It("Should return error", func() {
+				fakeNotificationStorage.GetServerIdentityReturns("", expectedError)
+				expectRegisterConsumerFail("getting database server identity failed "+expectedError.Error(), types.InvalidRevision)
+			})
+		})
+
+		Context("When database fails over while consumers are registered", func() {
+			BeforeEach(func() {
+				fakeNotificationStorage.GetServerIdentityReturns("primary", nil)
+			})
+
+			It("Should close the consumers with failover as resync reason", func() {
+				q := registerDefaultPlatform()
+				fakeNotificationStorage.GetServerIdentityReturns("replica", nil)
+				fakeNotificationStorage.GetLastRevisionReturns(defaultLastRevision-2, nil)
+				_, ok := <-q.Channel()
+				Expect(ok).To(BeFalse())
+				Expect(q.Err()).To(Equal(&util.ErrNotificationResyncRequired{Reason: util.ResyncReasonFailover}))
+			})
+
+			It("Should resume from the last revision on the new server", func() {
+				q := registerDefaultPlatform()
+				fakeNotificationStorage.GetServerIdentityReturns("replica", nil)
+				fakeNotificationStorage.GetLastRevisionReturns(defaultLastRevision-2, nil)
+				_, ok := <-q.Channel()
+				Expect(ok).To(BeFalse())
+
+				_, smRevision, err := testNotificator.RegisterConsumer(defaultPlatform, types.InvalidRevision)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(smRevision).To(Equal(defaultLastRevision - 2))
+
+				_, _, err = testNotificator.RegisterConsumer(defaultPlatform, defaultLastRevision)
+				Expect(err).To(Equal(&util.ErrNotificationResyncRequired{Reason: util.ResyncReasonFailover}))
+			})
+
+			It("Should not return failover as resync reason once notifications are stored on the new server", func() {
+				q := registerDefaultPlatform()
+				fakeNotificationStorage.GetServerIdentityReturns("replica", nil)
+				fakeNotificationStorage.GetLastRevisionReturns(defaultLastRevision-2, nil)
+				_, ok := <-q.Channel()
+				Expect(ok).To(BeFalse())
+
+				fakeNotificationStorage.GetLastRevisionReturns(defaultLastRevision-1, nil)
+				_, smRevision, err := testNotificator.RegisterConsumer(defaultPlatform, types.InvalidRevision)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(smRevision).To(Equal(defaultLastRevision - 1))
+
+				_, _, err = testNotificator.RegisterConsumer(defaultPlatform, defaultLastRevision)
+				Expect(err).To(Equal(&util.ErrNotificationResyncRequired{Reason: util.ResyncReasonRevisionAhead}))
+			})
 		})
 
 		Context("When registering with 0 < revision < sm_revision", func() {
@@ -399,6 +458,7 @@ var _ = Describe("Notificator", func() {
 				It("Should return ErrInvalidNotificationRevision", func() {
 					fakeNotificationStorage.GetNotificationByRevisionReturns(nil, util.ErrNotFoundInStorage)
 					expectRegisterConsumerFail(util.ErrInvalidNotificationRevision.Error(), defaultLastRevision-1)
+					expectRegisterConsumerFail(util.ResyncReasonRevisionNotFound, defaultLastRevision-1)
 				})
 			})
 
@@ -0,0 +1,315 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package postgres
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+)
+
+const partitionTimeLayout = "20060102150405"
+
+// partitionedTables maps the tables which are partitioned by creation time to the tables in which their ids are registered
+var partitionedTables = map[string]string{
+	OperationTable:    "operation_ids",
+	NotificationTable: "notification_ids",
+}
+
+// partitionRetentionConditions are the conditions for the rows of the partitioned tables which have to be kept
+// even after their retention has passed. Partitions containing such rows are not dropped.
+var partitionRetentionConditions = map[string]string{
+	OperationTable: fmt.Sprintf("state = '%s' OR reschedule OR deletion_scheduled <> '0001-01-01 00:00:00+00'", types.IN_PROGRESS),
+}
+
+var _ storage.PartitionManager = &Storage{}
+
+// partition is a partition of a table containing the rows created in [from, to)
+type partition struct {
+	name string
+	from time.Time
+	to   time.Time
+}
+
+func newPartition(table string, from time.Time, interval time.Duration) partition {
+	from = from.UTC()
+	to := from.Add(interval)
+	return partition{
+		name: fmt.Sprintf("%s_p%s_%s", table, from.Format(partitionTimeLayout), to.Format(partitionTimeLayout)),
+		from: from,
+		to:   to,
+	}
+}
+
+// parsePartition parses the name of a partition created by newPartition
+func parsePartition(table, name string) (partition, bool) {
+	bounds := strings.Split(strings.TrimPrefix(name, table+"_p"), "_")
+	if !strings.HasPrefix(name, table+"_p") || len(bounds) != 2 {
+		return partition{}, false
+	}
+	from, err := time.Parse(partitionTimeLayout, bounds[0])
+	if err != nil {
+		return partition{}, false
+	}
+	to, err := time.Parse(partitionTimeLayout, bounds[1])
+	if err != nil {
+		return partition{}, false
+	}
+	return partition{name: name, from: from, to: to}, true
+}
+
+// MaintainPartitions implements storage.PartitionManager. Partitions which are dropped are archived first if an archive directory is configured.
+func (ps *Storage) MaintainPartitions(ctx context.Context, objectType types.ObjectType, retention time.Duration) error {
+	ps.checkOpen()
+	entity, err := ps.scheme.provide(objectType)
+	if err != nil {
+		return err
+	}
+	table := entity.TableName()
+	if _, ok := partitionedTables[table]; !ok {
+		return fmt.Errorf("table %s of object type %s is not partitioned", table, objectType)
+	}
+	partitioned, err := ps.isPartitioned(ctx, table)
+	if err != nil {
+		return err
+	}
+	if !partitioned {
+		// partitioning is skipped by the migrations on PostgreSQL versions before 11
+		log.C(ctx).Debugf("Table %s is not partitioned, expired rows are removed by the regular cleanup", table)
+		return nil
+	}
+
+	ps.createPartitions(ctx, table, time.Now())
+	return ps.dropPartitions(ctx, entity, time.Now().Add(-retention))
+}
+
+func (ps *Storage) isPartitioned(ctx context.Context, table string) (bool, error) {
+	var partitioned bool
+	if err := ps.pgDB.GetContext(ctx, &partitioned, "SELECT EXISTS (SELECT 1 FROM pg_class WHERE relname = $1 AND relkind = 'p')", table); err != nil {
+		return false, fmt.Errorf("could not check whether table %s is partitioned: %s", table, err)
+	}
+	return partitioned, nil
+}
+
+// createPartitions creates the partitions of the table for the current period and the premade periods after it
+func (ps *Storage) createPartitions(ctx context.Context, table string, now time.Time) {
+	interval := ps.partitioning.Interval
+	current := now.UTC().Truncate(interval)
+	for i := 0; i <= ps.partitioning.Premake; i++ {
+		p := newPartition(table, current.Add(time.Duration(i)*interval), interval)
+		if err := ps.createPartition(ctx, table, p); err != nil {
+			log.C(ctx).WithError(err).Warnf("Could not create partition %s", p.name)
+			continue
+		}
+		log.C(ctx).Debugf("Ensured partition %s of table %s", p.name, table)
+	}
+}
+
+func (ps *Storage) createPartition(ctx context.Context, table string, p partition) error {
+	defaultPartition := table + "_default"
+	var inDefault bool
+	if err := ps.pgDB.GetContext(ctx, &inDefault, fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE created_at >= $1 AND created_at < $2)", defaultPartition),
+		p.from, p.to); err != nil {
+		return err
+	}
+	if !inDefault {
+		_, err := ps.pgDB.ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')",
+			p.name, table, p.from.Format(time.RFC3339), p.to.Format(time.RFC3339)))
+		return err
+	}
+
+	// a partition cannot be created while the default partition contains rows of its period, e.g. after the
+	// tables got partitioned or if the partitions were not maintained in time, so the rows are moved to the
+	// partition before it is attached
+	tx, err := ps.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.C(ctx).WithError(err).Error("Could not rollback transaction")
+		}
+	}()
+
+	statements := []string{
+		fmt.Sprintf("LOCK TABLE %s IN ACCESS EXCLUSIVE MODE", table),
+		fmt.Sprintf("CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS INCLUDING CONSTRAINTS)", p.name, table),
+		// the rows are only moved, so the triggers which unregister their ids and record their changes must not fire
+		fmt.Sprintf("ALTER TABLE %s DISABLE TRIGGER USER", defaultPartition),
+		fmt.Sprintf("WITH moved AS (DELETE FROM %s WHERE created_at >= '%s' AND created_at < '%s' RETURNING *) INSERT INTO %s SELECT * FROM moved",
+			defaultPartition, p.from.Format(time.RFC3339), p.to.Format(time.RFC3339), p.name),
+		fmt.Sprintf("ALTER TABLE %s ENABLE TRIGGER USER", defaultPartition),
+		fmt.Sprintf("ALTER TABLE %s ATTACH PARTITION %s FOR VALUES FROM ('%s') TO ('%s')",
+			table, p.name, p.from.Format(time.RFC3339), p.to.Format(time.RFC3339)),
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.C(ctx).Infof("Moved the rows of partition %s from the default partition of table %s", p.name, table)
+	return nil
+}
+
+// dropPartitions drops the partitions of the table which contain only rows created before the specified time
+func (ps *Storage) dropPartitions(ctx context.Context, entity PostgresEntity, before time.Time) error {
+	table := entity.TableName()
+	var names []string
+	if err := ps.pgDB.SelectContext(ctx, &names, `SELECT child.relname FROM pg_inherits
+		JOIN pg_class parent ON pg_inherits.inhparent = parent.oid
+		JOIN pg_class child ON pg_inherits.inhrelid = child.oid
+		WHERE parent.relname = $1`, table); err != nil {
+		return fmt.Errorf("could not list partitions of table %s: %s", table, err)
+	}
+
+	for _, name := range names {
+		p, ok := parsePartition(table, name)
+		if !ok || p.to.After(before) {
+			continue
+		}
+		dropped, err := ps.dropPartition(ctx, entity, p)
+		if err != nil {
+			return err
+		}
+		if !dropped {
+			log.C(ctx).Infof("Kept expired partition %s of table %s as it contains rows which are not finished yet", p.name, table)
+			continue
+		}
+		log.C(ctx).Infof("Dropped partition %s of table %s", p.name, table)
+	}
+	return nil
+}
+
+// dropPartition archives and drops the partition unless it contains rows which have to be kept
+func (ps *Storage) dropPartition(ctx context.Context, entity PostgresEntity, p partition) (bool, error) {
+	tx, err := ps.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.C(ctx).WithError(err).Error("Could not rollback transaction")
+		}
+	}()
+
+	// the partition is still readable but its rows cannot be modified until it is dropped
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("LOCK TABLE %s IN SHARE MODE", p.name)); err != nil {
+		return false, fmt.Errorf("could not lock partition %s: %s", p.name, err)
+	}
+	if condition, ok := partitionRetentionConditions[entity.TableName()]; ok {
+		var retained bool
+		if err := tx.GetContext(ctx, &retained, fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE %s)", p.name, condition)); err != nil {
+			return false, fmt.Errorf("could not check rows of partition %s: %s", p.name, err)
+		}
+		if retained {
+			return false, nil
+		}
+	}
+	if len(ps.partitioning.ArchiveDir) != 0 {
+		if err := ps.archivePartition(ctx, tx, entity, p); err != nil {
+			return false, fmt.Errorf("could not archive partition %s: %s", p.name, err)
+		}
+	}
+
+	// dropping a partition does not fire the triggers which unregister the ids of its rows,
+	// the labels of the rows are deleted together with their ids
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id IN (SELECT id FROM %s)",
+		partitionedTables[entity.TableName()], p.name)); err != nil {
+		return false, fmt.Errorf("could not drop partition %s: %s", p.name, err)
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", p.name)); err != nil {
+		return false, fmt.Errorf("could not drop partition %s: %s", p.name, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("could not drop partition %s: %s", p.name, err)
+	}
+	return true, nil
+}
+
+// archivePartition writes the rows of the partition together with their labels as compressed NDJSON to the archive directory
+func (ps *Storage) archivePartition(ctx context.Context, db pgDB, entity PostgresEntity, p partition) error {
+	if err := os.MkdirAll(ps.partitioning.ArchiveDir, 0700); err != nil {
+		return err
+	}
+	path := filepath.Join(ps.partitioning.ArchiveDir, p.name+".ndjson.gz")
+	tmpPath := path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	count, err := writePartition(ctx, db, file, entity, p)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		if removeErr := os.Remove(tmpPath); removeErr != nil {
+			log.C(ctx).WithError(removeErr).Errorf("Could not remove incomplete archive %s", tmpPath)
+		}
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	log.C(ctx).Infof("Archived %d rows of partition %s to %s", count, p.name, path)
+	return nil
+}
+
+func writePartition(ctx context.Context, db pgDB, file *os.File, entity PostgresEntity, p partition) (int, error) {
+	label := entity.LabelEntity()
+	rows, err := db.QueryxContext(ctx, fmt.Sprintf(`SELECT (to_jsonb(t) || jsonb_build_object('labels', COALESCE(
+		(SELECT jsonb_agg(jsonb_build_object('key', l.key, 'value', l.val)) FROM %s l WHERE l.%s = t.id), '[]'::jsonb)))::text
+		FROM %s t ORDER BY t.paging_sequence`, label.LabelsTableName(), label.ReferenceColumn(), p.name))
+	if err != nil {
+		return 0, err
+	}
+	defer closeRows(ctx, rows)
+
+	buffer := bufio.NewWriter(file)
+	gzipWriter := gzip.NewWriter(buffer)
+	count := 0
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return count, err
+		}
+		if _, err := gzipWriter.Write([]byte(line + "\n")); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return count, err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return count, err
+	}
+	return count, buffer.Flush()
+}
@@ -0,0 +1,246 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package postgres
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Partitions", func() {
+	var s *Storage
+	var mockdb *sql.DB
+	var mock sqlmock.Sqlmock
+	var settings *storage.Settings
+
+	const expiredPartition = "operations_p20000101000000_20000102000000"
+
+	BeforeEach(func() {
+		var err error
+		mockdb, mock, err = sqlmock.New()
+		Expect(err).ToNot(HaveOccurred())
+
+		s = &Storage{
+			ConnectFunc: func(driver string, url string) (*sql.DB, error) {
+				return mockdb, nil
+			},
+		}
+		mock.ExpectQuery(`SELECT CURRENT_DATABASE()`).WillReturnRows(sqlmock.NewRows([]string{"mock"}).FromCSVString("mock"))
+		mock.ExpectQuery(`SELECT COUNT(1)*`).WillReturnRows(sqlmock.NewRows([]string{"mock"}).FromCSVString("1"))
+		mock.ExpectExec("SELECT pg_advisory_lock*").WithArgs(sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(`SELECT version, dirty FROM "schema_migrations" LIMIT 1`).WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).FromCSVString(latestMigration + ",false"))
+		mock.ExpectExec("SELECT pg_advisory_unlock*").WithArgs(sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+
+		settings = storage.DefaultSettings()
+		settings.EncryptionKey = "ejHjRNHbS0NaqARSRvnweVV9zcmhQEa8"
+		settings.URI = "sqlmock://sqlmock"
+		settings.Partitioning.Premake = 1
+	})
+
+	AfterEach(func() {
+		Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+	})
+
+	expectPartitioned := func(partitioned bool) {
+		mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM pg_class").WithArgs("operations").WillReturnRows(
+			sqlmock.NewRows([]string{"exists"}).AddRow(partitioned))
+	}
+
+	expectRowsInDefaultPartition := func(found bool) {
+		mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM operations_default WHERE created_at >= \\$1 AND created_at < \\$2\\)").WillReturnRows(
+			sqlmock.NewRows([]string{"exists"}).AddRow(found))
+	}
+
+	expectPartitionCreated := func() {
+		expectRowsInDefaultPartition(false)
+		mock.ExpectExec("CREATE TABLE IF NOT EXISTS operations_p.* PARTITION OF operations").WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+
+	expectPartitionsCreated := func() {
+		expectPartitioned(true)
+		expectPartitionCreated()
+		expectPartitionCreated()
+	}
+
+	expectPartitionsListed := func() {
+		mock.ExpectQuery("SELECT child.relname FROM pg_inherits").WithArgs("operations").WillReturnRows(
+			sqlmock.NewRows([]string{"relname"}).AddRow("operations_default").AddRow(expiredPartition).
+				AddRow(newPartition("operations", time.Now().UTC().Truncate(24*time.Hour), 24*time.Hour).name))
+	}
+
+	expectPartitionLocked := func(unfinished bool) {
+		mock.ExpectBegin()
+		mock.ExpectExec("LOCK TABLE " + expiredPartition + " IN SHARE MODE").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM " + expiredPartition + " WHERE state = 'in progress' OR reschedule OR deletion_scheduled").WillReturnRows(
+			sqlmock.NewRows([]string{"exists"}).AddRow(unfinished))
+	}
+
+	expectPartitionDropped := func() {
+		mock.ExpectExec("DELETE FROM operation_ids WHERE id IN \\(SELECT id FROM " + expiredPartition + "\\)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DROP TABLE IF EXISTS " + expiredPartition).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+	}
+
+	Describe("partition names", func() {
+		It("contain the bounds of the partition", func() {
+			from := time.Date(2020, 2, 15, 0, 0, 0, 0, time.UTC)
+			p := newPartition("operations", from, 24*time.Hour)
+			Expect(p.name).To(Equal("operations_p20200215000000_20200216000000"))
+
+			parsed, ok := parsePartition("operations", p.name)
+			Expect(ok).To(BeTrue())
+			Expect(parsed.from).To(Equal(from))
+			Expect(parsed.to).To(Equal(from.Add(24 * time.Hour)))
+		})
+
+		It("are not parsed for partitions which are not created by time", func() {
+			_, ok := parsePartition("operations", "operations_default")
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("MaintainPartitions", func() {
+		Context("for a table which is not partitioned", func() {
+			It("returns an error", func() {
+				Expect(s.Open(settings)).To(Succeed())
+				err := s.MaintainPartitions(context.Background(), types.PlatformType, time.Hour)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("not partitioned"))
+			})
+		})
+
+		Context("for a table which is not partitioned on an earlier PostgreSQL version", func() {
+			It("does nothing", func() {
+				Expect(s.Open(settings)).To(Succeed())
+				expectPartitioned(false)
+
+				err := s.MaintainPartitions(context.Background(), types.OperationType, 24*time.Hour)
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("without archive directory", func() {
+			It("creates the upcoming partitions and drops the expired ones", func() {
+				Expect(s.Open(settings)).To(Succeed())
+				expectPartitionsCreated()
+				expectPartitionsListed()
+				expectPartitionLocked(false)
+				expectPartitionDropped()
+
+				err := s.MaintainPartitions(context.Background(), types.OperationType, 24*time.Hour)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("keeps the expired partitions which contain unfinished operations", func() {
+				Expect(s.Open(settings)).To(Succeed())
+				expectPartitionsCreated()
+				expectPartitionsListed()
+				expectPartitionLocked(true)
+				mock.ExpectRollback()
+
+				err := s.MaintainPartitions(context.Background(), types.OperationType, 24*time.Hour)
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("when the default partition contains rows of the current period", func() {
+			It("moves the rows to the partition of the current period", func() {
+				Expect(s.Open(settings)).To(Succeed())
+				expectPartitioned(true)
+				expectRowsInDefaultPartition(true)
+				mock.ExpectBegin()
+				mock.ExpectExec("LOCK TABLE operations IN ACCESS EXCLUSIVE MODE").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("CREATE TABLE operations_p.* \\(LIKE operations").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("ALTER TABLE operations_default DISABLE TRIGGER USER").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("WITH moved AS \\(DELETE FROM operations_default (.+) INSERT INTO operations_p").WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec("ALTER TABLE operations_default ENABLE TRIGGER USER").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec("ALTER TABLE operations ATTACH PARTITION operations_p").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+				expectPartitionCreated()
+				mock.ExpectQuery("SELECT child.relname FROM pg_inherits").WithArgs("operations").WillReturnRows(sqlmock.NewRows([]string{"relname"}))
+
+				err := s.MaintainPartitions(context.Background(), types.OperationType, 24*time.Hour)
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("with archive directory", func() {
+			var archiveDir string
+
+			BeforeEach(func() {
+				var err error
+				archiveDir, err = ioutil.TempDir("", "partitions")
+				Expect(err).ToNot(HaveOccurred())
+				settings.Partitioning.ArchiveDir = archiveDir
+				Expect(s.Open(settings)).To(Succeed())
+			})
+
+			AfterEach(func() {
+				Expect(os.RemoveAll(archiveDir)).To(Succeed())
+			})
+
+			It("archives the expired partitions before dropping them", func() {
+				expectPartitionsCreated()
+				expectPartitionsListed()
+				expectPartitionLocked(false)
+				mock.ExpectQuery("SELECT (.+) FROM " + expiredPartition).WillReturnRows(
+					sqlmock.NewRows([]string{"text"}).AddRow(`{"id":"1","labels":[]}`).AddRow(`{"id":"2","labels":[]}`))
+				expectPartitionDropped()
+
+				err := s.MaintainPartitions(context.Background(), types.OperationType, 24*time.Hour)
+				Expect(err).ToNot(HaveOccurred())
+
+				file, err := os.Open(filepath.Join(archiveDir, expiredPartition+".ndjson.gz"))
+				Expect(err).ToNot(HaveOccurred())
+				defer file.Close()
+				reader, err := gzip.NewReader(file)
+				Expect(err).ToNot(HaveOccurred())
+				content, err := ioutil.ReadAll(reader)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(content)).To(Equal("{\"id\":\"1\",\"labels\":[]}\n{\"id\":\"2\",\"labels\":[]}\n"))
+			})
+
+			It("does not drop the partition if it could not be archived", func() {
+				expectPartitionsCreated()
+				expectPartitionsListed()
+				expectPartitionLocked(false)
+				mock.ExpectQuery("SELECT (.+) FROM " + expiredPartition).WillReturnError(sql.ErrConnDone)
+				mock.ExpectRollback()
+
+				err := s.MaintainPartitions(context.Background(), types.OperationType, 24*time.Hour)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("could not archive partition"))
+
+				files, err := ioutil.ReadDir(archiveDir)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(files).To(BeEmpty())
+			})
+		})
+	})
+})
@@ -32,10 +32,12 @@ type Platform struct {
 	Type        string         `db:"type"`
 	Name        string         `db:"name"`
 	Description sql.NullString `db:"description"`
-	Username    string         `db:"username"`
-	Password    string         `db:"password"`
+	Username    string         `db:"username" query:"-"`
+	Password    string         `db:"password" query:"-"`
 	Active      bool           `db:"active"`
 	LastActive  time.Time      `db:"last_active"`
+
+	SearchVector sql.NullString `db:"search_vector,generated"`
 }
 
 func (p *Platform) FromObject(object types.Object) (storage.Entity, bool) {
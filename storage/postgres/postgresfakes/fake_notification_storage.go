@@ -50,6 +50,19 @@ type FakeNotificationStorage struct {
 		result1 *types.Notification
 		result2 error
 	}
+	GetServerIdentityStub        func(context.Context) (string, error)
+	getServerIdentityMutex       sync.RWMutex
+	getServerIdentityArgsForCall []struct {
+		arg1 context.Context
+	}
+	getServerIdentityReturns struct {
+		result1 string
+		result2 error
+	}
+	getServerIdentityReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
 	ListNotificationsStub        func(context.Context, string, int64, int64) ([]*types.Notification, error)
 	listNotificationsMutex       sync.RWMutex
 	listNotificationsArgsForCall []struct {
@@ -261,6 +274,69 @@ func (fake *FakeNotificationStorage) GetNotificationByRevisionReturnsOnCall(i in
 	}{result1, result2}
 }
 
+func (fake *FakeNotificationStorage) GetServerIdentity(arg1 context.Context) (string, error) {
+	fake.getServerIdentityMutex.Lock()
+	ret, specificReturn := fake.getServerIdentityReturnsOnCall[len(fake.getServerIdentityArgsForCall)]
+	fake.getServerIdentityArgsForCall = append(fake.getServerIdentityArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	fake.recordInvocation("GetServerIdentity", []interface{}{arg1})
+	fake.getServerIdentityMutex.Unlock()
+	if fake.GetServerIdentityStub != nil {
+		return fake.GetServerIdentityStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	fakeReturns := fake.getServerIdentityReturns
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeNotificationStorage) GetServerIdentityCallCount() int {
+	fake.getServerIdentityMutex.RLock()
+	defer fake.getServerIdentityMutex.RUnlock()
+	return len(fake.getServerIdentityArgsForCall)
+}
+
+func (fake *FakeNotificationStorage) GetServerIdentityCalls(stub func(context.Context) (string, error)) {
+	fake.getServerIdentityMutex.Lock()
+	defer fake.getServerIdentityMutex.Unlock()
+	fake.GetServerIdentityStub = stub
+}
+
+func (fake *FakeNotificationStorage) GetServerIdentityArgsForCall(i int) context.Context {
+	fake.getServerIdentityMutex.RLock()
+	defer fake.getServerIdentityMutex.RUnlock()
+	argsForCall := fake.getServerIdentityArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeNotificationStorage) GetServerIdentityReturns(result1 string, result2 error) {
+	fake.getServerIdentityMutex.Lock()
+	defer fake.getServerIdentityMutex.Unlock()
+	fake.GetServerIdentityStub = nil
+	fake.getServerIdentityReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeNotificationStorage) GetServerIdentityReturnsOnCall(i int, result1 string, result2 error) {
+	fake.getServerIdentityMutex.Lock()
+	defer fake.getServerIdentityMutex.Unlock()
+	fake.GetServerIdentityStub = nil
+	if fake.getServerIdentityReturnsOnCall == nil {
+		fake.getServerIdentityReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.getServerIdentityReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeNotificationStorage) ListNotifications(arg1 context.Context, arg2 string, arg3 int64, arg4 int64) ([]*types.Notification, error) {
 	fake.listNotificationsMutex.Lock()
 	ret, specificReturn := fake.listNotificationsReturnsOnCall[len(fake.listNotificationsArgsForCall)]
@@ -336,6 +412,8 @@ func (fake *FakeNotificationStorage) Invocations() map[string][][]interface{} {
 	defer fake.getNotificationMutex.RUnlock()
 	fake.getNotificationByRevisionMutex.RLock()
 	defer fake.getNotificationByRevisionMutex.RUnlock()
+	fake.getServerIdentityMutex.RLock()
+	defer fake.getServerIdentityMutex.RUnlock()
 	fake.listNotificationsMutex.RLock()
 	defer fake.listNotificationsMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
@@ -80,20 +80,6 @@ type FakePgDB struct {
 		result1 sql.Result
 		result2 error
 	}
-	NamedQueryStub        func(string, interface{}) (*sqlx.Rows, error)
-	namedQueryMutex       sync.RWMutex
-	namedQueryArgsForCall []struct {
-		arg1 string
-		arg2 interface{}
-	}
-	namedQueryReturns struct {
-		result1 *sqlx.Rows
-		result2 error
-	}
-	namedQueryReturnsOnCall map[int]struct {
-		result1 *sqlx.Rows
-		result2 error
-	}
 	PrepareNamedContextStub        func(context.Context, string) (*sqlx.NamedStmt, error)
 	prepareNamedContextMutex       sync.RWMutex
 	prepareNamedContextArgsForCall []struct {
@@ -492,70 +478,6 @@ func (fake *FakePgDB) NamedExecContextReturnsOnCall(i int, result1 sql.Result, r
 	}{result1, result2}
 }
 
-func (fake *FakePgDB) NamedQuery(arg1 string, arg2 interface{}) (*sqlx.Rows, error) {
-	fake.namedQueryMutex.Lock()
-	ret, specificReturn := fake.namedQueryReturnsOnCall[len(fake.namedQueryArgsForCall)]
-	fake.namedQueryArgsForCall = append(fake.namedQueryArgsForCall, struct {
-		arg1 string
-		arg2 interface{}
-	}{arg1, arg2})
-	fake.recordInvocation("NamedQuery", []interface{}{arg1, arg2})
-	fake.namedQueryMutex.Unlock()
-	if fake.NamedQueryStub != nil {
-		return fake.NamedQueryStub(arg1, arg2)
-	}
-	if specificReturn {
-		return ret.result1, ret.result2
-	}
-	fakeReturns := fake.namedQueryReturns
-	return fakeReturns.result1, fakeReturns.result2
-}
-
-func (fake *FakePgDB) NamedQueryCallCount() int {
-	fake.namedQueryMutex.RLock()
-	defer fake.namedQueryMutex.RUnlock()
-	return len(fake.namedQueryArgsForCall)
-}
-
-func (fake *FakePgDB) NamedQueryCalls(stub func(string, interface{}) (*sqlx.Rows, error)) {
-	fake.namedQueryMutex.Lock()
-	defer fake.namedQueryMutex.Unlock()
-	fake.NamedQueryStub = stub
-}
-
-func (fake *FakePgDB) NamedQueryArgsForCall(i int) (string, interface{}) {
-	fake.namedQueryMutex.RLock()
-	defer fake.namedQueryMutex.RUnlock()
-	argsForCall := fake.namedQueryArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
-}
-
-func (fake *FakePgDB) NamedQueryReturns(result1 *sqlx.Rows, result2 error) {
-	fake.namedQueryMutex.Lock()
-	defer fake.namedQueryMutex.Unlock()
-	fake.NamedQueryStub = nil
-	fake.namedQueryReturns = struct {
-		result1 *sqlx.Rows
-		result2 error
-	}{result1, result2}
-}
-
-func (fake *FakePgDB) NamedQueryReturnsOnCall(i int, result1 *sqlx.Rows, result2 error) {
-	fake.namedQueryMutex.Lock()
-	defer fake.namedQueryMutex.Unlock()
-	fake.NamedQueryStub = nil
-	if fake.namedQueryReturnsOnCall == nil {
-		fake.namedQueryReturnsOnCall = make(map[int]struct {
-			result1 *sqlx.Rows
-			result2 error
-		})
-	}
-	fake.namedQueryReturnsOnCall[i] = struct {
-		result1 *sqlx.Rows
-		result2 error
-	}{result1, result2}
-}
-
 func (fake *FakePgDB) PrepareNamedContext(arg1 context.Context, arg2 string) (*sqlx.NamedStmt, error) {
 	fake.prepareNamedContextMutex.Lock()
 	ret, specificReturn := fake.prepareNamedContextReturnsOnCall[len(fake.prepareNamedContextArgsForCall)]
@@ -948,8 +870,6 @@ func (fake *FakePgDB) Invocations() map[string][][]interface{} {
 	defer fake.getContextMutex.RUnlock()
 	fake.namedExecContextMutex.RLock()
 	defer fake.namedExecContextMutex.RUnlock()
-	fake.namedQueryMutex.RLock()
-	defer fake.namedQueryMutex.RUnlock()
 	fake.prepareNamedContextMutex.RLock()
 	defer fake.prepareNamedContextMutex.RUnlock()
 	fake.queryContextMutex.RLock()
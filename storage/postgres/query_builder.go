@@ -18,6 +18,13 @@ import (
 
 const PrimaryKeyColumn = "id"
 
+const (
+	// searchVectorColumn is the column with the full-text search document of searchable entities
+	searchVectorColumn = "search_vector"
+	// searchConfiguration is the text search configuration used to build search documents and queries
+	searchConfiguration = "pg_catalog.simple"
+)
+
 const CountQueryTemplate = `
 SELECT COUNT(DISTINCT {{.ENTITY_TABLE}}.{{.PRIMARY_KEY}})
 FROM {{.ENTITY_TABLE}}
@@ -31,7 +38,7 @@ FROM {{.ENTITY_TABLE}}
 
 const SelectQueryTemplate = `
 {{if or .hasFieldCriteria .hasLabelCriteria}}
-WITH matching_resources as (SELECT DISTINCT {{.ENTITY_TABLE}}.paging_sequence
+WITH matching_resources as (SELECT DISTINCT {{.ENTITY_TABLE}}.paging_sequence{{.SEQUENCE_RANK}}
 							FROM {{.ENTITY_TABLE}}
 							{{if .hasLabelCriteria}}
 							{{.JOIN}} {{.LABELS_TABLE}} 
@@ -120,9 +127,11 @@ type pgQuery struct {
 
 	orderByFields   []orderRule
 	hasLock         bool
+	lockForUpdate   bool
 	limit           string
 	returningFields []string
 	entityTableName string
+	searchTerms     []string
 
 	fieldsWhereClause *whereClauseTree
 	labelsWhereClause *whereClauseTree
@@ -130,12 +139,12 @@ type pgQuery struct {
 	err               error
 }
 
-func (pq *pgQuery) List(ctx context.Context) (*sqlx.Rows, error) {
+func (pq *pgQuery) List(ctx context.Context) (*rows, error) {
 	q, err := pq.resolveQueryTemplate(ctx, SelectQueryTemplate)
 	if err != nil {
 		return nil, err
 	}
-	return pq.db.QueryxContext(ctx, q, pq.queryParams...)
+	return queryRows(ctx, pq.db, q, pq.queryParams...)
 }
 
 func (pq *pgQuery) Count(ctx context.Context) (int, error) {
@@ -163,7 +172,7 @@ func (pq *pgQuery) Delete(ctx context.Context) (sql.Result, error) {
 	return result, nil
 }
 
-func (pq *pgQuery) DeleteReturning(ctx context.Context, fields ...string) (*sqlx.Rows, error) {
+func (pq *pgQuery) DeleteReturning(ctx context.Context, fields ...string) (*rows, error) {
 	if err := validateReturningFields(columnsByTags(pq.entityTags), fields...); err != nil {
 		return nil, err
 	}
@@ -174,11 +183,7 @@ func (pq *pgQuery) DeleteReturning(ctx context.Context, fields ...string) (*sqlx
 		return nil, err
 	}
 
-	rows, err := pq.db.QueryxContext(ctx, q, pq.queryParams...)
-	if err != nil {
-		return nil, err
-	}
-	return rows, nil
+	return queryRows(ctx, pq.db, q, pq.queryParams...)
 }
 
 func (pq *pgQuery) resolveQueryTemplate(ctx context.Context, template string) (string, error) {
@@ -188,7 +193,7 @@ func (pq *pgQuery) resolveQueryTemplate(ctx context.Context, template string) (s
 	if pq.labelEntity == nil {
 		return "", fmt.Errorf("query builder requires the entity to have associated label entity")
 	}
-	hasFieldCriteria := len(pq.fieldsWhereClause.children) != 0 || len(pq.limit) != 0
+	hasFieldCriteria := len(pq.fieldsWhereClause.children) != 0 || len(pq.limit) != 0 || len(pq.searchTerms) != 0
 	hasLabelCriteria := len(pq.labelsWhereClause.children) != 0
 	data := map[string]interface{}{
 		"hasFieldCriteria":  hasFieldCriteria,
@@ -201,6 +206,7 @@ func (pq *pgQuery) resolveQueryTemplate(ctx context.Context, template string) (s
 		"WHERE":             pq.whereSQL(),
 		"FOR_SHARE_OF":      pq.lockSQL(),
 		"ORDER_BY":          pq.orderBySQL(),
+		"SEQUENCE_RANK":     pq.sequenceRankSQL(),
 		"ORDER_BY_SEQUENCE": pq.orderBySequenceSQL(),
 		"LIMIT":             pq.limitSQL(),
 		"RETURNING":         pq.returningSQL(),
@@ -266,6 +272,12 @@ func (pq *pgQuery) WithCriteria(criteria ...query.Criterion) *pgQuery {
 			})
 		case query.ResultQuery:
 			pq.processResultCriteria(criterion)
+		case query.SearchQuery:
+			if !columnsByTags(pq.entityTags)[searchVectorColumn] {
+				pq.err = &util.UnsupportedQueryError{Message: fmt.Sprintf("full-text search is not supported for %s", pq.entityTableName)}
+				return pq
+			}
+			pq.searchTerms = query.SearchTerms(criterion.RightOp[0])
 		}
 	}
 
@@ -276,7 +288,11 @@ func (pq *pgQuery) WithLock() *pgQuery {
 	if pq.err != nil {
 		return pq
 	}
-	if _, ok := pq.db.(*sqlx.Tx); ok {
+	db := pq.db
+	if instrumented, ok := db.(*instrumentedDB); ok {
+		db = instrumented.pgDB
+	}
+	if _, ok := db.(*sqlx.Tx); ok {
 		pq.hasLock = true
 	}
 	return pq
@@ -308,6 +324,15 @@ func (pq *pgQuery) whereSQL() string {
 		},
 	}
 	whereSQL, queryParams := whereClause.compileSQL()
+	if len(pq.searchTerms) != 0 {
+		searchSQL, searchParams := pq.searchSQL()
+		if len(whereSQL) != 0 {
+			whereSQL = fmt.Sprintf("%s %s %s", whereSQL, AND, searchSQL)
+		} else {
+			whereSQL = searchSQL
+		}
+		queryParams = append(queryParams, searchParams...)
+	}
 	if len(whereSQL) == 0 {
 		return ""
 	}
@@ -315,6 +340,29 @@ func (pq *pgQuery) whereSQL() string {
 	return fmt.Sprintf(" WHERE %s", whereSQL)
 }
 
+// searchSQL matches the entities whose search document or any of whose label values contain all search terms as word prefixes
+func (pq *pgQuery) searchSQL() (string, []interface{}) {
+	tsQuery := toTSQuery(pq.searchTerms)
+	sql := fmt.Sprintf("(%[1]s.%[2]s @@ to_tsquery('%[3]s', ?) OR %[1]s.%[4]s IN (SELECT %[5]s FROM %[6]s WHERE to_tsvector('%[3]s', %[6]s.val) @@ to_tsquery('%[3]s', ?)))",
+		pq.entityTableName, searchVectorColumn, searchConfiguration, PrimaryKeyColumn, pq.labelEntity.ReferenceColumn(), pq.labelEntity.LabelsTableName())
+	return sql, []interface{}{tsQuery, tsQuery}
+}
+
+// rankSQL computes the relevance of the entity for the search terms. The search terms consist only of letters, digits
+// and underscores so the query can be inlined.
+func (pq *pgQuery) rankSQL() string {
+	return fmt.Sprintf("ts_rank(%s.%s, to_tsquery('%s', '%s'))", pq.entityTableName, searchVectorColumn, searchConfiguration, toTSQuery(pq.searchTerms))
+}
+
+// toTSQuery builds a text search query which matches documents containing all of the terms as word prefixes
+func toTSQuery(terms []string) string {
+	prefixes := make([]string, 0, len(terms))
+	for _, term := range terms {
+		prefixes = append(prefixes, term+":*")
+	}
+	return strings.Join(prefixes, " & ")
+}
+
 func (pq *pgQuery) returningSQL() string {
 	fieldsCount := len(pq.returningFields)
 	switch fieldsCount {
@@ -326,6 +374,9 @@ func (pq *pgQuery) returningSQL() string {
 }
 
 func (pq *pgQuery) lockSQL() string {
+	if pq.lockForUpdate {
+		return fmt.Sprintf("FOR UPDATE OF %s", pq.entityTableName)
+	}
 	if pq.hasLock {
 		// Lock the rows if we are in transaction so that update operations on those rows can rely on unchanged data
 		// This allows us to handle concurrent updates on the same rows by executing them sequentially as
@@ -378,34 +429,46 @@ func (pq *pgQuery) processResultCriteria(c query.Criterion) *pgQuery {
 			return pq
 		}
 		pq.limit = c.RightOp[0]
+	case query.Lock:
+		pq.lockForUpdate = true
 	}
 	return pq
 }
 
+// orderBySQL orders search results by relevance first
 func (pq *pgQuery) orderBySQL() string {
-	sql := ""
-	rules := pq.orderByFields
-	if len(rules) > 0 {
-		sql += "ORDER BY"
-		for _, orderRule := range rules {
-			sql += fmt.Sprintf(" %s %s,", orderRule.field, orderRule.orderType)
-		}
-		sql = sql[:len(sql)-1]
+	orderings := make([]string, 0, len(pq.orderByFields)+1)
+	if len(pq.searchTerms) != 0 {
+		orderings = append(orderings, pq.rankSQL()+" DESC")
+	}
+	for _, orderRule := range pq.orderByFields {
+		orderings = append(orderings, fmt.Sprintf("%s %s", orderRule.field, orderRule.orderType))
 	}
 
-	if sql == "" {
-		sql = fmt.Sprintf("ORDER BY %s.paging_sequence ASC", pq.entityTableName)
+	if len(pq.orderByFields) == 0 {
+		orderings = append(orderings, fmt.Sprintf("%s.paging_sequence ASC", pq.entityTableName))
 	}
-	return sql
+	return "ORDER BY " + strings.Join(orderings, ", ")
 }
 
-func (pq *pgQuery) orderBySequenceSQL() string {
-	if len(pq.limit) > 0 {
-		return fmt.Sprintf("ORDER BY %s.paging_sequence ASC", pq.entityTableName)
+// sequenceRankSQL selects the relevance of the matching resources when they are limited, so that the most relevant ones are kept
+func (pq *pgQuery) sequenceRankSQL() string {
+	if len(pq.limit) > 0 && len(pq.searchTerms) != 0 {
+		return ", " + pq.rankSQL() + " AS search_rank"
 	}
 	return ""
 }
 
+func (pq *pgQuery) orderBySequenceSQL() string {
+	if len(pq.limit) == 0 {
+		return ""
+	}
+	if len(pq.searchTerms) != 0 {
+		return fmt.Sprintf("ORDER BY search_rank DESC, %s.paging_sequence ASC", pq.entityTableName)
+	}
+	return fmt.Sprintf("ORDER BY %s.paging_sequence ASC", pq.entityTableName)
+}
+
 func validateOrderFields(columns map[string]bool, orderRules ...orderRule) error {
 	fields := make([]string, 0, len(orderRules))
 	orderTypes := make([]string, 0, len(orderRules))
@@ -215,6 +215,87 @@ ORDER BY id DESC, created_at ASC ;`)))
 			})
 		})
 
+		Context("when search criteria is used", func() {
+			It("builds query with full-text search and ranked order", func() {
+				_, err := qb.NewQuery(&postgres.Platform{}).
+					WithCriteria(query.ByText("My-Plat")).
+					List(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(executedQuery).Should(Equal(trim(`
+WITH matching_resources as (SELECT DISTINCT platforms.paging_sequence
+                            FROM platforms
+                            WHERE (platforms.search_vector @@ to_tsquery('pg_catalog.simple', ?) OR platforms.id IN
+                                (SELECT platform_id FROM platform_labels WHERE to_tsvector('pg_catalog.simple', platform_labels.val) @@ to_tsquery('pg_catalog.simple', ?))) )
+SELECT platforms.*,
+       platform_labels.id          "platform_labels.id",
+       platform_labels.key         "platform_labels.key",
+       platform_labels.val         "platform_labels.val",
+       platform_labels.created_at  "platform_labels.created_at",
+       platform_labels.updated_at  "platform_labels.updated_at",
+       platform_labels.platform_id "platform_labels.platform_id"
+FROM platforms
+         LEFT JOIN platform_labels ON platforms.id = platform_labels.platform_id
+WHERE platforms.paging_sequence IN (SELECT matching_resources.paging_sequence FROM matching_resources)
+ORDER BY ts_rank(platforms.search_vector, to_tsquery('pg_catalog.simple', 'my:* & plat:*')) DESC, platforms.paging_sequence ASC ;`)))
+				Expect(queryArgs).To(HaveLen(2))
+				Expect(queryArgs[0]).Should(Equal("my:* & plat:*"))
+				Expect(queryArgs[1]).Should(Equal("my:* & plat:*"))
+			})
+
+			It("limits the results to the most relevant ones", func() {
+				_, err := qb.NewQuery(&postgres.Platform{}).
+					WithCriteria(query.ByText("plat"), query.LimitResultBy(10), query.OrderResultBy("paging_sequence", query.AscOrder)).
+					List(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(executedQuery).Should(Equal(trim(`
+WITH matching_resources as (SELECT DISTINCT platforms.paging_sequence, ts_rank(platforms.search_vector, to_tsquery('pg_catalog.simple', 'plat:*')) AS search_rank
+                            FROM platforms
+                            WHERE (platforms.search_vector @@ to_tsquery('pg_catalog.simple', ?) OR platforms.id IN
+                                (SELECT platform_id FROM platform_labels WHERE to_tsvector('pg_catalog.simple', platform_labels.val) @@ to_tsquery('pg_catalog.simple', ?)))
+                            ORDER BY search_rank DESC, platforms.paging_sequence ASC
+                            LIMIT ?)
+SELECT platforms.*,
+       platform_labels.id          "platform_labels.id",
+       platform_labels.key         "platform_labels.key",
+       platform_labels.val         "platform_labels.val",
+       platform_labels.created_at  "platform_labels.created_at",
+       platform_labels.updated_at  "platform_labels.updated_at",
+       platform_labels.platform_id "platform_labels.platform_id"
+FROM platforms
+         LEFT JOIN platform_labels ON platforms.id = platform_labels.platform_id
+WHERE platforms.paging_sequence IN (SELECT matching_resources.paging_sequence FROM matching_resources)
+ORDER BY ts_rank(platforms.search_vector, to_tsquery('pg_catalog.simple', 'plat:*')) DESC, paging_sequence ASC ;`)))
+				Expect(queryArgs).To(Equal([]interface{}{"plat:*", "plat:*", "10"}))
+			})
+
+			It("combines full-text search with label criteria", func() {
+				_, err := qb.NewQuery(&postgres.Platform{}).
+					WithCriteria(query.ByLabel(query.EqualsOperator, "tenant", "t1"), query.ByText("plat")).
+					List(ctx)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(executedQuery).Should(ContainSubstring(trim(`
+WHERE (key::text = ? AND val::text = ?) AND (platforms.search_vector @@ to_tsquery('pg_catalog.simple', ?) OR platforms.id IN
+	(SELECT platform_id FROM platform_labels WHERE to_tsvector('pg_catalog.simple', platform_labels.val) @@ to_tsquery('pg_catalog.simple', ?)))`)))
+				Expect(queryArgs).To(Equal([]interface{}{"tenant", "t1", "plat:*", "plat:*"}))
+			})
+
+			Context("when the entity is not searchable", func() {
+				It("returns error", func() {
+					_, err := qb.NewQuery(entity).WithCriteria(query.ByText("plat")).List(ctx)
+					Expect(err).Should(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("full-text search is not supported for visibilities"))
+				})
+			})
+
+			Context("when the search text contains no words", func() {
+				It("returns error", func() {
+					_, err := qb.NewQuery(&postgres.Platform{}).WithCriteria(query.ByText("*&!")).List(ctx)
+					Expect(err).Should(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("does not contain any words"))
+				})
+			})
+		})
+
 		Context("when limit criteria is used", func() {
 			It("builds query with limit clause", func() {
 				_, err := qb.NewQuery(entity).
@@ -241,6 +322,16 @@ ORDER BY visibilities.paging_sequence ASC ;`)))
 				Expect(queryArgs[0]).Should(Equal("10"))
 			})
 
+			Context("when the result is locked for update", func() {
+				It("builds query which locks the rows of the entity", func() {
+					_, err := qb.NewQuery(entity).
+						WithCriteria(query.LimitResultBy(10), query.LockResultForUpdate()).
+						List(ctx)
+					Expect(err).ShouldNot(HaveOccurred())
+					Expect(executedQuery).Should(HaveSuffix("ORDER BY visibilities.paging_sequence ASC FOR UPDATE OF visibilities ;"))
+				})
+			})
+
 			Context("when limit is negative", func() {
 				It("returns error", func() {
 					_, err := qb.NewQuery(entity).WithCriteria(query.LimitResultBy(-1)).List(ctx)
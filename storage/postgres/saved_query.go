@@ -0,0 +1,76 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"database/sql"
+
+	"github.com/Peripli/service-manager/storage"
+
+	"github.com/Peripli/service-manager/pkg/types"
+)
+
+// SavedQuery entity
+//go:generate smgen storage SavedQuery github.com/Peripli/service-manager/pkg/types
+type SavedQuery struct {
+	BaseEntity
+	Name         string         `db:"name"`
+	ResourceType string         `db:"resource_type"`
+	FieldQuery   sql.NullString `db:"field_query"`
+	LabelQuery   sql.NullString `db:"label_query"`
+	Owner        sql.NullString `db:"owner"`
+}
+
+func (sq *SavedQuery) ToObject() types.Object {
+	return &types.SavedQuery{
+		Base: types.Base{
+			ID:             sq.ID,
+			CreatedAt:      sq.CreatedAt,
+			UpdatedAt:      sq.UpdatedAt,
+			Labels:         map[string][]string{},
+			PagingSequence: sq.PagingSequence,
+			Ready:          sq.Ready,
+		},
+		Name:         sq.Name,
+		ResourceType: types.ObjectType(sq.ResourceType),
+		FieldQuery:   sq.FieldQuery.String,
+		LabelQuery:   sq.LabelQuery.String,
+		Owner:        sq.Owner.String,
+	}
+}
+
+func (*SavedQuery) FromObject(object types.Object) (storage.Entity, bool) {
+	savedQuery, ok := object.(*types.SavedQuery)
+	if !ok {
+		return nil, false
+	}
+
+	return &SavedQuery{
+		BaseEntity: BaseEntity{
+			ID:             savedQuery.ID,
+			CreatedAt:      savedQuery.CreatedAt,
+			UpdatedAt:      savedQuery.UpdatedAt,
+			PagingSequence: savedQuery.PagingSequence,
+			Ready:          savedQuery.Ready,
+		},
+		Name:         savedQuery.Name,
+		ResourceType: string(savedQuery.ResourceType),
+		FieldQuery:   toNullString(savedQuery.FieldQuery),
+		LabelQuery:   toNullString(savedQuery.LabelQuery),
+		Owner:        toNullString(savedQuery.Owner),
+	}, true
+}
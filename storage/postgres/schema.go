@@ -0,0 +1,92 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/fatih/structs"
+	sqlxtypes "github.com/jmoiron/sqlx/types"
+	"github.com/lib/pq"
+)
+
+// nonQueryableTag is the struct tag used to exclude entity fields such as credentials from field queries
+const nonQueryableTag = "query"
+
+// schemaOf builds the field schema of an entity from the db tags and the types of its fields
+func schemaOf(entity PostgresEntity) *query.Schema {
+	schema := &query.Schema{}
+	collectSchemaFields(structs.New(entity).Fields(), schema)
+	return schema
+}
+
+func collectSchemaFields(fields []*structs.Field, schema *query.Schema) {
+	for _, field := range fields {
+		if field.IsEmbedded() {
+			collectSchemaFields(field.Fields(), schema)
+			continue
+		}
+		dbTag := field.Tag("db")
+		if dbTag == "-" || isGenerated(dbTag) {
+			continue
+		}
+		fieldType, nullable, ok := schemaFieldType(field.Value())
+		if !ok {
+			continue
+		}
+		name := strings.Split(dbTag, ",")[0]
+		if name == "" {
+			name = strings.ToLower(field.Name())
+		}
+		schema.Fields = append(schema.Fields, query.Field{
+			Name:      name,
+			Type:      fieldType,
+			Nullable:  nullable,
+			Queryable: field.Tag(nonQueryableTag) != "-",
+		})
+	}
+}
+
+func schemaFieldType(value interface{}) (fieldType query.FieldType, nullable bool, ok bool) {
+	switch value.(type) {
+	case string:
+		return query.StringField, false, true
+	case sql.NullString:
+		return query.StringField, true, true
+	case int, int32, int64:
+		return query.IntegerField, false, true
+	case sql.NullInt64:
+		return query.IntegerField, true, true
+	case bool:
+		return query.BooleanField, false, true
+	case sql.NullBool:
+		return query.BooleanField, true, true
+	case time.Time:
+		return query.DatetimeField, false, true
+	case pq.NullTime:
+		return query.DatetimeField, true, true
+	case sqlxtypes.JSONText:
+		return query.JSONField, false, true
+	case sqlxtypes.NullJSONText:
+		return query.JSONField, true, true
+	default:
+		return "", false, false
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"reflect"
 
+	"github.com/Peripli/service-manager/pkg/query"
 	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/Peripli/service-manager/storage"
 )
@@ -32,6 +33,7 @@ func newScheme() *scheme {
 		instanceProviders:           make(map[types.ObjectType]entityProvider),
 		converters:                  make(map[types.ObjectType]objectConverter),
 		entityToObjectTypeConverter: make(map[string]string),
+		schemas:                     make(map[types.ObjectType]*query.Schema),
 	}
 }
 
@@ -39,6 +41,7 @@ type scheme struct {
 	instanceProviders           map[types.ObjectType]entityProvider
 	converters                  map[types.ObjectType]objectConverter
 	entityToObjectTypeConverter map[string]string
+	schemas                     map[types.ObjectType]*query.Schema
 }
 
 func (s *scheme) introduce(entity storage.Entity) {
@@ -74,6 +77,7 @@ func (s *scheme) introduce(entity storage.Entity) {
 		panic(fmt.Sprintf("Unable to construct PostgresEntity when introducing object type %s: %s", objType.String(), err))
 	}
 	s.entityToObjectTypeConverter[pgEntity.TableName()] = objType.String()
+	s.schemas[objType] = schemaOf(pgEntity)
 }
 
 func (s *scheme) convert(object types.Object) (PostgresEntity, error) {
@@ -92,3 +96,11 @@ func (s *scheme) provide(objectType types.ObjectType) (PostgresEntity, error) {
 	}
 	return provider()
 }
+
+func (s *scheme) schema(objectType types.ObjectType) (*query.Schema, error) {
+	schema, exists := s.schemas[objectType]
+	if !exists {
+		return nil, fmt.Errorf("no postgres entity is introduced for object of type %s", objectType)
+	}
+	return schema, nil
+}
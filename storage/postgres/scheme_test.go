@@ -23,6 +23,7 @@ import (
 
 	"github.com/Peripli/service-manager/storage"
 
+	"github.com/Peripli/service-manager/pkg/query"
 	"github.com/Peripli/service-manager/pkg/types"
 
 	. "github.com/onsi/ginkgo"
@@ -151,6 +152,39 @@ var _ = Describe("Postgres Storage", func() {
 			})
 		})
 	})
+	Describe("Schema", func() {
+		Context("When no entity for this type is introduced", func() {
+			It("Returns error", func() {
+				schema, err := scheme.schema(types.PlatformType)
+				Expect(schema).To(BeNil())
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("When entity for this type is introduced", func() {
+			var schema *query.Schema
+
+			BeforeEach(func() {
+				scheme.introduce(&Platform{})
+				var err error
+				schema, err = scheme.schema(types.PlatformType)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("Describes the fields of the entity", func() {
+				Expect(schema.Fields).To(ContainElement(query.Field{Name: "id", Type: query.StringField, Queryable: true}))
+				Expect(schema.Fields).To(ContainElement(query.Field{Name: "created_at", Type: query.DatetimeField, Queryable: true}))
+				Expect(schema.Fields).To(ContainElement(query.Field{Name: "paging_sequence", Type: query.IntegerField, Queryable: true}))
+				Expect(schema.Fields).To(ContainElement(query.Field{Name: "active", Type: query.BooleanField, Queryable: true}))
+				Expect(schema.Fields).To(ContainElement(query.Field{Name: "description", Type: query.StringField, Nullable: true, Queryable: true}))
+			})
+
+			It("Marks credentials as not queryable", func() {
+				Expect(schema.Fields).To(ContainElement(query.Field{Name: "username", Type: query.StringField}))
+				Expect(schema.Fields).To(ContainElement(query.Field{Name: "password", Type: query.StringField}))
+			})
+		})
+	})
 })
 
 type obj struct {
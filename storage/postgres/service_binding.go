@@ -35,9 +35,11 @@ type ServiceBinding struct {
 	RouteServiceURL   sql.NullString         `db:"route_service_url"`
 	VolumeMounts      sqlxtypes.NullJSONText `db:"volume_mounts"`
 	Endpoints         sqlxtypes.NullJSONText `db:"endpoints"`
-	Context           sqlxtypes.JSONText     `db:"context"`
+	Context           sqlxtypes.JSONText     `db:"context" query:"-"`
 	BindResource      sqlxtypes.JSONText     `db:"bind_resource"`
-	Credentials       string                 `db:"credentials"`
+	Credentials       string                 `db:"credentials" query:"-"`
+
+	SearchVector sql.NullString `db:"search_vector,generated"`
 }
 
 func (sb *ServiceBinding) ToObject() types.Object {
@@ -34,9 +34,11 @@ type ServiceInstance struct {
 	PlatformID      string             `db:"platform_id"`
 	DashboardURL    sql.NullString     `db:"dashboard_url"`
 	MaintenanceInfo sqlxtypes.JSONText `db:"maintenance_info"`
-	Context         sqlxtypes.JSONText `db:"context"`
+	Context         sqlxtypes.JSONText `db:"context" query:"-"`
 	PreviousValues  sqlxtypes.JSONText `db:"previous_values"`
 	Usable          bool               `db:"usable"`
+
+	SearchVector sql.NullString `db:"search_vector,generated"`
 }
 
 func (si *ServiceInstance) ToObject() types.Object {
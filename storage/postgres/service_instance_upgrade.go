@@ -0,0 +1,108 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package postgres
+
+import (
+	"database/sql"
+
+	sqlxtypes "github.com/jmoiron/sqlx/types"
+
+	"github.com/Peripli/service-manager/storage"
+
+	"github.com/Peripli/service-manager/pkg/types"
+)
+
+// ServiceInstanceUpgrade entity stores the bulk upgrade with the fields of its last operation. The upgrades of its
+// instances are stored as UpgradedInstance entities.
+//go:generate smgen storage ServiceInstanceUpgrade github.com/Peripli/service-manager/pkg/types
+type ServiceInstanceUpgrade struct {
+	BaseEntity
+	ServicePlanID    sql.NullString     `db:"service_plan_id"`
+	BrokerID         sql.NullString     `db:"broker_id"`
+	Concurrency      int                `db:"concurrency"`
+	CanaryPercentage int                `db:"canary_percentage"`
+	Paused           bool               `db:"paused"`
+	State            string             `db:"state"`
+	Description      sql.NullString     `db:"description"`
+	Errors           sqlxtypes.JSONText `db:"errors"`
+	CorrelationID    sql.NullString     `db:"correlation_id"`
+}
+
+func (u *ServiceInstanceUpgrade) ToObject() types.Object {
+	return &types.ServiceInstanceUpgrade{
+		Base: types.Base{
+			ID:             u.ID,
+			CreatedAt:      u.CreatedAt,
+			UpdatedAt:      u.UpdatedAt,
+			Labels:         map[string][]string{},
+			PagingSequence: u.PagingSequence,
+			Ready:          u.Ready,
+		},
+		ServicePlanID:    u.ServicePlanID.String,
+		BrokerID:         u.BrokerID.String,
+		Concurrency:      u.Concurrency,
+		CanaryPercentage: u.CanaryPercentage,
+		Paused:           u.Paused,
+		LastOperation: &types.Operation{
+			Base: types.Base{
+				ID:        u.ID,
+				CreatedAt: u.CreatedAt,
+				UpdatedAt: u.UpdatedAt,
+				Ready:     true,
+			},
+			Description:   u.Description.String,
+			Type:          types.UPDATE,
+			State:         types.OperationState(u.State),
+			ResourceID:    u.ID,
+			ResourceType:  types.ServiceInstanceUpgradeType,
+			Errors:        getJSONRawMessage(u.Errors),
+			PlatformID:    types.SMPlatform,
+			CorrelationID: u.CorrelationID.String,
+		},
+	}
+}
+
+func (*ServiceInstanceUpgrade) FromObject(object types.Object) (storage.Entity, bool) {
+	upgrade, ok := object.(*types.ServiceInstanceUpgrade)
+	if !ok {
+		return nil, false
+	}
+
+	lastOperation := upgrade.LastOperation
+	if lastOperation == nil {
+		lastOperation = &types.Operation{}
+	}
+
+	return &ServiceInstanceUpgrade{
+		BaseEntity: BaseEntity{
+			ID:             upgrade.ID,
+			CreatedAt:      upgrade.CreatedAt,
+			UpdatedAt:      upgrade.UpdatedAt,
+			PagingSequence: upgrade.PagingSequence,
+			Ready:          upgrade.Ready,
+		},
+		ServicePlanID:    toNullString(upgrade.ServicePlanID),
+		BrokerID:         toNullString(upgrade.BrokerID),
+		Concurrency:      upgrade.Concurrency,
+		CanaryPercentage: upgrade.CanaryPercentage,
+		Paused:           upgrade.Paused,
+		State:            string(lastOperation.State),
+		Description:      toNullString(lastOperation.Description),
+		Errors:           getJSONText(lastOperation.Errors),
+		CorrelationID:    toNullString(lastOperation.CorrelationID),
+	}, true
+}
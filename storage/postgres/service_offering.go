@@ -17,6 +17,8 @@
 package postgres
 
 import (
+	"database/sql"
+
 	"github.com/Peripli/service-manager/storage"
 	sqlxtypes "github.com/jmoiron/sqlx/types"
 
@@ -43,6 +45,8 @@ type ServiceOffering struct {
 
 	BrokerID string `db:"broker_id"`
 
+	SearchVector sql.NullString `db:"search_vector,generated"`
+
 	Plans []*ServicePlan `db:"-"`
 }
 
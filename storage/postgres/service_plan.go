@@ -42,6 +42,8 @@ type ServicePlan struct {
 	MaintenanceInfo        sqlxtypes.JSONText `db:"maintenance_info"`
 
 	ServiceOfferingID string `db:"service_offering_id"`
+
+	SearchVector sql.NullString `db:"search_vector,generated"`
 }
 
 func (sp *ServicePlan) ToObject() types.Object {
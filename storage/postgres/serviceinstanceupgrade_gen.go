@@ -0,0 +1,72 @@
+// GENERATED. DO NOT MODIFY!
+
+package postgres
+
+import (
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"database/sql"
+	"time"
+)
+
+var _ PostgresEntity = &ServiceInstanceUpgrade{}
+
+const ServiceInstanceUpgradeTable = "service_instance_upgrades"
+
+func (*ServiceInstanceUpgrade) LabelEntity() PostgresLabel {
+	return &ServiceInstanceUpgradeLabel{}
+}
+
+func (*ServiceInstanceUpgrade) TableName() string {
+	return ServiceInstanceUpgradeTable
+}
+
+func (e *ServiceInstanceUpgrade) NewLabel(id, key, value string) storage.Label {
+	now := pq.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	return &ServiceInstanceUpgradeLabel{
+		BaseLabelEntity: BaseLabelEntity{
+			ID:        sql.NullString{String: id, Valid: id != ""},
+			Key:       sql.NullString{String: key, Valid: key != ""},
+			Val:       sql.NullString{String: value, Valid: value != ""},
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		ServiceInstanceUpgradeID: sql.NullString{String: e.ID, Valid: e.ID != ""},
+	}
+}
+
+func (e *ServiceInstanceUpgrade) RowsToList(rows *sqlx.Rows) (types.ObjectList, error) {
+	rowCreator := func() EntityLabelRow {
+		return &struct {
+			*ServiceInstanceUpgrade
+			ServiceInstanceUpgradeLabel `db:"service_instance_upgrade_labels"`
+		}{}
+	}
+	result := &types.ServiceInstanceUpgrades{
+		ServiceInstanceUpgrades: make([]*types.ServiceInstanceUpgrade, 0),
+	}
+	err := rowsToList(rows, rowCreator, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type ServiceInstanceUpgradeLabel struct {
+	BaseLabelEntity
+	ServiceInstanceUpgradeID sql.NullString `db:"service_instance_upgrade_id"`
+}
+
+func (el ServiceInstanceUpgradeLabel) LabelsTableName() string {
+	return "service_instance_upgrade_labels"
+}
+
+func (el ServiceInstanceUpgradeLabel) ReferenceColumn() string {
+	return "service_instance_upgrade_id"
+}
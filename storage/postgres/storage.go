@@ -30,8 +30,6 @@ import (
 	"github.com/Peripli/service-manager/pkg/types"
 	"github.com/Peripli/service-manager/pkg/util"
 	"github.com/Peripli/service-manager/storage"
-	"github.com/golang-migrate/migrate"
-	migratepg "github.com/golang-migrate/migrate/database/postgres"
 	_ "github.com/golang-migrate/migrate/source/file"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
@@ -50,8 +48,14 @@ type Storage struct {
 	queryBuilder          *QueryBuilder
 	state                 *storageState
 	layerOneEncryptionKey []byte
-	scheme                *scheme
-	mutex                 sync.Mutex
+	// previousLayerOneEncryptionKeys are used to decrypt the encryption keys in the safe until they are re-encrypted
+	previousLayerOneEncryptionKeys [][]byte
+	partitioning                   *storage.PartitioningSettings
+	statementTimeout               time.Duration
+	slowQueryThreshold             time.Duration
+	uniqueConstraints              *uniqueConstraints
+	scheme                         *scheme
+	mutex                          sync.Mutex
 }
 
 func (ps *Storage) Introduce(entity storage.Entity) {
@@ -71,11 +75,7 @@ func (ps *Storage) Open(settings *storage.Settings) error {
 	ps.mutex.Lock()
 	defer ps.mutex.Unlock()
 	if ps.db == nil {
-		sslModeParam := ""
-		if settings.SkipSSLValidation {
-			sslModeParam = "?sslmode=disable"
-		}
-		db, err := ps.ConnectFunc(postgresDriverName, settings.URI+sslModeParam)
+		db, err := ps.ConnectFunc(postgresDriverName, dataSourceName(settings))
 		if err != nil {
 			return fmt.Errorf("could not connect to PostgreSQL: %s", err)
 		}
@@ -88,12 +88,25 @@ func (ps *Storage) Open(settings *storage.Settings) error {
 			storageCheckInterval: time.Second * 5,
 		}
 		ps.layerOneEncryptionKey = []byte(settings.EncryptionKey)
+		for _, previousKey := range settings.PreviousEncryptionKeys {
+			ps.previousLayerOneEncryptionKeys = append(ps.previousLayerOneEncryptionKeys, []byte(previousKey))
+		}
+		ps.partitioning = settings.Partitioning
+		if ps.partitioning == nil {
+			ps.partitioning = storage.DefaultPartitioningSettings()
+		}
 		ps.db.SetMaxIdleConns(settings.MaxIdleConnections)
-		ps.pgDB = ps.db
+		ps.db.SetMaxOpenConns(settings.MaxOpenConnections)
+		ps.db.SetConnMaxLifetime(settings.ConnectionMaxLifetime)
+		ps.statementTimeout = settings.StatementTimeout
+		ps.slowQueryThreshold = settings.SlowQueryThreshold
+		ps.uniqueConstraints = newUniqueConstraints()
+		// the statement timeout is applied only to the statements of the storage and not to the
+		// migrations and the lockers, which share the connection pool but may wait for a long time
+		ps.pgDB = ps.instrument(ps.db, ps.statementTimeout)
 		ps.queryBuilder = NewQueryBuilder(ps.pgDB)
 
-		log.D().Debugf("Updating database schema using migrations from %s", settings.MigrationsURL)
-		if err := ps.updateSchema(settings.MigrationsURL, postgresDriverName); err != nil {
+		if err := ps.updateSchema(settings); err != nil {
 			return fmt.Errorf("could not update database schema: %s", err)
 		}
 		ps.scheme = newScheme()
@@ -106,6 +119,10 @@ func (ps *Storage) Open(settings *storage.Settings) error {
 		ps.scheme.introduce(&Operation{})
 		ps.scheme.introduce(&ServiceInstance{})
 		ps.scheme.introduce(&ServiceBinding{})
+		ps.scheme.introduce(&SavedQuery{})
+		ps.scheme.introduce(&LabelDefinition{})
+		ps.scheme.introduce(&TransformationRule{})
+		ps.scheme.introduce(&ServiceInstanceUpgrade{})
 	}
 
 	return nil
@@ -121,28 +138,42 @@ func (ps *Storage) Close() error {
 	return nil
 }
 
+func (ps *Storage) instrument(db pgDB, statementTimeout time.Duration) pgDB {
+	return &instrumentedDB{
+		pgDB:               db,
+		statementTimeout:   statementTimeout,
+		slowQueryThreshold: ps.slowQueryThreshold,
+	}
+}
+
 func (ps *Storage) checkOpen() {
 	if ps.pgDB == nil {
 		log.D().Panicln("Storage is not yet open")
 	}
 }
 
-func (ps *Storage) updateSchema(migrationsURL, pgDriverName string) error {
-	driver, err := migratepg.WithInstance(ps.db.DB, &migratepg.Config{})
+func (ps *Storage) updateSchema(settings *storage.Settings) error {
+	migrator, err := NewMigrator(ps.db.DB, settings.MigrationsURL, settings.MigrationsLockTimeout)
 	if err != nil {
 		return err
 	}
-	m, err := migrate.NewWithDatabaseInstance(migrationsURL, pgDriverName, driver)
+
+	if settings.AutoMigrate {
+		log.D().Debugf("Updating database schema using migrations from %s", settings.MigrationsURL)
+		return migrator.Up(0)
+	}
+
+	status, err := migrator.Status()
 	if err != nil {
 		return err
 	}
-	m.Log = migrateLogger{}
-	err = m.Up()
-	if err == migrate.ErrNoChange {
-		log.D().Debug("Database schema already up to date")
-		err = nil
+	if status.Dirty {
+		return fmt.Errorf("database schema is dirty at version %d. Fix it and run the migrate force command", status.Version)
+	}
+	if len(status.Pending) > 0 {
+		return fmt.Errorf("database schema is at version %d but %d migrations are pending. Run the migrate up command", status.Version, len(status.Pending))
 	}
-	return err
+	return nil
 }
 
 func (ps *Storage) PingContext(ctx context.Context) error {
@@ -150,6 +181,12 @@ func (ps *Storage) PingContext(ctx context.Context) error {
 	return ps.state.Get()
 }
 
+// Schema implements storage.SchemaProvider and returns the field schema of the entity introduced for the given object type
+func (ps *Storage) Schema(objectType types.ObjectType) (*query.Schema, error) {
+	ps.checkOpen()
+	return ps.scheme.schema(objectType)
+}
+
 func (ps *Storage) Create(ctx context.Context, obj types.Object) (types.Object, error) {
 	pgEntity, err := ps.scheme.convert(obj)
 	if err != nil {
@@ -176,6 +213,10 @@ func (ps *Storage) Create(ctx context.Context, obj types.Object) (types.Object,
 		return nil, err
 	}
 
+	if err = ps.insertUniqueKeys(ctx, createdObj.GetType(), createdObj.GetID()); err != nil {
+		return nil, err
+	}
+
 	return createdObj, nil
 }
 
@@ -220,17 +261,11 @@ func (ps *Storage) List(ctx context.Context, objType types.ObjectType, criteria
 	}
 
 	defer func() {
-		if rows == nil {
-			return
-		}
 		if err := rows.Close(); err != nil {
 			log.C(ctx).WithError(err).Error("Could not release connection when checking database")
 		}
 	}()
-	if err != nil {
-		return nil, err
-	}
-	return entity.RowsToList(rows)
+	return entity.RowsToList(rows.Rows)
 }
 
 func (ps *Storage) Count(ctx context.Context, objType types.ObjectType, criteria ...query.Criterion) (int, error) {
@@ -248,7 +283,6 @@ func (ps *Storage) DeleteReturning(ctx context.Context, objType types.ObjectType
 	}
 
 	rows, err := ps.queryBuilder.NewQuery(entity).WithCriteria(criteria...).DeleteReturning(ctx, "*")
-	defer closeRows(ctx, rows)
 	if err != nil {
 		pqError, ok := err.(*pq.Error)
 		if ok && pqError.Code.Name() == foreignKeyViolation {
@@ -263,7 +297,8 @@ func (ps *Storage) DeleteReturning(ctx context.Context, objType types.ObjectType
 
 		return nil, err
 	}
-	objectList, err := entity.RowsToList(rows)
+	defer closeRows(ctx, rows)
+	objectList, err := entity.RowsToList(rows.Rows)
 	if err != nil {
 		return nil, err
 	}
@@ -309,6 +344,9 @@ func (ps *Storage) Update(ctx context.Context, obj types.Object, labelChanges qu
 	if err = ps.updateLabels(ctx, entity.GetID(), entity, labelChanges); err != nil {
 		return nil, err
 	}
+	if err = ps.updateUniqueKeys(ctx, obj.GetType(), entity.GetID()); err != nil {
+		return nil, err
+	}
 
 	result := entity.ToObject()
 	return result, nil
@@ -328,7 +366,8 @@ func (ps *Storage) updateLabels(ctx context.Context, entityID string, entity Pos
 
 func (ps *Storage) InTransaction(ctx context.Context, f func(ctx context.Context, storage storage.Repository) error) error {
 	ok := false
-	tx, err := ps.db.Beginx()
+	// like Beginx the transaction is not bound to the context, only its options are taken from it
+	tx, err := ps.db.BeginTxx(context.Background(), storage.TxOptionsFromContext(ctx))
 	if err != nil {
 		return err
 	}
@@ -340,12 +379,24 @@ func (ps *Storage) InTransaction(ctx context.Context, f func(ctx context.Context
 		}
 	}()
 
+	// the statements of the transaction are limited by the server, the statement timeout from the context still applies to each of them
+	if ps.statementTimeout > 0 {
+		if _, err = tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", ps.statementTimeout.Nanoseconds()/int64(time.Millisecond))); err != nil {
+			return err
+		}
+	}
+
 	transactionalStorage := &Storage{
-		pgDB:                  tx,
-		db:                    ps.db,
-		queryBuilder:          NewQueryBuilder(tx),
-		scheme:                ps.scheme,
-		layerOneEncryptionKey: ps.layerOneEncryptionKey,
+		pgDB:                           ps.instrument(tx, 0),
+		db:                             ps.db,
+		queryBuilder:                   NewQueryBuilder(ps.instrument(tx, 0)),
+		scheme:                         ps.scheme,
+		layerOneEncryptionKey:          ps.layerOneEncryptionKey,
+		previousLayerOneEncryptionKeys: ps.previousLayerOneEncryptionKeys,
+		partitioning:                   ps.partitioning,
+		statementTimeout:               ps.statementTimeout,
+		slowQueryThreshold:             ps.slowQueryThreshold,
+		uniqueConstraints:              ps.uniqueConstraints,
 	}
 
 	if err = f(ctx, transactionalStorage); err != nil {
@@ -18,9 +18,12 @@ package postgres
 
 import (
 	"context"
+	"time"
 
 	"github.com/Peripli/service-manager/storage"
+	"github.com/jmoiron/sqlx"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -52,6 +55,30 @@ var _ = Describe("Postgres Storage", func() {
 		})
 	})
 
+	Context("GetEncryptionKeys", func() {
+		Context("Called with uninitialized db", func() {
+			It("Should panic", func() {
+				Expect(func() {
+					pgStorage.GetEncryptionKeys(context.TODO(), func(i context.Context, bytes3 []byte, bytes2 []byte) (bytes []byte, e error) {
+						return []byte{}, nil
+					})
+				}).To(Panic())
+			})
+		})
+	})
+
+	Context("AddEncryptionKey", func() {
+		Context("Called with uninitialized db", func() {
+			It("Should panic", func() {
+				Expect(func() {
+					pgStorage.AddEncryptionKey(context.TODO(), []byte{}, func(i context.Context, bytes3 []byte, bytes2 []byte) (bytes []byte, e error) {
+						return []byte{}, nil
+					})
+				}).To(Panic())
+			})
+		})
+	})
+
 	Describe("Ping", func() {
 		Context("Called with uninitialized db", func() {
 			It("Should panic", func() {
@@ -106,6 +133,48 @@ var _ = Describe("Postgres Storage", func() {
 		})
 	})
 
+	Describe("InTransaction", func() {
+		var s *Storage
+		var mock sqlmock.Sqlmock
+
+		BeforeEach(func() {
+			mockdb, sqlMock, err := sqlmock.New()
+			Expect(err).ToNot(HaveOccurred())
+			mock = sqlMock
+			s = &Storage{db: sqlx.NewDb(mockdb, postgresDriverName)}
+		})
+
+		AfterEach(func() {
+			Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+		})
+
+		Context("when a statement timeout is configured", func() {
+			It("limits the statements of the transaction", func() {
+				s.statementTimeout = 2 * time.Second
+				mock.ExpectBegin()
+				mock.ExpectExec("SET LOCAL statement_timeout = 2000").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+
+				err := s.InTransaction(context.Background(), func(ctx context.Context, storage storage.Repository) error {
+					return nil
+				})
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
+		Context("when no statement timeout is configured", func() {
+			It("does not limit the statements of the transaction", func() {
+				mock.ExpectBegin()
+				mock.ExpectCommit()
+
+				err := s.InTransaction(context.Background(), func(ctx context.Context, storage storage.Repository) error {
+					return nil
+				})
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+	})
+
 	Describe("Close", func() {
 		Context("Called with uninitialized db", func() {
 			It("Should not panic", func() {
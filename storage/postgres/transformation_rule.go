@@ -0,0 +1,114 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	sqlxtypes "github.com/jmoiron/sqlx/types"
+	"github.com/lib/pq"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/storage"
+
+	"github.com/Peripli/service-manager/pkg/types"
+)
+
+// TransformationRule entity
+//go:generate smgen storage TransformationRule github.com/Peripli/service-manager/pkg/types
+type TransformationRule struct {
+	BaseEntity
+	Name              string             `db:"name"`
+	Description       sql.NullString     `db:"description"`
+	BrokerID          sql.NullString     `db:"broker_id"`
+	ServiceOfferingID sql.NullString     `db:"service_offering_id"`
+	ServicePlanID     sql.NullString     `db:"service_plan_id"`
+	PlatformType      sql.NullString     `db:"platform_type"`
+	Operations        pq.StringArray     `db:"operations"`
+	Phase             sql.NullString     `db:"phase"`
+	Priority          int                `db:"priority"`
+	Actions           sqlxtypes.JSONText `db:"actions"`
+}
+
+func (tr *TransformationRule) ToObject() types.Object {
+	operations := make([]types.TransformationOperation, 0, len(tr.Operations))
+	for _, operation := range tr.Operations {
+		operations = append(operations, types.TransformationOperation(operation))
+	}
+	var actions []*types.TransformationAction
+	if err := json.Unmarshal(tr.Actions, &actions); err != nil {
+		log.D().WithError(err).Errorf("could not unmarshal actions of transformation rule with id %s", tr.ID)
+	}
+
+	return &types.TransformationRule{
+		Base: types.Base{
+			ID:             tr.ID,
+			CreatedAt:      tr.CreatedAt,
+			UpdatedAt:      tr.UpdatedAt,
+			Labels:         map[string][]string{},
+			PagingSequence: tr.PagingSequence,
+			Ready:          tr.Ready,
+		},
+		Name:              tr.Name,
+		Description:       tr.Description.String,
+		BrokerID:          tr.BrokerID.String,
+		ServiceOfferingID: tr.ServiceOfferingID.String,
+		ServicePlanID:     tr.ServicePlanID.String,
+		PlatformType:      tr.PlatformType.String,
+		Operations:        operations,
+		Phase:             types.TransformationPhase(tr.Phase.String),
+		Priority:          tr.Priority,
+		Actions:           actions,
+	}
+}
+
+func (*TransformationRule) FromObject(object types.Object) (storage.Entity, bool) {
+	rule, ok := object.(*types.TransformationRule)
+	if !ok {
+		return nil, false
+	}
+
+	operations := make(pq.StringArray, 0, len(rule.Operations))
+	for _, operation := range rule.Operations {
+		operations = append(operations, string(operation))
+	}
+	actions, err := json.Marshal(rule.Actions)
+	if err != nil {
+		log.D().WithError(err).Errorf("could not marshal actions of transformation rule with id %s", rule.ID)
+	}
+
+	return &TransformationRule{
+		BaseEntity: BaseEntity{
+			ID:             rule.ID,
+			CreatedAt:      rule.CreatedAt,
+			UpdatedAt:      rule.UpdatedAt,
+			PagingSequence: rule.PagingSequence,
+			Ready:          rule.Ready,
+		},
+		Name:              rule.Name,
+		Description:       toNullString(rule.Description),
+		BrokerID:          toNullString(rule.BrokerID),
+		ServiceOfferingID: toNullString(rule.ServiceOfferingID),
+		ServicePlanID:     toNullString(rule.ServicePlanID),
+		PlatformType:      toNullString(rule.PlatformType),
+		Operations:        operations,
+		Phase:             toNullString(string(rule.Phase)),
+		Priority:          rule.Priority,
+		Actions:           actions,
+	}, true
+}
@@ -0,0 +1,72 @@
+// GENERATED. DO NOT MODIFY!
+
+package postgres
+
+import (
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"database/sql"
+	"time"
+)
+
+var _ PostgresEntity = &TransformationRule{}
+
+const TransformationRuleTable = "transformation_rules"
+
+func (*TransformationRule) LabelEntity() PostgresLabel {
+	return &TransformationRuleLabel{}
+}
+
+func (*TransformationRule) TableName() string {
+	return TransformationRuleTable
+}
+
+func (e *TransformationRule) NewLabel(id, key, value string) storage.Label {
+	now := pq.NullTime{
+		Time:  time.Now(),
+		Valid: true,
+	}
+	return &TransformationRuleLabel{
+		BaseLabelEntity: BaseLabelEntity{
+			ID:        sql.NullString{String: id, Valid: id != ""},
+			Key:       sql.NullString{String: key, Valid: key != ""},
+			Val:       sql.NullString{String: value, Valid: value != ""},
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		TransformationRuleID: sql.NullString{String: e.ID, Valid: e.ID != ""},
+	}
+}
+
+func (e *TransformationRule) RowsToList(rows *sqlx.Rows) (types.ObjectList, error) {
+	rowCreator := func() EntityLabelRow {
+		return &struct {
+			*TransformationRule
+			TransformationRuleLabel `db:"transformation_rule_labels"`
+		}{}
+	}
+	result := &types.TransformationRules{
+		TransformationRules: make([]*types.TransformationRule, 0),
+	}
+	err := rowsToList(rows, rowCreator, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type TransformationRuleLabel struct {
+	BaseLabelEntity
+	TransformationRuleID sql.NullString `db:"transformation_rule_id"`
+}
+
+func (el TransformationRuleLabel) LabelsTableName() string {
+	return "transformation_rule_labels"
+}
+
+func (el TransformationRuleLabel) ReferenceColumn() string {
+	return "transformation_rule_id"
+}
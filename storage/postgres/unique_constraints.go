@@ -0,0 +1,293 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Peripli/service-manager/pkg/log"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const (
+	uniqueKeysTable             = "unique_keys"
+	uniqueKeyExemptionsTable    = "unique_key_exemptions"
+	uniqueConstraintsLockIndex  = 113 // 114 is taken by the assignments of the revisions of the changes
+	uniqueViolationErrorCodeKey = "unique_violation"
+)
+
+var _ storage.UniqueConstraintEnforcer = &Storage{}
+
+// uniqueConstraint is a unique constraint compiled to the SQL which computes the unique keys of the entities.
+// The unique keys are stored in the unique_keys table whose primary key guarantees their uniqueness. Partial unique
+// indexes on the tables of the entities cannot be used instead, because the constraints are declared at runtime and
+// their scope label is stored in the labels table, which neither index expressions nor generated columns can reference.
+type uniqueConstraint struct {
+	storage.UniqueConstraint
+	table string
+	// keyExpression computes the unique key of the entity aliased as t
+	keyExpression string
+	// condition restricts the entities aliased as t to which the constraint applies
+	condition string
+}
+
+// uniqueConstraints holds the declared unique constraints and is shared with the transactional storages
+type uniqueConstraints struct {
+	mutex  sync.RWMutex
+	byType map[types.ObjectType][]*uniqueConstraint
+}
+
+func newUniqueConstraints() *uniqueConstraints {
+	return &uniqueConstraints{
+		byType: make(map[types.ObjectType][]*uniqueConstraint),
+	}
+}
+
+func (uc *uniqueConstraints) add(constraints ...*uniqueConstraint) {
+	uc.mutex.Lock()
+	defer uc.mutex.Unlock()
+	for _, constraint := range constraints {
+		declared := uc.byType[constraint.ObjectType]
+		replaced := false
+		for i, existing := range declared {
+			if existing.Name == constraint.Name {
+				declared[i] = constraint
+				replaced = true
+			}
+		}
+		if !replaced {
+			uc.byType[constraint.ObjectType] = append(declared, constraint)
+		}
+	}
+}
+
+func (uc *uniqueConstraints) of(objectType types.ObjectType) []*uniqueConstraint {
+	if uc == nil {
+		return nil
+	}
+	uc.mutex.RLock()
+	defer uc.mutex.RUnlock()
+	return uc.byType[objectType]
+}
+
+// DeclareUniqueConstraints implements storage.UniqueConstraintEnforcer. The unique keys of the existing entities are
+// rebuilt, and entities which already violate a constraint are logged and exempted from it until they no longer violate it.
+func (ps *Storage) DeclareUniqueConstraints(ctx context.Context, constraints ...storage.UniqueConstraint) error {
+	ps.checkOpen()
+	compiled := make([]*uniqueConstraint, 0, len(constraints))
+	for _, constraint := range constraints {
+		c, err := ps.compileUniqueConstraint(constraint)
+		if err != nil {
+			return err
+		}
+		compiled = append(compiled, c)
+	}
+
+	tx, err := ps.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			log.C(ctx).WithError(err).Error("Could not rollback transaction")
+		}
+	}()
+
+	// serializes the declarations of the Service Manager instances which start at the same time
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", uniqueConstraintsLockIndex); err != nil {
+		return err
+	}
+	for _, c := range compiled {
+		if err := ensureDeleteUniqueKeysTrigger(ctx, tx, c.table); err != nil {
+			return fmt.Errorf("could not create trigger deleting the unique keys of table %s: %s", c.table, err)
+		}
+		if err := rebuildUniqueKeys(ctx, tx, c); err != nil {
+			return fmt.Errorf("could not build unique keys of constraint %s: %s", c.Name, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	ps.uniqueConstraints.add(compiled...)
+	return nil
+}
+
+func (ps *Storage) compileUniqueConstraint(constraint storage.UniqueConstraint) (*uniqueConstraint, error) {
+	if err := constraint.Validate(); err != nil {
+		return nil, err
+	}
+	entity, err := ps.scheme.provide(constraint.ObjectType)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := ps.scheme.schema(constraint.ObjectType)
+	if err != nil {
+		return nil, err
+	}
+
+	keyParts := make([]string, 0, len(constraint.Fields)+1)
+	for _, field := range constraint.Fields {
+		if _, found := schema.Field(field); !found {
+			return nil, fmt.Errorf("field %s of unique constraint %s not found in %s", field, constraint.Name, constraint.ObjectType)
+		}
+		keyParts = append(keyParts, "t."+pq.QuoteIdentifier(field))
+	}
+	if len(constraint.ScopeLabel) != 0 {
+		label := entity.LabelEntity()
+		// the smallest value in byte order scopes an entity with multiple values of the scope label, like in storage.UniqueConstraint
+		keyParts = append(keyParts, fmt.Sprintf(`(SELECT min(l.val COLLATE "C") FROM %s l WHERE l.%s = t.id AND l.key = %s)`,
+			label.LabelsTableName(), label.ReferenceColumn(), pq.QuoteLiteral(constraint.ScopeLabel)))
+	}
+
+	conditionFields := make([]string, 0, len(constraint.Conditions))
+	for field := range constraint.Conditions {
+		if _, found := schema.Field(field); !found {
+			return nil, fmt.Errorf("condition field %s of unique constraint %s not found in %s", field, constraint.Name, constraint.ObjectType)
+		}
+		conditionFields = append(conditionFields, field)
+	}
+	sort.Strings(conditionFields)
+	conditions := []string{"TRUE"}
+	for _, field := range conditionFields {
+		conditions = append(conditions, fmt.Sprintf("t.%s = %s", pq.QuoteIdentifier(field), pq.QuoteLiteral(constraint.Conditions[field])))
+	}
+
+	return &uniqueConstraint{
+		UniqueConstraint: constraint,
+		table:            entity.TableName(),
+		keyExpression:    fmt.Sprintf("json_build_array(%s)::text", strings.Join(keyParts, ", ")),
+		condition:        strings.Join(conditions, " AND "),
+	}, nil
+}
+
+func ensureDeleteUniqueKeysTrigger(ctx context.Context, tx *sqlx.Tx, table string) error {
+	trigger := table + "_delete_unique_keys"
+	var count int
+	if err := tx.GetContext(ctx, &count, "SELECT COUNT(*) FROM pg_trigger WHERE tgname = $1", trigger); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, fmt.Sprintf("CREATE TRIGGER %s AFTER DELETE ON %s FOR EACH ROW EXECUTE PROCEDURE delete_unique_keys()", trigger, table))
+	return err
+}
+
+func rebuildUniqueKeys(ctx context.Context, tx *sqlx.Tx, c *uniqueConstraint) error {
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE constraint_name = $1", uniqueKeysTable), c.Name); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, insertUniqueKeysQuery(c, "TRUE", true), c.Name); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE constraint_name = $1", uniqueKeyExemptionsTable), c.Name); err != nil {
+		return err
+	}
+	// the entities which match the constraint but got no unique key duplicate the key of another entity
+	result, err := tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (constraint_name, entity_id) SELECT $1, t.id FROM %s t WHERE %s
+AND NOT EXISTS (SELECT 1 FROM %s k WHERE k.constraint_name = $1 AND k.entity_id = t.id)`,
+		uniqueKeyExemptionsTable, c.table, c.condition, uniqueKeysTable), c.Name)
+	if err != nil {
+		return err
+	}
+	exempted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if exempted > 0 {
+		log.C(ctx).Warnf("%d entities of table %s violate unique constraint %s and are not unique until they are changed", exempted, c.table, c.Name)
+	}
+	return nil
+}
+
+// insertUniqueKeysQuery builds a query which inserts the unique keys of the entities matching the filter. The first
+// parameter of the query is the name of the constraint.
+func insertUniqueKeysQuery(c *uniqueConstraint, filter string, ignoreConflicts bool) string {
+	query := fmt.Sprintf("INSERT INTO %s (constraint_name, unique_key, entity_id) SELECT $1, %s, t.id FROM %s t WHERE %s AND %s",
+		uniqueKeysTable, c.keyExpression, c.table, c.condition, filter)
+	if ignoreConflicts {
+		query += " ON CONFLICT DO NOTHING"
+	}
+	return query
+}
+
+// insertUniqueKeys inserts the unique keys of the entity for the constraints declared for its type
+func (ps *Storage) insertUniqueKeys(ctx context.Context, objectType types.ObjectType, entityID string) error {
+	for _, c := range ps.uniqueConstraints.of(objectType) {
+		if err := ps.insertUniqueKey(ctx, c, entityID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateUniqueKeys replaces the unique keys of the entity for the constraints declared for its type
+func (ps *Storage) updateUniqueKeys(ctx context.Context, objectType types.ObjectType, entityID string) error {
+	for _, c := range ps.uniqueConstraints.of(objectType) {
+		if _, err := ps.pgDB.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE constraint_name = $1 AND entity_id = $2", uniqueKeysTable), c.Name, entityID); err != nil {
+			return err
+		}
+		var exempted bool
+		if err := ps.pgDB.GetContext(ctx, &exempted, fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE constraint_name = $1 AND entity_id = $2)", uniqueKeyExemptionsTable), c.Name, entityID); err != nil {
+			return err
+		}
+		if !exempted {
+			if err := ps.insertUniqueKey(ctx, c, entityID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// an entity which violated the constraint when it was declared must remain updatable while it still violates it
+		result, err := ps.pgDB.ExecContext(ctx, insertUniqueKeysQuery(c, "t.id = $2", true), c.Name, entityID)
+		if err != nil {
+			return err
+		}
+		inserted, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if inserted > 0 {
+			if _, err := ps.pgDB.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE constraint_name = $1 AND entity_id = $2", uniqueKeyExemptionsTable), c.Name, entityID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (ps *Storage) insertUniqueKey(ctx context.Context, c *uniqueConstraint, entityID string) error {
+	_, err := ps.pgDB.ExecContext(ctx, insertUniqueKeysQuery(c, "t.id = $2", false), c.Name, entityID)
+	if sqlErr, ok := err.(*pq.Error); ok && sqlErr.Code.Name() == uniqueViolationErrorCodeKey {
+		log.C(ctx).Debugf("Unique constraint %s violated by entity with id %s: %s", c.Name, entityID, sqlErr.Detail)
+		return &util.ErrUniqueConstraintViolation{
+			Constraint:  c.Name,
+			Description: c.Description,
+		}
+	}
+	return err
+}
@@ -0,0 +1,170 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/util"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/lib/pq"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Unique constraints", func() {
+	var s *Storage
+	var mock sqlmock.Sqlmock
+	var constraint storage.UniqueConstraint
+
+	BeforeEach(func() {
+		mockdb, m, err := sqlmock.New()
+		Expect(err).ToNot(HaveOccurred())
+		mock = m
+
+		s = &Storage{
+			ConnectFunc: func(driver string, url string) (*sql.DB, error) {
+				return mockdb, nil
+			},
+		}
+		mock.ExpectQuery(`SELECT CURRENT_DATABASE()`).WillReturnRows(sqlmock.NewRows([]string{"mock"}).FromCSVString("mock"))
+		mock.ExpectQuery(`SELECT COUNT(1)*`).WillReturnRows(sqlmock.NewRows([]string{"mock"}).FromCSVString("1"))
+		mock.ExpectExec("SELECT pg_advisory_lock*").WithArgs(sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(`SELECT version, dirty FROM "schema_migrations" LIMIT 1`).WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).FromCSVString(latestMigration + ",false"))
+		mock.ExpectExec("SELECT pg_advisory_unlock*").WithArgs(sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+
+		settings := storage.DefaultSettings()
+		settings.EncryptionKey = "ejHjRNHbS0NaqARSRvnweVV9zcmhQEa8"
+		settings.URI = "sqlmock://sqlmock"
+		Expect(s.Open(settings)).To(Succeed())
+
+		constraint = storage.UniqueConstraint{
+			Name:        "service_instance_name_per_tenant",
+			ObjectType:  types.ServiceInstanceType,
+			Fields:      []string{"name"},
+			ScopeLabel:  "tenant",
+			Conditions:  map[string]string{"platform_id": types.SMPlatform},
+			Description: "instance with same name exists for the current tenant",
+		}
+	})
+
+	AfterEach(func() {
+		Expect(mock.ExpectationsWereMet()).ToNot(HaveOccurred())
+	})
+
+	expectDeclared := func() {
+		mock.ExpectBegin()
+		mock.ExpectExec("SELECT pg_advisory_xact_lock").WithArgs(uniqueConstraintsLockIndex).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM pg_trigger").WithArgs("service_instances_delete_unique_keys").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectExec("CREATE TRIGGER service_instances_delete_unique_keys AFTER DELETE ON service_instances").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("DELETE FROM unique_keys WHERE constraint_name = \\$1").WithArgs(constraint.Name).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO unique_keys (.+) ON CONFLICT DO NOTHING").WithArgs(constraint.Name).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec("DELETE FROM unique_key_exemptions WHERE constraint_name = \\$1").WithArgs(constraint.Name).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO unique_key_exemptions (.+) FROM service_instances t WHERE TRUE AND t.\"platform_id\" = 'service-manager'\\s+AND NOT EXISTS").
+			WithArgs(constraint.Name).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+
+	Describe("DeclareUniqueConstraints", func() {
+		It("creates the trigger deleting the unique keys and builds the keys of the existing entities", func() {
+			expectDeclared()
+			Expect(s.DeclareUniqueConstraints(context.Background(), constraint)).To(Succeed())
+			Expect(s.uniqueConstraints.of(types.ServiceInstanceType)).To(HaveLen(1))
+		})
+
+		It("fails when a field of the constraint does not exist", func() {
+			constraint.Fields = []string{"unknown"}
+			err := s.DeclareUniqueConstraints(context.Background(), constraint)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("field unknown"))
+		})
+	})
+
+	Describe("insertUniqueKeys", func() {
+		BeforeEach(func() {
+			expectDeclared()
+			Expect(s.DeclareUniqueConstraints(context.Background(), constraint)).To(Succeed())
+		})
+
+		It("returns a unique constraint violation when the key exists", func() {
+			mock.ExpectExec("INSERT INTO unique_keys").WithArgs(constraint.Name, "instance-id").
+				WillReturnError(&pq.Error{Code: "23505"})
+			err := s.insertUniqueKeys(context.Background(), types.ServiceInstanceType, "instance-id")
+			Expect(err).To(Equal(&util.ErrUniqueConstraintViolation{
+				Constraint:  constraint.Name,
+				Description: constraint.Description,
+			}))
+		})
+
+		It("does not insert keys for types without constraints", func() {
+			Expect(s.insertUniqueKeys(context.Background(), types.ServiceBindingType, "binding-id")).To(Succeed())
+		})
+	})
+
+	Describe("updateUniqueKeys", func() {
+		BeforeEach(func() {
+			expectDeclared()
+			Expect(s.DeclareUniqueConstraints(context.Background(), constraint)).To(Succeed())
+		})
+
+		expectKeyDeleted := func(exempted bool) {
+			mock.ExpectExec("DELETE FROM unique_keys WHERE constraint_name = \\$1 AND entity_id = \\$2").WithArgs(constraint.Name, "instance-id").
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM unique_key_exemptions WHERE constraint_name = \\$1 AND entity_id = \\$2\\)").
+				WithArgs(constraint.Name, "instance-id").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(exempted))
+		}
+
+		It("replaces the key of the entity", func() {
+			expectKeyDeleted(false)
+			mock.ExpectExec("INSERT INTO unique_keys (.+) t.id = \\$2$").WithArgs(constraint.Name, "instance-id").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			Expect(s.updateUniqueKeys(context.Background(), types.ServiceInstanceType, "instance-id")).To(Succeed())
+		})
+
+		It("returns a unique constraint violation when an entity which was not exempted had no key", func() {
+			expectKeyDeleted(false)
+			mock.ExpectExec("INSERT INTO unique_keys (.+) t.id = \\$2$").WithArgs(constraint.Name, "instance-id").
+				WillReturnError(&pq.Error{Code: "23505"})
+			err := s.updateUniqueKeys(context.Background(), types.ServiceInstanceType, "instance-id")
+			Expect(err).To(Equal(&util.ErrUniqueConstraintViolation{
+				Constraint:  constraint.Name,
+				Description: constraint.Description,
+			}))
+		})
+
+		It("ignores conflicts for exempted entities", func() {
+			expectKeyDeleted(true)
+			mock.ExpectExec("INSERT INTO unique_keys (.+) ON CONFLICT DO NOTHING").WithArgs(constraint.Name, "instance-id").
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			Expect(s.updateUniqueKeys(context.Background(), types.ServiceInstanceType, "instance-id")).To(Succeed())
+		})
+
+		It("removes the exemption once the exempted entity gets a key", func() {
+			expectKeyDeleted(true)
+			mock.ExpectExec("INSERT INTO unique_keys (.+) ON CONFLICT DO NOTHING").WithArgs(constraint.Name, "instance-id").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec("DELETE FROM unique_key_exemptions WHERE constraint_name = \\$1 AND entity_id = \\$2").WithArgs(constraint.Name, "instance-id").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			Expect(s.updateUniqueKeys(context.Background(), types.ServiceInstanceType, "instance-id")).To(Succeed())
+		})
+	})
+})
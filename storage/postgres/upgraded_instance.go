@@ -0,0 +1,100 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	sqlxtypes "github.com/jmoiron/sqlx/types"
+
+	"github.com/Peripli/service-manager/pkg/types"
+)
+
+// UpgradedInstanceTable stores the upgrades of the single service instances of bulk upgrades
+const UpgradedInstanceTable = "service_instance_upgrade_instances"
+
+// UpgradedInstance entity stores the upgrade of a single service instance within a bulk upgrade
+type UpgradedInstance struct {
+	UpgradeID       string                 `db:"service_instance_upgrade_id"`
+	Position        int                    `db:"position"`
+	InstanceID      string                 `db:"instance_id"`
+	Version         sql.NullString         `db:"version"`
+	MaintenanceInfo sqlxtypes.NullJSONText `db:"maintenance_info"`
+	OperationID     sql.NullString         `db:"operation_id"`
+	State           sql.NullString         `db:"state"`
+	Error           sql.NullString         `db:"error"`
+}
+
+// AddUpgradedInstances implements storage.UpgradedInstanceStore
+func (ps *Storage) AddUpgradedInstances(ctx context.Context, upgradeID string, instances []*types.UpgradedInstance) error {
+	ps.checkOpen()
+	if len(instances) == 0 {
+		return nil
+	}
+
+	values := make([]string, 0, len(instances))
+	args := make([]interface{}, 0, 8*len(instances))
+	for i, instance := range instances {
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			len(args)+1, len(args)+2, len(args)+3, len(args)+4, len(args)+5, len(args)+6, len(args)+7, len(args)+8))
+		args = append(args, upgradeID, i, instance.InstanceID, toNullString(instance.Version), getNullJSONText(instance.MaintenanceInfo),
+			toNullString(instance.OperationID), toNullString(string(instance.State)), toNullString(instance.Error))
+	}
+	statement := fmt.Sprintf(`INSERT INTO %s
+		(service_instance_upgrade_id, position, instance_id, version, maintenance_info, operation_id, state, error)
+		VALUES %s`, UpgradedInstanceTable, strings.Join(values, ", "))
+	_, err := ps.pgDB.ExecContext(ctx, statement, args...)
+	return err
+}
+
+// ListUpgradedInstances implements storage.UpgradedInstanceStore
+func (ps *Storage) ListUpgradedInstances(ctx context.Context, upgradeID string) ([]*types.UpgradedInstance, error) {
+	ps.checkOpen()
+	var entities []UpgradedInstance
+	statement := fmt.Sprintf("SELECT * FROM %s WHERE service_instance_upgrade_id = $1 ORDER BY position", UpgradedInstanceTable)
+	if err := ps.pgDB.SelectContext(ctx, &entities, statement, upgradeID); err != nil {
+		return nil, err
+	}
+
+	instances := make([]*types.UpgradedInstance, 0, len(entities))
+	for _, entity := range entities {
+		instances = append(instances, &types.UpgradedInstance{
+			InstanceID:      entity.InstanceID,
+			Version:         entity.Version.String,
+			MaintenanceInfo: getJSONRawMessage(entity.MaintenanceInfo.JSONText),
+			OperationID:     entity.OperationID.String,
+			State:           types.OperationState(entity.State.String),
+			Error:           entity.Error.String,
+		})
+	}
+	return instances, nil
+}
+
+// UpdateUpgradedInstance implements storage.UpgradedInstanceStore. Only the row of the instance is updated, so that
+// the instances of a bulk upgrade are upgraded concurrently without locking the bulk upgrade.
+func (ps *Storage) UpdateUpgradedInstance(ctx context.Context, upgradeID string, instance *types.UpgradedInstance) error {
+	ps.checkOpen()
+	statement := fmt.Sprintf("UPDATE %s SET operation_id = $1, state = $2, error = $3 WHERE service_instance_upgrade_id = $4 AND instance_id = $5", UpgradedInstanceTable)
+	result, err := ps.pgDB.ExecContext(ctx, statement, toNullString(instance.OperationID), toNullString(string(instance.State)), toNullString(instance.Error), upgradeID, instance.InstanceID)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(ctx, result)
+}
@@ -23,6 +23,11 @@ type FakeNotificationQueue struct {
 	closeMutex       sync.RWMutex
 	closeArgsForCall []struct {
 	}
+	CloseWithErrorStub        func(error)
+	closeWithErrorMutex       sync.RWMutex
+	closeWithErrorArgsForCall []struct {
+		arg1 error
+	}
 	EnqueueStub        func(*types.Notification) error
 	enqueueMutex       sync.RWMutex
 	enqueueArgsForCall []struct {
@@ -34,6 +39,16 @@ type FakeNotificationQueue struct {
 	enqueueReturnsOnCall map[int]struct {
 		result1 error
 	}
+	ErrStub        func() error
+	errMutex       sync.RWMutex
+	errArgsForCall []struct {
+	}
+	errReturns struct {
+		result1 error
+	}
+	errReturnsOnCall map[int]struct {
+		result1 error
+	}
 	IDStub        func() string
 	iDMutex       sync.RWMutex
 	iDArgsForCall []struct {
@@ -123,6 +138,37 @@ func (fake *FakeNotificationQueue) CloseCalls(stub func()) {
 	fake.CloseStub = stub
 }
 
+func (fake *FakeNotificationQueue) CloseWithError(arg1 error) {
+	fake.closeWithErrorMutex.Lock()
+	fake.closeWithErrorArgsForCall = append(fake.closeWithErrorArgsForCall, struct {
+		arg1 error
+	}{arg1})
+	fake.recordInvocation("CloseWithError", []interface{}{arg1})
+	fake.closeWithErrorMutex.Unlock()
+	if fake.CloseWithErrorStub != nil {
+		fake.CloseWithErrorStub(arg1)
+	}
+}
+
+func (fake *FakeNotificationQueue) CloseWithErrorCallCount() int {
+	fake.closeWithErrorMutex.RLock()
+	defer fake.closeWithErrorMutex.RUnlock()
+	return len(fake.closeWithErrorArgsForCall)
+}
+
+func (fake *FakeNotificationQueue) CloseWithErrorCalls(stub func(error)) {
+	fake.closeWithErrorMutex.Lock()
+	defer fake.closeWithErrorMutex.Unlock()
+	fake.CloseWithErrorStub = stub
+}
+
+func (fake *FakeNotificationQueue) CloseWithErrorArgsForCall(i int) error {
+	fake.closeWithErrorMutex.RLock()
+	defer fake.closeWithErrorMutex.RUnlock()
+	argsForCall := fake.closeWithErrorArgsForCall[i]
+	return argsForCall.arg1
+}
+
 func (fake *FakeNotificationQueue) Enqueue(arg1 *types.Notification) error {
 	fake.enqueueMutex.Lock()
 	ret, specificReturn := fake.enqueueReturnsOnCall[len(fake.enqueueArgsForCall)]
@@ -183,6 +229,58 @@ func (fake *FakeNotificationQueue) EnqueueReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeNotificationQueue) Err() error {
+	fake.errMutex.Lock()
+	ret, specificReturn := fake.errReturnsOnCall[len(fake.errArgsForCall)]
+	fake.errArgsForCall = append(fake.errArgsForCall, struct {
+	}{})
+	fake.recordInvocation("Err", []interface{}{})
+	fake.errMutex.Unlock()
+	if fake.ErrStub != nil {
+		return fake.ErrStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.errReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeNotificationQueue) ErrCallCount() int {
+	fake.errMutex.RLock()
+	defer fake.errMutex.RUnlock()
+	return len(fake.errArgsForCall)
+}
+
+func (fake *FakeNotificationQueue) ErrCalls(stub func() error) {
+	fake.errMutex.Lock()
+	defer fake.errMutex.Unlock()
+	fake.ErrStub = stub
+}
+
+func (fake *FakeNotificationQueue) ErrReturns(result1 error) {
+	fake.errMutex.Lock()
+	defer fake.errMutex.Unlock()
+	fake.ErrStub = nil
+	fake.errReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeNotificationQueue) ErrReturnsOnCall(i int, result1 error) {
+	fake.errMutex.Lock()
+	defer fake.errMutex.Unlock()
+	fake.ErrStub = nil
+	if fake.errReturnsOnCall == nil {
+		fake.errReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.errReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeNotificationQueue) ID() string {
 	fake.iDMutex.Lock()
 	ret, specificReturn := fake.iDReturnsOnCall[len(fake.iDArgsForCall)]
@@ -242,8 +340,12 @@ func (fake *FakeNotificationQueue) Invocations() map[string][][]interface{} {
 	defer fake.channelMutex.RUnlock()
 	fake.closeMutex.RLock()
 	defer fake.closeMutex.RUnlock()
+	fake.closeWithErrorMutex.RLock()
+	defer fake.closeWithErrorMutex.RUnlock()
 	fake.enqueueMutex.RLock()
 	defer fake.enqueueMutex.RUnlock()
+	fake.errMutex.RLock()
+	defer fake.errMutex.RUnlock()
 	fake.iDMutex.RLock()
 	defer fake.iDMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
@@ -0,0 +1,35 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+	"database/sql"
+)
+
+type txOptionsKey struct{}
+
+// ContextWithTxOptions returns a context whose transactions are started with the options, e.g. with another isolation level
+func ContextWithTxOptions(ctx context.Context, options *sql.TxOptions) context.Context {
+	return context.WithValue(ctx, txOptionsKey{}, options)
+}
+
+// TxOptionsFromContext returns the transaction options set in the context or nil for the default options
+func TxOptionsFromContext(ctx context.Context) *sql.TxOptions {
+	options, _ := ctx.Value(txOptionsKey{}).(*sql.TxOptions)
+	return options
+}
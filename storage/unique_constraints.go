@@ -0,0 +1,142 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+)
+
+// UniqueConstraint declares that the objects of a type must have unique values of a set of fields. The uniqueness
+// can be scoped by the value of a label, e.g. a name unique per tenant, and restricted to the objects whose fields
+// have specific values, e.g. only the instances of a platform.
+type UniqueConstraint struct {
+	// Name identifies the constraint
+	Name string
+	// ObjectType is the type of the objects to which the constraint applies
+	ObjectType types.ObjectType
+	// Fields are the names of the fields whose values must be unique
+	Fields []string
+	// ScopeLabel is the key of the label whose value scopes the uniqueness. Optional.
+	ScopeLabel string
+	// Conditions restrict the constraint to the objects whose fields have the specified values. Optional.
+	Conditions map[string]string
+	// Description is returned to the client when the constraint is violated
+	Description string
+}
+
+// UniqueConstraintEnforcer enforces the declared unique constraints when objects are created or updated
+type UniqueConstraintEnforcer interface {
+	// DeclareUniqueConstraints declares unique constraints which are enforced from then on
+	DeclareUniqueConstraints(ctx context.Context, constraints ...UniqueConstraint) error
+}
+
+// Validate validates the unique constraint
+func (c *UniqueConstraint) Validate() error {
+	if len(c.Name) == 0 {
+		return fmt.Errorf("unique constraint name missing")
+	}
+	if len(c.ObjectType) == 0 {
+		return fmt.Errorf("object type of unique constraint %s missing", c.Name)
+	}
+	if len(c.Fields) == 0 {
+		return fmt.Errorf("fields of unique constraint %s missing", c.Name)
+	}
+	if len(c.Description) == 0 {
+		return fmt.Errorf("description of unique constraint %s missing", c.Name)
+	}
+	return nil
+}
+
+// AppliesTo returns true if the object matches the conditions of the constraint
+func (c *UniqueConstraint) AppliesTo(obj types.Object) (bool, error) {
+	fields, err := objectFields(obj)
+	if err != nil {
+		return false, err
+	}
+	for field, value := range c.Conditions {
+		if fmt.Sprint(fields[field]) != value {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// ConflictCriteria returns the criteria matching the objects which have the same values as the specified object
+// for the fields of the constraint and the same value of the scope label in the specified labels
+func (c *UniqueConstraint) ConflictCriteria(obj types.Object, labels types.Labels) ([]query.Criterion, error) {
+	fields, err := objectFields(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	criteria := make([]query.Criterion, 0, len(c.Fields)+len(c.Conditions)+2)
+	for _, field := range c.Fields {
+		value, found := fields[field]
+		if !found {
+			return nil, fmt.Errorf("field %s of unique constraint %s not found in %s", field, c.Name, c.ObjectType)
+		}
+		criteria = append(criteria, query.ByField(query.EqualsOperator, field, fmt.Sprint(value)))
+	}
+	// the conditions are sorted, so that the criteria of the same object are equal
+	conditionFields := make([]string, 0, len(c.Conditions))
+	for field := range c.Conditions {
+		conditionFields = append(conditionFields, field)
+	}
+	sort.Strings(conditionFields)
+	for _, field := range conditionFields {
+		criteria = append(criteria, query.ByField(query.EqualsOperator, field, c.Conditions[field]))
+	}
+	if len(c.ScopeLabel) != 0 {
+		if scope := labels[c.ScopeLabel]; len(scope) != 0 {
+			criteria = append(criteria, query.ByLabel(query.EqualsOperator, c.ScopeLabel, minValue(scope)))
+		}
+	}
+	if len(obj.GetID()) != 0 {
+		criteria = append(criteria, query.ByField(query.NotEqualsOperator, "id", obj.GetID()))
+	}
+	return criteria, nil
+}
+
+// minValue returns the smallest of the values of a label in byte order, which scopes an object with multiple values
+// of the scope label like the unique keys in the storage
+func minValue(values []string) string {
+	result := values[0]
+	for _, value := range values[1:] {
+		if value < result {
+			result = value
+		}
+	}
+	return result
+}
+
+func objectFields(obj types.Object) (map[string]interface{}, error) {
+	bytes, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(bytes, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
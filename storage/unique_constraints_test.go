@@ -0,0 +1,116 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package storage_test
+
+import (
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/storage"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UniqueConstraint", func() {
+	var constraint storage.UniqueConstraint
+	var instance *types.ServiceInstance
+
+	BeforeEach(func() {
+		constraint = storage.UniqueConstraint{
+			Name:        "service_instance_name_per_tenant",
+			ObjectType:  types.ServiceInstanceType,
+			Fields:      []string{"name"},
+			ScopeLabel:  "tenant",
+			Conditions:  map[string]string{"platform_id": types.SMPlatform},
+			Description: "instance with same name exists for the current tenant",
+		}
+		instance = &types.ServiceInstance{
+			Base: types.Base{
+				ID: "instance-id",
+			},
+			Name:       "instance",
+			PlatformID: types.SMPlatform,
+		}
+	})
+
+	Describe("Validate", func() {
+		It("succeeds for a complete constraint", func() {
+			Expect(constraint.Validate()).To(Succeed())
+		})
+
+		It("fails when the fields are missing", func() {
+			constraint.Fields = nil
+			Expect(constraint.Validate()).ToNot(Succeed())
+		})
+
+		It("fails when the description is missing", func() {
+			constraint.Description = ""
+			Expect(constraint.Validate()).ToNot(Succeed())
+		})
+	})
+
+	Describe("AppliesTo", func() {
+		It("returns true when the object matches the conditions", func() {
+			Expect(constraint.AppliesTo(instance)).To(BeTrue())
+		})
+
+		It("returns false when the object does not match the conditions", func() {
+			instance.PlatformID = "cf-platform"
+			Expect(constraint.AppliesTo(instance)).To(BeFalse())
+		})
+	})
+
+	Describe("ConflictCriteria", func() {
+		It("matches the other objects with the same fields in the same scope", func() {
+			criteria, err := constraint.ConflictCriteria(instance, types.Labels{"tenant": {"tenant-id"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(criteria).To(ConsistOf(
+				query.ByField(query.EqualsOperator, "name", "instance"),
+				query.ByField(query.EqualsOperator, "platform_id", types.SMPlatform),
+				query.ByLabel(query.EqualsOperator, "tenant", "tenant-id"),
+				query.ByField(query.NotEqualsOperator, "id", "instance-id"),
+			))
+		})
+
+		It("is scoped by the smallest value of the scope label", func() {
+			criteria, err := constraint.ConflictCriteria(instance, types.Labels{"tenant": {"tenant-b", "tenant-a"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(criteria).To(ContainElement(query.ByLabel(query.EqualsOperator, "tenant", "tenant-a")))
+		})
+
+		It("returns the same criteria for the same object regardless of the order of the conditions", func() {
+			constraint.Conditions = map[string]string{"platform_id": types.SMPlatform, "name": "instance", "id": "instance-id"}
+			criteria, err := constraint.ConflictCriteria(instance, types.Labels{})
+			Expect(err).ToNot(HaveOccurred())
+			for i := 0; i < 10; i++ {
+				Expect(constraint.ConflictCriteria(instance, types.Labels{})).To(Equal(criteria))
+			}
+		})
+
+		It("is not scoped when the object has no scope label", func() {
+			criteria, err := constraint.ConflictCriteria(instance, types.Labels{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(criteria).To(HaveLen(3))
+		})
+
+		It("fails when a field does not exist", func() {
+			constraint.Fields = []string{"unknown"}
+			_, err := constraint.ConflictCriteria(instance, types.Labels{})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -422,6 +422,77 @@ var _ = test.DescribeTestsFor(test.TestCase{
 					})
 				})
 
+				Context("when the broker catalog violates the OSB API", func() {
+					Context("when a service has plans with the same id", func() {
+						BeforeEach(func() {
+							plan := gjson.Get(string(brokerServer.Catalog), "services.0.plans.0").String()
+							duplicatePlan, err := sjson.Set(plan, "name", "duplicate-plan")
+							Expect(err).ToNot(HaveOccurred())
+							catalog, err := sjson.SetRaw(string(brokerServer.Catalog), "services.0.plans.-1", duplicatePlan)
+							Expect(err).ToNot(HaveOccurred())
+							brokerServer.Catalog = common.SBCatalog(catalog)
+						})
+
+						It("returns 400", func() {
+							ctx.SMWithOAuth.POST(web.ServiceBrokersURL).
+								WithJSON(postBrokerRequestWithNoLabels).
+								Expect().Status(http.StatusBadRequest).
+								JSON().Object().Value("description").String().Contains("services[0].plans[4].id")
+						})
+					})
+
+					Context("when a plan has an invalid parameters schema", func() {
+						BeforeEach(func() {
+							catalog, err := sjson.SetRaw(string(brokerServer.Catalog), "services.0.plans.0.schemas",
+								`{"service_instance": {"create": {"parameters": {"type": "unknown"}}}}`)
+							Expect(err).ToNot(HaveOccurred())
+							brokerServer.Catalog = common.SBCatalog(catalog)
+						})
+
+						It("returns 400", func() {
+							ctx.SMWithOAuth.POST(web.ServiceBrokersURL).
+								WithJSON(postBrokerRequestWithNoLabels).
+								Expect().Status(http.StatusBadRequest).
+								JSON().Object().Value("description").String().Contains("schemas.service_instance.create.parameters")
+						})
+					})
+
+					Context("when a plan has maintenance info without semantic version", func() {
+						BeforeEach(func() {
+							catalog, err := sjson.SetRaw(string(brokerServer.Catalog), "services.0.plans.0.maintenance_info", `{"version": "latest"}`)
+							Expect(err).ToNot(HaveOccurred())
+							brokerServer.Catalog = common.SBCatalog(catalog)
+						})
+
+						It("returns 400", func() {
+							ctx.SMWithOAuth.POST(web.ServiceBrokersURL).
+								WithJSON(postBrokerRequestWithNoLabels).
+								Expect().Status(http.StatusBadRequest).
+								JSON().Object().Value("description").String().Contains("is not a semantic version")
+						})
+					})
+
+					Context("when a service has no description", func() {
+						BeforeEach(func() {
+							catalog, err := sjson.Delete(string(brokerServer.Catalog), "services.0.description")
+							Expect(err).ToNot(HaveOccurred())
+							brokerServer.Catalog = common.SBCatalog(catalog)
+						})
+
+						It("returns 201 with the catalog warnings", func() {
+							warnings := ctx.SMWithOAuth.POST(web.ServiceBrokersURL).
+								WithJSON(postBrokerRequestWithNoLabels).
+								Expect().Status(http.StatusCreated).
+								JSON().Object().Value("catalog_warnings").Array()
+							warnings.Length().Equal(1)
+							warnings.First().Object().ContainsMap(map[string]interface{}{
+								"severity": "warning",
+								"path":     "services[0].description",
+							})
+						})
+					})
+				})
+
 				Context("when fetching catalog fails", func() {
 					BeforeEach(func() {
 						brokerServer.CatalogHandler = func(w http.ResponseWriter, req *http.Request) {
@@ -0,0 +1,117 @@
+/*
+ * Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package changes_test
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/storage"
+	"github.com/Peripli/service-manager/test/common"
+	"github.com/gofrs/uuid"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestChanges(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Changes Tests Suite")
+}
+
+var _ = Describe("Changes", func() {
+	var ctx *common.TestContext
+
+	BeforeEach(func() {
+		ctx = common.NewTestContextBuilder().Build()
+	})
+
+	AfterEach(func() {
+		ctx.Cleanup()
+	})
+
+	newPlatform := func() *types.Platform {
+		id, err := uuid.NewV4()
+		Expect(err).ToNot(HaveOccurred())
+		return &types.Platform{
+			Base: types.Base{
+				ID:    id.String(),
+				Ready: true,
+			},
+			Name: "platform-" + id.String(),
+			Type: "kubernetes",
+		}
+	}
+
+	// listPlatformChanges returns the ids of the platforms changed since the revision and the revision to continue from
+	listPlatformChanges := func(since int64) ([]string, int64) {
+		var ids []string
+		for {
+			response := ctx.SMWithOAuth.GET(web.ChangesURL).
+				WithQuery("since", strconv.FormatInt(since, 10)).
+				WithQuery("resource", types.PlatformType.String()).
+				Expect().Status(http.StatusOK).JSON().Object()
+			changes := response.Value("changes").Array()
+			if len(changes.Iter()) == 0 {
+				return ids, since
+			}
+			for _, change := range changes.Iter() {
+				ids = append(ids, change.Object().Value("resource_id").String().Raw())
+			}
+			since = int64(response.Value("revision").Number().Raw())
+		}
+	}
+
+	Context("when a transaction commits after a later transaction", func() {
+		It("returns the changes of the earlier transaction after the changes of the later one", func() {
+			_, since := listPlatformChanges(0)
+
+			earlierPlatform := newPlatform()
+			started := make(chan struct{})
+			release := make(chan struct{})
+			committed := make(chan error, 1)
+			go func() {
+				committed <- ctx.SMRepository.InTransaction(context.Background(), func(txCtx context.Context, repository storage.Repository) error {
+					if _, err := repository.Create(txCtx, earlierPlatform); err != nil {
+						return err
+					}
+					close(started)
+					<-release
+					return nil
+				})
+			}()
+			Eventually(started).Should(BeClosed())
+
+			laterPlatform := newPlatform()
+			_, err := ctx.SMRepository.Create(context.Background(), laterPlatform)
+			Expect(err).ToNot(HaveOccurred())
+
+			ids, revision := listPlatformChanges(since)
+			Expect(ids).To(ConsistOf(laterPlatform.ID))
+
+			close(release)
+			Eventually(committed).Should(Receive(BeNil()))
+
+			ids, _ = listPlatformChanges(revision)
+			Expect(ids).To(ConsistOf(earlierPlatform.ID))
+		})
+	})
+})
@@ -91,6 +91,44 @@ var testPaidPlan = `
     }
 `
 
+var testParametersSchemas = `
+{
+  "service_instance": {
+    "create": {
+      "parameters": {
+        "$schema": "http://json-schema.org/draft-04/schema#",
+        "type": "object",
+        "required": ["region"],
+        "properties": {
+          "region": {"type": "string"},
+          "size": {"type": "integer"}
+        }
+      }
+    },
+    "update": {
+      "parameters": {
+        "$schema": "http://json-schema.org/draft-04/schema#",
+        "type": "object",
+        "properties": {
+          "size": {"type": "integer"}
+        }
+      }
+    }
+  },
+  "service_binding": {
+    "create": {
+      "parameters": {
+        "$schema": "http://json-schema.org/draft-04/schema#",
+        "type": "object",
+        "properties": {
+          "role": {"type": "string", "enum": ["reader", "writer"]}
+        }
+      }
+    }
+  }
+}
+`
+
 var testService = `
 {
     "name": "another-fake-service-%[1]s",
@@ -248,6 +286,16 @@ func GeneratePaidTestPlan() string {
 	return GenerateTestPlanFromTemplate(UUID.String(), testPaidPlan)
 }
 
+// GenerateTestPlanWithParametersSchemas returns a paid plan whose schemas require a string region and an integer size
+// on provision, an integer size on update and a reader or writer role on bind
+func GenerateTestPlanWithParametersSchemas() string {
+	plan, err := sjson.SetRaw(GeneratePaidTestPlan(), "schemas", testParametersSchemas)
+	if err != nil {
+		panic(err)
+	}
+	return plan
+}
+
 func GenerateTestPlanFromTemplate(id, planTemplate string) string {
 	if len(id) == 0 {
 		UUID, err := uuid.NewV4()
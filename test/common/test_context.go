@@ -416,6 +416,8 @@ func newSMServer(smEnv env.Environment, wg *sync.WaitGroup, fs []func(ctx contex
 	if err != nil {
 		panic(err)
 	}
+	smb.EncryptionKeys.Start(ctx, wg)
+	smb.CredentialsReencrypter.Start(ctx, wg)
 
 	testServer := httptest.NewUnstartedServer(serviceManager.Server.Router)
 	if listener != nil {
@@ -0,0 +1,214 @@
+/*
+ *    Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package label_definition_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Peripli/service-manager/pkg/query"
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/test"
+	"github.com/Peripli/service-manager/test/common"
+	"github.com/gofrs/uuid"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// TestLabelDefinitions tests for label definitions API
+func TestLabelDefinitions(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Label Definitions API Tests Suite")
+}
+
+var _ = test.DescribeTestsFor(test.TestCase{
+	API: web.LabelDefinitionsURL,
+	SupportedOps: []test.Op{
+		test.Get, test.List, test.Delete, test.DeleteList, test.Patch,
+	},
+	SupportsAsyncOperations:                false,
+	DisableTenantResources:                 true,
+	ResourceBlueprint:                      blueprint(true),
+	ResourceWithoutNullableFieldsBlueprint: blueprint(false),
+	ResourcePropertiesToIgnore:             []string{"allowed_values", "pattern"},
+	PatchResource:                          test.APIResourcePatch,
+	AdditionalTests: func(ctx *common.TestContext, t *test.TestCase) {
+		Context("non-generic tests", func() {
+			Describe("POST", func() {
+				Context("with unsupported resource type", func() {
+					It("returns 400", func() {
+						definition := generateLabelDefinition(string(types.NotificationType))
+						ctx.SMWithOAuth.POST(web.LabelDefinitionsURL).WithJSON(definition).
+							Expect().Status(http.StatusBadRequest)
+					})
+				})
+
+				Context("with invalid pattern", func() {
+					It("returns 400", func() {
+						definition := generateLabelDefinition(string(types.PlatformType))
+						definition["pattern"] = "[a-z"
+						ctx.SMWithOAuth.POST(web.LabelDefinitionsURL).WithJSON(definition).
+							Expect().Status(http.StatusBadRequest).JSON().Object().Value("description").String().Contains("pattern")
+					})
+				})
+
+				Context("with already defined key", func() {
+					It("returns 409", func() {
+						definition := generateLabelDefinition(string(types.PlatformType))
+						ctx.SMWithOAuth.POST(web.LabelDefinitionsURL).WithJSON(definition).
+							Expect().Status(http.StatusCreated)
+						defer ctx.SMWithOAuth.DELETE(web.LabelDefinitionsURL + "/" + definition["id"].(string)).Expect()
+
+						duplicate := generateLabelDefinition(string(types.PlatformType))
+						duplicate["key"] = definition["key"]
+						ctx.SMWithOAuth.POST(web.LabelDefinitionsURL).WithJSON(duplicate).
+							Expect().Status(http.StatusConflict)
+					})
+				})
+			})
+
+			Describe("enforcement", func() {
+				var definition common.Object
+
+				BeforeEach(func() {
+					definition = generateLabelDefinition(string(types.PlatformType))
+					definition["key"] = "env"
+					definition["allowed_values"] = []string{"dev", "prod"}
+					definition["cardinality"] = types.SingleValued
+					definition["required"] = true
+					ctx.SMWithOAuth.POST(web.LabelDefinitionsURL).WithJSON(definition).
+						Expect().Status(http.StatusCreated)
+				})
+
+				AfterEach(func() {
+					ctx.SMWithOAuth.DELETE(web.LabelDefinitionsURL + "/" + definition["id"].(string)).Expect()
+					ctx.CleanupPlatforms()
+				})
+
+				Context("on create", func() {
+					It("accepts labels which satisfy the definitions", func() {
+						platform := common.GenerateRandomPlatform()
+						platform["labels"] = types.Labels{"env": {"prod"}}
+						ctx.SMWithOAuth.POST(web.PlatformsURL).WithJSON(platform).
+							Expect().Status(http.StatusCreated)
+					})
+
+					It("rejects missing required labels", func() {
+						platform := common.GenerateRandomPlatform()
+						ctx.SMWithOAuth.POST(web.PlatformsURL).WithJSON(platform).
+							Expect().Status(http.StatusBadRequest).
+							JSON().Object().Value("description").String().Contains("label env is required")
+					})
+
+					It("rejects values which are not allowed", func() {
+						platform := common.GenerateRandomPlatform()
+						platform["labels"] = types.Labels{"env": {"Prod"}}
+						ctx.SMWithOAuth.POST(web.PlatformsURL).WithJSON(platform).
+							Expect().Status(http.StatusBadRequest).
+							JSON().Object().Value("description").String().Contains("Allowed values are: dev, prod")
+					})
+
+					It("rejects multiple values of single valued labels", func() {
+						platform := common.GenerateRandomPlatform()
+						platform["labels"] = types.Labels{"env": {"dev", "prod"}}
+						ctx.SMWithOAuth.POST(web.PlatformsURL).WithJSON(platform).
+							Expect().Status(http.StatusBadRequest)
+					})
+
+					It("rejects labels which are not defined", func() {
+						platform := common.GenerateRandomPlatform()
+						platform["labels"] = types.Labels{"env": {"dev"}, "environment": {"dev"}}
+						ctx.SMWithOAuth.POST(web.PlatformsURL).WithJSON(platform).
+							Expect().Status(http.StatusBadRequest).
+							JSON().Object().Value("description").String().Contains("label environment is not defined")
+					})
+				})
+
+				Context("on label changes", func() {
+					var platformID string
+
+					BeforeEach(func() {
+						platform := common.GenerateRandomPlatform()
+						platform["labels"] = types.Labels{"env": {"dev"}}
+						platformID = ctx.SMWithOAuth.POST(web.PlatformsURL).WithJSON(platform).
+							Expect().Status(http.StatusCreated).JSON().Object().Value("id").String().Raw()
+					})
+
+					It("accepts changes which satisfy the definitions", func() {
+						ctx.SMWithOAuth.PATCH(web.PlatformsURL + "/" + platformID).WithJSON(common.Object{
+							"labels": []*query.LabelChange{
+								{Operation: query.RemoveLabelValuesOperation, Key: "env", Values: []string{"dev"}},
+								{Operation: query.AddLabelValuesOperation, Key: "env", Values: []string{"prod"}},
+							},
+						}).Expect().Status(http.StatusOK)
+					})
+
+					It("rejects removal of required labels", func() {
+						ctx.SMWithOAuth.PATCH(web.PlatformsURL + "/" + platformID).WithJSON(common.Object{
+							"labels": []*query.LabelChange{
+								{Operation: query.RemoveLabelOperation, Key: "env"},
+							},
+						}).Expect().Status(http.StatusBadRequest)
+					})
+
+					It("rejects values which are not allowed", func() {
+						ctx.SMWithOAuth.PATCH(web.PlatformsURL + "/" + platformID).WithJSON(common.Object{
+							"labels": []*query.LabelChange{
+								{Operation: query.AddLabelValuesOperation, Key: "env", Values: []string{"production"}},
+							},
+						}).Expect().Status(http.StatusBadRequest)
+					})
+
+					It("allows changes of other fields", func() {
+						ctx.SMWithOAuth.PATCH(web.PlatformsURL + "/" + platformID).WithJSON(common.Object{
+							"description": "new description",
+						}).Expect().Status(http.StatusOK)
+					})
+				})
+			})
+		})
+	},
+})
+
+func generateLabelDefinition(resourceType string) common.Object {
+	UUID, err := uuid.NewV4()
+	if err != nil {
+		panic(err)
+	}
+	return common.Object{
+		"id":            UUID.String(),
+		"resource_type": resourceType,
+		"key":           "key-" + UUID.String(),
+	}
+}
+
+func blueprint(setNullFieldsValues bool) func(ctx *common.TestContext, auth *common.SMExpect, async bool) common.Object {
+	return func(_ *common.TestContext, auth *common.SMExpect, _ bool) common.Object {
+		definition := generateLabelDefinition(string(types.ServiceBindingType))
+		if setNullFieldsValues {
+			definition["description"] = "label definition"
+			definition["allowed_values"] = []string{"a", "b"}
+			definition["pattern"] = "^[a-z]$"
+			definition["cardinality"] = types.MultiValued
+		}
+		return auth.POST(web.LabelDefinitionsURL).WithJSON(definition).
+			Expect().
+			Status(http.StatusCreated).JSON().Object().Raw()
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/gavv/httpexpect"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
 )
 
 var _ = Describe("Bind", func() {
@@ -58,6 +59,31 @@ var _ = Describe("Bind", func() {
 		})
 	})
 
+	Context("when plan has parameters schemas", func() {
+		BeforeEach(func() {
+			brokerServer.BindingHandler = parameterizedHandler(http.StatusCreated, `{}`)
+			provisionRequestBody = buildRequestBody(service1CatalogID, plan4CatalogID)
+		})
+
+		It("returns 400 with the violated JSON pointers before calling the broker if parameters do not match the bind schema", func() {
+			resp := ctx.SMWithBasic.PUT(smBrokerURL+"/v2/service_instances/iid/service_bindings/bid").WithHeader(brokerAPIVersionHeaderKey, brokerAPIVersionHeaderValue).
+				WithJSON(provisionRequestBodyMapWith("parameters.role", "admin")()).Expect().
+				Status(http.StatusBadRequest).JSON().Object()
+			resp.ValueEqual("error", "BadRequest")
+			resp.Path("$.details[*].pointer").Array().ContainsOnly("/role")
+
+			Expect(brokerServer.BindingEndpointRequests).To(BeEmpty())
+			verifyOperationDoesNotExist("bid", "create")
+		})
+
+		It("binds if parameters match the bind schema", func() {
+			ctx.SMWithBasic.PUT(smBrokerURL+"/v2/service_instances/iid/service_bindings/bid").WithHeader(brokerAPIVersionHeaderKey, brokerAPIVersionHeaderValue).
+				WithJSON(provisionRequestBodyMapWith("parameters.role", "reader")()).Expect().Status(http.StatusCreated)
+
+			Expect(brokerServer.BindingEndpointRequests).ToNot(BeEmpty())
+		})
+	})
+
 	Context("when call contains query params", func() {
 		It("propagates them to the service broker", func() {
 			headerKey, headerValue := generateRandomQueryParam()
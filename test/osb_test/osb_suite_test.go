@@ -56,6 +56,7 @@ const (
 	plan1CatalogID              = "plan1CatalogID"
 	plan2CatalogID              = "plan2CatalogID"
 	plan3CatalogID              = "plan3CatalogID"
+	plan4CatalogID              = "plan4CatalogID"
 	service0CatalogID           = "service0CatalogID"
 	service1CatalogID           = "service1CatalogID"
 	organizationGUID            = "1113aa0-124e-4af2-1526-6bfacf61b111"
@@ -133,14 +134,16 @@ var _ = BeforeSuite(func() {
 	plan1 := common.GenerateTestPlanWithID(plan1CatalogID)
 	plan2 := common.GenerateTestPlanWithID(plan2CatalogID)
 	plan3 := common.GenerateTestPlanWithID(plan3CatalogID)
+	plan4, err := sjson.Set(common.GenerateTestPlanWithParametersSchemas(), "id", plan4CatalogID)
+	Expect(err).ToNot(HaveOccurred())
 
-	service1 := common.GenerateTestServiceWithPlansWithID(service1CatalogID, plan1, plan2, plan3)
+	service1 := common.GenerateTestServiceWithPlansWithID(service1CatalogID, plan1, plan2, plan3, plan4)
 	catalog = common.NewEmptySBCatalog()
 	catalog.AddService(service1)
 
 	var brokerObject common.Object
 	brokerID, brokerObject, brokerServer = ctx.RegisterBrokerWithCatalog(catalog)
-	plans := ctx.SMWithOAuth.ListWithQuery(web.ServicePlansURL, "fieldQuery="+fmt.Sprintf("catalog_id in ('%s','%s')", plan1CatalogID, plan2CatalogID)).Iter()
+	plans := ctx.SMWithOAuth.ListWithQuery(web.ServicePlansURL, "fieldQuery="+fmt.Sprintf("catalog_id in ('%s','%s','%s')", plan1CatalogID, plan2CatalogID, plan4CatalogID)).Iter()
 	for _, p := range plans {
 		common.RegisterVisibilityForPlanAndPlatform(ctx.SMWithOAuth, p.Object().Value("id").String().Raw(), ctx.TestPlatform.ID)
 	}
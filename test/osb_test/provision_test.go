@@ -28,6 +28,7 @@ import (
 	"github.com/gavv/httpexpect"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
 )
 
 var _ = Describe("Provision", func() {
@@ -283,6 +284,53 @@ var _ = Describe("Provision", func() {
 		})
 	})
 
+	Context("when a label of service instances is required", func() {
+		var definitionID string
+
+		BeforeEach(func() {
+			definitionID = ctx.SMWithOAuth.POST(web.LabelDefinitionsURL).WithJSON(common.Object{
+				"resource_type": string(types.ServiceInstanceType),
+				"key":           "cost_center",
+				"required":      true,
+			}).Expect().Status(http.StatusCreated).JSON().Object().Value("id").String().Raw()
+		})
+
+		AfterEach(func() {
+			ctx.SMWithOAuth.DELETE(web.LabelDefinitionsURL + "/" + definitionID).Expect()
+		})
+
+		It("does not require the label for instances of the platform", func() {
+			brokerServer.ServiceInstanceHandler = parameterizedHandler(http.StatusCreated, `{}`)
+			ctx.SMWithBasic.PUT(smBrokerURL+"/v2/service_instances/"+SID).WithHeader(brokerAPIVersionHeaderKey, brokerAPIVersionHeaderValue).
+				WithJSON(provisionRequestBodyMap()()).Expect().Status(http.StatusCreated)
+		})
+	})
+
+	Context("when plan has parameters schemas", func() {
+		BeforeEach(func() {
+			brokerServer.ServiceInstanceHandler = parameterizedHandler(http.StatusCreated, `{}`)
+			provisionRequestBody = buildRequestBody(service1CatalogID, plan4CatalogID)
+		})
+
+		It("returns 400 with the violated JSON pointers before calling the broker if parameters do not match the create schema", func() {
+			resp := ctx.SMWithBasic.PUT(smBrokerURL+"/v2/service_instances/"+SID).WithHeader(brokerAPIVersionHeaderKey, brokerAPIVersionHeaderValue).
+				WithJSON(provisionRequestBodyMapWith("parameters.size", "large")()).Expect().
+				Status(http.StatusBadRequest).JSON().Object()
+			resp.ValueEqual("error", "BadRequest")
+			resp.Path("$.details[*].pointer").Array().ContainsOnly("", "/size")
+
+			Expect(brokerServer.ServiceInstanceEndpointRequests).To(BeEmpty())
+			verifyOperationDoesNotExist(SID, "create")
+		})
+
+		It("provisions the instance if parameters match the create schema", func() {
+			ctx.SMWithBasic.PUT(smBrokerURL+"/v2/service_instances/"+SID).WithHeader(brokerAPIVersionHeaderKey, brokerAPIVersionHeaderValue).
+				WithJSON(provisionRequestBodyMapWith("parameters.region", "eu")()).Expect().Status(http.StatusCreated)
+
+			Expect(brokerServer.ServiceInstanceEndpointRequests).ToNot(BeEmpty())
+		})
+	})
+
 	Context("when call contains query params", func() {
 		It("propagates them to the service broker", func() {
 			headerKey, headerValue := generateRandomQueryParam()
@@ -2,6 +2,7 @@ package query_test
 
 import (
 	"context"
+	"net/http"
 	"testing"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/Peripli/service-manager/pkg/query"
 
 	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
 	"github.com/Peripli/service-manager/storage"
 	"github.com/gofrs/uuid"
 
@@ -95,6 +97,185 @@ var _ = Describe("Service Manager Query", func() {
 			expectNotifications(list)
 		})
 	})
+	Context("with field schemas", func() {
+		It("returns the schema of the resource", func() {
+			fields := ctx.SMWithOAuth.GET(web.PlatformsURL + web.SchemaURL).
+				Expect().
+				Status(http.StatusOK).
+				JSON().Object().Value("fields").Array()
+
+			fields.Contains(map[string]interface{}{
+				"name":      "created_at",
+				"type":      "datetime",
+				"nullable":  false,
+				"queryable": true,
+			})
+			fields.Contains(map[string]interface{}{
+				"name":      "description",
+				"type":      "string",
+				"nullable":  true,
+				"queryable": true,
+			})
+			fields.Contains(map[string]interface{}{
+				"name":      "password",
+				"type":      "string",
+				"nullable":  false,
+				"queryable": false,
+			})
+		})
+
+		It("returns 400 with the supported fields when field is unknown", func() {
+			ctx.SMWithOAuth.GET(web.PlatformsURL).
+				WithQuery("fieldQuery", "unknown eq 'value'").
+				Expect().
+				Status(http.StatusBadRequest).
+				JSON().Object().Value("description").String().Contains("Supported fields are: id, created_at")
+		})
+
+		It("returns 400 when field is not queryable", func() {
+			ctx.SMWithOAuth.GET(web.PlatformsURL).
+				WithQuery("fieldQuery", "password eq 'value'").
+				Expect().
+				Status(http.StatusBadRequest)
+		})
+
+		It("returns 400 when value does not match the type of the field", func() {
+			ctx.SMWithOAuth.GET(web.PlatformsURL).
+				WithQuery("fieldQuery", "ready eq 'yes'").
+				Expect().
+				Status(http.StatusBadRequest).
+				JSON().Object().Value("description").String().Contains("invalid value 'yes' for boolean field ready")
+		})
+
+		It("returns 400 when datetime value is malformed", func() {
+			ctx.SMWithOAuth.GET(web.PlatformsURL).
+				WithQuery("fieldQuery", "created_at gt '2020-01-01'").
+				Expect().
+				Status(http.StatusBadRequest)
+		})
+
+		It("returns 200 when values match the types of the fields", func() {
+			ctx.SMWithOAuth.GET(web.PlatformsURL).
+				WithQuery("fieldQuery", "ready eq true and created_at gt '2020-01-01T00:00:00Z'").
+				Expect().
+				Status(http.StatusOK)
+		})
+	})
+
+	Context("with full-text search", func() {
+		var platformIDs []string
+
+		createPlatform := func(name, description string, labels types.Labels) string {
+			platform := common.Object{
+				"name":        name,
+				"type":        "kubernetes",
+				"description": description,
+				"labels":      labels,
+			}
+			id := ctx.SMWithOAuth.POST(web.PlatformsURL).WithJSON(platform).
+				Expect().
+				Status(http.StatusCreated).
+				JSON().Object().Value("id").String().Raw()
+			platformIDs = append(platformIDs, id)
+			return id
+		}
+
+		searchPlatforms := func(params map[string]string) []string {
+			req := ctx.SMWithOAuth.GET(web.PlatformsURL)
+			for key, value := range params {
+				req = req.WithQuery(key, value)
+			}
+			ids := []string{}
+			items := req.Expect().Status(http.StatusOK).JSON().Object().Value("items").Array()
+			for _, item := range items.Iter() {
+				ids = append(ids, item.Object().Value("id").String().Raw())
+			}
+			return ids
+		}
+
+		BeforeEach(func() {
+			platformIDs = []string{}
+		})
+
+		AfterEach(func() {
+			for _, id := range platformIDs {
+				ctx.SMWithOAuth.DELETE(web.PlatformsURL + "/" + id).Expect().Status(http.StatusOK)
+			}
+		})
+
+		It("finds resources by name, description and label values", func() {
+			byName := createPlatform("frankfurt-cluster", "primary", nil)
+			byDescription := createPlatform("platform-two", "Cluster in Frankfurt", nil)
+			byLabel := createPlatform("platform-three", "secondary", types.Labels{"region": {"frankfurt"}})
+			createPlatform("platform-four", "unrelated", types.Labels{"region": {"amsterdam"}})
+
+			ids := searchPlatforms(map[string]string{"q": "frank"})
+			Expect(ids).To(ConsistOf(byName, byDescription, byLabel))
+		})
+
+		It("requires all words to match", func() {
+			id := createPlatform("frankfurt-cluster", "primary", nil)
+			createPlatform("frankfurt-backup", "secondary", nil)
+
+			ids := searchPlatforms(map[string]string{"q": "frankfurt primary"})
+			Expect(ids).To(ConsistOf(id))
+		})
+
+		It("orders the results by relevance", func() {
+			weakMatch := createPlatform("platform-one", "cluster in frankfurt", nil)
+			strongMatch := createPlatform("frankfurt-cluster", "primary cluster in frankfurt, backed up in frankfurt", nil)
+
+			ids := searchPlatforms(map[string]string{"q": "frankfurt"})
+			Expect(ids).To(Equal([]string{strongMatch, weakMatch}))
+		})
+
+		It("returns only the most relevant results without a token for the next page", func() {
+			createPlatform("platform-one", "cluster in frankfurt", nil)
+			strongMatch := createPlatform("frankfurt-cluster", "primary cluster in frankfurt, backed up in frankfurt", nil)
+
+			page := ctx.SMWithOAuth.GET(web.PlatformsURL).
+				WithQuery("q", "frankfurt").
+				WithQuery("max_items", 1).
+				Expect().
+				Status(http.StatusOK).
+				JSON().Object()
+			page.Value("num_items").Number().Equal(2)
+			page.Value("items").Array().Length().Equal(1)
+			page.Value("items").Array().First().Object().Value("id").Equal(strongMatch)
+			page.NotContainsKey("token")
+		})
+
+		It("returns 400 when a paging token is provided", func() {
+			ctx.SMWithOAuth.GET(web.PlatformsURL).
+				WithQuery("q", "frankfurt").
+				WithQuery("token", "MTA=").
+				Expect().
+				Status(http.StatusBadRequest)
+		})
+
+		It("combines the search with field queries", func() {
+			createPlatform("frankfurt-cluster", "primary", nil)
+			id := createPlatform("frankfurt-backup", "secondary", nil)
+
+			ids := searchPlatforms(map[string]string{"q": "frankfurt", "fieldQuery": "description eq 'secondary'"})
+			Expect(ids).To(ConsistOf(id))
+		})
+
+		It("returns 400 when the search text does not contain any words", func() {
+			ctx.SMWithOAuth.GET(web.PlatformsURL).
+				WithQuery("q", "*&!").
+				Expect().
+				Status(http.StatusBadRequest)
+		})
+
+		It("returns 400 when the resource does not support full-text search", func() {
+			ctx.SMWithOAuth.GET(web.VisibilitiesURL).
+				WithQuery("q", "frankfurt").
+				Expect().
+				Status(http.StatusBadRequest).
+				JSON().Object().Value("description").String().Contains("full-text search is not supported")
+		})
+	})
 })
 
 func expectNotifications(list types.ObjectList, ids ...string) {
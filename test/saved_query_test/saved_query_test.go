@@ -0,0 +1,140 @@
+/*
+ *    Copyright 2018 The Service Manager Authors
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package saved_query_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Peripli/service-manager/pkg/types"
+	"github.com/Peripli/service-manager/pkg/web"
+	"github.com/Peripli/service-manager/test"
+	"github.com/Peripli/service-manager/test/common"
+	"github.com/gofrs/uuid"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// TestSavedQueries tests for saved queries API
+func TestSavedQueries(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Saved Queries API Tests Suite")
+}
+
+var _ = test.DescribeTestsFor(test.TestCase{
+	API: web.SavedQueriesURL,
+	SupportedOps: []test.Op{
+		test.Get, test.List, test.Delete, test.DeleteList, test.Patch,
+	},
+	SupportsAsyncOperations:                false,
+	DisableTenantResources:                 true,
+	ResourceBlueprint:                      blueprint(true),
+	ResourceWithoutNullableFieldsBlueprint: blueprint(false),
+	ResourcePropertiesToIgnore:             []string{"field_query", "label_query"},
+	PatchResource:                          test.APIResourcePatch,
+	AdditionalTests: func(ctx *common.TestContext, t *test.TestCase) {
+		Context("non-generic tests", func() {
+			Describe("POST", func() {
+				Context("with unsupported resource type", func() {
+					It("returns 400", func() {
+						savedQuery := generateSavedQuery(string(types.NotificationType))
+						ctx.SMWithOAuth.POST(web.SavedQueriesURL).WithJSON(savedQuery).
+							Expect().Status(http.StatusBadRequest)
+					})
+				})
+
+				Context("with invalid field query", func() {
+					It("returns 400", func() {
+						savedQuery := generateSavedQuery(string(types.PlatformType))
+						savedQuery["field_query"] = "name eqq 'value'"
+						ctx.SMWithOAuth.POST(web.SavedQueriesURL).WithJSON(savedQuery).
+							Expect().Status(http.StatusBadRequest).JSON().Object().Value("description").String().Contains("field_query")
+					})
+				})
+			})
+
+			Describe("List with view", func() {
+				var platform *types.Platform
+				var savedQuery common.Object
+
+				BeforeEach(func() {
+					platform = common.RegisterPlatformInSM(common.GenerateRandomPlatform(), ctx.SMWithOAuth, nil)
+					common.RegisterPlatformInSM(common.GenerateRandomPlatform(), ctx.SMWithOAuth, nil)
+
+					savedQuery = generateSavedQuery(string(types.PlatformType))
+					savedQuery["field_query"] = fmt.Sprintf("name eq '%s'", platform.Name)
+					ctx.SMWithOAuth.POST(web.SavedQueriesURL).WithJSON(savedQuery).
+						Expect().Status(http.StatusCreated)
+				})
+
+				AfterEach(func() {
+					ctx.SMWithOAuth.DELETE(web.SavedQueriesURL + "/" + savedQuery["id"].(string)).Expect()
+					ctx.CleanupPlatforms()
+				})
+
+				It("applies the criteria of the saved query", func() {
+					platforms := ctx.SMWithOAuth.ListWithQuery(web.PlatformsURL, "view="+savedQuery["name"].(string))
+					platforms.Length().Equal(1)
+					platforms.First().Object().Value("id").Equal(platform.ID)
+				})
+
+				It("composes the criteria of the saved query with the request criteria", func() {
+					ctx.SMWithOAuth.ListWithQuery(web.PlatformsURL,
+						fmt.Sprintf("view=%s&fieldQuery=id ne '%s'", savedQuery["name"], platform.ID)).
+						Length().Equal(0)
+				})
+
+				It("returns 404 for unknown saved query", func() {
+					ctx.SMWithOAuth.GET(web.PlatformsURL).WithQuery("view", "unknown").
+						Expect().Status(http.StatusNotFound)
+				})
+
+				It("returns 404 when the saved query is for a different resource type", func() {
+					ctx.SMWithOAuth.GET(web.ServiceBrokersURL).WithQuery("view", savedQuery["name"]).
+						Expect().Status(http.StatusNotFound)
+				})
+			})
+		})
+	},
+})
+
+func generateSavedQuery(resourceType string) common.Object {
+	UUID, err := uuid.NewV4()
+	if err != nil {
+		panic(err)
+	}
+	return common.Object{
+		"id":            UUID.String(),
+		"name":          "query-" + UUID.String(),
+		"resource_type": resourceType,
+	}
+}
+
+func blueprint(setNullFieldsValues bool) func(ctx *common.TestContext, auth *common.SMExpect, async bool) common.Object {
+	return func(_ *common.TestContext, auth *common.SMExpect, _ bool) common.Object {
+		savedQuery := generateSavedQuery(string(types.ServiceInstanceType))
+		if setNullFieldsValues {
+			savedQuery["field_query"] = "name eq 'instance'"
+			savedQuery["label_query"] = "env eq 'prod'"
+		}
+		return auth.POST(web.SavedQueriesURL).WithJSON(savedQuery).
+			Expect().
+			Status(http.StatusCreated).JSON().Object().Raw()
+	}
+}
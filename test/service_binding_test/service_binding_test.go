@@ -321,6 +321,45 @@ var _ = DescribeTestsFor(TestCase{
 							})
 						})
 
+						Context("when plan has parameters schemas", func() {
+							BeforeEach(func() {
+								brokerID, brokerServer, servicePlanID = newServicePlanWithParametersSchemas(ctx)
+								EnsurePlanVisibility(ctx.SMRepository, TenantIdentifier, types.SMPlatform, servicePlanID, TenantIDValue)
+								createInstance(ctx.SMWithOAuthForTenant, false, http.StatusCreated)
+								brokerServer.ResetCallHistory()
+							})
+
+							It("returns 400 with the violated JSON pointers before calling the broker if parameters do not match the bind schema", func() {
+								byResourceType := query.ByField(query.EqualsOperator, "resource_type", string(types.ServiceBindingType))
+								operationsCount, err := ctx.SMRepository.Count(context.Background(), types.OperationType, byResourceType)
+								Expect(err).ToNot(HaveOccurred())
+								postBindingRequest["parameters"] = Object{"role": "admin"}
+
+								obj := createBinding(ctx.SMWithOAuthForTenant, testCase.async, http.StatusBadRequest).JSON().Object()
+								obj.ValueEqual("error", "BadRequest")
+								obj.Path("$.details[*].pointer").Array().ContainsOnly("/role")
+
+								Expect(brokerServer.BindingEndpointRequests).To(BeEmpty())
+								Expect(ctx.SMRepository.Count(context.Background(), types.OperationType, byResourceType)).To(Equal(operationsCount))
+							})
+
+							It("creates the binding if parameters match the bind schema", func() {
+								postBindingRequest["parameters"] = Object{"role": "reader"}
+
+								resp := createBinding(ctx.SMWithOAuthForTenant, testCase.async, testCase.expectedCreateSuccessStatusCode)
+								bindingID, _ = VerifyOperationExists(ctx, resp.Header("Location").Raw(), OperationExpectations{
+									Category:          types.CREATE,
+									State:             types.SUCCEEDED,
+									ResourceType:      types.ServiceBindingType,
+									Reschedulable:     false,
+									DeletionScheduled: false,
+								})
+
+								verifyBindingExists(ctx.SMWithOAuth, bindingID, true)
+								Expect(brokerServer.BindingEndpointRequests).ToNot(BeEmpty())
+							})
+						})
+
 						Context("when a request body field is missing", func() {
 							assertPOSTReturns400WhenFieldIsMissing := func(field string) {
 								JustBeforeEach(func() {
@@ -707,6 +746,26 @@ var _ = DescribeTestsFor(TestCase{
 									verifyBindingExists(ctx.SMWithOAuthForTenant, bindingID, true)
 								})
 
+								When("fetching the binding fails after polling succeeds", func() {
+									BeforeEach(func() {
+										brokerServer.BindingHandlerFunc(http.MethodGet, http.MethodGet+"1", ParameterizedHandler(http.StatusNotFound, Object{"error": "error"}))
+									})
+
+									It("orphan mitigates the binding and marks the operation as failed", func() {
+										resp := createBinding(ctx.SMWithOAuthForTenant, testCase.async, testCase.expectedBrokerFailureStatusCode)
+
+										bindingID, _ = VerifyOperationExists(ctx, resp.Header("Location").Raw(), OperationExpectations{
+											Category:          types.CREATE,
+											State:             types.FAILED,
+											ResourceType:      types.ServiceBindingType,
+											Reschedulable:     false,
+											DeletionScheduled: false,
+										})
+
+										verifyBindingDoesNotExist(ctx.SMWithOAuthForTenant, bindingID)
+									})
+								})
+
 								if testCase.async {
 									When("action timeout is reached while polling", func() {
 										var oldCtx *TestContext
@@ -1129,7 +1188,7 @@ var _ = DescribeTestsFor(TestCase{
 								It("should reject", func() {
 									if testCase.async {
 										resp := createBinding(ctx.SMWithOAuthForTenant, true, testCase.expectedCreateSuccessStatusCode)
-										_, err := ExpectOperationWithError(ctx.SMWithOAuthForTenant, resp, types.FAILED, "binding with same name exists for instance with id")
+										_, err := ExpectOperationWithError(ctx.SMWithOAuthForTenant, resp, types.FAILED, "binding with same name exists for the service instance")
 										Expect(err).ToNot(HaveOccurred())
 									} else {
 										createBinding(ctx.SMWithOAuthForTenant, false, http.StatusConflict)
@@ -1773,6 +1832,15 @@ func newServicePlan(ctx *TestContext, bindable bool) (string, *BrokerServer, str
 	return brokerID, brokerServer, servicePlanID
 }
 
+func newServicePlanWithParametersSchemas(ctx *TestContext) (string, *BrokerServer, string) {
+	catalog := NewEmptySBCatalog()
+	catalog.AddService(GenerateTestServiceWithPlans(GenerateTestPlanWithParametersSchemas()))
+	brokerID, _, brokerServer := ctx.RegisterBrokerWithCatalog(catalog)
+	ctx.Servers[BrokerServerPrefix+brokerID] = brokerServer
+	servicePlanID := findPlanIDForBrokerID(ctx, brokerID, true)
+	return brokerID, brokerServer, servicePlanID
+}
+
 func findPlanIDForBrokerID(ctx *TestContext, brokerID string, bindable bool) string {
 	so := ctx.SMWithOAuth.ListWithQuery(web.ServiceOfferingsURL, fmt.Sprintf("fieldQuery=broker_id eq '%s'", brokerID)).First()
 	servicePlanID := ctx.SMWithOAuth.ListWithQuery(web.ServicePlansURL, "fieldQuery="+fmt.Sprintf("service_offering_id eq '%s' and bindable eq %t", so.Object().Value("id").String().Raw(), bindable)).
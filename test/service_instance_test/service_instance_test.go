@@ -205,6 +205,18 @@ var _ = DescribeTestsFor(TestCase{
 				}
 			}
 
+			countOperations := func(criteria ...query.Criterion) int {
+				count, err := ctx.SMRepository.Count(context.Background(), types.OperationType, criteria...)
+				Expect(err).ToNot(HaveOccurred())
+				return count
+			}
+
+			expectParametersError := func(resp *httpexpect.Response, pointers ...interface{}) {
+				obj := resp.Status(http.StatusBadRequest).JSON().Object()
+				obj.ValueEqual("error", "BadRequest")
+				obj.Path("$.details[*].pointer").Array().ContainsOnly(pointers...)
+			}
+
 			BeforeEach(func() {
 				ID, err := uuid.NewV4()
 				Expect(err).ToNot(HaveOccurred())
@@ -297,6 +309,42 @@ var _ = DescribeTestsFor(TestCase{
 							})
 						})
 
+						When("plan has parameters schemas", func() {
+							BeforeEach(func() {
+								brokerServer, servicePlanID = prepareBrokerWithParametersSchemas(ctx)
+								EnsurePlanVisibility(ctx.SMRepository, TenantIdentifier, types.SMPlatform, servicePlanID, TenantIDValue)
+								postInstanceRequest["service_plan_id"] = servicePlanID
+							})
+
+							It("returns 400 with the violated JSON pointers before calling the broker if parameters do not match the create schema", func() {
+								byResourceType := query.ByField(query.EqualsOperator, "resource_type", string(types.ServiceInstanceType))
+								operationsCount := countOperations(byResourceType)
+								postInstanceRequest["parameters"] = Object{"size": "large"}
+
+								resp := createInstanceWithAsync(ctx.SMWithOAuthForTenant, testCase.async, http.StatusBadRequest)
+								expectParametersError(resp, "", "/size")
+
+								Expect(brokerServer.ServiceInstanceEndpointRequests).To(BeEmpty())
+								Expect(countOperations(byResourceType)).To(Equal(operationsCount))
+							})
+
+							It("provisions the instance if parameters match the create schema", func() {
+								postInstanceRequest["parameters"] = Object{"region": "eu", "size": 1}
+
+								resp := createInstanceWithAsync(ctx.SMWithOAuthForTenant, testCase.async, testCase.expectedCreateSuccessStatusCode)
+								instanceID, _ = VerifyOperationExists(ctx, resp.Header("Location").Raw(), OperationExpectations{
+									Category:          types.CREATE,
+									State:             types.SUCCEEDED,
+									ResourceType:      types.ServiceInstanceType,
+									Reschedulable:     false,
+									DeletionScheduled: false,
+								})
+
+								verifyInstanceExists(ctx, instanceID, true)
+								Expect(brokerServer.ServiceInstanceEndpointRequests).ToNot(BeEmpty())
+							})
+						})
+
 						When("a request body field is missing", func() {
 							assertPOSTReturns400WhenFieldIsMissing := func(field string) {
 								var servicePlanID string
@@ -1102,6 +1150,27 @@ var _ = DescribeTestsFor(TestCase{
 					})
 				})
 
+				When("parameters do not match the update schema of the plan", func() {
+					BeforeEach(func() {
+						brokerServer, servicePlanID = prepareBrokerWithParametersSchemas(ctx)
+						EnsurePlanVisibility(ctx.SMRepository, TenantIdentifier, types.SMPlatform, servicePlanID, TenantIDValue)
+						postInstanceRequest["service_plan_id"] = servicePlanID
+						postInstanceRequest["parameters"] = Object{"region": "eu"}
+						createInstanceWithAsync(ctx.SMWithOAuthForTenant, false, http.StatusCreated)
+						brokerServer.ResetCallHistory()
+					})
+
+					It("returns 400 with the violated JSON pointers before calling the broker", func() {
+						resp := ctx.SMWithOAuthForTenant.PATCH(web.ServiceInstancesURL + "/" + instanceID).
+							WithJSON(Object{"parameters": Object{"size": "large"}}).
+							Expect()
+						expectParametersError(resp, "/size")
+
+						Expect(brokerServer.ServiceInstanceEndpointRequests).To(BeEmpty())
+						Expect(countOperations(query.ByField(query.EqualsOperator, "resource_id", instanceID))).To(Equal(1))
+					})
+				})
+
 				When("created_at provided in body", func() {
 					It("should not change created at", func() {
 						EnsurePlanVisibility(ctx.SMRepository, TenantIdentifier, types.SMPlatform, postInstanceRequest["service_plan_id"].(string), TenantIDValue)
@@ -1178,7 +1247,7 @@ var _ = DescribeTestsFor(TestCase{
 						instance := ExpectSuccessfulAsyncResourceCreation(resp, ctx.SMWithOAuth, web.ServiceInstancesURL)
 						instanceID := instance["id"].(string)
 
-						for _, prop := range []string{"name", "maintenance_info"} {
+						for _, prop := range []string{"name"} {
 							updatedBrokerJSON := Object{}
 							updatedBrokerJSON[prop] = "updated-" + prop
 							resp = ctx.SMWithOAuthForTenant.PATCH(web.ServiceInstancesURL + "/" + instanceID).
@@ -1198,6 +1267,98 @@ var _ = DescribeTestsFor(TestCase{
 					})
 				})
 
+				When("maintenance_info does not match the plan", func() {
+					It("fails to update", func() {
+						EnsurePlanVisibility(ctx.SMRepository, TenantIdentifier, types.SMPlatform, postInstanceRequest["service_plan_id"].(string), TenantIDValue)
+						resp := createInstance(ctx.SMWithOAuthForTenant, http.StatusAccepted)
+						instance := ExpectSuccessfulAsyncResourceCreation(resp, ctx.SMWithOAuth, web.ServiceInstancesURL)
+						instanceID := instance["id"].(string)
+
+						resp = ctx.SMWithOAuthForTenant.PATCH(web.ServiceInstancesURL + "/" + instanceID).
+							WithJSON(Object{"maintenance_info": Object{"version": "2.0.0"}}).
+							Expect().
+							Status(http.StatusAccepted)
+
+						_, err := ExpectOperationWithError(ctx.SMWithOAuthForTenant, resp, types.FAILED, "does not match the maintenance_info of plan")
+						Expect(err).ToNot(HaveOccurred())
+						ctx.SMWithOAuth.GET(web.ServiceInstancesURL + "/" + instanceID).
+							Expect().
+							Status(http.StatusOK).
+							JSON().Object().
+							NotContainsKey("maintenance_info")
+					})
+				})
+
+				When("the broker fails the update and returns that the instance is not usable", func() {
+					var updateRepeatable bool
+
+					BeforeEach(func() {
+						brokerServer.ServiceInstanceHandlerFunc(http.MethodPatch, http.MethodPatch+"1", ParameterizedHandler(http.StatusAccepted, Object{"async": true}))
+						brokerServer.ServiceInstanceLastOpHandlerFunc(http.MethodPatch+"1", func(req *http.Request) (int, map[string]interface{}) {
+							return http.StatusOK, Object{
+								"state":             "failed",
+								"instance_usable":   false,
+								"update_repeatable": updateRepeatable,
+							}
+						})
+					})
+
+					updateInstanceParameters := func() (string, *httpexpect.Response) {
+						EnsurePlanVisibility(ctx.SMRepository, TenantIdentifier, types.SMPlatform, postInstanceRequest["service_plan_id"].(string), TenantIDValue)
+						resp := createInstance(ctx.SMWithOAuthForTenant, http.StatusAccepted)
+						instance := ExpectSuccessfulAsyncResourceCreation(resp, ctx.SMWithOAuth, web.ServiceInstancesURL)
+						instanceID := instance["id"].(string)
+
+						resp = ctx.SMWithOAuthForTenant.PATCH(web.ServiceInstancesURL + "/" + instanceID).
+							WithJSON(Object{"parameters": Object{"param1": "value1"}}).
+							Expect().
+							Status(http.StatusAccepted)
+						return instanceID, resp
+					}
+
+					When("the update is repeatable", func() {
+						BeforeEach(func() {
+							updateRepeatable = true
+						})
+
+						It("keeps the instance and marks it as not usable", func() {
+							instanceID, resp := updateInstanceParameters()
+
+							VerifyOperationExists(ctx, resp.Header("Location").Raw(), OperationExpectations{
+								Category:          types.UPDATE,
+								State:             types.FAILED,
+								ResourceType:      types.ServiceInstanceType,
+								Reschedulable:     false,
+								DeletionScheduled: false,
+							})
+							ctx.SMWithOAuth.GET(web.ServiceInstancesURL + "/" + instanceID).
+								Expect().
+								Status(http.StatusOK).
+								JSON().Object().
+								ContainsMap(Object{"usable": false})
+						})
+					})
+
+					When("the update is not repeatable", func() {
+						BeforeEach(func() {
+							updateRepeatable = false
+						})
+
+						It("deletes the instance and marks the operation as failed", func() {
+							instanceID, resp := updateInstanceParameters()
+
+							VerifyOperationExists(ctx, resp.Header("Location").Raw(), OperationExpectations{
+								Category:          types.UPDATE,
+								State:             types.FAILED,
+								ResourceType:      types.ServiceInstanceType,
+								Reschedulable:     false,
+								DeletionScheduled: false,
+							})
+							verifyInstanceDoesNotExist(instanceID)
+						})
+					})
+				})
+
 				Context("instance visibility", func() {
 					When("tenant doesn't have plan visibility", func() {
 						It("returns 404", func() {
@@ -1973,3 +2134,15 @@ func prepareBrokerWithCatalog(ctx *TestContext, auth *SMExpect) (string, *Broker
 
 	return brokerID, server, auth.ListWithQuery(web.ServicePlansURL, "fieldQuery="+fmt.Sprintf("service_offering_id eq '%s'", so.Object().Value("id").String().Raw()))
 }
+
+func prepareBrokerWithParametersSchemas(ctx *TestContext) (*BrokerServer, string) {
+	catalog := NewEmptySBCatalog()
+	catalog.AddService(GenerateTestServiceWithPlans(GenerateTestPlanWithParametersSchemas()))
+	brokerID, _, server := ctx.RegisterBrokerWithCatalog(catalog)
+	ctx.Servers[BrokerServerPrefix+brokerID] = server
+
+	so := ctx.SMWithOAuth.ListWithQuery(web.ServiceOfferingsURL, fmt.Sprintf("fieldQuery=broker_id eq '%s'", brokerID)).First()
+	plan := ctx.SMWithOAuth.ListWithQuery(web.ServicePlansURL, "fieldQuery="+fmt.Sprintf("service_offering_id eq '%s'", so.Object().Value("id").String().Raw())).First()
+
+	return server, plan.Object().Value("id").String().Raw()
+}
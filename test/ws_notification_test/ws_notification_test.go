@@ -19,6 +19,7 @@ package ws_notification_test
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"strconv"
 	"testing"
@@ -212,6 +213,9 @@ var _ = Describe("WS", func() {
 				_, resp, err := ctx.ConnectWebSocket(platform, queryParams)
 				Expect(resp.StatusCode).To(Equal(http.StatusGone))
 				Expect(err).Should(HaveOccurred())
+				body, err := ioutil.ReadAll(resp.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(body)).To(ContainSubstring(util.ResyncReasonRevisionAhead))
 			})
 		})
 